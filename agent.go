@@ -0,0 +1,262 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stwalsh4118/phanes/internal/agent"
+	"github.com/stwalsh4118/phanes/internal/log"
+	"github.com/stwalsh4118/phanes/internal/report"
+	"github.com/stwalsh4118/phanes/internal/runner"
+	"github.com/stwalsh4118/phanes/internal/svcmgr"
+)
+
+var (
+	agentModeFlag     string
+	agentIntervalFlag time.Duration
+	agentJitterFlag   time.Duration
+)
+
+// agentCmd groups the commands managing the periodic agent.
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Re-apply or check the configuration periodically",
+	Long: `The agent runs phanes periodically from a systemd timer, turning a one-shot
+bootstrap into continuous configuration enforcement.
+
+In apply mode, every run re-applies the profile and modules. In check mode,
+every run only previews them (like --dry-run) and reports drift: modules that
+would change something.`,
+}
+
+// agentInstallCmd installs the systemd service and timer.
+var agentInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install the systemd service and timer of the agent",
+	Long: `Install phanes-agent.service and phanes-agent.timer, and enable the timer.
+
+The timer runs the agent --interval after the previous run finished, plus a
+random delay of up to --jitter. Runs never overlap. The results of the last run
+are written to /var/lib/phanes/agent/last-run.json and every run is logged to
+/var/log/phanes/agent.log.
+
+The installed units refer to this binary and the absolute path of --config, so
+both must stay in place.`,
+	Example: `  # Re-apply the web profile every 30 minutes
+  phanes agent install --profile web --config /etc/phanes/config.yaml
+
+  # Only report drift every hour
+  phanes agent install --profile web --config /etc/phanes/config.yaml --mode check --interval 1h`,
+	Args: cobra.NoArgs,
+	RunE: runAgentInstall,
+}
+
+// agentUninstallCmd removes the systemd service and timer.
+var agentUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the systemd service and timer of the agent",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return agent.Uninstall(dryRunFlag)
+	},
+}
+
+// agentRunCmd performs a single agent run. It is started by the timer.
+var agentRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Run the agent once (started by the timer)",
+	Long: `Run the profile and modules once in apply or check mode, skipping the run if
+another one is in progress. In check mode, the command fails if drift was
+detected, so the service shows up in 'systemctl --failed'.`,
+	Args: cobra.NoArgs,
+	RunE: runAgent,
+}
+
+// agentStatusCmd shows the timer state and the last run.
+var agentStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the state of the agent and its last run",
+	Args:  cobra.NoArgs,
+	RunE:  runAgentStatus,
+}
+
+func init() {
+	install := agentInstallCmd.Flags()
+	install.StringVar(&profileFlag, "profile", "", "Profile name to enforce (e.g., 'dev', 'web', 'database')")
+	install.StringVar(&modulesFlag, "modules", "", "Comma-separated list of module names to enforce")
+	install.StringVar(&configFlag, "config", "config.yaml", "Path to configuration file")
+	install.StringVar(&agentModeFlag, "mode", string(agent.ModeApply), "What each run does: apply or check")
+	install.DurationVar(&agentIntervalFlag, "interval", agent.DefaultInterval, "Time between the end of a run and the next one")
+	install.DurationVar(&agentJitterFlag, "jitter", agent.DefaultJitter, "Maximum random delay added to each run")
+	install.BoolVarP(&yesFlag, "yes", "y", false, "Approve risky changes on every run (apply mode)")
+	install.BoolVar(&safeFlag, "safe", false, "Refuse risky changes on every run (apply mode)")
+	install.BoolVar(&dryRunFlag, "dry-run", false, "Preview the units without installing them")
+
+	agentUninstallCmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "Show what would be removed")
+
+	run := agentRunCmd.Flags()
+	run.StringVar(&profileFlag, "profile", "", "Profile name to enforce")
+	run.StringVar(&modulesFlag, "modules", "", "Comma-separated list of module names to enforce")
+	run.StringVar(&configFlag, "config", "config.yaml", "Path to configuration file")
+	run.StringVar(&agentModeFlag, "mode", string(agent.ModeApply), "What to do: apply or check")
+	run.BoolVarP(&yesFlag, "yes", "y", false, "Approve risky changes")
+	run.BoolVar(&safeFlag, "safe", false, "Refuse risky changes")
+
+	agentCmd.AddCommand(agentInstallCmd, agentUninstallCmd, agentRunCmd, agentStatusCmd)
+	rootCmd.AddCommand(agentCmd)
+}
+
+// runAgentInstall validates the selection and installs the units.
+func runAgentInstall(cmd *cobra.Command, args []string) error {
+	mode, err := agent.ParseMode(agentModeFlag)
+	if err != nil {
+		return &usageError{message: err.Error()}
+	}
+	if agentIntervalFlag < time.Minute {
+		return &usageError{message: "--interval must be at least 1m"}
+	}
+	if agentJitterFlag < 0 {
+		return &usageError{message: "--jitter must not be negative"}
+	}
+	if profileFlag == "" && modulesFlag == "" {
+		return &usageError{message: "invalid usage: either --profile or --modules must be specified"}
+	}
+	if _, err := selectModules(); err != nil {
+		return err
+	}
+
+	configPath, err := filepath.Abs(configFlag)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", configFlag, err)
+	}
+	if _, err := os.Stat(configPath); err != nil {
+		return &usageError{message: fmt.Sprintf("config file %s not found: the agent needs a config file", configPath)}
+	}
+	if _, err := loadConfig(configPath); err != nil {
+		return fmt.Errorf("config loading failed: %w", err)
+	}
+
+	binary, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate phanes binary: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(binary); err == nil {
+		binary = resolved
+	}
+
+	opts := agent.Options{
+		Binary:   binary,
+		Config:   configPath,
+		Profile:  profileFlag,
+		Mode:     mode,
+		Interval: agentIntervalFlag,
+		Jitter:   agentJitterFlag,
+		Yes:      yesFlag,
+		Safe:     safeFlag,
+	}
+	if modulesFlag != "" {
+		opts.Modules, err = parseModuleList(modulesFlag)
+		if err != nil {
+			return fmt.Errorf("module parsing failed: %w", err)
+		}
+	}
+
+	if err := agent.Install(opts, dryRunFlag); err != nil {
+		return fmt.Errorf("failed to install agent: %w", err)
+	}
+	return nil
+}
+
+// runAgent performs a single agent run under the agent lock.
+func runAgent(cmd *cobra.Command, args []string) error {
+	mode, err := agent.ParseMode(agentModeFlag)
+	if err != nil {
+		return &usageError{message: err.Error()}
+	}
+
+	unlock, err := agent.Lock()
+	if errors.Is(err, agent.ErrLocked) {
+		log.Skip("Skipping run: %v", err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Check mode is a dry run; its results show the drift
+	dryRunFlag = mode == agent.ModeCheck
+	resultsJSONFlag = agent.ResultsPath()
+	if err := os.Remove(resultsJSONFlag); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove previous results: %w", err)
+	}
+
+	log.Info("Starting agent run (%s)", mode)
+	runErr := runCommand(cmd, args)
+
+	results, err := report.ReadResults(resultsJSONFlag)
+	if err != nil {
+		results = nil
+	}
+	if err := agent.AppendLog(mode, results, runErr); err != nil {
+		log.Warn("Failed to log agent run: %v", err)
+	}
+	if runErr != nil {
+		return runErr
+	}
+
+	if mode == agent.ModeCheck && results != nil {
+		drifted := agent.Drift(results)
+		if len(drifted) > 0 {
+			log.Warn("Drift detected in: %s", strings.Join(drifted, ", "))
+			return fmt.Errorf("drift detected in %d modules: %s", len(drifted), strings.Join(drifted, ", "))
+		}
+		log.Success("No drift detected")
+	}
+	return nil
+}
+
+// runAgentStatus prints whether the timer is active and the last run.
+func runAgentStatus(cmd *cobra.Command, args []string) error {
+	if !agent.Installed() {
+		log.Info("The phanes agent is not installed")
+	} else {
+		svc, err := svcmgr.Detect()
+		if err != nil {
+			return err
+		}
+		enabled, _ := svc.IsEnabled(agent.TimerName)
+		active, _ := svc.IsActive(agent.TimerName)
+		log.Info("%s: enabled=%t active=%t", agent.TimerName, enabled, active)
+	}
+
+	results, err := report.ReadResults(agent.ResultsPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			log.Info("No agent run recorded yet")
+			return nil
+		}
+		return err
+	}
+
+	mode := agent.ModeApply
+	if results.DryRun {
+		mode = agent.ModeCheck
+	}
+	outcome := "succeeded"
+	if !results.Success {
+		outcome = "failed"
+	}
+	log.Info("Last run: %s (%s), finished %s", mode, outcome, results.FinishedAt.Local().Format(time.RFC1123))
+	runner.PrintSummary(results.ModuleResults(), results.DryRun)
+	if drifted := agent.Drift(results); len(drifted) > 0 {
+		log.Warn("Drift detected in: %s", strings.Join(drifted, ", "))
+	}
+	log.Info("Run log: %s", agent.LogPath())
+	return nil
+}
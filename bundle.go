@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/stwalsh4118/phanes/internal/bundle"
+	"github.com/stwalsh4118/phanes/internal/config"
+	"github.com/stwalsh4118/phanes/internal/download"
+	"github.com/stwalsh4118/phanes/internal/exec"
+	"github.com/stwalsh4118/phanes/internal/log"
+	"github.com/stwalsh4118/phanes/internal/module"
+	"github.com/stwalsh4118/phanes/internal/osinfo"
+	"github.com/stwalsh4118/phanes/internal/pkgmgr"
+	"github.com/stwalsh4118/phanes/internal/proxy"
+)
+
+var bundleOutputFlag string
+
+// bundleCmd creates an offline bundle for a profile or module list.
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Create an offline bundle for servers without internet access",
+	Long: `Download everything the selected modules need into a single tar archive:
+install scripts, release tarballs, repository signing keys, and the .deb
+packages with all their dependencies.
+
+Create the bundle on a connected machine running the same distribution release
+and architecture as the target servers, then copy it over and run
+'phanes --bundle bundle.tar'.`,
+	Example: `  # Bundle the dev profile
+  phanes bundle --profile dev --config config.yaml -o bundle.tar
+
+  # Install on the offline server
+  phanes --profile dev --config config.yaml --bundle bundle.tar`,
+	Args: cobra.NoArgs,
+	RunE: runBundle,
+}
+
+func init() {
+	bundleCmd.Flags().StringVar(&profileFlag, "profile", "", "Profile name to bundle (e.g., 'dev', 'web', 'database')")
+	bundleCmd.Flags().StringVar(&modulesFlag, "modules", "", "Comma-separated list of module names to bundle")
+	bundleCmd.Flags().StringVar(&configFlag, "config", "config.yaml", "Path to configuration file")
+	bundleCmd.Flags().StringVarP(&bundleOutputFlag, "output", "o", "bundle.tar", "Path of the bundle to write")
+	rootCmd.AddCommand(bundleCmd)
+}
+
+// runBundle creates the bundle for the selected modules.
+func runBundle(cmd *cobra.Command, args []string) error {
+	if profileFlag == "" && modulesFlag == "" {
+		return &usageError{message: "invalid usage: either --profile or --modules must be specified"}
+	}
+
+	cfg, err := loadConfig(configFlag)
+	if err != nil {
+		return fmt.Errorf("config loading failed: %w", err)
+	}
+	download.Configure(cfg.Downloads)
+	proxy.Configure(cfg.Network.Proxy)
+
+	moduleNames, err := selectModules()
+	if err != nil {
+		return err
+	}
+
+	requirements, err := collectRequirements(moduleNames, cfg)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Creating offline bundle for: %s", strings.Join(moduleNames, ", "))
+	manifest, err := bundle.Create(bundleOutputFlag, bundle.Options{
+		Modules:      moduleNames,
+		Requirements: requirements,
+		Version:      version,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+
+	log.Success("Wrote %s for %s (%d files)", bundleOutputFlag, manifest.OS, len(manifest.Files))
+	warnNeedsNetwork(manifest)
+	return nil
+}
+
+// collectRequirements returns what each module downloads during installation.
+// Modules that download nothing have no entry.
+func collectRequirements(moduleNames []string, cfg *config.Config) (map[string]module.Requirements, error) {
+	available := make(map[string]module.Module)
+	for _, mod := range allModules() {
+		available[mod.Name()] = mod
+	}
+
+	requirements := make(map[string]module.Requirements)
+	for _, name := range moduleNames {
+		mod, ok := available[name]
+		if !ok {
+			return nil, &usageError{message: fmt.Sprintf("unknown module: %s", name)}
+		}
+		bundler, ok := mod.(module.Bundler)
+		if !ok {
+			continue
+		}
+		reqs, err := bundler.Requirements(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine downloads of %s: %w", name, err)
+		}
+		requirements[name] = reqs
+	}
+	return requirements, nil
+}
+
+// useBundle verifies the bundle at path and serves downloads and packages from
+// it for the rest of the run. In dry-run mode the bundle is only verified.
+func useBundle(path string, moduleNames []string, dryRun bool) error {
+	var manifest *bundle.Manifest
+	var err error
+	if dryRun {
+		manifest, err = bundle.Verify(path)
+	} else {
+		manifest, err = bundle.Extract(path, bundle.DefaultDir)
+	}
+	if err != nil {
+		return err
+	}
+
+	info, err := osinfo.Detect()
+	if err != nil {
+		return fmt.Errorf("failed to detect operating system: %w", err)
+	}
+	if err := manifest.CheckOS(info); err != nil {
+		if !dryRun {
+			os.RemoveAll(exec.Path(bundle.DefaultDir))
+		}
+		return err
+	}
+
+	log.Info("Using offline bundle %s (created %s with phanes %s)", path, manifest.Created.Format("2006-01-02"), manifest.PhanesVersion)
+
+	bundled := make(map[string]bool)
+	for _, name := range manifest.Modules {
+		bundled[name] = true
+	}
+	for _, name := range moduleNames {
+		if !bundled[name] {
+			log.Warn("Module %s is not in the bundle and may need network access", name)
+		}
+	}
+	warnNeedsNetwork(manifest)
+
+	if dryRun {
+		return nil
+	}
+	download.UseLocal(manifest.Sources(bundle.DefaultDir))
+	if dir := manifest.PackagesDir(bundle.DefaultDir); dir != "" {
+		if err := pkgmgr.UseLocalRepository(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// warnNeedsNetwork warns about the bundled modules that still need network
+// access during installation.
+func warnNeedsNetwork(manifest *bundle.Manifest) {
+	for _, name := range manifest.Modules {
+		if reason, ok := manifest.NeedsNetwork[name]; ok {
+			log.Warn("Module %s still needs network access: %s", name, reason)
+		}
+	}
+}
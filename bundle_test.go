@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stwalsh4118/phanes/internal/config"
+)
+
+func TestCollectRequirements(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Redis.Enabled = true
+
+	requirements, err := collectRequirements([]string{"baseline", "docker", "redis"}, cfg)
+	if err != nil {
+		t.Fatalf("collectRequirements() error = %v", err)
+	}
+	if _, ok := requirements["baseline"]; ok {
+		t.Error("baseline downloads nothing and should have no requirements")
+	}
+	if len(requirements["docker"].Packages) == 0 || len(requirements["docker"].Repositories) != 1 {
+		t.Errorf("docker requirements = %+v", requirements["docker"])
+	}
+	if len(requirements["redis"].Packages) == 0 {
+		t.Errorf("redis requirements = %+v", requirements["redis"])
+	}
+
+	var usageErr *usageError
+	if _, err := collectRequirements([]string{"nope"}, cfg); !errors.As(err, &usageErr) {
+		t.Errorf("collectRequirements() with an unknown module error = %v, want usage error", err)
+	}
+}
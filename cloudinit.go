@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/stwalsh4118/phanes/internal/cloudinit"
+	"github.com/stwalsh4118/phanes/internal/config"
+	"github.com/stwalsh4118/phanes/internal/download"
+	"github.com/stwalsh4118/phanes/internal/proxy"
+)
+
+var (
+	cloudInitOutputFlag    string
+	cloudInitReleaseFlag   string
+	cloudInitChecksumsFlag string
+)
+
+// cloudInitCmd generates cloud-init user data.
+var cloudInitCmd = &cobra.Command{
+	Use:   "cloud-init",
+	Short: "Generate cloud-init user data that provisions a server on first boot",
+	Long: `Generate a #cloud-config document for cloud providers. On first boot it
+writes the configuration to /etc/phanes/config.yaml, downloads the pinned
+phanes release for the server's architecture, verifies its SHA-256 checksum,
+installs it to /usr/local/bin/phanes, and runs the profile and modules.
+
+Output goes to /var/log/phanes/cloud-init.log and the results of the run to
+/var/log/phanes/cloud-init-results.json.
+
+The checksums are taken from the checksums.txt published with the release, or
+from --checksums-file. First-boot runs are non-interactive, so risky changes
+are declined unless --yes is given.`,
+	Example: `  # Generate user data for the web profile
+  phanes cloud-init --profile web --config config.yaml -o user-data.yaml
+
+  # Pin another release with a local checksums file
+  phanes cloud-init --profile web --config config.yaml --release v0.2.0 --checksums-file checksums.txt`,
+	Args: cobra.NoArgs,
+	RunE: runCloudInit,
+}
+
+func init() {
+	flags := cloudInitCmd.Flags()
+	flags.StringVar(&profileFlag, "profile", "", "Profile name to run on first boot (e.g., 'dev', 'web', 'database')")
+	flags.StringVar(&modulesFlag, "modules", "", "Comma-separated list of module names to run on first boot")
+	flags.StringVar(&configFlag, "config", "config.yaml", "Path to configuration file")
+	flags.StringVarP(&cloudInitOutputFlag, "output", "o", "-", "Path of the user data to write (- for stdout)")
+	flags.StringVar(&cloudInitReleaseFlag, "release", "v"+version, "phanes release to install")
+	flags.StringVar(&cloudInitChecksumsFlag, "checksums-file", "", "Local checksums.txt of the release (default: downloaded from the release)")
+	flags.BoolVarP(&yesFlag, "yes", "y", false, "Approve risky changes on the first-boot run")
+	flags.BoolVar(&safeFlag, "safe", false, "Refuse risky changes on the first-boot run")
+	rootCmd.AddCommand(cloudInitCmd)
+}
+
+// runCloudInit writes the user data. It does not log to stdout, which may
+// receive the user data.
+func runCloudInit(cmd *cobra.Command, args []string) error {
+	if profileFlag == "" && modulesFlag == "" {
+		return &usageError{message: "invalid usage: either --profile or --modules must be specified"}
+	}
+	var modules []string
+	for _, name := range strings.Split(modulesFlag, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			modules = append(modules, name)
+		}
+	}
+	if err := validateSelection(profileFlag, modules); err != nil {
+		return &usageError{message: err.Error()}
+	}
+
+	content, err := os.ReadFile(configFlag)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	cfg, err := config.Parse(content)
+	if err != nil {
+		return fmt.Errorf("invalid config %s: %w", configFlag, err)
+	}
+	download.Configure(cfg.Downloads)
+	proxy.Configure(cfg.Network.Proxy)
+
+	release := cloudInitReleaseFlag
+	if !strings.HasPrefix(release, "v") {
+		release = "v" + release
+	}
+	var checksums []byte
+	if cloudInitChecksumsFlag != "" {
+		checksums, err = os.ReadFile(cloudInitChecksumsFlag)
+	} else {
+		checksums, err = download.Get(cloudinit.ChecksumsURL(release))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get checksums of release %s: %w", release, err)
+	}
+	sums, err := cloudinit.ParseChecksums(checksums, release)
+	if err != nil {
+		return err
+	}
+
+	userData, err := cloudinit.Generate(cloudinit.Options{
+		Version:   release,
+		Checksums: sums,
+		Config:    content,
+		Profile:   profileFlag,
+		Modules:   modules,
+		Yes:       yesFlag,
+		Safe:      safeFlag,
+		Env:       proxy.Env(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if cloudInitOutputFlag == "-" {
+		_, err = cmd.OutOrStdout().Write(userData)
+		return err
+	}
+	// The user data contains the configuration, which may hold secrets
+	if err := os.WriteFile(cloudInitOutputFlag, userData, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", cloudInitOutputFlag, err)
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Wrote cloud-init user data to %s\n", cloudInitOutputFlag)
+	return nil
+}
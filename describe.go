@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/stwalsh4118/phanes/internal/config"
+	"github.com/stwalsh4118/phanes/internal/module"
+	"github.com/stwalsh4118/phanes/internal/profile"
+)
+
+const (
+	describeFormatText = "text"
+	describeFormatJSON = "json"
+)
+
+var describeFormatFlag string
+
+// describeCmd shows the full metadata of a single module.
+var describeCmd = &cobra.Command{
+	Use:   "describe <module>",
+	Short: "Show detailed information about a module",
+	Long: `Show what a module reads and manages: the config keys it uses with their
+defaults, the files and services it manages, the ports it opens, the packages
+it installs, the remote URLs it uses, and the profiles that include it.`,
+	Example: `  # Describe the docker module
+  phanes describe docker
+
+  # Machine-readable output
+  phanes describe postgres --format json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDescribe,
+}
+
+func init() {
+	describeCmd.Flags().StringVar(&describeFormatFlag, "format", describeFormatText, "Output format (text or json)")
+	rootCmd.AddCommand(describeCmd)
+}
+
+// configKeyDescription is a config key together with its default value.
+type configKeyDescription struct {
+	Key         string      `json:"key"`
+	Default     interface{} `json:"default"`
+	Description string      `json:"description"`
+}
+
+// moduleDescription is the full description of a module as shown by describe.
+type moduleDescription struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Profiles    []string               `json:"profiles"`
+	Requires    []string               `json:"requires"`
+	ConfigKeys  []configKeyDescription `json:"config_keys"`
+	Files       []string               `json:"files"`
+	Services    []string               `json:"services"`
+	Ports       []module.Port          `json:"ports"`
+	Packages    []string               `json:"packages"`
+	URLs        []string               `json:"urls"`
+}
+
+// runDescribe looks up the requested module and prints its description.
+func runDescribe(cmd *cobra.Command, args []string) error {
+	if describeFormatFlag != describeFormatText && describeFormatFlag != describeFormatJSON {
+		return &usageError{message: fmt.Sprintf("invalid usage: unknown format %q (use %s or %s)", describeFormatFlag, describeFormatText, describeFormatJSON)}
+	}
+
+	name := args[0]
+	var mod module.Module
+	var names []string
+	for _, m := range allModules() {
+		names = append(names, m.Name())
+		if m.Name() == name {
+			mod = m
+		}
+	}
+	if mod == nil {
+		sort.Strings(names)
+		return &usageError{message: fmt.Sprintf("invalid usage: module %s not found (available: %s)", name, strings.Join(names, ", "))}
+	}
+
+	desc, err := describeModule(mod)
+	if err != nil {
+		return err
+	}
+
+	if describeFormatFlag == describeFormatJSON {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(desc); err != nil {
+			return fmt.Errorf("failed to encode module description: %w", err)
+		}
+		return nil
+	}
+
+	printModuleDescription(cmd.OutOrStdout(), desc)
+	return nil
+}
+
+// describeModule builds the description of mod, resolving config key defaults
+// from the default configuration. Modules that do not implement module.Describer
+// are described by name, description, and profiles only.
+func describeModule(mod module.Module) (moduleDescription, error) {
+	desc := moduleDescription{
+		Name:        mod.Name(),
+		Description: mod.Description(),
+		Profiles:    profile.ProfilesContaining(mod.Name()),
+		ConfigKeys:  []configKeyDescription{},
+	}
+
+	var meta module.Metadata
+	if describer, ok := mod.(module.Describer); ok {
+		meta = describer.Metadata()
+	}
+
+	defaults := config.DefaultConfig()
+	for _, key := range meta.ConfigKeys {
+		value, err := defaults.Lookup(key.Key)
+		if err != nil {
+			return moduleDescription{}, fmt.Errorf("module %s: %w", mod.Name(), err)
+		}
+		desc.ConfigKeys = append(desc.ConfigKeys, configKeyDescription{
+			Key:         key.Key,
+			Default:     value,
+			Description: key.Description,
+		})
+	}
+
+	desc.Requires = nonNil(meta.Requires)
+	desc.Files = nonNil(meta.Files)
+	desc.Services = nonNil(meta.Services)
+	desc.Packages = nonNil(meta.Packages)
+	desc.URLs = nonNil(meta.URLs)
+	desc.Ports = meta.Ports
+	if desc.Ports == nil {
+		desc.Ports = []module.Port{}
+	}
+
+	return desc, nil
+}
+
+// printModuleDescription writes a human-readable module description to w.
+func printModuleDescription(w io.Writer, desc moduleDescription) {
+	fmt.Fprintf(w, "%s - %s\n", desc.Name, desc.Description)
+
+	fmt.Fprintf(w, "\nProfiles:\n")
+	printList(w, desc.Profiles)
+
+	fmt.Fprintf(w, "\nRequires:\n")
+	printList(w, desc.Requires)
+
+	fmt.Fprintf(w, "\nConfig keys:\n")
+	if len(desc.ConfigKeys) == 0 {
+		fmt.Fprintf(w, "  (none)\n")
+	}
+	for _, key := range desc.ConfigKeys {
+		fmt.Fprintf(w, "  %s (default: %s)\n", key.Key, formatDefault(key.Default))
+		if key.Description != "" {
+			fmt.Fprintf(w, "      %s\n", key.Description)
+		}
+	}
+
+	fmt.Fprintf(w, "\nFiles:\n")
+	printList(w, desc.Files)
+
+	fmt.Fprintf(w, "\nServices:\n")
+	printList(w, desc.Services)
+
+	fmt.Fprintf(w, "\nPorts:\n")
+	if len(desc.Ports) == 0 {
+		fmt.Fprintf(w, "  (none)\n")
+	}
+	for _, port := range desc.Ports {
+		fmt.Fprintf(w, "  %d/%s  %s\n", port.Number, port.Protocol, port.Description)
+	}
+
+	fmt.Fprintf(w, "\nPackages:\n")
+	printList(w, desc.Packages)
+
+	fmt.Fprintf(w, "\nURLs:\n")
+	printList(w, desc.URLs)
+}
+
+// printList writes each item on its own indented line, or "(none)" if empty.
+func printList(w io.Writer, items []string) {
+	if len(items) == 0 {
+		fmt.Fprintf(w, "  (none)\n")
+		return
+	}
+	for _, item := range items {
+		fmt.Fprintf(w, "  %s\n", item)
+	}
+}
+
+// formatDefault formats a default config value for text output.
+// Empty strings are shown as "" so they are distinguishable from missing values.
+func formatDefault(value interface{}) string {
+	if s, ok := value.(string); ok && s == "" {
+		return `""`
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// nonNil returns items, or an empty slice if items is nil, so JSON output
+// always contains arrays.
+func nonNil(items []string) []string {
+	if items == nil {
+		return []string{}
+	}
+	return items
+}
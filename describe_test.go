@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stwalsh4118/phanes/internal/module"
+)
+
+func TestDescribeModule_AllModules(t *testing.T) {
+	names := make(map[string]bool)
+	for _, mod := range allModules() {
+		names[mod.Name()] = true
+	}
+
+	for _, mod := range allModules() {
+		t.Run(mod.Name(), func(t *testing.T) {
+			if _, ok := mod.(module.Describer); !ok {
+				t.Errorf("module %s does not implement module.Describer", mod.Name())
+			}
+
+			// Every config key must resolve against the default config
+			desc, err := describeModule(mod)
+			if err != nil {
+				t.Fatalf("describeModule() error = %v", err)
+			}
+			if desc.Name != mod.Name() {
+				t.Errorf("describeModule().Name = %q, want %q", desc.Name, mod.Name())
+			}
+			for _, required := range desc.Requires {
+				if !names[required] {
+					t.Errorf("module %s requires unknown module %s", mod.Name(), required)
+				}
+			}
+		})
+	}
+}
+
+func TestPrintModuleDescription(t *testing.T) {
+	desc := moduleDescription{
+		Name:        "redis",
+		Description: "Installs Redis",
+		Profiles:    []string{"database"},
+		ConfigKeys: []configKeyDescription{
+			{Key: "redis.password", Default: "", Description: "Password required by Redis clients"},
+			{Key: "redis.enabled", Default: true},
+		},
+		Files: []string{"/etc/redis/redis.conf"},
+		Ports: []module.Port{{Number: 6379, Protocol: "tcp", Description: "Redis"}},
+	}
+
+	var buf bytes.Buffer
+	printModuleDescription(&buf, desc)
+	output := buf.String()
+
+	for _, want := range []string{
+		"redis - Installs Redis",
+		"  database",
+		`redis.password (default: "")`,
+		"redis.enabled (default: true)",
+		"/etc/redis/redis.conf",
+		"6379/tcp  Redis",
+		"Services:\n  (none)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("printModuleDescription() output missing %q\nOutput:\n%s", want, output)
+		}
+	}
+}
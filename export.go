@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/stwalsh4118/phanes/internal/config"
+	"github.com/stwalsh4118/phanes/internal/export"
+	"github.com/stwalsh4118/phanes/internal/module"
+	"github.com/stwalsh4118/phanes/internal/profile"
+)
+
+var (
+	exportFormatFlag string
+	exportOutputFlag string
+)
+
+// exportCmd writes a run as a shell script.
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export a run as a standalone shell script",
+	Long: `Write the checks and commands the selected modules perform as an idempotent
+bash script, for audits and for hosts that cannot run the phanes binary. The
+script targets Debian and Ubuntu and must run as root.
+
+Secrets (passwords, auth keys) are not written into the script. It reads them
+from environment variables listed at its top, and refuses to run until they
+are set. Modules that cannot be exported are reported as an error.`,
+	Example: `  # Export the minimal profile
+  phanes export --format sh --profile minimal --config config.yaml -o provision.sh
+
+  # Run it on a host, supplying the secrets
+  sudo REDIS_PASSWORD=... bash provision.sh`,
+	Args: cobra.NoArgs,
+	RunE: runExport,
+}
+
+func init() {
+	flags := exportCmd.Flags()
+	flags.StringVar(&exportFormatFlag, "format", "sh", "Output format (sh)")
+	flags.StringVar(&profileFlag, "profile", "", "Profile name to export (e.g., 'dev', 'web', 'database')")
+	flags.StringVar(&modulesFlag, "modules", "", "Comma-separated list of module names to export")
+	flags.StringVar(&configFlag, "config", "config.yaml", "Path to configuration file")
+	flags.StringVarP(&exportOutputFlag, "output", "o", "-", "Path of the script to write (- for stdout)")
+	rootCmd.AddCommand(exportCmd)
+}
+
+// runExport writes the script. It does not log to stdout, which may receive
+// the script.
+func runExport(cmd *cobra.Command, args []string) error {
+	if exportFormatFlag != "sh" {
+		return &usageError{message: fmt.Sprintf("invalid usage: unsupported format %q (supported: sh)", exportFormatFlag)}
+	}
+	if profileFlag == "" && modulesFlag == "" {
+		return &usageError{message: "invalid usage: either --profile or --modules must be specified"}
+	}
+
+	var selected []string
+	for _, name := range strings.Split(modulesFlag, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			selected = append(selected, name)
+		}
+	}
+	if err := validateSelection(profileFlag, selected); err != nil {
+		return &usageError{message: err.Error()}
+	}
+	var profileModules []string
+	if profileFlag != "" {
+		modules, err := profile.GetProfile(profileFlag)
+		if err != nil {
+			return err
+		}
+		profileModules = modules
+	}
+	moduleNames := combineModules(profileModules, selected)
+
+	cfg, err := config.Load(configFlag)
+	if err != nil {
+		return fmt.Errorf("invalid config %s: %w", configFlag, err)
+	}
+
+	title := fmt.Sprintf("Modules: %s", strings.Join(moduleNames, ", "))
+	if profileFlag != "" {
+		title = fmt.Sprintf("Profile %s. %s", profileFlag, title)
+	}
+	script, err := exportScript(moduleNames, cfg, fmt.Sprintf("Exported by phanes %s. %s", version, title))
+	if err != nil {
+		return err
+	}
+
+	if exportOutputFlag == "-" {
+		_, err = cmd.OutOrStdout().Write(script)
+		return err
+	}
+	if err := os.WriteFile(exportOutputFlag, script, 0700); err != nil {
+		return fmt.Errorf("failed to write %s: %w", exportOutputFlag, err)
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Wrote %s\n", exportOutputFlag)
+	return nil
+}
+
+// exportScript returns the script performing the modules' installation with
+// cfg. All modules must implement module.Exporter.
+func exportScript(moduleNames []string, cfg *config.Config, title string) ([]byte, error) {
+	available := make(map[string]module.Module)
+	for _, mod := range allModules() {
+		available[mod.Name()] = mod
+	}
+
+	var exporters []module.Exporter
+	var unsupported []string
+	for _, name := range moduleNames {
+		mod, ok := available[name]
+		if !ok {
+			return nil, &usageError{message: fmt.Sprintf("unknown module: %s", name)}
+		}
+		exporter, ok := mod.(module.Exporter)
+		if !ok {
+			unsupported = append(unsupported, name)
+			continue
+		}
+		exporters = append(exporters, exporter)
+	}
+	if len(unsupported) > 0 {
+		return nil, fmt.Errorf("modules cannot be exported as a script: %s", strings.Join(unsupported, ", "))
+	}
+
+	script := export.New(title)
+	for i, exporter := range exporters {
+		mod := available[moduleNames[i]]
+		script.Module(mod.Name(), mod.Description())
+		if err := exporter.Export(cfg, script); err != nil {
+			return nil, fmt.Errorf("failed to export module %s: %w", mod.Name(), err)
+		}
+	}
+	return script.Bytes(), nil
+}
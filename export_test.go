@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stwalsh4118/phanes/internal/config"
+	"github.com/stwalsh4118/phanes/internal/profile"
+)
+
+func TestExportScript(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.User.Username = "deploy"
+	cfg.User.SSHPublicKey = "ssh-ed25519 AAAA test"
+	cfg.Redis.Password = "hunter2"
+
+	modules, err := profile.GetProfile("minimal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := exportScript(append(modules, "redis"), cfg, "test")
+	if err != nil {
+		t.Fatalf("exportScript() error = %v", err)
+	}
+	for _, name := range append(modules, "redis") {
+		if !strings.Contains(string(script), "\nlog 'module "+name+"'\n") {
+			t.Errorf("script has no steps for %s", name)
+		}
+	}
+	if strings.Contains(string(script), "hunter2") {
+		t.Error("script must not contain secrets")
+	}
+	if !strings.Contains(string(script), `"${REDIS_PASSWORD}"`) {
+		t.Error("script should read the Redis password from the environment")
+	}
+
+	if _, err := exportScript([]string{"baseline", "docker"}, cfg, "test"); err == nil || !strings.Contains(err.Error(), "docker") {
+		t.Errorf("exportScript() with docker error = %v, want unsupported module", err)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/stwalsh4118/phanes/internal/fleet"
+	"github.com/stwalsh4118/phanes/internal/inventory"
+	"github.com/stwalsh4118/phanes/internal/log"
+	"github.com/stwalsh4118/phanes/internal/remote"
+	"github.com/stwalsh4118/phanes/internal/report"
+)
+
+var (
+	inventoryFlag   string
+	limitFlag       string
+	parallelFlag    int
+	batchSizeFlag   int
+	maxFailuresFlag int
+)
+
+// fleetCmd groups the commands operating on an inventory of hosts.
+var fleetCmd = &cobra.Command{
+	Use:   "fleet",
+	Short: "Provision many servers from an inventory file",
+}
+
+// fleetApplyCmd provisions the hosts of an inventory over SSH.
+var fleetApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Provision the hosts of an inventory over SSH",
+	Long: `Provision every host of an inventory file (or the hosts and groups given
+with --limit) over SSH, like --remote does for a single server.
+
+Each host gets its own configuration: the base config file, with the overlays
+of the inventory defaults, its groups and the host itself merged on top.
+
+Hosts are provisioned in rolling batches of --batch-size hosts, up to
+--parallel at a time. When more than --max-failures hosts have failed, no
+further batches are started. Output is prefixed with the host name, and a
+per-host summary is printed at the end.
+
+Hosts run without a terminal: sudo on the servers must not ask for a password,
+and risky changes are declined unless --yes is given.`,
+	Example: `  # Provision all hosts, 5 at a time
+  phanes fleet apply -i inventory.yaml
+
+  # Preview the web group
+  phanes fleet apply -i inventory.yaml --limit web --dry-run
+
+  # Roll out in batches of 10, stopping once a host fails
+  phanes fleet apply -i inventory.yaml --batch-size 10 --max-failures 0 --yes`,
+	Args: cobra.NoArgs,
+	RunE: runFleetApply,
+}
+
+func init() {
+	flags := fleetApplyCmd.Flags()
+	flags.StringVarP(&inventoryFlag, "inventory", "i", "inventory.yaml", "Path to the inventory file")
+	flags.StringVar(&limitFlag, "limit", "", "Comma-separated list of hosts and groups to provision (default: all hosts)")
+	flags.IntVar(&parallelFlag, "parallel", 5, "Maximum number of hosts provisioned at the same time")
+	flags.IntVar(&batchSizeFlag, "batch-size", 0, "Number of hosts per rolling batch (default: all hosts in one batch)")
+	flags.IntVar(&maxFailuresFlag, "max-failures", 0, "Number of failed hosts tolerated before remaining batches are skipped")
+	flags.BoolVar(&dryRunFlag, "dry-run", false, "Preview changes on every host without applying them")
+	flags.BoolVar(&yesFlag, "yes", false, "Apply risky changes without confirmation")
+	flags.BoolVar(&safeFlag, "safe", false, "Skip risky changes")
+	flags.StringVar(&remoteBinaryFlag, "remote-binary", "", "phanes binary to upload (default: this binary or phanes-linux-<arch> next to it)")
+	fleetCmd.AddCommand(fleetApplyCmd)
+	rootCmd.AddCommand(fleetCmd)
+}
+
+// runFleetApply provisions the selected hosts of the inventory.
+func runFleetApply(cmd *cobra.Command, args []string) error {
+	if parallelFlag < 1 {
+		return &usageError{message: "--parallel must be at least 1"}
+	}
+	if batchSizeFlag < 0 || maxFailuresFlag < 0 {
+		return &usageError{message: "--batch-size and --max-failures must not be negative"}
+	}
+
+	inv, err := inventory.Load(inventoryFlag)
+	if err != nil {
+		return err
+	}
+	var limit []string
+	for _, name := range strings.Split(limitFlag, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			limit = append(limit, name)
+		}
+	}
+	hosts, err := inv.Select(limit)
+	if err != nil {
+		return fmt.Errorf("failed to resolve inventory %s: %w", inventoryFlag, err)
+	}
+	for _, host := range hosts {
+		if err := validateHost(host); err != nil {
+			return &usageError{message: fmt.Sprintf("host %s: %v", host.Name, err)}
+		}
+	}
+
+	names := make([]string, len(hosts))
+	for i, host := range hosts {
+		names[i] = host.Name
+	}
+	log.Info("Provisioning %d hosts: %s", len(hosts), strings.Join(names, ", "))
+
+	results := fleet.Apply(hosts, fleet.Options{
+		Parallel:    parallelFlag,
+		BatchSize:   batchSizeFlag,
+		MaxFailures: maxFailuresFlag,
+		Output:      os.Stdout,
+		Provision:   provisionHost,
+	})
+
+	fmt.Println()
+	fleet.PrintSummary(os.Stdout, results)
+
+	if failed := fleet.Failed(results); failed > 0 {
+		return fmt.Errorf("%d of %d hosts failed", failed, len(results))
+	}
+	return nil
+}
+
+// validateHost checks the profile, modules and address of a host before
+// anything runs.
+func validateHost(host inventory.Host) error {
+	if err := validateSelection(host.Profile, host.Modules); err != nil {
+		return err
+	}
+	if _, err := remote.ParseTarget(host.Address); err != nil {
+		return err
+	}
+	return nil
+}
+
+// provisionHost provisions a single host over SSH with its resolved
+// configuration.
+func provisionHost(host inventory.Host, out io.Writer) (*report.Results, error) {
+	target, err := remote.ParseTarget(host.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	configFile, err := os.CreateTemp("", "phanes-host-*.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config file: %w", err)
+	}
+	defer os.Remove(configFile.Name())
+	if _, err := configFile.Write(host.Config); err != nil {
+		configFile.Close()
+		return nil, fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := configFile.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	fmt.Fprintf(out, "Provisioning %s over SSH\n", target)
+	transport := &remote.SSH{Target: target, Options: []string{"-o", "BatchMode=yes"}}
+	return remote.Provision(transport, remote.Options{
+		Binary: remoteBinaryFlag,
+		Config: configFile.Name(),
+		Args:   remoteArgs(host.Profile, strings.Join(host.Modules, ",")),
+		Stdout: out,
+		Stderr: out,
+	})
+}
@@ -0,0 +1,232 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/stwalsh4118/phanes/internal/exec"
+	"github.com/stwalsh4118/phanes/internal/files"
+	"github.com/stwalsh4118/phanes/internal/log"
+	"github.com/stwalsh4118/phanes/internal/svcmgr"
+)
+
+// Mode is what a periodic run does.
+type Mode string
+
+const (
+	// ModeApply re-applies the profile and modules on every run.
+	ModeApply Mode = "apply"
+
+	// ModeCheck only checks the profile and modules with a dry run and
+	// reports drift.
+	ModeCheck Mode = "check"
+)
+
+const (
+	// ServiceName and TimerName are the systemd units of the agent.
+	ServiceName = "phanes-agent.service"
+	TimerName   = "phanes-agent.timer"
+
+	// DefaultInterval is the time between the end of a run and the next one.
+	DefaultInterval = 30 * time.Minute
+
+	// DefaultJitter is the maximum random delay added to each run.
+	DefaultJitter = 5 * time.Minute
+
+	// bootDelay is the time after boot before the first run.
+	bootDelay = 5 * time.Minute
+
+	unitPerm = 0644
+)
+
+// The unit, state and log directories. They are variables so tests can use
+// temporary directories.
+var (
+	unitDir  = "/etc/systemd/system"
+	stateDir = "/var/lib/phanes/agent"
+	logDir   = "/var/log/phanes"
+
+	detect = svcmgr.Detect
+)
+
+// Options describes the periodic run.
+type Options struct {
+	// Binary is the absolute path of the phanes binary to run.
+	Binary string
+
+	// Config is the absolute path of the configuration file.
+	Config string
+
+	// Profile and Modules select what to run.
+	Profile string
+	Modules []string
+
+	// Mode is what each run does.
+	Mode Mode
+
+	// Interval is the time between the end of a run and the next one.
+	Interval time.Duration
+
+	// Jitter is the maximum random delay added to each run.
+	Jitter time.Duration
+
+	// Yes approves risky changes, Safe refuses them (ModeApply only).
+	Yes  bool
+	Safe bool
+}
+
+// ParseMode parses "apply" or "check".
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case ModeApply, ModeCheck:
+		return Mode(s), nil
+	}
+	return "", fmt.Errorf("invalid mode %q: must be %s or %s", s, ModeApply, ModeCheck)
+}
+
+// Args returns the arguments of 'phanes agent run' for opts.
+func (o Options) Args() []string {
+	args := []string{"agent", "run", "--mode", string(o.Mode), "--config", o.Config}
+	if o.Profile != "" {
+		args = append(args, "--profile", o.Profile)
+	}
+	if len(o.Modules) > 0 {
+		args = append(args, "--modules", strings.Join(o.Modules, ","))
+	}
+	if o.Yes {
+		args = append(args, "--yes")
+	}
+	if o.Safe {
+		args = append(args, "--safe")
+	}
+	return args
+}
+
+// ServiceUnit returns the systemd service that runs the agent once.
+func ServiceUnit(opts Options) string {
+	command := []string{svcmgr.QuoteExecArg(opts.Binary)}
+	for _, arg := range opts.Args() {
+		command = append(command, svcmgr.QuoteExecArg(arg))
+	}
+
+	var b strings.Builder
+	b.WriteString("# Managed by phanes (agent)\n")
+	b.WriteString("[Unit]\n")
+	fmt.Fprintf(&b, "Description=phanes agent (%s)\n", opts.Mode)
+	b.WriteString("Wants=network-online.target\n")
+	b.WriteString("After=network-online.target\n")
+	b.WriteString("\n[Service]\n")
+	b.WriteString("Type=oneshot\n")
+	fmt.Fprintf(&b, "ExecStart=%s\n", strings.Join(command, " "))
+	return b.String()
+}
+
+// TimerUnit returns the systemd timer that starts the service periodically.
+func TimerUnit(opts Options) string {
+	var b strings.Builder
+	b.WriteString("# Managed by phanes (agent)\n")
+	b.WriteString("[Unit]\n")
+	fmt.Fprintf(&b, "Description=Run the phanes agent every %s\n", opts.Interval)
+	b.WriteString("\n[Timer]\n")
+	fmt.Fprintf(&b, "OnBootSec=%s\n", systemdDuration(bootDelay))
+	fmt.Fprintf(&b, "OnUnitInactiveSec=%s\n", systemdDuration(opts.Interval))
+	fmt.Fprintf(&b, "RandomizedDelaySec=%s\n", systemdDuration(opts.Jitter))
+	b.WriteString("\n[Install]\n")
+	b.WriteString("WantedBy=timers.target\n")
+	return b.String()
+}
+
+// Install writes the service and timer and enables the timer. On a dry run,
+// the changes to the units are previewed instead.
+func Install(opts Options, dryRun bool) error {
+	svc, err := detect()
+	if err != nil {
+		return err
+	}
+	if svc.Name() != svcmgr.NameSystemd {
+		return fmt.Errorf("the agent requires systemd (found %s)", svc.Name())
+	}
+
+	units := []struct{ name, content string }{
+		{ServiceName, ServiceUnit(opts)},
+		{TimerName, TimerUnit(opts)},
+	}
+	if dryRun {
+		for _, unit := range units {
+			if _, err := files.Preview(unitPath(unit.name), []byte(unit.content)); err != nil {
+				return fmt.Errorf("failed to preview %s: %w", unitPath(unit.name), err)
+			}
+		}
+		log.Info("Would enable and start %s", TimerName)
+		return nil
+	}
+
+	for _, unit := range units {
+		if _, err := files.Write(unitPath(unit.name), []byte(unit.content), files.Options{Mode: unitPerm}); err != nil {
+			return fmt.Errorf("failed to write %s: %w", unitPath(unit.name), err)
+		}
+	}
+	if err := exec.Run("systemctl", "daemon-reload"); err != nil {
+		return fmt.Errorf("failed to reload systemd: %w", err)
+	}
+	if err := svc.Enable(TimerName); err != nil {
+		return fmt.Errorf("failed to enable %s: %w", TimerName, err)
+	}
+	if err := svc.Restart(TimerName); err != nil {
+		return fmt.Errorf("failed to start %s: %w", TimerName, err)
+	}
+	log.Success("Installed the phanes agent (%s every %s, jitter %s)", opts.Mode, opts.Interval, opts.Jitter)
+	return nil
+}
+
+// Uninstall stops and removes the timer and service. The state and log
+// directories are kept.
+func Uninstall(dryRun bool) error {
+	var installed []string
+	for _, name := range []string{TimerName, ServiceName} {
+		if exec.FileExists(unitPath(name)) {
+			installed = append(installed, name)
+		}
+	}
+	if len(installed) == 0 {
+		log.Skip("The phanes agent is not installed")
+		return nil
+	}
+	if dryRun {
+		log.Info("Would disable %s and remove %s", TimerName, strings.Join(installed, ", "))
+		return nil
+	}
+
+	if err := exec.Run("systemctl", "disable", "--now", TimerName); err != nil {
+		log.Warn("Failed to disable %s: %v", TimerName, err)
+	}
+	for _, name := range installed {
+		if err := os.Remove(exec.Path(unitPath(name))); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", unitPath(name), err)
+		}
+	}
+	if err := exec.Run("systemctl", "daemon-reload"); err != nil {
+		return fmt.Errorf("failed to reload systemd: %w", err)
+	}
+	log.Success("Removed the phanes agent")
+	return nil
+}
+
+// Installed reports whether the agent's units exist.
+func Installed() bool {
+	return exec.FileExists(unitPath(ServiceName)) && exec.FileExists(unitPath(TimerName))
+}
+
+// unitPath returns the path of a unit file.
+func unitPath(name string) string {
+	return filepath.Join(unitDir, name)
+}
+
+// systemdDuration formats d as seconds, which systemd accepts in all time
+// settings.
+func systemdDuration(d time.Duration) string {
+	return fmt.Sprintf("%ds", int64(d.Round(time.Second)/time.Second))
+}
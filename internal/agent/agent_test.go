@@ -0,0 +1,163 @@
+package agent
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stwalsh4118/phanes/internal/report"
+)
+
+func TestParseMode(t *testing.T) {
+	for _, s := range []string{"apply", "check"} {
+		if mode, err := ParseMode(s); err != nil || string(mode) != s {
+			t.Errorf("ParseMode(%q) = %q, %v", s, mode, err)
+		}
+	}
+	if _, err := ParseMode("enforce"); err == nil {
+		t.Error("ParseMode(\"enforce\") should fail")
+	}
+}
+
+func TestServiceUnit(t *testing.T) {
+	unit := ServiceUnit(Options{
+		Binary:  "/usr/local/bin/phanes",
+		Config:  "/etc/phanes/my config.yaml",
+		Profile: "web",
+		Modules: []string{"docker", "redis"},
+		Mode:    ModeApply,
+		Yes:     true,
+	})
+
+	for _, want := range []string{
+		"[Service]\nType=oneshot\n",
+		`ExecStart=/usr/local/bin/phanes agent run --mode apply --config "/etc/phanes/my config.yaml" --profile web --modules docker,redis --yes` + "\n",
+		"After=network-online.target\n",
+	} {
+		if !strings.Contains(unit, want) {
+			t.Errorf("service unit missing %q:\n%s", want, unit)
+		}
+	}
+	if strings.Contains(unit, "--safe") {
+		t.Errorf("service unit should not contain --safe:\n%s", unit)
+	}
+}
+
+func TestTimerUnit(t *testing.T) {
+	unit := TimerUnit(Options{Interval: 30 * time.Minute, Jitter: 90 * time.Second})
+
+	for _, want := range []string{
+		"OnBootSec=300s\n",
+		"OnUnitInactiveSec=1800s\n",
+		"RandomizedDelaySec=90s\n",
+		"WantedBy=timers.target\n",
+	} {
+		if !strings.Contains(unit, want) {
+			t.Errorf("timer unit missing %q:\n%s", want, unit)
+		}
+	}
+}
+
+func TestLock(t *testing.T) {
+	orig := stateDir
+	stateDir = filepath.Join(t.TempDir(), "agent")
+	t.Cleanup(func() { stateDir = orig })
+
+	unlock, err := Lock()
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+
+	if _, err := Lock(); !errors.Is(err, ErrLocked) {
+		t.Errorf("second Lock() error = %v, want ErrLocked", err)
+	}
+
+	unlock()
+	unlock2, err := Lock()
+	if err != nil {
+		t.Fatalf("Lock() after unlock error = %v", err)
+	}
+	unlock2()
+}
+
+func TestDrift(t *testing.T) {
+	results := &report.Results{Results: []report.ModuleResult{
+		{Name: "baseline", Status: "skipped"},
+		{Name: "docker", Status: "would_install"},
+		{Name: "redis", Status: "would_install"},
+	}}
+
+	got := Drift(results)
+	if strings.Join(got, ",") != "docker,redis" {
+		t.Errorf("Drift() = %v, want [docker redis]", got)
+	}
+}
+
+func TestLogLine(t *testing.T) {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		mode    Mode
+		results *report.Results
+		err     error
+		want    string
+	}{
+		{
+			name: "drift",
+			mode: ModeCheck,
+			results: &report.Results{Success: true, DurationSeconds: 12.4, Results: []report.ModuleResult{
+				{Name: "baseline", Status: "skipped"},
+				{Name: "docker", Status: "would_install"},
+			}},
+			want: "time=2026-01-02T03:04:05Z mode=check status=drift duration=12s changed=docker\n",
+		},
+		{
+			name: "failed",
+			mode: ModeApply,
+			results: &report.Results{DurationSeconds: 3, Results: []report.ModuleResult{
+				{Name: "docker", Status: "failed"},
+			}},
+			err:  errors.New("module execution failed"),
+			want: "time=2026-01-02T03:04:05Z mode=apply status=failed duration=3s failed=docker error=\"module execution failed\"\n",
+		},
+		{
+			name: "no results",
+			mode: ModeApply,
+			err:  errors.New("config loading failed"),
+			want: "time=2026-01-02T03:04:05Z mode=apply status=error error=\"config loading failed\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := logLine(at, tt.mode, tt.results, tt.err); got != tt.want {
+				t.Errorf("logLine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppendLog(t *testing.T) {
+	orig := logDir
+	logDir = filepath.Join(t.TempDir(), "log")
+	t.Cleanup(func() { logDir = orig })
+
+	results := &report.Results{Success: true}
+	for i := 0; i < 2; i++ {
+		if err := AppendLog(ModeApply, results, nil); err != nil {
+			t.Fatalf("AppendLog() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(LogPath())
+	if err != nil {
+		t.Fatalf("failed to read log: %v", err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 2 {
+		t.Errorf("log has %d lines, want 2:\n%s", lines, data)
+	}
+}
@@ -0,0 +1,29 @@
+// Package agent turns phanes into continuous configuration enforcement.
+// Install writes a systemd service and timer that run 'phanes agent run'
+// periodically. Each run either re-applies the configured profile and modules
+// (ModeApply) or only checks them with a dry run and reports drift
+// (ModeCheck).
+//
+// Key Features:
+//   - Timer with a fixed interval and a randomized delay (jitter), so a fleet
+//     does not converge all at once
+//   - A lock file so overlapping runs are skipped
+//   - The results of the last run in StateDir and a line per run in LogPath
+//
+// Usage:
+//
+//	err := agent.Install(agent.Options{
+//	    Binary:   "/usr/local/bin/phanes",
+//	    Config:   "/etc/phanes/config.yaml",
+//	    Profile:  "web",
+//	    Mode:     agent.ModeCheck,
+//	    Interval: 30 * time.Minute,
+//	    Jitter:   5 * time.Minute,
+//	}, false)
+//
+//	unlock, err := agent.Lock()
+//	if errors.Is(err, agent.ErrLocked) {
+//	    // another run is in progress
+//	}
+//	defer unlock()
+package agent
@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/stwalsh4118/phanes/internal/lock"
+	"github.com/stwalsh4118/phanes/internal/report"
+	"github.com/stwalsh4118/phanes/internal/runner"
+)
+
+const (
+	lockFile    = "agent.lock"
+	resultsFile = "last-run.json"
+	logFile     = "agent.log"
+
+	stateDirPerm = 0700
+	logDirPerm   = 0755
+	logFilePerm  = 0640
+)
+
+// ErrLocked is returned by Lock when another run holds the lock.
+var ErrLocked = errors.New("another phanes agent run is in progress")
+
+// ResultsPath returns the path of the results of the last run.
+func ResultsPath() string {
+	return filepath.Join(stateDir, resultsFile)
+}
+
+// LogPath returns the path of the run log.
+func LogPath() string {
+	return filepath.Join(logDir, logFile)
+}
+
+// Lock takes the agent lock, creating the state directory if needed. It
+// returns ErrLocked without waiting if another run holds it. The returned
+// function releases the lock.
+func Lock() (func(), error) {
+	if err := os.MkdirAll(stateDir, stateDirPerm); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", stateDir, err)
+	}
+	release, err := lock.Acquire(filepath.Join(stateDir, lockFile))
+	if errors.Is(err, lock.ErrLocked) {
+		return nil, ErrLocked
+	}
+	return release, err
+}
+
+// Drift returns the modules a check run found out of date.
+func Drift(results *report.Results) []string {
+	var drifted []string
+	for _, r := range results.Results {
+		if runner.ModuleStatus(r.Status) == runner.StatusWouldInstall {
+			drifted = append(drifted, r.Name)
+		}
+	}
+	return drifted
+}
+
+// AppendLog appends a line describing a run to LogPath. results may be nil if
+// the run failed before any module ran, in which case runErr is logged.
+func AppendLog(mode Mode, results *report.Results, runErr error) error {
+	if err := os.MkdirAll(logDir, logDirPerm); err != nil {
+		return fmt.Errorf("failed to create %s: %w", logDir, err)
+	}
+	f, err := os.OpenFile(LogPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, logFilePerm)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", LogPath(), err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(logLine(time.Now(), mode, results, runErr)); err != nil {
+		return fmt.Errorf("failed to write %s: %w", LogPath(), err)
+	}
+	return nil
+}
+
+// logLine formats a run as a single logfmt line.
+func logLine(at time.Time, mode Mode, results *report.Results, runErr error) string {
+	fields := []string{"time=" + at.UTC().Format(time.RFC3339), "mode=" + string(mode)}
+	if results != nil {
+		status := "ok"
+		if !results.Success {
+			status = "failed"
+		} else if len(Drift(results)) > 0 {
+			status = "drift"
+		}
+		fields = append(fields,
+			"status="+status,
+			fmt.Sprintf("duration=%.0fs", results.DurationSeconds),
+		)
+		var changed, failed []string
+		for _, r := range results.Results {
+			switch runner.ModuleStatus(r.Status) {
+			case runner.StatusInstalled, runner.StatusWouldInstall:
+				changed = append(changed, r.Name)
+			case runner.StatusFailed, runner.StatusError, runner.StatusDeclined, runner.StatusDependencyFailed:
+				failed = append(failed, r.Name)
+			}
+		}
+		if len(changed) > 0 {
+			fields = append(fields, "changed="+strings.Join(changed, ","))
+		}
+		if len(failed) > 0 {
+			fields = append(fields, "failed="+strings.Join(failed, ","))
+		}
+	} else {
+		fields = append(fields, "status=error")
+	}
+	if runErr != nil {
+		fields = append(fields, fmt.Sprintf("error=%q", runErr.Error()))
+	}
+	return strings.Join(fields, " ") + "\n"
+}
@@ -0,0 +1,220 @@
+package aptrepo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stwalsh4118/phanes/internal/download"
+	"github.com/stwalsh4118/phanes/internal/exec"
+)
+
+// Locations of keyrings and source files. They are variables so tests can use
+// a temporary directory.
+var (
+	keyringDir = "/etc/apt/keyrings"
+	sourcesDir = "/etc/apt/sources.list.d"
+)
+
+// Repo describes an apt repository.
+type Repo struct {
+	// Name identifies the repository and names its files
+	// (/etc/apt/sources.list.d/<name>.sources, /etc/apt/keyrings/<name>.gpg).
+	Name string
+
+	// URL is the repository base URL.
+	URL string
+
+	// Suites are the distributions (e.g., ["noble"], ["bookworm-pgdg"]).
+	Suites []string
+
+	// Components are the repository components (e.g., ["main"]).
+	Components []string
+
+	// Architectures restricts the repository to the given architectures (optional).
+	Architectures []string
+
+	// KeyURL is the URL of the signing key (ASCII-armored or binary).
+	KeyURL string
+
+	// Fingerprint is the pinned fingerprint of the signing key. Spaces are
+	// ignored and case does not matter. Required when KeyURL is set.
+	Fingerprint string
+}
+
+// KeyringPath returns the keyring location for the named repository.
+func KeyringPath(name string) string {
+	return filepath.Join(keyringDir, name+".gpg")
+}
+
+// SourcesPath returns the deb822 sources file location for the named repository.
+func SourcesPath(name string) string {
+	return filepath.Join(sourcesDir, name+".sources")
+}
+
+// legacyListPath returns the one-line sources file location for the named repository.
+func legacyListPath(name string) string {
+	return filepath.Join(sourcesDir, name+".list")
+}
+
+// Render returns the deb822 sources entry for repo.
+func Render(repo Repo) string {
+	return RenderWithKeyring(repo, KeyringPath(repo.Name))
+}
+
+// RenderWithKeyring returns the deb822 sources entry for repo with its signing
+// key read from keyring instead of the standard location.
+func RenderWithKeyring(repo Repo, keyring string) string {
+	var b strings.Builder
+	b.WriteString("Types: deb\n")
+	fmt.Fprintf(&b, "URIs: %s\n", repo.URL)
+	fmt.Fprintf(&b, "Suites: %s\n", strings.Join(repo.Suites, " "))
+	if len(repo.Components) > 0 {
+		fmt.Fprintf(&b, "Components: %s\n", strings.Join(repo.Components, " "))
+	}
+	if len(repo.Architectures) > 0 {
+		fmt.Fprintf(&b, "Architectures: %s\n", strings.Join(repo.Architectures, " "))
+	}
+	if repo.KeyURL != "" {
+		fmt.Fprintf(&b, "Signed-By: %s\n", keyring)
+	}
+	return b.String()
+}
+
+// Add configures repo. The signing key is downloaded, verified against the
+// pinned fingerprint, and stored as a binary keyring. A legacy <name>.list file
+// for the same repository is removed.
+// Returns true if anything was changed, so callers know to refresh the package
+// indexes. Nothing is downloaded or written if the repository already matches.
+func Add(repo Repo) (bool, error) {
+	if err := validate(repo); err != nil {
+		return false, err
+	}
+
+	sources := Render(repo)
+	if current(repo, sources) {
+		return false, nil
+	}
+
+	if repo.KeyURL != "" {
+		if err := installKey(repo); err != nil {
+			return false, err
+		}
+	}
+
+	if err := os.MkdirAll(exec.Path(sourcesDir), 0755); err != nil {
+		return false, fmt.Errorf("failed to create %s: %w", sourcesDir, err)
+	}
+	path := SourcesPath(repo.Name)
+	if err := exec.WriteFile(path, []byte(sources), 0644); err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	// A one-line entry for the same repository with a different Signed-By
+	// makes apt refuse to update
+	if err := removeIfExists(legacyListPath(repo.Name)); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Remove deletes the sources file and keyring of the named repository.
+// Returns true if anything was removed.
+func Remove(name string) (bool, error) {
+	removed := false
+	for _, path := range []string{SourcesPath(name), legacyListPath(name), KeyringPath(name)} {
+		if !exec.FileExists(path) {
+			continue
+		}
+		if err := os.Remove(exec.Path(path)); err != nil {
+			return removed, fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		removed = true
+	}
+	return removed, nil
+}
+
+// validate checks that repo has the required fields.
+func validate(repo Repo) error {
+	switch {
+	case repo.Name == "":
+		return fmt.Errorf("repository name is required")
+	case strings.ContainsAny(repo.Name, "/ "):
+		return fmt.Errorf("invalid repository name %q", repo.Name)
+	case repo.URL == "":
+		return fmt.Errorf("repository %s: URL is required", repo.Name)
+	case len(repo.Suites) == 0:
+		return fmt.Errorf("repository %s: at least one suite is required", repo.Name)
+	case repo.KeyURL != "" && repo.Fingerprint == "":
+		return fmt.Errorf("repository %s: a pinned key fingerprint is required", repo.Name)
+	}
+	return nil
+}
+
+// current reports whether the sources file matches and the installed keyring
+// has the pinned fingerprint.
+func current(repo Repo, sources string) bool {
+	existing, err := os.ReadFile(exec.Path(SourcesPath(repo.Name)))
+	if err != nil || string(existing) != sources {
+		return false
+	}
+	if exec.FileExists(legacyListPath(repo.Name)) {
+		return false
+	}
+	if repo.KeyURL == "" {
+		return true
+	}
+	keyring, err := os.ReadFile(exec.Path(KeyringPath(repo.Name)))
+	if err != nil {
+		return false
+	}
+	return verifyFingerprint(keyring, repo.Fingerprint) == nil
+}
+
+// installKey downloads the signing key of repo, verifies its fingerprint, and
+// writes it as a binary keyring.
+func installKey(repo Repo) error {
+	keyring, err := FetchKeyring(repo)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(exec.Path(keyringDir), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", keyringDir, err)
+	}
+	path := KeyringPath(repo.Name)
+	if err := exec.WriteFile(path, keyring, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// FetchKeyring downloads the signing key of repo, verifies it against the
+// pinned fingerprint, and returns it as a binary keyring.
+func FetchKeyring(repo Repo) ([]byte, error) {
+	// Keys are pinned by fingerprint rather than file checksum, which stays
+	// the same when upstream re-exports the key with new signatures
+	data, err := download.Get(repo.KeyURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s signing key: %w", repo.Name, err)
+	}
+
+	keyring, err := dearmor(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s signing key: %w", repo.Name, err)
+	}
+	if err := verifyFingerprint(keyring, repo.Fingerprint); err != nil {
+		return nil, fmt.Errorf("refusing %s signing key from %s: %w", repo.Name, repo.KeyURL, err)
+	}
+	return keyring, nil
+}
+
+// removeIfExists removes path if it exists.
+func removeIfExists(path string) error {
+	if err := os.Remove(exec.Path(path)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return nil
+}
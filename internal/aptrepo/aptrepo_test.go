@@ -0,0 +1,261 @@
+package aptrepo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	// Fingerprints of the keys in testdata
+	repoKeyFingerprint  = "40C3774936B33F487D2945E86A3EA9E665AAD7A6"
+	otherKeyFingerprint = "86DC00CEA591FA86E9879D9075AA4997E4378C76"
+)
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// setup points the keyring and sources directories at a temporary directory
+// and serves key at /gpg. Returns the key URL and a pointer to the request count.
+func setup(t *testing.T, key []byte) (string, *int) {
+	t.Helper()
+	dir := t.TempDir()
+	origKeyrings, origSources := keyringDir, sourcesDir
+	keyringDir = filepath.Join(dir, "keyrings")
+	sourcesDir = filepath.Join(dir, "sources.list.d")
+	t.Cleanup(func() { keyringDir, sourcesDir = origKeyrings, origSources })
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/gpg" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(key)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/gpg", &requests
+}
+
+func testRepo(keyURL string) Repo {
+	return Repo{
+		Name:          "example",
+		URL:           "https://packages.example.com/debian",
+		Suites:        []string{"bookworm"},
+		Components:    []string{"main"},
+		Architectures: []string{"amd64"},
+		KeyURL:        keyURL,
+		Fingerprint:   "40C3 7749 36B3 3F48 7D29  45E8 6A3E A9E6 65AA D7A6",
+	}
+}
+
+func TestFingerprints(t *testing.T) {
+	armored := readTestdata(t, "repo-key.asc")
+	keyring, err := dearmor(armored)
+	if err != nil {
+		t.Fatalf("dearmor() error = %v", err)
+	}
+	fprs, err := fingerprints(keyring)
+	if err != nil {
+		t.Fatalf("fingerprints() error = %v", err)
+	}
+	if len(fprs) != 1 || fprs[0] != repoKeyFingerprint {
+		t.Errorf("fingerprints() = %v, want [%s]", fprs, repoKeyFingerprint)
+	}
+
+	// Binary keys are used as-is
+	binary := readTestdata(t, "other-key.gpg")
+	unchanged, err := dearmor(binary)
+	if err != nil {
+		t.Fatalf("dearmor() error = %v", err)
+	}
+	fprs, err = fingerprints(unchanged)
+	if err != nil {
+		t.Fatalf("fingerprints() error = %v", err)
+	}
+	if len(fprs) != 1 || fprs[0] != otherKeyFingerprint {
+		t.Errorf("fingerprints() = %v, want [%s]", fprs, otherKeyFingerprint)
+	}
+}
+
+func TestVerifyFingerprint(t *testing.T) {
+	keyring, err := dearmor(readTestdata(t, "repo-key.asc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := readTestdata(t, "other-key.gpg")
+
+	tests := []struct {
+		name    string
+		keyring []byte
+		pinned  string
+		wantErr string
+	}{
+		{"matching", keyring, repoKeyFingerprint, ""},
+		{"spaces and lower case", keyring, "40c3 7749 36b3 3f48 7d29  45e8 6a3e a9e6 65aa d7a6", ""},
+		{"mismatch", keyring, otherKeyFingerprint, "does not match pinned fingerprint"},
+		{"extra key", append(append([]byte{}, keyring...), other...), repoKeyFingerprint, "expected one key, found 2"},
+		{"not a key", []byte("<html>not found</html>"), repoKeyFingerprint, "invalid OpenPGP packet"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyFingerprint(tt.keyring, tt.pinned)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("verifyFingerprint() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("verifyFingerprint() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	setup(t, nil)
+	repo := testRepo("https://packages.example.com/gpg")
+	want := `Types: deb
+URIs: https://packages.example.com/debian
+Suites: bookworm
+Components: main
+Architectures: amd64
+Signed-By: ` + filepath.Join(keyringDir, "example.gpg") + "\n"
+	if got := Render(repo); got != want {
+		t.Errorf("Render() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	keyURL, requests := setup(t, readTestdata(t, "repo-key.asc"))
+	repo := testRepo(keyURL)
+
+	// A legacy one-line entry for the same repository is replaced
+	if err := os.MkdirAll(sourcesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	legacy := filepath.Join(sourcesDir, "example.list")
+	if err := os.WriteFile(legacy, []byte("deb https://packages.example.com/debian bookworm main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := Add(repo)
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if !changed {
+		t.Error("Add() = false on first run, want true")
+	}
+
+	sources, err := os.ReadFile(SourcesPath("example"))
+	if err != nil {
+		t.Fatalf("sources file not written: %v", err)
+	}
+	if string(sources) != Render(repo) {
+		t.Errorf("sources file = %q, want %q", sources, Render(repo))
+	}
+	keyring, err := os.ReadFile(KeyringPath("example"))
+	if err != nil {
+		t.Fatalf("keyring not written: %v", err)
+	}
+	if err := verifyFingerprint(keyring, repoKeyFingerprint); err != nil {
+		t.Errorf("keyring is not the dearmored key: %v", err)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Error("legacy .list file was not removed")
+	}
+
+	// Second run: nothing to do, nothing downloaded
+	changed, err = Add(repo)
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if changed {
+		t.Error("Add() = true on second run, want false")
+	}
+	if *requests != 1 {
+		t.Errorf("key downloaded %d times, want 1", *requests)
+	}
+
+	// A changed entry is rewritten
+	repo.Suites = []string{"trixie"}
+	changed, err = Add(repo)
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if !changed {
+		t.Error("Add() = false after changing the suite, want true")
+	}
+}
+
+func TestAdd_FingerprintMismatch(t *testing.T) {
+	keyURL, _ := setup(t, readTestdata(t, "other-key.gpg"))
+
+	_, err := Add(testRepo(keyURL))
+	if err == nil || !strings.Contains(err.Error(), "does not match pinned fingerprint") {
+		t.Fatalf("Add() error = %v, want fingerprint mismatch", err)
+	}
+	if _, err := os.Stat(KeyringPath("example")); !os.IsNotExist(err) {
+		t.Error("keyring written despite fingerprint mismatch")
+	}
+	if _, err := os.Stat(SourcesPath("example")); !os.IsNotExist(err) {
+		t.Error("sources file written despite fingerprint mismatch")
+	}
+}
+
+func TestAdd_Validation(t *testing.T) {
+	setup(t, nil)
+	tests := []struct {
+		name string
+		repo Repo
+	}{
+		{"missing name", Repo{URL: "https://example.com", Suites: []string{"stable"}}},
+		{"path in name", Repo{Name: "../evil", URL: "https://example.com", Suites: []string{"stable"}}},
+		{"missing suite", Repo{Name: "example", URL: "https://example.com"}},
+		{"key without fingerprint", Repo{Name: "example", URL: "https://example.com", Suites: []string{"stable"}, KeyURL: "https://example.com/gpg"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Add(tt.repo); err == nil {
+				t.Error("Add() should fail")
+			}
+		})
+	}
+}
+
+func TestRemove(t *testing.T) {
+	keyURL, _ := setup(t, readTestdata(t, "repo-key.asc"))
+	if _, err := Add(testRepo(keyURL)); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := Remove("example")
+	if err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if !removed {
+		t.Error("Remove() = false, want true")
+	}
+	for _, path := range []string{SourcesPath("example"), KeyringPath("example")} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s still exists", path)
+		}
+	}
+
+	removed, err = Remove("example")
+	if err != nil || removed {
+		t.Errorf("Remove() on a missing repository = (%v, %v), want (false, nil)", removed, err)
+	}
+}
@@ -0,0 +1,33 @@
+// Package aptrepo manages third-party apt repositories. Repositories are written
+// as deb822 .sources files in /etc/apt/sources.list.d, and their signing keys are
+// stored under /etc/apt/keyrings and referenced with Signed-By, so a key is only
+// trusted for its own repository.
+//
+// Signing keys are verified against a pinned fingerprint before they are
+// installed. A key whose fingerprint does not match is rejected, so a compromised
+// or mistyped key URL cannot add an untrusted key.
+//
+// Key Features:
+//   - deb822 .sources files with per-repository keyrings
+//   - OpenPGP key fingerprint pinning (no gpg required)
+//   - Idempotent: nothing is downloaded or written if the repository already matches
+//   - Replaces a legacy one-line <name>.list file for the same repository
+//   - Clean removal of the repository and its keyring
+//
+// Usage:
+//
+//	changed, err := aptrepo.Add(aptrepo.Repo{
+//	    Name:        "docker",
+//	    URL:         "https://download.docker.com/linux/ubuntu",
+//	    Suites:      []string{"noble"},
+//	    Components:  []string{"stable"},
+//	    KeyURL:      "https://download.docker.com/linux/ubuntu/gpg",
+//	    Fingerprint: "9DC8 5822 9FC7 DD38 854A  E2D8 8D81 803C 0EBF CD88",
+//	})
+//	if err != nil {
+//	    return err
+//	}
+//	if changed {
+//	    // refresh package indexes
+//	}
+package aptrepo
@@ -0,0 +1,160 @@
+package aptrepo
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	armorBegin = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+	armorEnd   = "-----END PGP PUBLIC KEY BLOCK-----"
+
+	// packetTagPublicKey is the OpenPGP packet tag of a primary public key.
+	packetTagPublicKey = 6
+)
+
+// dearmor returns the binary form of an OpenPGP public key. Binary input is
+// returned unchanged.
+func dearmor(data []byte) ([]byte, error) {
+	if !bytes.Contains(data, []byte(armorBegin)) {
+		return data, nil
+	}
+
+	var body strings.Builder
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	inBlock, inHeaders := false, false
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == armorBegin:
+			inBlock, inHeaders = true, true
+		case !inBlock:
+		case line == armorEnd:
+			decoded, err := base64.StdEncoding.DecodeString(body.String())
+			if err != nil {
+				return nil, fmt.Errorf("invalid armor: %w", err)
+			}
+			return decoded, nil
+		case inHeaders && line == "":
+			inHeaders = false
+		case inHeaders && strings.Contains(line, ": "):
+			// Armor header such as "Version: GnuPG v1"
+		case strings.HasPrefix(line, "=") && len(line) == 5:
+			// CRC-24 checksum
+		default:
+			inHeaders = false
+			body.WriteString(line)
+		}
+	}
+	return nil, fmt.Errorf("invalid armor: missing %q", armorEnd)
+}
+
+// fingerprints returns the fingerprints of the primary keys in a binary
+// OpenPGP keyring, as upper-case hex.
+func fingerprints(keyring []byte) ([]string, error) {
+	var fprs []string
+	for len(keyring) > 0 {
+		tag, body, rest, err := readPacket(keyring)
+		if err != nil {
+			return nil, err
+		}
+		keyring = rest
+
+		if tag != packetTagPublicKey {
+			continue
+		}
+		if len(body) == 0 || body[0] != 4 {
+			return nil, fmt.Errorf("unsupported OpenPGP key version")
+		}
+		// v4 fingerprint: SHA-1 over 0x99, the two-octet body length, and the body
+		h := sha1.New()
+		h.Write([]byte{0x99, byte(len(body) >> 8), byte(len(body))})
+		h.Write(body)
+		fprs = append(fprs, strings.ToUpper(hex.EncodeToString(h.Sum(nil))))
+	}
+	if len(fprs) == 0 {
+		return nil, fmt.Errorf("no OpenPGP public key found")
+	}
+	return fprs, nil
+}
+
+// readPacket reads one OpenPGP packet and returns its tag, body, and the
+// remaining data.
+func readPacket(data []byte) (int, []byte, []byte, error) {
+	if len(data) < 2 || data[0]&0x80 == 0 {
+		return 0, nil, nil, fmt.Errorf("invalid OpenPGP packet")
+	}
+
+	var tag, offset, length int
+	if data[0]&0x40 != 0 {
+		// New format: tag in the low six bits, variable-length length
+		tag = int(data[0] & 0x3f)
+		switch first := int(data[1]); {
+		case first < 192:
+			offset, length = 2, first
+		case first < 224:
+			if len(data) < 3 {
+				return 0, nil, nil, fmt.Errorf("truncated OpenPGP packet")
+			}
+			offset, length = 3, (first-192)<<8+int(data[2])+192
+		case first == 255:
+			if len(data) < 6 {
+				return 0, nil, nil, fmt.Errorf("truncated OpenPGP packet")
+			}
+			offset, length = 6, int(binary.BigEndian.Uint32(data[2:6]))
+		default:
+			return 0, nil, nil, fmt.Errorf("unsupported partial-length OpenPGP packet")
+		}
+	} else {
+		// Old format: tag in bits 5-2, length type in bits 1-0
+		tag = int(data[0]>>2) & 0x0f
+		switch data[0] & 0x03 {
+		case 0:
+			offset, length = 2, int(data[1])
+		case 1:
+			if len(data) < 3 {
+				return 0, nil, nil, fmt.Errorf("truncated OpenPGP packet")
+			}
+			offset, length = 3, int(binary.BigEndian.Uint16(data[1:3]))
+		case 2:
+			if len(data) < 5 {
+				return 0, nil, nil, fmt.Errorf("truncated OpenPGP packet")
+			}
+			offset, length = 5, int(binary.BigEndian.Uint32(data[1:5]))
+		default:
+			offset, length = 1, len(data)-1
+		}
+	}
+
+	if length < 0 || offset+length > len(data) {
+		return 0, nil, nil, fmt.Errorf("truncated OpenPGP packet")
+	}
+	return tag, data[offset : offset+length], data[offset+length:], nil
+}
+
+// normalizeFingerprint removes spaces and upper-cases a fingerprint.
+func normalizeFingerprint(fpr string) string {
+	return strings.ToUpper(strings.ReplaceAll(fpr, " ", ""))
+}
+
+// verifyFingerprint checks that keyring contains exactly one primary key and
+// that its fingerprint is the pinned one.
+func verifyFingerprint(keyring []byte, pinned string) error {
+	fprs, err := fingerprints(keyring)
+	if err != nil {
+		return err
+	}
+	if len(fprs) != 1 {
+		return fmt.Errorf("expected one key, found %d (%s)", len(fprs), strings.Join(fprs, ", "))
+	}
+	if fprs[0] != normalizeFingerprint(pinned) {
+		return fmt.Errorf("fingerprint %s does not match pinned fingerprint %s", fprs[0], normalizeFingerprint(pinned))
+	}
+	return nil
+}
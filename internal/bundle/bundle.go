@@ -0,0 +1,532 @@
+package bundle
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/stwalsh4118/phanes/internal/download"
+	"github.com/stwalsh4118/phanes/internal/exec"
+	"github.com/stwalsh4118/phanes/internal/log"
+	"github.com/stwalsh4118/phanes/internal/module"
+	"github.com/stwalsh4118/phanes/internal/osinfo"
+	"github.com/stwalsh4118/phanes/internal/pkgmgr"
+)
+
+const (
+	// DefaultDir is where bundles are extracted on the target.
+	DefaultDir = "/var/cache/phanes/bundle"
+
+	// FormatVersion is the version of the bundle layout.
+	FormatVersion = 1
+
+	manifestName = "manifest.json"
+	filesDir     = "files"
+	packagesDir  = "packages"
+
+	// maxManifestSize limits the manifest read into memory.
+	maxManifestSize = 16 << 20
+)
+
+// Package operations and OS detection. They are variables so tests can run
+// without apt.
+var (
+	downloadPackages = pkgmgr.DownloadPackages
+	writeIndex       = pkgmgr.WriteIndex
+	detectOS         = osinfo.Detect
+	now              = time.Now
+)
+
+// Manifest describes the content of a bundle.
+type Manifest struct {
+	// Version is the bundle format version.
+	Version int `json:"version"`
+
+	// Created is when the bundle was created.
+	Created time.Time `json:"created"`
+
+	// PhanesVersion is the version of phanes that created the bundle.
+	PhanesVersion string `json:"phanes_version"`
+
+	// Modules are the modules the bundle was created for.
+	Modules []string `json:"modules"`
+
+	// OS is the system the bundle was created on.
+	OS OS `json:"os"`
+
+	// Packages are the requested packages (Debian names). Their dependencies
+	// are included as well.
+	Packages []string `json:"packages,omitempty"`
+
+	// NeedsNetwork maps module names to why they still need network access.
+	NeedsNetwork map[string]string `json:"needs_network,omitempty"`
+
+	// Files lists every file in the bundle except the manifest.
+	Files []File `json:"files"`
+}
+
+// OS identifies a distribution release and architecture.
+type OS struct {
+	ID        string `json:"id"`
+	VersionID string `json:"version_id"`
+	Arch      string `json:"arch"`
+}
+
+// String returns the OS as "<id> <version> (<arch>)".
+func (o OS) String() string {
+	return fmt.Sprintf("%s %s (%s)", o.ID, o.VersionID, o.Arch)
+}
+
+// File is a file in a bundle.
+type File struct {
+	// Path is the slash-separated path of the file within the bundle.
+	Path string `json:"path"`
+
+	// URL is where the file was downloaded from. Empty for packages.
+	URL string `json:"url,omitempty"`
+
+	// SHA256 is the hex-encoded checksum of the file.
+	SHA256 string `json:"sha256"`
+
+	// Size is the file size in bytes.
+	Size int64 `json:"size"`
+}
+
+// Options controls what a bundle contains.
+type Options struct {
+	// Modules are the module names in execution order.
+	Modules []string
+
+	// Requirements maps module names to what they download. Modules without
+	// requirements may be omitted.
+	Requirements map[string]module.Requirements
+
+	// Version is the phanes version recorded in the manifest.
+	Version string
+}
+
+// Create downloads everything the modules require and writes the bundle to
+// output. The bundle is only written if every download succeeds.
+func Create(output string, opts Options) (*Manifest, error) {
+	info, err := detectOS()
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect operating system: %w", err)
+	}
+
+	work, err := os.MkdirTemp("", "phanes-bundle-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(work)
+
+	manifest := &Manifest{
+		Version:       FormatVersion,
+		Created:       now().UTC(),
+		PhanesVersion: opts.Version,
+		Modules:       opts.Modules,
+		OS:            OS{ID: info.ID, VersionID: info.VersionID, Arch: info.Arch},
+	}
+
+	var packages, documents []string
+	var repos []pkgmgr.Repository
+	seenURLs := make(map[string]bool)
+	seenPackages := make(map[string]bool)
+	seenRepos := make(map[string]bool)
+	for _, name := range opts.Modules {
+		reqs := opts.Requirements[name]
+		if reqs.NeedsNetwork != "" {
+			if manifest.NeedsNetwork == nil {
+				manifest.NeedsNetwork = make(map[string]string)
+			}
+			manifest.NeedsNetwork[name] = reqs.NeedsNetwork
+		}
+
+		for _, a := range reqs.Artifacts {
+			if seenURLs[a.URL] {
+				continue
+			}
+			seenURLs[a.URL] = true
+
+			local, err := download.Fetch(a)
+			if err != nil {
+				return nil, err
+			}
+			file, err := addFile(work, a.URL, artifactName(a.URL, a.Name), func(w io.Writer) error {
+				return copyFile(w, exec.Path(local))
+			})
+			if err != nil {
+				return nil, err
+			}
+			manifest.Files = append(manifest.Files, file)
+		}
+
+		documents = append(documents, reqs.Documents...)
+		for _, repo := range reqs.Repositories {
+			if !seenRepos[repo.Name] {
+				seenRepos[repo.Name] = true
+				repos = append(repos, repo)
+				if repo.KeyURL != "" {
+					documents = append(documents, repo.KeyURL)
+				}
+			}
+		}
+		for _, pkg := range reqs.Packages {
+			if !seenPackages[pkg] {
+				seenPackages[pkg] = true
+				packages = append(packages, pkg)
+			}
+		}
+	}
+
+	for _, rawURL := range documents {
+		if seenURLs[rawURL] {
+			continue
+		}
+		seenURLs[rawURL] = true
+
+		log.Info("Downloading %s", rawURL)
+		body, err := download.Get(rawURL)
+		if err != nil {
+			return nil, err
+		}
+		file, err := addFile(work, rawURL, artifactName(rawURL, "document"), func(w io.Writer) error {
+			_, err := w.Write(body)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+
+	if len(packages) > 0 {
+		files, err := addPackages(work, repos, packages)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Packages = packages
+		manifest.Files = append(manifest.Files, files...)
+	}
+
+	if err := writeArchive(output, work, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// addFile writes a file with the content produced by write to
+// files/<sha256>/<name> under work.
+func addFile(work, rawURL, name string, write func(io.Writer) error) (File, error) {
+	tmp, err := os.CreateTemp(work, ".file-*")
+	if err != nil {
+		return File{}, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	counter := &countingWriter{w: io.MultiWriter(tmp, hash)}
+	if err := write(counter); err != nil {
+		tmp.Close()
+		return File{}, fmt.Errorf("failed to copy %s: %w", rawURL, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return File{}, fmt.Errorf("failed to copy %s: %w", rawURL, err)
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	file := File{Path: path.Join(filesDir, sum, name), URL: rawURL, SHA256: sum, Size: counter.n}
+	dest := filepath.Join(work, filepath.FromSlash(file.Path))
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return File{}, fmt.Errorf("failed to create %s: %w", filepath.Dir(dest), err)
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return File{}, fmt.Errorf("failed to store %s: %w", rawURL, err)
+	}
+	return file, nil
+}
+
+// addPackages downloads packages with their dependencies into packages/ under
+// work and indexes them.
+func addPackages(work string, repos []pkgmgr.Repository, packages []string) ([]File, error) {
+	dir := filepath.Join(work, packagesDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	log.Info("Downloading packages and their dependencies: %v", packages)
+	if err := downloadPackages(dir, repos, packages...); err != nil {
+		return nil, err
+	}
+	if err := writeIndex(dir); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	var files []File
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		size, sum, err := checksumFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Name(), err)
+		}
+		files = append(files, File{Path: path.Join(packagesDir, entry.Name()), SHA256: sum, Size: size})
+	}
+	return files, nil
+}
+
+// writeArchive writes the manifest and the files it lists from work to output.
+// The archive is written to a temporary file and renamed into place.
+func writeArchive(output, work string, manifest *Manifest) (err error) {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(output), "."+filepath.Base(output)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", output, err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	tw := tar.NewWriter(tmp)
+	modTime := manifest.Created
+	if err := tw.WriteHeader(&tar.Header{Name: manifestName, Mode: 0644, Size: int64(len(data)), ModTime: modTime}); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	written := make(map[string]bool)
+	for _, f := range manifest.Files {
+		// Downloads with the same name and content share a file
+		if written[f.Path] {
+			continue
+		}
+		written[f.Path] = true
+
+		if err := tw.WriteHeader(&tar.Header{Name: f.Path, Mode: 0644, Size: f.Size, ModTime: modTime}); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.Path, err)
+		}
+		if err := copyFile(tw, filepath.Join(work, filepath.FromSlash(f.Path))); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.Path, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", output, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", output, err)
+	}
+	if err := os.Rename(tmp.Name(), output); err != nil {
+		return fmt.Errorf("failed to write %s: %w", output, err)
+	}
+	return nil
+}
+
+// Extract unpacks the bundle at archive into dir, replacing its previous
+// content, and verifies every file against the manifest. dir is resolved under
+// the alternate root, if one is set.
+func Extract(archive, dir string) (*Manifest, error) {
+	target := exec.Path(dir)
+	if err := os.RemoveAll(target); err != nil {
+		return nil, fmt.Errorf("failed to remove previous bundle at %s: %w", dir, err)
+	}
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	manifest, err := read(archive, func(f File, r io.Reader) error {
+		dest := filepath.Join(target, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, r); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+	if err != nil {
+		// Never leave unverified files behind
+		os.RemoveAll(target)
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// Verify reads the bundle at archive and verifies every file against the
+// manifest without extracting anything.
+func Verify(archive string) (*Manifest, error) {
+	return read(archive, func(f File, r io.Reader) error {
+		_, err := io.Copy(io.Discard, r)
+		return err
+	})
+}
+
+// read reads the bundle at archive, passing the content of every file to
+// handle, and verifies the checksums and completeness of the files.
+func read(archive string, handle func(File, io.Reader) error) (*Manifest, error) {
+	file, err := os.Open(archive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer file.Close()
+
+	tr := tar.NewReader(file)
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != manifestName {
+		return nil, fmt.Errorf("invalid bundle %s: %s must be the first entry", archive, manifestName)
+	}
+	var manifest Manifest
+	if err := json.NewDecoder(io.LimitReader(tr, maxManifestSize)).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid bundle manifest: %w", err)
+	}
+	if manifest.Version != FormatVersion {
+		return nil, fmt.Errorf("unsupported bundle format version %d (this phanes supports %d)", manifest.Version, FormatVersion)
+	}
+
+	pending := make(map[string]File, len(manifest.Files))
+	for _, f := range manifest.Files {
+		if !filepath.IsLocal(filepath.FromSlash(f.Path)) {
+			return nil, fmt.Errorf("invalid bundle manifest: unsafe path %q", f.Path)
+		}
+		pending[f.Path] = f
+	}
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		if hdr.Typeflag == tar.TypeDir {
+			continue
+		}
+		f, ok := pending[hdr.Name]
+		if !ok || hdr.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("invalid bundle: unexpected entry %q", hdr.Name)
+		}
+		delete(pending, hdr.Name)
+
+		hash := sha256.New()
+		if err := handle(f, io.TeeReader(tr, hash)); err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", f.Path, err)
+		}
+		if sum := hex.EncodeToString(hash.Sum(nil)); sum != f.SHA256 || hdr.Size != f.Size {
+			return nil, fmt.Errorf("bundle file %s is corrupt: checksum %s, want %s", f.Path, sum, f.SHA256)
+		}
+	}
+
+	if len(pending) > 0 {
+		missing := make([]string, 0, len(pending))
+		for p := range pending {
+			missing = append(missing, p)
+		}
+		sort.Strings(missing)
+		return nil, fmt.Errorf("bundle is incomplete: missing %v", missing)
+	}
+	return &manifest, nil
+}
+
+// Sources maps the source URL of every downloaded file to its path in the
+// bundle extracted at dir, for download.UseLocal.
+func (m *Manifest) Sources(dir string) map[string]string {
+	sources := make(map[string]string)
+	for _, f := range m.Files {
+		if f.URL != "" {
+			sources[f.URL] = filepath.Join(dir, filepath.FromSlash(f.Path))
+		}
+	}
+	return sources
+}
+
+// PackagesDir returns the apt repository directory of the bundle extracted at
+// dir, or an empty string if the bundle contains no packages.
+func (m *Manifest) PackagesDir(dir string) string {
+	if len(m.Packages) == 0 {
+		return ""
+	}
+	return filepath.Join(dir, packagesDir)
+}
+
+// CheckOS returns an error unless info is the release and architecture the
+// bundle was created on. Packages and release tarballs only work there.
+func (m *Manifest) CheckOS(info *osinfo.Info) error {
+	current := OS{ID: info.ID, VersionID: info.VersionID, Arch: info.Arch}
+	if current != m.OS {
+		return fmt.Errorf("bundle was created for %s, but this system is %s", m.OS, current)
+	}
+	return nil
+}
+
+// artifactName returns the file name for a download: the last element of its
+// URL path, or fallback if the URL has none.
+func artifactName(rawURL, fallback string) string {
+	if parsed, err := url.Parse(rawURL); err == nil {
+		if base := path.Base(parsed.Path); base != "." && base != "/" {
+			return base
+		}
+	}
+	return fallback
+}
+
+// copyFile copies the file at path to w.
+func copyFile(w io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(w, file)
+	return err
+}
+
+// checksumFile returns the size and hex-encoded SHA-256 checksum of a file.
+func checksumFile(path string) (int64, string, error) {
+	hash := sha256.New()
+	counter := &countingWriter{w: hash}
+	if err := copyFile(counter, path); err != nil {
+		return 0, "", err
+	}
+	return counter.n, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,273 @@
+package bundle
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stwalsh4118/phanes/internal/config"
+	"github.com/stwalsh4118/phanes/internal/download"
+	"github.com/stwalsh4118/phanes/internal/module"
+	"github.com/stwalsh4118/phanes/internal/osinfo"
+	"github.com/stwalsh4118/phanes/internal/pkgmgr"
+)
+
+var testOS = osinfo.Info{ID: "ubuntu", VersionID: "24.04", Arch: "amd64"}
+
+// setup serves test downloads and replaces the package and OS operations.
+// It returns the server URL and the packages requested from apt.
+func setup(t *testing.T) (string, *[]string) {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/install.sh":
+			w.Write([]byte("#!/bin/sh\n"))
+		case "/go.tar.gz.sha256":
+			w.Write([]byte("abc\n"))
+		case "/gpg":
+			w.Write([]byte("key"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	download.Configure(config.Downloads{CacheDir: t.TempDir()})
+	t.Cleanup(func() { download.Configure(config.DefaultConfig().Downloads) })
+
+	var requested []string
+	origDownload, origIndex, origDetect, origNow := downloadPackages, writeIndex, detectOS, now
+	downloadPackages = func(dir string, repos []pkgmgr.Repository, packages ...string) error {
+		requested = append(requested, packages...)
+		return os.WriteFile(filepath.Join(dir, "hello_1.0_amd64.deb"), []byte("deb"), 0644)
+	}
+	writeIndex = func(dir string) error {
+		return os.WriteFile(filepath.Join(dir, "Packages"), []byte("Package: hello\n"), 0644)
+	}
+	detectOS = func() (*osinfo.Info, error) {
+		info := testOS
+		return &info, nil
+	}
+	now = func() time.Time { return time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC) }
+	t.Cleanup(func() {
+		downloadPackages, writeIndex, detectOS, now = origDownload, origIndex, origDetect, origNow
+	})
+
+	return srv.URL, &requested
+}
+
+func sum(content string) string {
+	h := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(h[:])
+}
+
+func TestCreateAndExtract(t *testing.T) {
+	base, requested := setup(t)
+	output := filepath.Join(t.TempDir(), "bundle.tar")
+
+	manifest, err := Create(output, Options{
+		Modules: []string{"docker", "devtools", "redis"},
+		Requirements: map[string]module.Requirements{
+			"docker": {
+				Packages:     []string{"docker-ce", "curl"},
+				Repositories: []pkgmgr.Repository{{Name: "docker", KeyURL: base + "/gpg"}},
+			},
+			"devtools": {
+				Packages:     []string{"curl", "git"},
+				Artifacts:    []download.Artifact{{Name: "tool-install", URL: base + "/install.sh"}},
+				Documents:    []string{base + "/go.tar.gz.sha256"},
+				NeedsNetwork: "nvm downloads Node.js",
+			},
+		},
+		Version: "1.2.3",
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	if want := []string{"docker-ce", "curl", "git"}; !reflect.DeepEqual(*requested, want) {
+		t.Errorf("requested packages = %q, want %q", *requested, want)
+	}
+	if manifest.OS != (OS{ID: "ubuntu", VersionID: "24.04", Arch: "amd64"}) || manifest.PhanesVersion != "1.2.3" {
+		t.Errorf("manifest = %+v", manifest)
+	}
+	if manifest.NeedsNetwork["devtools"] != "nvm downloads Node.js" {
+		t.Errorf("NeedsNetwork = %v", manifest.NeedsNetwork)
+	}
+
+	var paths []string
+	for _, f := range manifest.Files {
+		paths = append(paths, f.Path)
+	}
+	wantPaths := []string{
+		"files/" + sum("#!/bin/sh\n") + "/install.sh",
+		"files/" + sum("key") + "/gpg",
+		"files/" + sum("abc\n") + "/go.tar.gz.sha256",
+		"packages/Packages",
+		"packages/hello_1.0_amd64.deb",
+	}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Errorf("files = %q, want %q", paths, wantPaths)
+	}
+
+	if _, err := Verify(output); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "bundle")
+	extracted, err := Extract(output, dir)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if !reflect.DeepEqual(extracted, manifest) {
+		t.Errorf("extracted manifest = %+v, want %+v", extracted, manifest)
+	}
+
+	sources := extracted.Sources(dir)
+	if len(sources) != 3 {
+		t.Errorf("Sources() = %v, want 3 entries", sources)
+	}
+	content, err := os.ReadFile(sources[base+"/install.sh"])
+	if err != nil || string(content) != "#!/bin/sh\n" {
+		t.Errorf("extracted install script = %q, %v", content, err)
+	}
+	if got := extracted.PackagesDir(dir); got != filepath.Join(dir, "packages") {
+		t.Errorf("PackagesDir() = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "packages", "hello_1.0_amd64.deb")); err != nil {
+		t.Errorf("package not extracted: %v", err)
+	}
+}
+
+func TestCreateFailsOnDownloadError(t *testing.T) {
+	base, _ := setup(t)
+	output := filepath.Join(t.TempDir(), "bundle.tar")
+
+	_, err := Create(output, Options{
+		Modules: []string{"coolify"},
+		Requirements: map[string]module.Requirements{
+			"coolify": {Artifacts: []download.Artifact{{Name: "missing", URL: base + "/missing.sh"}}},
+		},
+	})
+	if err == nil {
+		t.Fatal("Create() should fail when a download fails")
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Error("no bundle should be written when a download fails")
+	}
+}
+
+// writeTar writes a bundle with the given manifest and entries.
+func writeTar(t *testing.T, manifest Manifest, entries map[string]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "bundle.tar")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	tw := tar.NewWriter(file)
+	data, _ := json.Marshal(manifest)
+	tw.WriteHeader(&tar.Header{Name: manifestName, Mode: 0644, Size: int64(len(data))})
+	tw.Write(data)
+	for name, content := range entries {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))})
+		tw.Write([]byte(content))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestVerifyRejectsInvalidBundles(t *testing.T) {
+	file := File{Path: "files/x/install.sh", SHA256: sum("script"), Size: 6}
+
+	tests := []struct {
+		name     string
+		manifest Manifest
+		entries  map[string]string
+		wantErr  string
+	}{
+		{
+			"corrupt file",
+			Manifest{Version: FormatVersion, Files: []File{file}},
+			map[string]string{file.Path: "tamper"},
+			"corrupt",
+		},
+		{
+			"missing file",
+			Manifest{Version: FormatVersion, Files: []File{file}},
+			nil,
+			"incomplete",
+		},
+		{
+			"unlisted file",
+			Manifest{Version: FormatVersion, Files: []File{file}},
+			map[string]string{"files/x/other.sh": "script"},
+			"unexpected entry",
+		},
+		{
+			"unsafe path",
+			Manifest{Version: FormatVersion, Files: []File{{Path: "../etc/passwd", SHA256: sum("x"), Size: 1}}},
+			map[string]string{"../etc/passwd": "x"},
+			"unsafe path",
+		},
+		{
+			"unknown version",
+			Manifest{Version: FormatVersion + 1},
+			nil,
+			"unsupported bundle format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTar(t, tt.manifest, tt.entries)
+			if _, err := Verify(path); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExtractRemovesPartialBundle(t *testing.T) {
+	file := File{Path: "files/x/install.sh", SHA256: sum("script"), Size: 6}
+	path := writeTar(t, Manifest{Version: FormatVersion, Files: []File{file}}, map[string]string{file.Path: "tamper"})
+
+	dir := filepath.Join(t.TempDir(), "bundle")
+	if _, err := Extract(path, dir); err == nil {
+		t.Fatal("Extract() of a corrupt bundle should fail")
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Error("Extract() should remove a partially extracted bundle")
+	}
+}
+
+func TestCheckOS(t *testing.T) {
+	m := Manifest{OS: OS{ID: "ubuntu", VersionID: "24.04", Arch: "amd64"}}
+
+	if err := m.CheckOS(&osinfo.Info{ID: "ubuntu", VersionID: "24.04", Arch: "amd64", PrettyName: "Ubuntu 24.04 LTS"}); err != nil {
+		t.Errorf("CheckOS() on the same OS error = %v", err)
+	}
+	for _, info := range []osinfo.Info{
+		{ID: "ubuntu", VersionID: "22.04", Arch: "amd64"},
+		{ID: "ubuntu", VersionID: "24.04", Arch: "arm64"},
+		{ID: "debian", VersionID: "12", Arch: "amd64"},
+	} {
+		if err := m.CheckOS(&info); err == nil {
+			t.Errorf("CheckOS(%s %s %s) should fail", info.ID, info.VersionID, info.Arch)
+		}
+	}
+}
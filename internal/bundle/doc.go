@@ -0,0 +1,32 @@
+// Package bundle creates and unpacks offline bundles: tar archives with
+// everything the selected modules download during installation, for servers
+// without outbound internet access.
+//
+// A bundle is created on a connected machine running the same distribution
+// release and architecture as the target servers. It contains:
+//
+//   - manifest.json: the modules, the OS the bundle was created on, and the
+//     path, source URL and SHA-256 checksum of every other file
+//   - files/: install scripts, release tarballs, checksum files and repository
+//     signing keys, each stored under its checksum
+//   - packages/: the requested .deb packages with all their dependencies and a
+//     Packages index, usable as a flat apt repository
+//
+// On the target, Extract unpacks the bundle and verifies every file against
+// the manifest. download.UseLocal and pkgmgr.UseLocalRepository then serve
+// downloads and packages from the extracted files.
+//
+// Usage:
+//
+//	manifest, err := bundle.Create("bundle.tar", bundle.Options{
+//	    Modules:      []string{"docker"},
+//	    Requirements: map[string]module.Requirements{"docker": reqs},
+//	    Version:      version,
+//	})
+//
+//	manifest, err := bundle.Extract("bundle.tar", bundle.DefaultDir)
+//	if err != nil {
+//	    return err
+//	}
+//	download.UseLocal(manifest.Sources(bundle.DefaultDir))
+package bundle
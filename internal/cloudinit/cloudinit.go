@@ -0,0 +1,243 @@
+package cloudinit
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ConfigPath is where the configuration is written on the server.
+	ConfigPath = "/etc/phanes/config.yaml"
+
+	// ScriptPath is the bootstrap script run on first boot.
+	ScriptPath = "/usr/local/sbin/phanes-bootstrap"
+
+	// LogPath receives the output of the bootstrap script.
+	LogPath = "/var/log/phanes/cloud-init.log"
+
+	// ResultsPath receives the results of the first-boot run.
+	ResultsPath = "/var/log/phanes/cloud-init-results.json"
+
+	// BinaryPath is where phanes is installed.
+	BinaryPath = "/usr/local/bin/phanes"
+
+	releaseBaseURL = "https://github.com/stwalsh4118/phanes/releases/download"
+)
+
+// unameArch maps the release architectures to "uname -m" patterns.
+var unameArch = map[string]string{
+	"amd64": "x86_64|amd64",
+	"arm64": "aarch64|arm64",
+}
+
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Options describes the first-boot run.
+type Options struct {
+	// Version is the release tag to install (e.g., "v0.1.0").
+	Version string
+
+	// Checksums maps release architectures (amd64, arm64) to the SHA-256
+	// checksums of their release tarballs. Servers with other architectures
+	// fail to bootstrap.
+	Checksums map[string]string
+
+	// Config is the phanes configuration (YAML).
+	Config []byte
+
+	// Profile and Modules select what to run.
+	Profile string
+	Modules []string
+
+	// Yes approves risky changes, Safe refuses them.
+	Yes  bool
+	Safe bool
+
+	// Env are NAME=value pairs exported before the download (e.g., the
+	// proxy settings).
+	Env []string
+}
+
+// ReleaseFile returns the name of the release tarball for version and arch.
+func ReleaseFile(version, arch string) string {
+	return fmt.Sprintf("phanes_%s_linux_%s.tar.gz", strings.TrimPrefix(version, "v"), arch)
+}
+
+// ReleaseURL returns the download URL of the release tarball.
+func ReleaseURL(version, arch string) string {
+	return fmt.Sprintf("%s/%s/%s", releaseBaseURL, version, ReleaseFile(version, arch))
+}
+
+// ChecksumsURL returns the URL of the checksums published with a release.
+func ChecksumsURL(version string) string {
+	return fmt.Sprintf("%s/%s/checksums.txt", releaseBaseURL, version)
+}
+
+// ParseChecksums extracts the checksums of the Linux tarballs of version from
+// a sha256sum-style checksums file.
+func ParseChecksums(data []byte, version string) (map[string]string, error) {
+	byFile := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			byFile[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+		}
+	}
+
+	sums := make(map[string]string)
+	for arch := range unameArch {
+		if sum, ok := byFile[ReleaseFile(version, arch)]; ok {
+			if !sha256Pattern.MatchString(sum) {
+				return nil, fmt.Errorf("invalid checksum for %s", ReleaseFile(version, arch))
+			}
+			sums[arch] = sum
+		}
+	}
+	if len(sums) == 0 {
+		return nil, fmt.Errorf("no checksums for the Linux release tarballs of %s", version)
+	}
+	return sums, nil
+}
+
+// cloudConfig is the subset of the cloud-config format used.
+type cloudConfig struct {
+	WriteFiles []writeFile `yaml:"write_files"`
+	RunCmd     [][]string  `yaml:"runcmd"`
+}
+
+type writeFile struct {
+	Path        string `yaml:"path"`
+	Owner       string `yaml:"owner"`
+	Permissions string `yaml:"permissions"`
+	Content     string `yaml:"content"`
+}
+
+// Generate returns the #cloud-config document for opts.
+func Generate(opts Options) ([]byte, error) {
+	if opts.Version == "" {
+		return nil, fmt.Errorf("a release version is required")
+	}
+	if len(opts.Checksums) == 0 {
+		return nil, fmt.Errorf("at least one release checksum is required")
+	}
+	for arch, sum := range opts.Checksums {
+		if _, ok := unameArch[arch]; !ok {
+			return nil, fmt.Errorf("unsupported architecture %s", arch)
+		}
+		if !sha256Pattern.MatchString(sum) {
+			return nil, fmt.Errorf("invalid checksum for %s: expected 64 lowercase hex characters", arch)
+		}
+	}
+	if opts.Profile == "" && len(opts.Modules) == 0 {
+		return nil, fmt.Errorf("a profile or modules are required")
+	}
+
+	doc := cloudConfig{
+		WriteFiles: []writeFile{
+			{Path: ConfigPath, Owner: "root:root", Permissions: "0600", Content: string(opts.Config)},
+			{Path: ScriptPath, Owner: "root:root", Permissions: "0700", Content: Script(opts)},
+		},
+		RunCmd: [][]string{{ScriptPath}},
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("#cloud-config\n")
+	fmt.Fprintf(&buf, "# Generated by phanes: installs phanes %s and runs it on first boot.\n", opts.Version)
+	fmt.Fprintf(&buf, "# Output: %s\n", LogPath)
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, fmt.Errorf("failed to encode cloud-config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode cloud-config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Script returns the bootstrap script that installs the pinned release and
+// runs phanes.
+func Script(opts Options) string {
+	args := []string{BinaryPath}
+	if opts.Profile != "" {
+		args = append(args, "--profile", opts.Profile)
+	}
+	if len(opts.Modules) > 0 {
+		args = append(args, "--modules", strings.Join(opts.Modules, ","))
+	}
+	args = append(args, "--config", ConfigPath, "--results-json", ResultsPath)
+	if opts.Yes {
+		args = append(args, "--yes")
+	}
+	if opts.Safe {
+		args = append(args, "--safe")
+	}
+	for i, arg := range args {
+		args[i] = shellQuote(arg)
+	}
+
+	arches := make([]string, 0, len(opts.Checksums))
+	for arch := range opts.Checksums {
+		arches = append(arches, arch)
+	}
+	sort.Strings(arches)
+
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	b.WriteString("# Installs a pinned phanes release and runs it (generated by phanes cloud-init)\n")
+	b.WriteString("set -eu\n\n")
+	fmt.Fprintf(&b, "mkdir -p %s\n", shellQuote(dirOf(LogPath)))
+	fmt.Fprintf(&b, "exec >>%s 2>&1\n", shellQuote(LogPath))
+	fmt.Fprintf(&b, "echo \"phanes bootstrap started at $(date -u)\"\n\n")
+	if len(opts.Env) > 0 {
+		for _, kv := range opts.Env {
+			name, value, _ := strings.Cut(kv, "=")
+			fmt.Fprintf(&b, "export %s=%s\n", name, shellQuote(value))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("case \"$(uname -m)\" in\n")
+	for _, arch := range arches {
+		fmt.Fprintf(&b, "  %s) URL=%s; SHA256=%s ;;\n", unameArch[arch], shellQuote(ReleaseURL(opts.Version, arch)), opts.Checksums[arch])
+	}
+	b.WriteString("  *) echo \"unsupported architecture: $(uname -m)\"; exit 1 ;;\n")
+	b.WriteString("esac\n\n")
+
+	b.WriteString(`TMP=$(mktemp -d)
+trap 'rm -rf "$TMP"' EXIT
+
+echo "Downloading $URL"
+if command -v curl >/dev/null 2>&1; then
+  curl -fsSL --retry 5 --retry-delay 5 -o "$TMP/phanes.tar.gz" "$URL"
+else
+  wget -q --tries=5 -O "$TMP/phanes.tar.gz" "$URL"
+fi
+echo "$SHA256  $TMP/phanes.tar.gz" | sha256sum -c -
+tar -xzf "$TMP/phanes.tar.gz" -C "$TMP" --strip-components=1
+`)
+	fmt.Fprintf(&b, "install -m 0755 \"$TMP/phanes\" %s\n\n", shellQuote(BinaryPath))
+	fmt.Fprintf(&b, "%s\n", strings.Join(args, " "))
+	b.WriteString("echo \"phanes bootstrap finished at $(date -u)\"\n")
+	return b.String()
+}
+
+// dirOf returns the directory of a slash-separated path.
+func dirOf(path string) string {
+	return path[:strings.LastIndex(path, "/")]
+}
+
+// shellQuote quotes s as a single word for sh if needed.
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`;&|<>()*?[]#~!{}") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,130 @@
+package cloudinit
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	sumAMD64 = strings.Repeat("a", 64)
+	sumARM64 = strings.Repeat("b", 64)
+)
+
+func TestParseChecksums(t *testing.T) {
+	data := []byte(sumAMD64 + "  phanes_0.2.0_linux_amd64.tar.gz\n" +
+		sumARM64 + " *phanes_0.2.0_linux_arm64.tar.gz\n" +
+		strings.Repeat("c", 64) + "  phanes_0.2.0_darwin_arm64.tar.gz\n")
+
+	sums, err := ParseChecksums(data, "v0.2.0")
+	if err != nil {
+		t.Fatalf("ParseChecksums() error = %v", err)
+	}
+	if len(sums) != 2 || sums["amd64"] != sumAMD64 || sums["arm64"] != sumARM64 {
+		t.Errorf("ParseChecksums() = %v", sums)
+	}
+
+	if _, err := ParseChecksums(data, "v0.3.0"); err == nil {
+		t.Error("ParseChecksums() for another version should fail")
+	}
+	if _, err := ParseChecksums([]byte("xyz  phanes_0.2.0_linux_amd64.tar.gz\n"), "v0.2.0"); err == nil {
+		t.Error("ParseChecksums() with an invalid checksum should fail")
+	}
+}
+
+func TestReleaseURL(t *testing.T) {
+	want := "https://github.com/stwalsh4118/phanes/releases/download/v0.2.0/phanes_0.2.0_linux_arm64.tar.gz"
+	if got := ReleaseURL("v0.2.0", "arm64"); got != want {
+		t.Errorf("ReleaseURL() = %s, want %s", got, want)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	config := "user:\n  username: deploy\n  ssh_public_key: \"ssh-ed25519 AAAA test\"\n"
+	data, err := Generate(Options{
+		Version:   "v0.2.0",
+		Checksums: map[string]string{"amd64": sumAMD64, "arm64": sumARM64},
+		Config:    []byte(config),
+		Profile:   "web",
+		Modules:   []string{"docker", "redis"},
+		Yes:       true,
+		Env:       []string{"https_proxy=http://proxy:3128"},
+	})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if !strings.HasPrefix(string(data), "#cloud-config\n") {
+		t.Errorf("user data must start with #cloud-config:\n%s", data)
+	}
+
+	var doc cloudConfig
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("user data is not valid YAML: %v\n%s", err, data)
+	}
+	if len(doc.WriteFiles) != 2 {
+		t.Fatalf("write_files = %+v", doc.WriteFiles)
+	}
+	if f := doc.WriteFiles[0]; f.Path != ConfigPath || f.Permissions != "0600" || f.Content != config {
+		t.Errorf("config file = %+v", f)
+	}
+	script := doc.WriteFiles[1]
+	if script.Path != ScriptPath || script.Permissions != "0700" {
+		t.Errorf("script file = %+v", script)
+	}
+	if len(doc.RunCmd) != 1 || len(doc.RunCmd[0]) != 1 || doc.RunCmd[0][0] != ScriptPath {
+		t.Errorf("runcmd = %v", doc.RunCmd)
+	}
+
+	for _, want := range []string{
+		"exec >>/var/log/phanes/cloud-init.log 2>&1\n",
+		"export https_proxy=http://proxy:3128\n",
+		"x86_64|amd64) URL=https://github.com/stwalsh4118/phanes/releases/download/v0.2.0/phanes_0.2.0_linux_amd64.tar.gz; SHA256=" + sumAMD64,
+		"aarch64|arm64) URL=",
+		`echo "$SHA256  $TMP/phanes.tar.gz" | sha256sum -c -`,
+		"/usr/local/bin/phanes --profile web --modules docker,redis --config /etc/phanes/config.yaml --results-json /var/log/phanes/cloud-init-results.json --yes\n",
+	} {
+		if !strings.Contains(script.Content, want) {
+			t.Errorf("script missing %q:\n%s", want, script.Content)
+		}
+	}
+}
+
+func TestGenerate_Invalid(t *testing.T) {
+	valid := Options{Version: "v0.2.0", Checksums: map[string]string{"amd64": sumAMD64}, Profile: "web"}
+
+	tests := map[string]func(*Options){
+		"no version":     func(o *Options) { o.Version = "" },
+		"no checksums":   func(o *Options) { o.Checksums = nil },
+		"bad checksum":   func(o *Options) { o.Checksums = map[string]string{"amd64": "abc"} },
+		"unknown arch":   func(o *Options) { o.Checksums = map[string]string{"mips": sumAMD64} },
+		"nothing to run": func(o *Options) { o.Profile = "" },
+	}
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts := valid
+			mutate(&opts)
+			if _, err := Generate(opts); err == nil {
+				t.Error("Generate() should fail")
+			}
+		})
+	}
+}
+
+func TestScript_Syntax(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not available")
+	}
+	path := filepath.Join(t.TempDir(), "bootstrap")
+	script := Script(Options{Version: "v0.2.0", Checksums: map[string]string{"amd64": sumAMD64}, Modules: []string{"baseline"}, Safe: true})
+	if err := os.WriteFile(path, []byte(script), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command(sh, "-n", path).CombinedOutput(); err != nil {
+		t.Errorf("script has syntax errors: %v\n%s\n%s", err, out, script)
+	}
+}
@@ -0,0 +1,19 @@
+// Package cloudinit generates cloud-init user data that provisions a new
+// server with phanes on first boot.
+//
+// The generated #cloud-config document writes the phanes configuration to
+// ConfigPath and a bootstrap script that downloads a pinned phanes release,
+// verifies it against the SHA-256 checksum for the server's architecture,
+// installs it, and runs the selected profile and modules. The script's output
+// is appended to LogPath.
+//
+// Usage:
+//
+//	sums, err := cloudinit.ParseChecksums(checksumsTxt, "v0.1.0")
+//	userData, err := cloudinit.Generate(cloudinit.Options{
+//	    Version:   "v0.1.0",
+//	    Checksums: sums,
+//	    Config:    configYAML,
+//	    Profile:   "web",
+//	})
+package cloudinit
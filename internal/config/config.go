@@ -1,9 +1,12 @@
 package config
 
 import (
+	"encoding/hex"
 	"fmt"
+	"net/url"
 	"os"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -22,6 +25,11 @@ type Config struct {
 	DevTools  DevTools  `yaml:"devtools"`
 	Coolify   Coolify   `yaml:"coolify"`
 	Tailscale Tailscale `yaml:"tailscale"`
+
+	Notifications Notifications `yaml:"notifications"`
+	Downloads     Downloads     `yaml:"downloads"`
+	Packages      Packages      `yaml:"packages"`
+	Network       Network       `yaml:"network"`
 }
 
 // User contains user-related configuration.
@@ -130,6 +138,126 @@ type Tailscale struct {
 	SkipAuth bool `yaml:"skip_auth"`
 }
 
+// Webhook payload formats.
+const (
+	WebhookFormatGeneric = "generic"
+	WebhookFormatSlack   = "slack"
+	WebhookFormatDiscord = "discord"
+)
+
+// Webhook notification triggers.
+const (
+	WebhookOnAlways  = "always"
+	WebhookOnFailure = "failure"
+)
+
+// Webhook defaults applied to each configured webhook.
+const (
+	defaultWebhookTimeout = 10 * time.Second
+	defaultWebhookRetries = 2
+)
+
+// Notifications contains run completion notification configuration.
+type Notifications struct {
+	// Webhooks are the endpoints notified when a run finishes.
+	Webhooks []Webhook `yaml:"webhooks"`
+}
+
+// Webhook is an HTTP endpoint that receives a JSON summary of each run.
+type Webhook struct {
+	// Name identifies the webhook in logs (optional; defaults to the URL host).
+	Name string `yaml:"name"`
+	// URL is the http(s) endpoint the summary is POSTed to.
+	URL string `yaml:"url"`
+	// Format is the payload shape: "generic" (default), "slack", or "discord".
+	Format string `yaml:"format"`
+	// On controls when the webhook fires: "always" (default) or "failure".
+	On string `yaml:"on"`
+	// Timeout is the timeout for each delivery attempt (default: 10s).
+	Timeout time.Duration `yaml:"timeout"`
+	// Retries is the number of retries after a failed attempt (default: 2).
+	Retries int `yaml:"retries"`
+	// Headers are extra HTTP headers sent with the request (e.g., Authorization).
+	Headers map[string]string `yaml:"headers,omitempty"`
+}
+
+// UnmarshalYAML decodes a webhook, applying defaults for omitted fields.
+func (w *Webhook) UnmarshalYAML(value *yaml.Node) error {
+	type rawWebhook Webhook
+	raw := rawWebhook{
+		Format:  WebhookFormatGeneric,
+		On:      WebhookOnAlways,
+		Timeout: defaultWebhookTimeout,
+		Retries: defaultWebhookRetries,
+	}
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+	*w = Webhook(raw)
+	return nil
+}
+
+// Download defaults.
+const (
+	DefaultDownloadCacheDir = "/var/cache/phanes/downloads"
+	defaultDownloadRetries  = 3
+)
+
+// DefaultPackageLockTimeout is how long package operations wait for package
+// manager locks held by other processes (e.g., unattended-upgrades).
+const DefaultPackageLockTimeout = 10 * time.Minute
+
+// Packages configures package installation.
+type Packages struct {
+	// LockTimeout is how long package operations wait for apt and dpkg locks
+	// held by other processes before failing (default: 10m). 0 fails
+	// immediately.
+	LockTimeout time.Duration `yaml:"lock_timeout"`
+}
+
+// Downloads configures how remote artifacts (install scripts, tarballs) are
+// fetched and verified.
+type Downloads struct {
+	// CacheDir is where verified downloads are kept (default: /var/cache/phanes/downloads).
+	CacheDir string `yaml:"cache_dir"`
+	// Retries is the number of retries after a failed download attempt (default: 3).
+	Retries int `yaml:"retries"`
+	// RequireChecksums refuses to use artifacts without a pinned SHA-256 checksum.
+	RequireChecksums bool `yaml:"require_checksums"`
+	// AllowUnpinnedScripts runs install scripts without a pinned SHA-256
+	// checksum, which are refused by default.
+	AllowUnpinnedScripts bool `yaml:"allow_unpinned_scripts"`
+	// Checksums pins the SHA-256 checksum of artifacts by name (e.g., "coolify-install").
+	Checksums map[string]string `yaml:"checksums,omitempty"`
+	// Mirrors maps URL prefixes to replacement base URLs that are tried first
+	// (e.g., "https://go.dev/dl/": "https://mirror.example.com/go/").
+	Mirrors map[string]string `yaml:"mirrors,omitempty"`
+}
+
+// Network contains network access settings.
+type Network struct {
+	// Proxy is the HTTP(S) proxy used for all outbound connections.
+	Proxy Proxy `yaml:"proxy"`
+}
+
+// Proxy configures an HTTP(S) proxy for phanes' own downloads, apt, the Docker
+// daemon, and installers run as the configured user. An empty HTTP and HTTPS
+// disables the proxy.
+type Proxy struct {
+	// HTTP is the proxy URL for http:// connections (e.g., "http://proxy.example.com:3128").
+	HTTP string `yaml:"http"`
+	// HTTPS is the proxy URL for https:// connections (default: the HTTP proxy).
+	HTTPS string `yaml:"https"`
+	// NoProxy lists hosts, domains (".example.com" or "example.com" include
+	// subdomains), IP addresses and CIDR ranges reached directly.
+	NoProxy []string `yaml:"no_proxy,omitempty"`
+}
+
+// Enabled reports whether a proxy is configured.
+func (p Proxy) Enabled() bool {
+	return p.HTTP != "" || p.HTTPS != ""
+}
+
 // DefaultConfig returns a Config with sensible defaults.
 func DefaultConfig() *Config {
 	return &Config{
@@ -184,7 +312,69 @@ func DefaultConfig() *Config {
 			AuthKey:  "",
 			SkipAuth: false,
 		},
+		Downloads: Downloads{
+			CacheDir: DefaultDownloadCacheDir,
+			Retries:  defaultDownloadRetries,
+		},
+		Packages: Packages{
+			LockTimeout: DefaultPackageLockTimeout,
+		},
+	}
+}
+
+// maskedValue replaces secret values in masked configuration output.
+const maskedValue = "********"
+
+// Masked returns a copy of the Config with secret values (passwords, auth keys)
+// replaced by a placeholder. Empty secrets are left empty so it remains visible
+// that they are not set. The original Config is not modified.
+func (c *Config) Masked() *Config {
+	masked := *c
+	masked.Postgres.Password = maskSecret(c.Postgres.Password)
+	masked.Redis.Password = maskSecret(c.Redis.Password)
+	masked.Tailscale.AuthKey = maskSecret(c.Tailscale.AuthKey)
+	masked.Network.Proxy.HTTP = MaskURLPassword(c.Network.Proxy.HTTP)
+	masked.Network.Proxy.HTTPS = MaskURLPassword(c.Network.Proxy.HTTPS)
+
+	// Webhook URLs and headers often embed tokens (e.g., Slack webhook URLs)
+	masked.Notifications.Webhooks = make([]Webhook, len(c.Notifications.Webhooks))
+	for i, webhook := range c.Notifications.Webhooks {
+		webhook.URL = maskSecret(webhook.URL)
+		if webhook.Headers != nil {
+			headers := make(map[string]string, len(webhook.Headers))
+			for name, value := range webhook.Headers {
+				headers[name] = maskSecret(value)
+			}
+			webhook.Headers = headers
+		}
+		masked.Notifications.Webhooks[i] = webhook
+	}
+
+	return &masked
+}
+
+// maskSecret returns the masked placeholder for non-empty secrets.
+func maskSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	return maskedValue
+}
+
+// MaskURLPassword masks the password of a URL with credentials, such as a
+// proxy URL, as in masked configuration output.
+func MaskURLPassword(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.User == nil {
+		return raw
 	}
+	if _, ok := parsed.User.Password(); !ok {
+		return raw
+	}
+	username := url.User(parsed.User.Username()).String()
+	parsed.User = nil
+	rest := strings.TrimPrefix(parsed.String(), parsed.Scheme+"://")
+	return parsed.Scheme + "://" + username + ":" + maskedValue + "@" + rest
 }
 
 // Load reads and parses a YAML configuration file, applies defaults, and validates it.
@@ -194,7 +384,11 @@ func Load(path string) (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
+	return Parse(data)
+}
 
+// Parse parses YAML configuration, applies defaults, and validates it.
+func Parse(data []byte) (*Config, error) {
 	cfg := DefaultConfig()
 	if err := yaml.Unmarshal(data, cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse YAML: %w", err)
@@ -233,5 +427,109 @@ func Validate(cfg *Config) error {
 		}
 	}
 
+	for i, webhook := range cfg.Notifications.Webhooks {
+		if err := validateWebhook(webhook); err != nil {
+			return fmt.Errorf("notifications.webhooks[%d]: %w", i, err)
+		}
+	}
+
+	if err := validateDownloads(cfg.Downloads); err != nil {
+		return fmt.Errorf("downloads.%w", err)
+	}
+
+	if cfg.Packages.LockTimeout < 0 {
+		return fmt.Errorf("packages.lock_timeout must not be negative")
+	}
+
+	if err := validateProxy(cfg.Network.Proxy); err != nil {
+		return fmt.Errorf("network.proxy.%w", err)
+	}
+
+	return nil
+}
+
+// validateDownloads checks download settings. Returned errors start with the
+// offending key below downloads.
+func validateDownloads(downloads Downloads) error {
+	if downloads.CacheDir != "" && !strings.HasPrefix(downloads.CacheDir, "/") {
+		return fmt.Errorf("cache_dir must be an absolute path")
+	}
+	if downloads.Retries < 0 {
+		return fmt.Errorf("retries must not be negative")
+	}
+	for name, sum := range downloads.Checksums {
+		if !isSHA256(sum) {
+			return fmt.Errorf("checksums.%s must be a hex-encoded SHA-256 checksum", name)
+		}
+	}
+	for prefix, mirror := range downloads.Mirrors {
+		if !isHTTPURL(prefix) || !isHTTPURL(mirror) {
+			return fmt.Errorf("mirrors: %s and its mirror must be absolute http or https URLs", prefix)
+		}
+	}
+	return nil
+}
+
+// validateProxy checks proxy settings. Returned errors start with the offending
+// key below network.proxy.
+func validateProxy(proxy Proxy) error {
+	if proxy.HTTP != "" && !isHTTPURL(proxy.HTTP) {
+		return fmt.Errorf("http must be an absolute http or https URL")
+	}
+	if proxy.HTTPS != "" && !isHTTPURL(proxy.HTTPS) {
+		return fmt.Errorf("https must be an absolute http or https URL")
+	}
+	for i, entry := range proxy.NoProxy {
+		if entry == "" || strings.ContainsAny(entry, " \t,;\"'") {
+			return fmt.Errorf("no_proxy[%d] must be a single host, domain, IP address or CIDR range", i)
+		}
+	}
+	return nil
+}
+
+// isSHA256 reports whether sum is a hex-encoded SHA-256 checksum.
+func isSHA256(sum string) bool {
+	if len(sum) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(sum)
+	return err == nil
+}
+
+// isHTTPURL reports whether raw is an absolute http or https URL.
+func isHTTPURL(raw string) bool {
+	parsed, err := url.Parse(raw)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// validateWebhook checks that a webhook has a usable URL and known options.
+func validateWebhook(webhook Webhook) error {
+	if webhook.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	parsed, err := url.Parse(webhook.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+
+	switch webhook.Format {
+	case WebhookFormatGeneric, WebhookFormatSlack, WebhookFormatDiscord:
+	default:
+		return fmt.Errorf("format must be one of %s, %s, %s", WebhookFormatGeneric, WebhookFormatSlack, WebhookFormatDiscord)
+	}
+
+	switch webhook.On {
+	case WebhookOnAlways, WebhookOnFailure:
+	default:
+		return fmt.Errorf("on must be %s or %s", WebhookOnAlways, WebhookOnFailure)
+	}
+
+	if webhook.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+	if webhook.Retries < 0 {
+		return fmt.Errorf("retries must not be negative")
+	}
+
 	return nil
 }
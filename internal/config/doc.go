@@ -16,6 +16,8 @@
 //   - Caddy: Caddy web server configuration
 //   - DevTools: Development tools configuration
 //   - Coolify: Coolify PaaS platform configuration
+//   - Downloads: Checksum pinning, caching and mirrors for remote artifacts
+//   - Network: HTTP(S) proxy for downloads, apt, Docker and user installers
 //
 // Usage:
 //
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Lookup returns the value of the configuration key at the dotted YAML path
+// (e.g., "postgres.version") in c.
+// Returns an error if the key does not exist or does not refer to a value.
+func (c *Config) Lookup(key string) (interface{}, error) {
+	if key == "" {
+		return nil, fmt.Errorf("config key is empty")
+	}
+
+	value := reflect.ValueOf(c).Elem()
+	for _, part := range strings.Split(key, ".") {
+		if value.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("config key %s not found", key)
+		}
+		field, ok := fieldByYAMLName(value, part)
+		if !ok {
+			return nil, fmt.Errorf("config key %s not found", key)
+		}
+		value = field
+	}
+
+	if value.Kind() == reflect.Struct {
+		return nil, fmt.Errorf("config key %s is a section, not a value", key)
+	}
+
+	return value.Interface(), nil
+}
+
+// fieldByYAMLName returns the field of struct value v whose yaml tag is name.
+func fieldByYAMLName(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if tag == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
@@ -0,0 +1,31 @@
+// Package download fetches the remote artifacts phanes uses (install scripts,
+// release tarballs) and verifies them before they are run or extracted.
+//
+// Every artifact has a name (e.g., "coolify-install") by which its SHA-256
+// checksum can be pinned in the downloads.checksums configuration. A pinned
+// download whose checksum does not match is rejected. Artifacts without a pin
+// are used with a warning that shows their checksum, or refused entirely when
+// downloads.require_checksums is set. Unpinned scripts, which phanes executes,
+// are refused unless downloads.allow_unpinned_scripts is set. A checksum
+// published by the artifact's host (Artifact.Published) is verified as well,
+// but does not count as a pin.
+//
+// Downloads are retried with exponential backoff, and URLs can be redirected to
+// mirrors (downloads.mirrors) which are tried before the original URL. Verified
+// artifacts are kept in a cache directory keyed by checksum, so pinned artifacts
+// are only downloaded once.
+//
+// Usage:
+//
+//	download.Configure(cfg.Downloads)
+//
+//	path, err := download.Fetch(download.Artifact{
+//	    Name:   "coolify-install",
+//	    URL:    "https://cdn.coollabs.io/coolify/install.sh",
+//	    Script: true,
+//	})
+//	if err != nil {
+//	    return err
+//	}
+//	err = exec.Run("bash", path)
+package download
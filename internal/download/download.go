@@ -0,0 +1,385 @@
+package download
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stwalsh4118/phanes/internal/config"
+	"github.com/stwalsh4118/phanes/internal/exec"
+	"github.com/stwalsh4118/phanes/internal/log"
+	"github.com/stwalsh4118/phanes/internal/proxy"
+)
+
+const (
+	userAgent = "phanes"
+
+	// downloadTimeout bounds a single download attempt, including large tarballs.
+	downloadTimeout = 10 * time.Minute
+
+	// maxGetSize limits responses read into memory by Get.
+	maxGetSize = 1 << 20
+
+	cacheDirPerm  = 0755
+	cacheFilePerm = 0644
+)
+
+var (
+	// httpClient and retryBackoff are variables so tests can use a local
+	// server and short delays. The backoff doubles on each further retry.
+	httpClient   = &http.Client{Timeout: downloadTimeout, Transport: proxy.Transport()}
+	retryBackoff = time.Second
+
+	mu       sync.RWMutex
+	settings = config.DefaultConfig().Downloads
+
+	// localFiles maps URLs to local files when downloads are served offline.
+	localFiles map[string]string
+)
+
+// Artifact is a remote file.
+type Artifact struct {
+	// Name identifies the artifact in logs and in downloads.checksums.
+	Name string
+
+	// URL is where the artifact is downloaded from.
+	URL string
+
+	// SHA256 is the expected hex-encoded checksum. A checksum pinned in
+	// downloads.checksums takes precedence. Empty means not pinned.
+	SHA256 string
+
+	// Published is a checksum the artifact's host publishes next to it. It
+	// verifies unpinned downloads against corruption, but is not a pin: it
+	// comes from the same place as the artifact, so require_checksums still
+	// refuses the artifact without a pinned checksum.
+	Published string
+
+	// Script marks an artifact that is executed, such as an install script.
+	// Unpinned scripts are refused unless downloads.allow_unpinned_scripts is
+	// set.
+	Script bool
+}
+
+// Configure applies the download settings from the configuration. It should
+// be called once before modules run.
+func Configure(downloads config.Downloads) {
+	if downloads.CacheDir == "" {
+		downloads.CacheDir = config.DefaultDownloadCacheDir
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	settings = downloads
+}
+
+// UseLocal makes Fetch and Get read from local files instead of the network,
+// e.g. from an extracted offline bundle. files maps URLs to file paths; URLs
+// missing from it fail without a network request. Passing nil restores
+// network downloads.
+func UseLocal(files map[string]string) {
+	mu.Lock()
+	defer mu.Unlock()
+	localFiles = files
+}
+
+// current returns the active download settings.
+func current() config.Downloads {
+	mu.RLock()
+	defer mu.RUnlock()
+	return settings
+}
+
+// local returns the local files set with UseLocal.
+func local() map[string]string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return localFiles
+}
+
+// Fetch downloads the artifact, verifies its checksum, and returns the path of
+// the verified file in the cache directory. The path is valid for commands run
+// through the exec package, also under an alternate root.
+func Fetch(a Artifact) (string, error) {
+	s := current()
+
+	pinned := strings.ToLower(s.Checksums[a.Name])
+	if pinned == "" {
+		pinned = strings.ToLower(a.SHA256)
+	}
+	if pinned == "" && s.RequireChecksums {
+		return "", fmt.Errorf("%s has no pinned SHA-256 checksum; set downloads.checksums.%s", a.Name, a.Name)
+	}
+	if pinned == "" && a.Script && !s.AllowUnpinnedScripts {
+		return "", fmt.Errorf("%s is a script without a pinned SHA-256 checksum; set downloads.checksums.%s, or downloads.allow_unpinned_scripts to run it unverified", a.Name, a.Name)
+	}
+
+	want := pinned
+	if want == "" {
+		want = strings.ToLower(a.Published)
+	}
+
+	if files := local(); files != nil {
+		return fetchLocal(a, want, files)
+	}
+
+	fileName := artifactFileName(a)
+	if want != "" {
+		cached := filepath.Join(s.CacheDir, want, fileName)
+		if sum, err := checksumFile(exec.Path(cached)); err == nil && sum == want {
+			log.Skip("Using cached %s (%s)", a.Name, cached)
+			return cached, nil
+		}
+	}
+
+	if err := os.MkdirAll(exec.Path(s.CacheDir), cacheDirPerm); err != nil {
+		return "", fmt.Errorf("failed to create download cache %s: %w", s.CacheDir, err)
+	}
+
+	var errs []error
+	for _, candidate := range candidates(a.URL, s.Mirrors) {
+		log.Info("Downloading %s from %s", a.Name, candidate)
+		var tmpPath, sum string
+		err := withRetries(a.Name, s.Retries, func() (bool, error) {
+			var retryable bool
+			var err error
+			tmpPath, sum, retryable, err = fetchToFile(candidate, exec.Path(s.CacheDir))
+			return retryable, err
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", candidate, err))
+			continue
+		}
+		if want != "" && sum != want {
+			os.Remove(tmpPath)
+			errs = append(errs, fmt.Errorf("%s: checksum mismatch: got %s, want %s", candidate, sum, want))
+			continue
+		}
+
+		dest := filepath.Join(s.CacheDir, sum, fileName)
+		if err := os.MkdirAll(exec.Path(filepath.Dir(dest)), cacheDirPerm); err != nil {
+			os.Remove(tmpPath)
+			return "", fmt.Errorf("failed to create download cache for %s: %w", a.Name, err)
+		}
+		if err := os.Rename(tmpPath, exec.Path(dest)); err != nil {
+			os.Remove(tmpPath)
+			return "", fmt.Errorf("failed to store %s in download cache: %w", a.Name, err)
+		}
+
+		if pinned == "" {
+			log.Warn("%s is not pinned to a checksum (downloaded SHA-256 %s); pin it with downloads.checksums.%s", a.Name, sum, a.Name)
+		} else {
+			log.Success("Verified %s (SHA-256 %s)", a.Name, sum)
+		}
+		return dest, nil
+	}
+
+	return "", fmt.Errorf("failed to download %s: %w", a.Name, errors.Join(errs...))
+}
+
+// Get downloads a small document such as a published checksum file, with the
+// same mirrors and retries as Fetch. The content is not verified or cached.
+func Get(rawURL string) ([]byte, error) {
+	if files := local(); files != nil {
+		path, ok := files[rawURL]
+		if !ok {
+			return nil, fmt.Errorf("%s is not available offline", rawURL)
+		}
+		return os.ReadFile(exec.Path(path))
+	}
+
+	s := current()
+
+	var errs []error
+	for _, candidate := range candidates(rawURL, s.Mirrors) {
+		var body []byte
+		err := withRetries(candidate, s.Retries, func() (bool, error) {
+			var retryable bool
+			var err error
+			body, retryable, err = getBody(candidate)
+			return retryable, err
+		})
+		if err == nil {
+			return body, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", candidate, err))
+	}
+	return nil, fmt.Errorf("failed to download %s: %w", rawURL, errors.Join(errs...))
+}
+
+// fetchLocal returns the local file of an artifact after verifying it against
+// the expected checksum, if any.
+func fetchLocal(a Artifact, want string, files map[string]string) (string, error) {
+	path, ok := files[a.URL]
+	if !ok {
+		return "", fmt.Errorf("%s (%s) is not available offline", a.Name, a.URL)
+	}
+
+	sum, err := checksumFile(exec.Path(path))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if want != "" && sum != want {
+		return "", fmt.Errorf("%s: checksum mismatch: got %s, want %s", path, sum, want)
+	}
+
+	log.Info("Using %s from %s", a.Name, path)
+	return path, nil
+}
+
+// withRetries calls attempt until it succeeds, fails permanently (attempt
+// reports the failure as not retryable), or the retries are used up. The
+// backoff doubles after each retry.
+func withRetries(name string, retries int, attempt func() (bool, error)) error {
+	backoff := retryBackoff
+
+	var lastErr error
+	for i := 0; i <= retries; i++ {
+		if i > 0 {
+			log.Info("Retrying download of %s in %s (attempt %d of %d)", name, backoff, i+1, retries+1)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		retryable, err := attempt()
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retryable {
+			break
+		}
+	}
+	return lastErr
+}
+
+// request performs a GET request. It reports whether a failure is worth
+// retrying: network errors, 429, and 5xx responses are retried, other
+// responses are not.
+func request(rawURL string) (*http.Response, bool, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return nil, retryable, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp, false, nil
+}
+
+// fetchToFile downloads rawURL into a temporary file in dir and returns its
+// path and SHA-256 checksum.
+func fetchToFile(rawURL, dir string) (string, string, bool, error) {
+	resp, retryable, err := request(rawURL)
+	if err != nil {
+		return "", "", retryable, err
+	}
+	defer resp.Body.Close()
+
+	tmp, err := os.CreateTemp(dir, ".download-*")
+	if err != nil {
+		return "", "", false, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), resp.Body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		// A connection dropped mid-transfer is worth retrying
+		return "", "", true, fmt.Errorf("failed to read response: %w", err)
+	}
+	if err := tmp.Chmod(cacheFilePerm); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", "", false, fmt.Errorf("failed to set permissions: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", "", false, fmt.Errorf("failed to write download: %w", err)
+	}
+
+	return tmp.Name(), hex.EncodeToString(hash.Sum(nil)), false, nil
+}
+
+// getBody downloads rawURL into memory.
+func getBody(rawURL string) ([]byte, bool, error) {
+	resp, retryable, err := request(rawURL)
+	if err != nil {
+		return nil, retryable, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxGetSize+1))
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to read response: %w", err)
+	}
+	if len(body) > maxGetSize {
+		return nil, false, fmt.Errorf("response is larger than %d bytes", maxGetSize)
+	}
+	return body, false, nil
+}
+
+// candidates returns the URLs to try for rawURL: mirrors of matching prefixes,
+// longest prefix first, followed by the original URL.
+func candidates(rawURL string, mirrors map[string]string) []string {
+	prefixes := make([]string, 0, len(mirrors))
+	for prefix := range mirrors {
+		if strings.HasPrefix(rawURL, prefix) {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		return len(prefixes[i]) > len(prefixes[j])
+	})
+
+	urls := make([]string, 0, len(prefixes)+1)
+	for _, prefix := range prefixes {
+		urls = append(urls, mirrors[prefix]+strings.TrimPrefix(rawURL, prefix))
+	}
+	return append(urls, rawURL)
+}
+
+// artifactFileName returns the cache file name of an artifact: the last
+// element of its URL path, or its name if the URL has none.
+func artifactFileName(a Artifact) string {
+	if parsed, err := url.Parse(a.URL); err == nil {
+		if base := path.Base(parsed.Path); base != "." && base != "/" {
+			return base
+		}
+	}
+	return a.Name
+}
+
+// checksumFile returns the hex-encoded SHA-256 checksum of the file at path.
+func checksumFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
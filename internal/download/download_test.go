@@ -0,0 +1,332 @@
+package download
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stwalsh4118/phanes/internal/config"
+)
+
+const scriptContent = "#!/bin/sh\necho installed\n"
+
+// server serves scriptContent at /install.sh and counts requests per path.
+// Paths listed in failures return the given status that many times first.
+type server struct {
+	*httptest.Server
+	mu       sync.Mutex
+	hits     map[string]int
+	failures map[string][]int
+}
+
+func newServer(t *testing.T) *server {
+	t.Helper()
+	s := &server{hits: map[string]int{}, failures: map[string][]int{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.hits[r.URL.Path]++
+		var status int
+		if pending := s.failures[r.URL.Path]; len(pending) > 0 {
+			status, s.failures[r.URL.Path] = pending[0], pending[1:]
+		}
+		s.mu.Unlock()
+
+		switch {
+		case status != 0:
+			w.WriteHeader(status)
+		case strings.HasSuffix(r.URL.Path, "/install.sh"):
+			w.Write([]byte(scriptContent))
+		case strings.HasSuffix(r.URL.Path, "/large"):
+			w.Write(make([]byte, maxGetSize+1))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// setup configures a temporary cache directory and no retry delay.
+func setup(t *testing.T, downloads config.Downloads) string {
+	t.Helper()
+	origSettings, origBackoff := current(), retryBackoff
+	t.Cleanup(func() {
+		Configure(origSettings)
+		UseLocal(nil)
+		retryBackoff = origBackoff
+	})
+
+	retryBackoff = 0
+	downloads.CacheDir = t.TempDir()
+	Configure(downloads)
+	return downloads.CacheDir
+}
+
+func sum(content string) string {
+	h := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(h[:])
+}
+
+func TestFetchPinned(t *testing.T) {
+	srv := newServer(t)
+	cacheDir := setup(t, config.Downloads{Retries: 1})
+
+	artifact := Artifact{Name: "tool-install", URL: srv.URL + "/install.sh", SHA256: sum(scriptContent)}
+	path, err := Fetch(artifact)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if want := filepath.Join(cacheDir, sum(scriptContent), "install.sh"); path != want {
+		t.Errorf("Fetch() path = %q, want %q", path, want)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil || string(content) != scriptContent {
+		t.Errorf("cached content = %q, %v", content, err)
+	}
+
+	// A second fetch is served from the cache
+	if _, err := Fetch(artifact); err != nil {
+		t.Fatalf("Fetch() from cache error = %v", err)
+	}
+	if srv.hits["/install.sh"] != 1 {
+		t.Errorf("server hits = %d, want 1", srv.hits["/install.sh"])
+	}
+}
+
+func TestFetchChecksumMismatch(t *testing.T) {
+	srv := newServer(t)
+	cacheDir := setup(t, config.Downloads{})
+
+	_, err := Fetch(Artifact{Name: "tool-install", URL: srv.URL + "/install.sh", SHA256: strings.Repeat("0", 64)})
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("Fetch() error = %v, want checksum mismatch", err)
+	}
+
+	// Nothing unverified is left in the cache
+	entries, _ := os.ReadDir(cacheDir)
+	if len(entries) != 0 {
+		t.Errorf("cache has %d entries after a mismatch, want 0", len(entries))
+	}
+}
+
+func TestFetchConfiguredChecksumTakesPrecedence(t *testing.T) {
+	srv := newServer(t)
+	setup(t, config.Downloads{Checksums: map[string]string{"tool-install": strings.Repeat("0", 64)}})
+
+	_, err := Fetch(Artifact{Name: "tool-install", URL: srv.URL + "/install.sh", SHA256: sum(scriptContent)})
+	if err == nil {
+		t.Error("Fetch() should verify against the configured checksum")
+	}
+}
+
+func TestFetchUnpinned(t *testing.T) {
+	srv := newServer(t)
+	artifact := Artifact{Name: "tool-install", URL: srv.URL + "/install.sh"}
+
+	setup(t, config.Downloads{RequireChecksums: true})
+	if _, err := Fetch(artifact); err == nil || !strings.Contains(err.Error(), "downloads.checksums.tool-install") {
+		t.Errorf("Fetch() with require_checksums error = %v, want missing checksum error", err)
+	}
+	if srv.hits["/install.sh"] != 0 {
+		t.Error("unpinned artifact should not be downloaded when checksums are required")
+	}
+
+	cacheDir := setup(t, config.Downloads{})
+	path, err := Fetch(artifact)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if want := filepath.Join(cacheDir, sum(scriptContent), "install.sh"); path != want {
+		t.Errorf("Fetch() path = %q, want %q", path, want)
+	}
+}
+
+func TestFetchUnpinnedScript(t *testing.T) {
+	srv := newServer(t)
+	script := Artifact{Name: "tool-install", URL: srv.URL + "/install.sh", Script: true}
+
+	setup(t, config.Downloads{})
+	if _, err := Fetch(script); err == nil || !strings.Contains(err.Error(), "downloads.allow_unpinned_scripts") {
+		t.Errorf("Fetch() of an unpinned script error = %v, want a refusal", err)
+	}
+	if srv.hits["/install.sh"] != 0 {
+		t.Error("unpinned script should not be downloaded")
+	}
+
+	setup(t, config.Downloads{Checksums: map[string]string{"tool-install": sum(scriptContent)}})
+	if _, err := Fetch(script); err != nil {
+		t.Errorf("Fetch() of a pinned script error = %v", err)
+	}
+
+	setup(t, config.Downloads{AllowUnpinnedScripts: true})
+	if _, err := Fetch(script); err != nil {
+		t.Errorf("Fetch() with allow_unpinned_scripts error = %v", err)
+	}
+}
+
+func TestFetchPublished(t *testing.T) {
+	srv := newServer(t)
+	artifact := Artifact{Name: "tool", URL: srv.URL + "/install.sh", Published: sum(scriptContent)}
+
+	// A published checksum is not a pin
+	setup(t, config.Downloads{RequireChecksums: true})
+	if _, err := Fetch(artifact); err == nil || !strings.Contains(err.Error(), "downloads.checksums.tool") {
+		t.Errorf("Fetch() with require_checksums error = %v, want missing checksum error", err)
+	}
+
+	setup(t, config.Downloads{})
+	if _, err := Fetch(artifact); err != nil {
+		t.Errorf("Fetch() error = %v", err)
+	}
+
+	// but it is verified
+	artifact.Published = strings.Repeat("0", 64)
+	if _, err := Fetch(artifact); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Fetch() error = %v, want checksum mismatch", err)
+	}
+}
+
+func TestFetchRetries(t *testing.T) {
+	srv := newServer(t)
+	setup(t, config.Downloads{Retries: 2})
+	srv.failures["/install.sh"] = []int{http.StatusBadGateway, http.StatusServiceUnavailable}
+
+	if _, err := Fetch(Artifact{Name: "tool-install", URL: srv.URL + "/install.sh", SHA256: sum(scriptContent)}); err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if srv.hits["/install.sh"] != 3 {
+		t.Errorf("server hits = %d, want 3", srv.hits["/install.sh"])
+	}
+}
+
+func TestFetchDoesNotRetryClientErrors(t *testing.T) {
+	srv := newServer(t)
+	setup(t, config.Downloads{Retries: 3})
+
+	if _, err := Fetch(Artifact{Name: "missing", URL: srv.URL + "/missing.sh"}); err == nil {
+		t.Fatal("Fetch() of a missing file should fail")
+	}
+	if srv.hits["/missing.sh"] != 1 {
+		t.Errorf("server hits = %d, want 1", srv.hits["/missing.sh"])
+	}
+}
+
+func TestFetchMirror(t *testing.T) {
+	srv := newServer(t)
+	setup(t, config.Downloads{Mirrors: map[string]string{
+		"https://upstream.example.com/": srv.URL + "/mirror/",
+	}})
+
+	_, err := Fetch(Artifact{Name: "tool-install", URL: "https://upstream.example.com/install.sh", SHA256: sum(scriptContent)})
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if srv.hits["/mirror/install.sh"] != 1 {
+		t.Errorf("mirror hits = %d, want 1", srv.hits["/mirror/install.sh"])
+	}
+}
+
+func TestFetchFallsBackToOriginal(t *testing.T) {
+	srv := newServer(t)
+	setup(t, config.Downloads{Mirrors: map[string]string{
+		srv.URL + "/": srv.URL + "/broken/",
+	}})
+	srv.failures["/broken/install.sh"] = []int{http.StatusNotFound}
+
+	if _, err := Fetch(Artifact{Name: "tool-install", URL: srv.URL + "/install.sh", SHA256: sum(scriptContent)}); err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if srv.hits["/broken/install.sh"] != 1 || srv.hits["/install.sh"] != 1 {
+		t.Errorf("hits = %v, want one on the mirror and one on the original", srv.hits)
+	}
+}
+
+func TestGet(t *testing.T) {
+	srv := newServer(t)
+	setup(t, config.Downloads{})
+
+	body, err := Get(srv.URL + "/install.sh")
+	if err != nil || string(body) != scriptContent {
+		t.Errorf("Get() = %q, %v", body, err)
+	}
+	if _, err := Get(srv.URL + "/missing"); err == nil {
+		t.Error("Get() of a missing document should fail")
+	}
+	if _, err := Get(srv.URL + "/large"); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("Get() of an oversized document error = %v, want a size error", err)
+	}
+}
+
+func TestUseLocal(t *testing.T) {
+	srv := newServer(t)
+	setup(t, config.Downloads{})
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "install.sh")
+	if err := os.WriteFile(script, []byte(scriptContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	scriptURL := srv.URL + "/install.sh"
+	UseLocal(map[string]string{scriptURL: script})
+
+	path, err := Fetch(Artifact{Name: "tool-install", URL: scriptURL, SHA256: sum(scriptContent)})
+	if err != nil || path != script {
+		t.Errorf("Fetch() = %q, %v, want %q", path, err, script)
+	}
+	if _, err := Fetch(Artifact{Name: "tool-install", URL: scriptURL, SHA256: strings.Repeat("0", 64)}); err == nil {
+		t.Error("Fetch() should verify local files against the checksum")
+	}
+	if body, err := Get(scriptURL); err != nil || string(body) != scriptContent {
+		t.Errorf("Get() = %q, %v", body, err)
+	}
+
+	if _, err := Fetch(Artifact{Name: "other", URL: srv.URL + "/other.sh"}); err == nil {
+		t.Error("Fetch() of a URL without a local file should fail")
+	}
+	if _, err := Get(srv.URL + "/other.sh"); err == nil {
+		t.Error("Get() of a URL without a local file should fail")
+	}
+	if len(srv.hits) != 0 {
+		t.Errorf("server hits = %v, want none", srv.hits)
+	}
+}
+
+func TestCandidates(t *testing.T) {
+	mirrors := map[string]string{
+		"https://go.dev/":    "https://a.example.com/",
+		"https://go.dev/dl/": "https://b.example.com/go/",
+		"https://other/":     "https://c.example.com/",
+	}
+	got := candidates("https://go.dev/dl/go1.25.5.linux-amd64.tar.gz", mirrors)
+	want := []string{
+		"https://b.example.com/go/go1.25.5.linux-amd64.tar.gz",
+		"https://a.example.com/dl/go1.25.5.linux-amd64.tar.gz",
+		"https://go.dev/dl/go1.25.5.linux-amd64.tar.gz",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("candidates() = %q, want %q", got, want)
+	}
+}
+
+func TestArtifactFileName(t *testing.T) {
+	tests := []struct {
+		artifact Artifact
+		want     string
+	}{
+		{Artifact{Name: "go", URL: "https://go.dev/dl/go1.25.5.linux-amd64.tar.gz"}, "go1.25.5.linux-amd64.tar.gz"},
+		{Artifact{Name: "script", URL: "https://example.com/install.sh?channel=stable"}, "install.sh"},
+		{Artifact{Name: "root", URL: "https://example.com/"}, "root"},
+	}
+	for _, tt := range tests {
+		if got := artifactFileName(tt.artifact); got != tt.want {
+			t.Errorf("artifactFileName(%q) = %q, want %q", tt.artifact.URL, got, tt.want)
+		}
+	}
+}
@@ -11,6 +11,17 @@
 //   - Check if commands exist in PATH
 //   - File existence checks
 //   - File writing utilities
+//   - Alternate root: resolve paths under a directory and chroot commands into it
+//
+// Alternate root:
+//
+// SetRoot makes FileExists, WriteFile and CommandExists operate on a directory
+// tree instead of /, and runs commands inside it with chroot. Code doing its own
+// file I/O maps absolute paths with Path, which resolves symbolic links inside
+// the tree so that absolute link targets cannot lead to the host. Commands
+// missing from the tree, and all commands when not running as root, are
+// skipped: Run and RunWithOutput return ErrSkipped, and callers decide whether
+// the command was optional.
 //
 // Usage:
 //
@@ -29,4 +40,10 @@
 //	if exec.FileExists("/etc/nginx/nginx.conf") {
 //	    log.Info("Nginx config exists")
 //	}
+//
+//	// Build an image tree instead of touching the host
+//	if err := exec.SetRoot("/tmp/sysroot"); err != nil {
+//	    return err
+//	}
+//	content, err := os.ReadFile(exec.Path("/etc/fstab")) // /tmp/sysroot/etc/fstab
 package exec
@@ -3,13 +3,20 @@ package exec
 import (
 	"os"
 	"os/exec"
+	"syscall"
+
+	"github.com/stwalsh4118/phanes/internal/log"
 )
 
 // Run executes a command with the given name and arguments.
 // The command's stdout and stderr are connected to os.Stdout and os.Stderr respectively.
 // Returns an error if the command fails to execute or exits with a non-zero status.
+// Under an alternate root, commands that cannot run inside it return ErrSkipped.
 func Run(name string, args ...string) error {
-	cmd := exec.Command(name, args...)
+	cmd, ok := command(name, args...)
+	if !ok {
+		return ErrSkipped
+	}
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
@@ -18,8 +25,12 @@ func Run(name string, args ...string) error {
 // RunWithOutput executes a command with the given name and arguments and captures its stdout.
 // Returns the command's stdout as a string and an error if the command fails to execute
 // or exits with a non-zero status.
+// Under an alternate root, commands that cannot run inside it return ErrSkipped.
 func RunWithOutput(name string, args ...string) (string, error) {
-	cmd := exec.Command(name, args...)
+	cmd, ok := command(name, args...)
+	if !ok {
+		return "", ErrSkipped
+	}
 	output, err := cmd.Output()
 	if err != nil {
 		return "", err
@@ -27,9 +38,40 @@ func RunWithOutput(name string, args ...string) (string, error) {
 	return string(output), nil
 }
 
+// command builds the command to run, chrooted into the alternate root if one is set.
+// Returns false if the command has to be skipped.
+func command(name string, args ...string) (*exec.Cmd, bool) {
+	r := Root()
+	if r == "" {
+		return exec.Command(name, args...), true
+	}
+
+	if geteuid() != 0 {
+		log.Skip("Skipping %s: running commands under %s requires root", name, r)
+		return nil, false
+	}
+	path, found := lookPathInRoot(name)
+	if !found {
+		log.Skip("Skipping %s: not available under %s", name, r)
+		return nil, false
+	}
+
+	// The path is resolved inside the root since the child chroots before exec
+	cmd := exec.Command(path, args...)
+	cmd.Args[0] = name
+	cmd.Dir = "/"
+	cmd.SysProcAttr = &syscall.SysProcAttr{Chroot: r}
+	return cmd, true
+}
+
 // CommandExists checks if a command exists in the system PATH.
 // Returns true if the command is found, false otherwise.
+// Under an alternate root, the standard binary directories inside the root are searched.
 func CommandExists(cmd string) bool {
+	if Root() != "" {
+		_, found := lookPathInRoot(cmd)
+		return found
+	}
 	_, err := exec.LookPath(cmd)
 	return err == nil
 }
@@ -37,14 +79,14 @@ func CommandExists(cmd string) bool {
 // FileExists checks if a file or directory exists at the given path.
 // Returns true if the path exists, false otherwise.
 func FileExists(path string) bool {
-	_, err := os.Stat(path)
+	_, err := os.Stat(Path(path))
 	return err == nil
 }
 
 // WriteFile writes content to a file at the given path with the specified permissions.
-// If the file already exists, it will be overwritten.
+// If the file already exists, it will be overwritten. Under an alternate root,
+// symbolic links are resolved inside the root (see Path).
 // Returns an error if the file cannot be written.
 func WriteFile(path string, content []byte, perm os.FileMode) error {
-	return os.WriteFile(path, content, perm)
+	return os.WriteFile(Path(path), content, perm)
 }
-
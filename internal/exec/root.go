@@ -0,0 +1,203 @@
+package exec
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// rootPathDirs are searched for commands inside an alternate root.
+var rootPathDirs = []string{"/usr/local/sbin", "/usr/local/bin", "/usr/sbin", "/usr/bin", "/sbin", "/bin"}
+
+// geteuid is a variable so tests can simulate unprivileged runs.
+var geteuid = os.Geteuid
+
+var (
+	rootMu sync.RWMutex
+	root   string
+)
+
+// ErrSkipped is returned by Run and RunWithOutput when a command cannot run
+// inside the alternate root (see SetRoot).
+var ErrSkipped = errors.New("command skipped under alternate root")
+
+// SetRoot makes all file operations of this package (and of packages using
+// Path) resolve under dir, and runs commands inside dir with chroot. Commands
+// that do not exist under dir, or all commands when not running as root, are
+// skipped. An empty dir restores the real root.
+func SetRoot(dir string) error {
+	if dir != "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return fmt.Errorf("failed to resolve root %s: %w", dir, err)
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return fmt.Errorf("failed to access root %s: %w", dir, err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("root %s is not a directory", dir)
+		}
+		dir = abs
+		if dir == "/" {
+			dir = ""
+		}
+	}
+
+	rootMu.Lock()
+	defer rootMu.Unlock()
+	root = dir
+	return nil
+}
+
+// Root returns the alternate root directory, or an empty string when running
+// against the real root.
+func Root() string {
+	rootMu.RLock()
+	defer rootMu.RUnlock()
+	return root
+}
+
+// Path maps an absolute path to its location under the alternate root.
+// Symbolic links are resolved under the root (see Resolve), so file
+// operations on the result cannot leave it; a link in the last element is
+// followed as well, so operations apply to its target. Relative paths, and all
+// paths when no alternate root is set, are returned unchanged.
+func Path(path string) string {
+	r := Root()
+	if r == "" || !filepath.IsAbs(path) {
+		return path
+	}
+	resolved, err := Resolve(path)
+	if err != nil {
+		// Operations on the unresolved path fail the same way (e.g., a
+		// symbolic link loop)
+		resolved = path
+	}
+	return filepath.Join(r, resolved)
+}
+
+// maxSymlinks limits the symbolic links Resolve follows, as the kernel does.
+const maxSymlinks = 40
+
+// Resolve returns the absolute path with all symbolic links resolved, as seen
+// from inside the alternate root: absolute link targets and ".." are resolved
+// under the root rather than on the host. Missing path components are kept as
+// they are, so files that do not exist yet can be resolved.
+func Resolve(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("%s is not an absolute path", path)
+	}
+
+	resolved := "/"
+	rest := strings.Split(path, "/")
+	links := 0
+	for len(rest) > 0 {
+		name := rest[0]
+		rest = rest[1:]
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			resolved = filepath.Dir(resolved)
+			continue
+		}
+
+		next := filepath.Join(resolved, name)
+		info, err := os.Lstat(filepath.Join(Root(), next))
+		if errors.Is(err, os.ErrNotExist) {
+			return filepath.Join(append([]string{next}, rest...)...), nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		links++
+		if links > maxSymlinks {
+			return "", fmt.Errorf("failed to resolve %s: too many symbolic links", path)
+		}
+		target, err := os.Readlink(filepath.Join(Root(), next))
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+		}
+		if filepath.IsAbs(target) {
+			resolved = "/"
+		}
+		rest = append(strings.Split(target, "/"), rest...)
+	}
+	return resolved, nil
+}
+
+// lookPathInRoot finds a command inside the alternate root and returns its
+// path as seen from inside the root. Symbolic links (e.g., /bin -> usr/bin,
+// or /usr/bin/sh -> /bin/dash) are resolved under the root, not on the host.
+func lookPathInRoot(name string) (string, bool) {
+	candidates := []string{name}
+	if !strings.Contains(name, "/") {
+		candidates = candidates[:0]
+		for _, dir := range rootPathDirs {
+			candidates = append(candidates, filepath.Join(dir, name))
+		}
+	}
+	for _, candidate := range candidates {
+		if !filepath.IsAbs(candidate) {
+			candidate = filepath.Join("/", candidate)
+		}
+		resolved, err := Resolve(candidate)
+		if err != nil {
+			continue
+		}
+		info, err := os.Lstat(Path(resolved))
+		if err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0111 != 0 {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// LookupUser looks up a user by name. Under an alternate root, the root's
+// /etc/passwd is read instead of the host's user database.
+func LookupUser(name string) (*user.User, error) {
+	if Root() == "" {
+		return user.Lookup(name)
+	}
+
+	passwdPath := Path("/etc/passwd")
+	file, err := os.Open(passwdPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, user.UnknownUserError(name)
+		}
+		return nil, fmt.Errorf("failed to open %s: %w", passwdPath, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// name:password:uid:gid:gecos:home:shell
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 7 || fields[0] != name {
+			continue
+		}
+		return &user.User{
+			Username: fields[0],
+			Uid:      fields[2],
+			Gid:      fields[3],
+			Name:     strings.SplitN(fields[4], ",", 2)[0],
+			HomeDir:  fields[5],
+		}, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", passwdPath, err)
+	}
+	return nil, user.UnknownUserError(name)
+}
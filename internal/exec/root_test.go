@@ -0,0 +1,243 @@
+package exec
+
+import (
+	"errors"
+	"os"
+	"os/user"
+	"path/filepath"
+	"testing"
+)
+
+// withRoot sets the alternate root to dir for the duration of the test.
+func withRoot(t *testing.T, dir string) {
+	t.Helper()
+	if err := SetRoot(dir); err != nil {
+		t.Fatalf("SetRoot(%q) error = %v", dir, err)
+	}
+	t.Cleanup(func() {
+		SetRoot("")
+	})
+}
+
+// writeRootFile creates a file under dir, creating parent directories.
+func writeRootFile(t *testing.T, dir, path, content string, perm os.FileMode) {
+	t.Helper()
+	full := filepath.Join(dir, path)
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), perm); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetRoot(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := SetRoot(filepath.Join(dir, "missing")); err == nil {
+		t.Error("SetRoot() with a missing directory should fail")
+	}
+	writeRootFile(t, dir, "file", "", 0644)
+	if err := SetRoot(filepath.Join(dir, "file")); err == nil {
+		t.Error("SetRoot() with a file should fail")
+	}
+
+	withRoot(t, "/")
+	if Root() != "" {
+		t.Errorf("Root() after SetRoot(\"/\") = %q, want empty", Root())
+	}
+}
+
+func TestPath(t *testing.T) {
+	if got := Path("/etc/fstab"); got != "/etc/fstab" {
+		t.Errorf("Path() without root = %q, want unchanged", got)
+	}
+
+	dir := t.TempDir()
+	withRoot(t, dir)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/etc/fstab", filepath.Join(dir, "etc/fstab")},
+		{"/", dir},
+		{"relative/file", "relative/file"},
+	}
+	for _, tt := range tests {
+		if got := Path(tt.path); got != tt.want {
+			t.Errorf("Path(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFileOperationsUnderRoot(t *testing.T) {
+	dir := t.TempDir()
+	writeRootFile(t, dir, "etc/hostname", "image\n", 0644)
+	withRoot(t, dir)
+
+	if !FileExists("/etc/hostname") {
+		t.Error("FileExists() should find files under the root")
+	}
+	if FileExists("/etc/does-not-exist") {
+		t.Error("FileExists() should not find missing files under the root")
+	}
+
+	if err := WriteFile("/etc/hostname", []byte("web1\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "etc/hostname"))
+	if err != nil || string(content) != "web1\n" {
+		t.Errorf("file under root = %q, %v, want %q", content, err, "web1\n")
+	}
+}
+
+func TestFileOperationsThroughSymlinksUnderRoot(t *testing.T) {
+	dir := t.TempDir()
+	host := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "etc/apt"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, host), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// An absolute target names the directory inside the root, not on the host
+	if err := os.Symlink(host, filepath.Join(dir, "etc/apt/sources.list.d")); err != nil {
+		t.Fatal(err)
+	}
+	withRoot(t, dir)
+
+	if got, want := Path("/etc/apt/sources.list.d/x.list"), filepath.Join(dir, host, "x.list"); got != want {
+		t.Errorf("Path() = %q, want %q", got, want)
+	}
+	if err := WriteFile("/etc/apt/sources.list.d/x.list", []byte("deb\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(host, "x.list")); !os.IsNotExist(err) {
+		t.Error("WriteFile() followed a symbolic link out of the root")
+	}
+	if !FileExists("/etc/apt/sources.list.d/x.list") {
+		t.Error("FileExists() should find the file written through the link")
+	}
+}
+
+func TestCommandsUnderRoot(t *testing.T) {
+	dir := t.TempDir()
+	writeRootFile(t, dir, "usr/bin/tool", "#!/bin/sh\n", 0755)
+	writeRootFile(t, dir, "usr/bin/not-executable", "", 0644)
+	withRoot(t, dir)
+
+	if !CommandExists("tool") {
+		t.Error("CommandExists() should find executables under the root")
+	}
+	if CommandExists("not-executable") {
+		t.Error("CommandExists() should ignore non-executable files")
+	}
+	if CommandExists("echo") {
+		t.Error("CommandExists() should not find host commands")
+	}
+
+	// Links are resolved under the root, not on the host
+	for link, target := range map[string]string{
+		"usr/bin/host-sh": "/bin/sh",
+		"usr/bin/alias":   "/usr/bin/tool",
+		"bin":             "usr/bin",
+	} {
+		if err := os.Symlink(target, filepath.Join(dir, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if CommandExists("host-sh") {
+		t.Error("CommandExists() should not follow links to host commands")
+	}
+	if !CommandExists("alias") || !CommandExists("/bin/tool") {
+		t.Error("CommandExists() should follow links inside the root")
+	}
+
+	// Missing commands are skipped
+	if err := Run("echo", "test"); !errors.Is(err, ErrSkipped) {
+		t.Errorf("Run() of a missing command error = %v, want ErrSkipped", err)
+	}
+	if _, err := RunWithOutput("echo", "test"); !errors.Is(err, ErrSkipped) {
+		t.Errorf("RunWithOutput() of a missing command error = %v, want ErrSkipped", err)
+	}
+
+	// Without root privileges nothing can be chrooted
+	origGeteuid := geteuid
+	geteuid = func() int { return 1000 }
+	t.Cleanup(func() { geteuid = origGeteuid })
+	if _, err := RunWithOutput("tool"); !errors.Is(err, ErrSkipped) {
+		t.Errorf("RunWithOutput() when unprivileged error = %v, want ErrSkipped", err)
+	}
+	if err := Run("tool"); !errors.Is(err, ErrSkipped) {
+		t.Errorf("Run() when unprivileged error = %v, want ErrSkipped", err)
+	}
+}
+
+func TestLookupUserUnderRoot(t *testing.T) {
+	dir := t.TempDir()
+	writeRootFile(t, dir, "etc/passwd",
+		"root:x:0:0:root:/root:/bin/bash\ndeploy:x:1001:1002:Deploy User,,,:/home/deploy:/bin/bash\n", 0644)
+	withRoot(t, dir)
+
+	u, err := LookupUser("deploy")
+	if err != nil {
+		t.Fatalf("LookupUser() error = %v", err)
+	}
+	if u.Uid != "1001" || u.Gid != "1002" || u.HomeDir != "/home/deploy" || u.Name != "Deploy User" {
+		t.Errorf("LookupUser() = %+v", u)
+	}
+
+	var unknown user.UnknownUserError
+	if _, err := LookupUser("nobody-here"); !errors.As(err, &unknown) {
+		t.Errorf("LookupUser() of a missing user error = %v, want UnknownUserError", err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	withRoot(t, dir)
+	writeRootFile(t, dir, "etc/real.conf", "", 0644)
+	for link, target := range map[string]string{
+		"etc/abs.conf": "/etc/real.conf",
+		"etc/rel.conf": "real.conf",
+		"etc/up.conf":  "../../../etc/real.conf",
+		"etc/chain":    "abs.conf",
+		"etc/dangling": "/etc/new.conf",
+		"etc/loop":     "loop",
+		"etc/linkdir":  "/etc",
+		"etc/hostlink": "/nonexistent-host-path/x",
+	} {
+		if err := os.Symlink(target, filepath.Join(dir, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{path: "/etc/real.conf", want: "/etc/real.conf"},
+		{path: "/etc/abs.conf", want: "/etc/real.conf"},
+		{path: "/etc/rel.conf", want: "/etc/real.conf"},
+		{path: "/etc/up.conf", want: "/etc/real.conf"},
+		{path: "/etc/chain", want: "/etc/real.conf"},
+		{path: "/etc/dangling", want: "/etc/new.conf"},
+		{path: "/etc/linkdir/real.conf", want: "/etc/real.conf"},
+		{path: "/etc/hostlink", want: "/nonexistent-host-path/x"},
+		{path: "/missing/dir/file", want: "/missing/dir/file"},
+		{path: "/etc/loop", wantErr: true},
+		{path: "etc/real.conf", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Resolve(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Resolve(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("Resolve(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
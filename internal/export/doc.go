@@ -0,0 +1,12 @@
+// Package export writes provisioning runs as standalone shell scripts.
+//
+// A Script is built from idempotent steps: commands guarded by checks,
+// files that are only replaced when their content differs, and lines that are
+// only appended when missing. Modules implementing module.Exporter add the
+// steps their Install() would perform; `phanes export` assembles them into a
+// bash script for audits and for hosts that cannot run the phanes binary.
+//
+// Secrets (passwords, auth keys) are never written into the script. Modules
+// reference them with Secret, and the script reads them from environment
+// variables that must be set when it runs.
+package export
@@ -0,0 +1,367 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// heredocDelimiter ends file contents written with a here-document.
+const heredocDelimiter = "PHANES_EOF"
+
+// secretMark delimits secret references (see Secret) in values passed to
+// Script methods.
+const secretMark = "\x00"
+
+// prelude defines the helpers used by the steps. It is written after the
+// header of every script.
+const prelude = `if [ "$(id -u)" -ne 0 ]; then
+  echo "this script must run as root" >&2
+  exit 1
+fi
+if ! command -v apt-get >/dev/null 2>&1; then
+  echo "this script requires apt (Debian or Ubuntu)" >&2
+  exit 1
+fi
+
+export DEBIAN_FRONTEND=noninteractive
+changed=0
+
+log() { printf '==> %s\n' "$*"; }
+
+# write_file PATH MODE OWNER [VALIDATE...] writes stdin to PATH if the content
+# differs, keeping the previous file as PATH.phanes-bak. VALIDATE runs with the
+# path of the new content appended before PATH is replaced.
+write_file() {
+  local path=$1 mode=$2 owner=$3 tmp
+  shift 3
+  tmp=$(mktemp)
+  cat >"$tmp"
+  if [ -f "$path" ] && cmp -s "$tmp" "$path"; then
+    rm -f "$tmp"
+  else
+    if [ "$#" -gt 0 ] && ! "$@" "$tmp"; then
+      rm -f "$tmp"
+      echo "validation of $path failed" >&2
+      return 1
+    fi
+    mkdir -p "$(dirname "$path")"
+    if [ -f "$path" ]; then
+      cp -p "$path" "$path.phanes-bak"
+    fi
+    cp "$tmp" "$path"
+    rm -f "$tmp"
+    changed=1
+    log "wrote $path"
+  fi
+  chmod "$mode" "$path"
+  chown "$owner" "$path"
+}
+
+# ensure_line PATH LINE appends LINE to PATH unless it is already present.
+ensure_line() {
+  if ! grep -qxF -- "$2" "$1" 2>/dev/null; then
+    printf '%s\n' "$2" >>"$1"
+    changed=1
+    log "updated $1"
+  fi
+}
+
+# set_directive PATH KEY VALUE sets "KEY VALUE" on the lines starting with KEY
+# or #KEY, appending it if there is none. An empty VALUE comments KEY out.
+set_directive() {
+  local path=$1 tmp
+  tmp=$(mktemp)
+  KEY=$2 VALUE=$3 awk '
+    { t = $0; sub(/^[ \t]+/, "", t) }
+    index(t, ENVIRON["KEY"]) == 1 || index(t, "#" ENVIRON["KEY"]) == 1 {
+      found = 1
+      if (ENVIRON["VALUE"] != "") print ENVIRON["KEY"] " " ENVIRON["VALUE"]
+      else if (t ~ /^#/) print
+      else print "# " $0
+      next
+    }
+    { print }
+    END { if (!found && ENVIRON["VALUE"] != "") print ENVIRON["KEY"] " " ENVIRON["VALUE"] }
+  ' "$path" >"$tmp"
+  write_file "$path" "$(stat -c %a "$path")" "$(stat -c %U:%G "$path")" <"$tmp"
+  rm -f "$tmp"
+}
+
+# pkg_install PACKAGE... installs the packages that are not installed yet.
+pkg_install() {
+  local missing=() pkg
+  for pkg in "$@"; do
+    if ! dpkg-query -W -f='${Status}' "$pkg" 2>/dev/null | grep -q 'install ok installed'; then
+      missing+=("$pkg")
+    fi
+  done
+  if [ "${#missing[@]}" -gt 0 ]; then
+    log "installing ${missing[*]}"
+    apt-get install -y "${missing[@]}"
+  fi
+}
+
+# service_enable NAME enables NAME at boot and starts it.
+service_enable() {
+  if [ -d /run/systemd/system ]; then
+    systemctl enable --now "$1"
+  else
+    update-rc.d "$1" defaults >/dev/null 2>&1 || true
+    service "$1" start
+  fi
+}
+
+# service_reload NAME reloads NAME, or restarts it if it cannot reload.
+service_reload() {
+  if [ -d /run/systemd/system ]; then
+    systemctl reload-or-restart "$1"
+  else
+    service "$1" reload || service "$1" restart
+  fi
+}
+`
+
+// FileOptions control how WriteFile installs a file.
+type FileOptions struct {
+	// Mode is the file mode (default: 0644).
+	Mode os.FileMode
+
+	// Owner is the owner as "user:group" (default: "root:root"). "user:"
+	// selects the user's login group.
+	Owner string
+
+	// Validate is a command checking the new content before the file is
+	// replaced; the path of the new content is appended to it
+	// (e.g., "visudo", "-c", "-f").
+	Validate []string
+}
+
+type secret struct {
+	name string
+	key  string
+}
+
+// Script is an idempotent bash script under construction.
+type Script struct {
+	title   string
+	body    strings.Builder
+	indent  int
+	secrets []secret
+}
+
+// New returns an empty script. title is written at the top of the script.
+func New(title string) *Script {
+	return &Script{title: title}
+}
+
+// Secret returns a reference to the environment variable name (e.g.,
+// "REDIS_PASSWORD"), which the script requires to be set when it runs. key is
+// the configuration key the value comes from. The reference can be used in the
+// values passed to all other methods, alone or as part of a longer value.
+func (s *Script) Secret(name, key string) string {
+	found := false
+	for _, sec := range s.secrets {
+		found = found || sec.name == name
+	}
+	if !found {
+		s.secrets = append(s.secrets, secret{name: name, key: key})
+	}
+	return secretMark + name + secretMark
+}
+
+// Module starts the steps of a module.
+func (s *Script) Module(name, description string) {
+	s.line("")
+	s.line("# --- %s: %s", name, description)
+	s.line("log %s", Quote("module "+name))
+	s.line("changed=0")
+}
+
+// Comment adds a comment.
+func (s *Script) Comment(format string, args ...interface{}) {
+	for _, line := range strings.Split(fmt.Sprintf(format, args...), "\n") {
+		s.line("# %s", line)
+	}
+}
+
+// Log prints a message when the script runs.
+func (s *Script) Log(format string, args ...interface{}) {
+	s.line("log %s", Quote(fmt.Sprintf(format, args...)))
+}
+
+// Run runs a command. The script stops if it fails.
+func (s *Script) Run(name string, args ...string) {
+	s.line("%s", Command(name, args...))
+}
+
+// Shell adds shell code as is. Values in it must be quoted with Quote.
+func (s *Script) Shell(code string) {
+	for _, line := range strings.Split(code, "\n") {
+		s.line("%s", line)
+	}
+}
+
+// If runs the steps added by then if the shell condition cond succeeds.
+func (s *Script) If(cond string, then func()) {
+	s.IfElse(cond, then, nil)
+}
+
+// IfElse runs the steps added by then if the shell condition cond succeeds,
+// and the steps added by otherwise if not.
+func (s *Script) IfElse(cond string, then, otherwise func()) {
+	s.line("if %s; then", cond)
+	s.block(then)
+	if otherwise != nil {
+		s.line("else")
+		s.block(otherwise)
+	}
+	s.line("fi")
+}
+
+// IfChanged runs the steps added by then if WriteFile, EnsureLine or
+// SetDirective changed a file since the start of the module or the previous
+// IfChanged (e.g., to reload a service).
+func (s *Script) IfChanged(then func()) {
+	s.If(`[ "$changed" -eq 1 ]`, then)
+	s.line("changed=0")
+}
+
+// WriteFile replaces the file at path if its content differs from content.
+func (s *Script) WriteFile(path, content string, opts FileOptions) {
+	mode := opts.Mode
+	if mode == 0 {
+		mode = 0644
+	}
+	owner := opts.Owner
+	if owner == "" {
+		owner = "root:root"
+	}
+	write := Command("write_file", append([]string{path, fmt.Sprintf("%04o", mode), owner}, opts.Validate...)...)
+
+	// Here-documents keep file contents readable, but cannot hold secrets
+	// or content without a final newline
+	if strings.Contains(content, secretMark) || !strings.HasSuffix(content, "\n") ||
+		strings.Contains("\n"+content, "\n"+heredocDelimiter+"\n") {
+		// Process substitution keeps write_file in this shell to track changes
+		s.line("%s < <(printf '%%s' %s)", write, Quote(content))
+		return
+	}
+	s.line("%s <<'%s'", write, heredocDelimiter)
+	s.body.WriteString(content)
+	s.body.WriteString(heredocDelimiter + "\n")
+}
+
+// EnsureLine appends line to the file at path unless the file contains it.
+func (s *Script) EnsureLine(path, line string) {
+	s.Run("ensure_line", path, line)
+}
+
+// SetDirective sets a "key value" directive in the configuration file at path
+// (e.g., redis.conf), commenting the directive out if value is empty.
+func (s *Script) SetDirective(path, key, value string) {
+	s.Run("set_directive", path, key, value)
+}
+
+// RefreshPackages refreshes the package indexes.
+func (s *Script) RefreshPackages() {
+	s.Run("apt-get", "update")
+}
+
+// InstallPackages installs the packages (Debian names) that are missing.
+func (s *Script) InstallPackages(packages ...string) {
+	s.Run("pkg_install", packages...)
+}
+
+// EnableService enables a service at boot and starts it.
+func (s *Script) EnableService(name string) {
+	s.Run("service_enable", name)
+}
+
+// ReloadService reloads or restarts a service.
+func (s *Script) ReloadService(name string) {
+	s.Run("service_reload", name)
+}
+
+// Bytes returns the complete script.
+func (s *Script) Bytes() []byte {
+	var b strings.Builder
+	b.WriteString("#!/usr/bin/env bash\n")
+	for _, line := range strings.Split(s.title, "\n") {
+		fmt.Fprintf(&b, "# %s\n", line)
+	}
+	b.WriteString("#\n")
+	b.WriteString("# Targets Debian and Ubuntu (apt) and must run as root. Every step checks\n")
+	b.WriteString("# the system first, so the script can run repeatedly.\n")
+	b.WriteString("set -euo pipefail\n\n")
+
+	if len(s.secrets) > 0 {
+		b.WriteString("# Secrets are read from the environment:\n")
+		for _, sec := range s.secrets {
+			fmt.Fprintf(&b, "#   %s (%s)\n", sec.name, sec.key)
+		}
+		for _, sec := range s.secrets {
+			fmt.Fprintf(&b, ": \"${%s:?must be set (%s)}\"\n", sec.name, sec.key)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString(prelude)
+	b.WriteString(s.body.String())
+	b.WriteString("\nlog done\n")
+	return []byte(b.String())
+}
+
+// Command returns a quoted command line.
+func Command(name string, args ...string) string {
+	words := make([]string, 0, len(args)+1)
+	words = append(words, Quote(name))
+	for _, arg := range args {
+		words = append(words, Quote(arg))
+	}
+	return strings.Join(words, " ")
+}
+
+// Quote quotes value as a single shell word if needed. Secret references in
+// value are expanded from the environment.
+func Quote(value string) string {
+	if !strings.Contains(value, secretMark) {
+		return quoteLiteral(value)
+	}
+
+	// Parts alternate between literal text and secret names
+	var b strings.Builder
+	for i, part := range strings.Split(value, secretMark) {
+		switch {
+		case i%2 == 1:
+			fmt.Fprintf(&b, `"${%s}"`, part)
+		case part != "":
+			b.WriteString(quoteLiteral(part))
+		}
+	}
+	return b.String()
+}
+
+// quoteLiteral quotes s as a single shell word without expansions.
+func quoteLiteral(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`;&|<>()*?[]#~!{}") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// line writes a formatted line at the current indentation.
+func (s *Script) line(format string, args ...interface{}) {
+	line := fmt.Sprintf(format, args...)
+	if line != "" {
+		s.body.WriteString(strings.Repeat("  ", s.indent))
+	}
+	s.body.WriteString(line + "\n")
+}
+
+// block writes the steps added by fn one level deeper.
+func (s *Script) block(fn func()) {
+	s.indent++
+	defer func() { s.indent-- }()
+	fn()
+}
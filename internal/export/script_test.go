@@ -0,0 +1,177 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	s := New("test")
+	password := s.Secret("DB_PASSWORD", "postgres.password")
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"plain", "plain"},
+		{"LANG=en_US.UTF-8", "LANG=en_US.UTF-8"},
+		{"", "''"},
+		{"two words", "'two words'"},
+		{"it's", `'it'\''s'`},
+		{"$HOME", "'$HOME'"},
+		{password, `"${DB_PASSWORD}"`},
+		{"pass " + password + "!", `'pass '"${DB_PASSWORD}"'!'`},
+	}
+	for _, tt := range tests {
+		if got := Quote(tt.value); got != tt.want {
+			t.Errorf("Quote(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestBytes(t *testing.T) {
+	s := New("Exported for tests")
+	password := s.Secret("REDIS_PASSWORD", "redis.password")
+	s.Secret("REDIS_PASSWORD", "redis.password")
+	s.Module("redis", "Installs Redis")
+	s.If("! command -v redis-cli >/dev/null 2>&1", func() {
+		s.InstallPackages("redis-server")
+	})
+	s.WriteFile("/etc/example.conf", "key ${literal}\n", FileOptions{})
+	s.WriteFile("/etc/secret.conf", "password "+password+"\n", FileOptions{Mode: 0600, Owner: "redis:"})
+	s.SetDirective("/etc/redis/redis.conf", "requirepass", password)
+	s.IfChanged(func() {
+		s.ReloadService("redis-server")
+	})
+	script := string(s.Bytes())
+
+	for _, want := range []string{
+		"#!/usr/bin/env bash\n# Exported for tests\n",
+		"set -euo pipefail\n",
+		": \"${REDIS_PASSWORD:?must be set (redis.password)}\"\n",
+		"if ! command -v redis-cli >/dev/null 2>&1; then\n  pkg_install redis-server\nfi\n",
+		"write_file /etc/example.conf 0644 root:root <<'PHANES_EOF'\nkey ${literal}\nPHANES_EOF\n",
+		`write_file /etc/secret.conf 0600 redis: < <(printf '%s' 'password '"${REDIS_PASSWORD}"'` + "\n')\n",
+		`set_directive /etc/redis/redis.conf requirepass "${REDIS_PASSWORD}"`,
+		"if [ \"$changed\" -eq 1 ]; then\n  service_reload redis-server\nfi\nchanged=0\n",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script missing %q:\n%s", want, script)
+		}
+	}
+	if strings.Count(script, "REDIS_PASSWORD (redis.password)") != 1 {
+		t.Errorf("secret should be listed once:\n%s", script)
+	}
+
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+	path := filepath.Join(t.TempDir(), "script.sh")
+	if err := os.WriteFile(path, []byte(script), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command(bash, "-n", path).CombinedOutput(); err != nil {
+		t.Errorf("script has syntax errors: %v\n%s", err, out)
+	}
+}
+
+// runHelpers runs steps with the helpers of the prelude (without the root and
+// apt checks) and returns the output.
+func runHelpers(t *testing.T, steps func(s *Script)) (string, error) {
+	t.Helper()
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+	s := New("test")
+	steps(s)
+	helpers := prelude[strings.Index(prelude, "export DEBIAN_FRONTEND"):]
+	cmd := exec.Command(bash, "-c", "set -euo pipefail\n"+helpers+s.body.String())
+	cmd.Env = append(os.Environ(), "SECRET=p@ss word")
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+func TestHelpers_WriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "etc", "app.conf")
+	owner := fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid())
+
+	write := func(s *Script, content string) {
+		s.WriteFile(path, content, FileOptions{Mode: 0640, Owner: owner})
+		s.IfChanged(func() { s.Log("changed") })
+	}
+	out, err := runHelpers(t, func(s *Script) {
+		write(s, "a = 1\n")
+		write(s, "a = 1\n")
+		write(s, "a = "+s.Secret("SECRET", "app.secret")+"\n")
+	})
+	if err != nil {
+		t.Fatalf("script failed: %v\n%s", err, out)
+	}
+	if got := strings.Count(out, "==> changed"); got != 2 {
+		t.Errorf("changes = %d, want 2:\n%s", got, out)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "a = p@ss word\n" {
+		t.Errorf("content = %q", data)
+	}
+	if data, _ := os.ReadFile(path + ".phanes-bak"); string(data) != "a = 1\n" {
+		t.Errorf("backup = %q", data)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("mode = %v, %v", info.Mode().Perm(), err)
+	}
+
+	// A failed validation keeps the file
+	out, err = runHelpers(t, func(s *Script) {
+		s.WriteFile(path, "invalid\n", FileOptions{Owner: owner, Validate: []string{"false"}})
+	})
+	if err == nil {
+		t.Errorf("script with a failed validation should fail:\n%s", out)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "a = p@ss word\n" {
+		t.Errorf("content after failed validation = %q", data)
+	}
+}
+
+func TestHelpers_EnsureLineAndSetDirective(t *testing.T) {
+	dir := t.TempDir()
+	keys := filepath.Join(dir, "authorized_keys")
+	conf := filepath.Join(dir, "redis.conf")
+	if err := os.WriteFile(conf, []byte("port 6379\n#requirepass foobared\nbind 0.0.0.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runHelpers(t, func(s *Script) {
+		s.EnsureLine(keys, "ssh-ed25519 AAAA test")
+		s.EnsureLine(keys, "ssh-ed25519 AAAA test")
+		s.SetDirective(conf, "bind", "127.0.0.1")
+		s.SetDirective(conf, "requirepass", s.Secret("SECRET", "redis.password"))
+		s.SetDirective(conf, "maxmemory", "")
+	})
+	if err != nil {
+		t.Fatalf("script failed: %v\n%s", err, out)
+	}
+	if data, _ := os.ReadFile(keys); string(data) != "ssh-ed25519 AAAA test\n" {
+		t.Errorf("authorized_keys = %q", data)
+	}
+	want := "port 6379\nrequirepass p@ss word\nbind 127.0.0.1\n"
+	if data, _ := os.ReadFile(conf); string(data) != want {
+		t.Errorf("redis.conf = %q, want %q", data, want)
+	}
+
+	out, err = runHelpers(t, func(s *Script) {
+		s.SetDirective(conf, "requirepass", "")
+	})
+	if err != nil {
+		t.Fatalf("script failed: %v\n%s", err, out)
+	}
+	if data, _ := os.ReadFile(conf); !strings.Contains(string(data), "# requirepass p@ss word\n") {
+		t.Errorf("requirepass should be commented out: %q", data)
+	}
+}
@@ -0,0 +1,222 @@
+package files
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/stwalsh4118/phanes/internal/exec"
+	"github.com/stwalsh4118/phanes/internal/log"
+)
+
+const (
+	// diffContext is the number of unchanged lines shown around each change.
+	diffContext = 3
+
+	// maxDiffCells bounds the size of the line comparison table. Larger files
+	// are shown as a full replacement.
+	maxDiffCells = 4_000_000
+)
+
+// opKind is the kind of a line in an edit script.
+type opKind byte
+
+const (
+	opEqual  opKind = ' '
+	opDelete opKind = '-'
+	opInsert opKind = '+'
+)
+
+// op is one line of an edit script.
+type op struct {
+	kind opKind
+	line string
+}
+
+// Diff returns a unified diff from the file at path to content. It returns an
+// empty string when they are identical. A missing file diffs as empty.
+func Diff(path string, content []byte) (string, error) {
+	path, err := resolve(path)
+	if err != nil {
+		return "", err
+	}
+
+	existing, _, err := read(exec.Path(path))
+	if err != nil {
+		return "", err
+	}
+	return unified(path, string(existing), string(content)), nil
+}
+
+// Preview logs what writing content to path would change. It returns whether
+// anything would change.
+func Preview(path string, content []byte) (bool, error) {
+	return PreviewMasked(path, content, nil)
+}
+
+// PreviewMasked is like Preview, but passes the diff through mask before it
+// is logged, e.g. to hide passwords in the file.
+func PreviewMasked(path string, content []byte, mask func(string) string) (bool, error) {
+	diff, err := Diff(path, content)
+	if err != nil {
+		return false, err
+	}
+	if mask != nil {
+		diff = mask(diff)
+	}
+	if diff == "" {
+		log.Skip("%s is already up to date", path)
+		return false, nil
+	}
+
+	log.Info("Would write %s:", path)
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		log.Info("  %s", line)
+	}
+	return true, nil
+}
+
+// unified renders the unified diff between old and new, both labeled path.
+func unified(path, old, new string) string {
+	if old == new {
+		return ""
+	}
+
+	ops := editScript(splitLines(old), splitLines(new))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", path, path)
+
+	// oldLine and newLine are the 1-based line numbers at ops[i].
+	oldLine, newLine := 1, 1
+	for i := 0; i < len(ops); {
+		if ops[i].kind == opEqual {
+			oldLine++
+			newLine++
+			i++
+			continue
+		}
+
+		// Extend the hunk backwards over leading context and forwards until
+		// the gap to the next change exceeds twice the context.
+		start := i
+		for start > 0 && i-start < diffContext && ops[start-1].kind == opEqual {
+			start--
+		}
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != opEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == opEqual {
+				run++
+			}
+			if run == len(ops) || run-end > 2*diffContext {
+				end += min(run-end, diffContext)
+				break
+			}
+			end = run
+		}
+
+		hunkOld, hunkNew := oldLine-(i-start), newLine-(i-start)
+		oldCount, newCount := 0, 0
+		for _, o := range ops[start:end] {
+			if o.kind != opInsert {
+				oldCount++
+			}
+			if o.kind != opDelete {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(hunkOld, oldCount), hunkRange(hunkNew, newCount))
+		for _, o := range ops[start:end] {
+			fmt.Fprintf(&b, "%c%s\n", o.kind, o.line)
+		}
+
+		for _, o := range ops[i:end] {
+			if o.kind != opInsert {
+				oldLine++
+			}
+			if o.kind != opDelete {
+				newLine++
+			}
+		}
+		i = end
+	}
+	return b.String()
+}
+
+// hunkRange formats the start,count pair of a hunk header.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		// An empty range refers to the line before the change
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines splits s into lines without their trailing newlines.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// editScript returns a shortest edit script from a to b based on their
+// longest common subsequence.
+func editScript(a, b []string) []op {
+	if len(a)*len(b) > maxDiffCells {
+		ops := make([]op, 0, len(a)+len(b))
+		for _, line := range a {
+			ops = append(ops, op{opDelete, line})
+		}
+		for _, line := range b {
+			ops = append(ops, op{opInsert, line})
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := make([]op, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, op{opEqual, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, op{opDelete, a[i]})
+			i++
+		default:
+			ops = append(ops, op{opInsert, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, op{opDelete, a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, op{opInsert, b[j]})
+	}
+	return ops
+}
@@ -0,0 +1,85 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want string
+	}{
+		{
+			name: "identical",
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+			want: "",
+		},
+		{
+			name: "new file",
+			old:  "",
+			new:  "a\nb\n",
+			want: "--- f\n+++ f\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "single change with context",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			new:  "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			want: "--- f\n+++ f\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name: "separate hunks",
+			old:  "a\n1\n2\n3\n4\n5\n6\n7\n8\nb\n",
+			new:  "A\n1\n2\n3\n4\n5\n6\n7\n8\nB\n",
+			want: "--- f\n+++ f\n" +
+				"@@ -1,4 +1,4 @@\n-a\n+A\n 1\n 2\n 3\n" +
+				"@@ -7,4 +7,4 @@\n 6\n 7\n 8\n-b\n+B\n",
+		},
+		{
+			name: "nearby changes share a hunk",
+			old:  "a\n1\n2\nb\n",
+			new:  "A\n1\n2\nB\n",
+			want: "--- f\n+++ f\n@@ -1,4 +1,4 @@\n-a\n+A\n 1\n 2\n-b\n+B\n",
+		},
+		{
+			name: "deletion",
+			old:  "a\nb\nc\n",
+			new:  "a\nc\n",
+			want: "--- f\n+++ f\n@@ -1,3 +1,2 @@\n a\n-b\n c\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unified("f", tt.old, tt.new); got != tt.want {
+				t.Errorf("unified() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir, _ := setup(t)
+	path := filepath.Join(dir, "jail.local")
+	if err := os.WriteFile(path, []byte("[sshd]\nport = 22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := Diff(path, []byte("[sshd]\nport = 2222\n"))
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if !strings.Contains(diff, "-port = 22\n+port = 2222\n") {
+		t.Errorf("Diff() = %q, missing changed lines", diff)
+	}
+
+	diff, err = Diff(filepath.Join(dir, "missing"), []byte("x\n"))
+	if err != nil || !strings.Contains(diff, "+x\n") {
+		t.Errorf("Diff() for missing file = %q, %v", diff, err)
+	}
+}
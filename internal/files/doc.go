@@ -0,0 +1,31 @@
+// Package files writes the configuration files phanes manages (sshd_config,
+// fstab, redis.conf, jail.local, ...) safely.
+//
+// Writes are atomic: content goes to a temporary file in the target directory,
+// is synced to disk, and is renamed over the target, so a crash never leaves a
+// truncated file behind. The mode and owner of an existing file are preserved
+// unless set explicitly.
+//
+// Before a file is replaced, its previous content is copied to a timestamped
+// backup under /var/lib/phanes/backups (the last DefaultBackups backups are
+// kept per file). The SHA-256 checksum of every written file is recorded in
+// /var/lib/phanes/files.json, so edits made by hand since the last write can be
+// detected with Drifted; Write warns before replacing such a file.
+//
+// Diff produces a unified diff between the file on disk and new content, which
+// Preview logs for dry runs.
+//
+// Usage:
+//
+//	if log.IsDryRun() {
+//	    files.Preview("/etc/redis/redis.conf", content)
+//	    return nil
+//	}
+//	result, err := files.Write("/etc/redis/redis.conf", content, files.Options{Mode: 0644})
+//	if err != nil {
+//	    return err
+//	}
+//	if result.Changed {
+//	    // reload the service
+//	}
+package files
@@ -0,0 +1,382 @@
+package files
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/stwalsh4118/phanes/internal/exec"
+	"github.com/stwalsh4118/phanes/internal/log"
+)
+
+const (
+	// DefaultMode is the mode of newly created files when Options.Mode is zero.
+	DefaultMode os.FileMode = 0644
+
+	// DefaultBackups is the number of backups kept per file.
+	DefaultBackups = 5
+
+	// backupTimeFormat sorts lexically in chronological order.
+	backupTimeFormat = "20060102T150405.000000000Z"
+
+	stateDirPerm  = 0700
+	checksumsFile = "files.json"
+	backupsDir    = "backups"
+)
+
+// stateDir holds backups and recorded checksums. now and chown are variables
+// so tests can use a temporary directory and run unprivileged.
+var (
+	stateDir = "/var/lib/phanes"
+	now      = time.Now
+	chown    = os.Chown
+
+	// mu serializes updates to the checksum database.
+	mu sync.Mutex
+)
+
+// Owner is a numeric file owner.
+type Owner struct {
+	UID int
+	GID int
+}
+
+// Options controls how a file is written.
+type Options struct {
+	// Mode is the permission bits of the file. Zero keeps the mode of an
+	// existing file and uses DefaultMode for new files.
+	Mode os.FileMode
+
+	// Owner sets the file owner. Nil keeps the owner of an existing file;
+	// new files are owned by the current user.
+	Owner *Owner
+}
+
+// Result describes what Write did.
+type Result struct {
+	// Changed is true when the content, mode or owner of the file changed.
+	Changed bool
+
+	// Backup is the path of the backup of the previous content, if one was taken.
+	Backup string
+
+	// Drifted is true when the file had been modified since phanes last wrote it.
+	Drifted bool
+}
+
+// Checksum returns the hex-encoded SHA-256 checksum of content.
+func Checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Write atomically replaces the file at path with content. The previous
+// content, if any and if different, is backed up first, and the checksum of
+// the new content is recorded. Replacing a file that was edited by hand since
+// phanes last wrote it logs a warning. Writing identical content only fixes
+// mode and owner.
+func Write(path string, content []byte, opts Options) (Result, error) {
+	var result Result
+
+	path, err := resolve(path)
+	if err != nil {
+		return result, err
+	}
+
+	target := exec.Path(path)
+	existing, info, err := read(target)
+	if err != nil {
+		return result, err
+	}
+
+	mode := opts.Mode.Perm()
+	owner := opts.Owner
+	if info != nil {
+		if opts.Mode == 0 {
+			mode = info.Mode().Perm()
+		}
+		if owner == nil {
+			owner = ownerOf(info)
+		}
+	} else if opts.Mode == 0 {
+		mode = DefaultMode
+	}
+
+	if info != nil && bytes.Equal(existing, content) {
+		if info.Mode().Perm() != mode {
+			if err := os.Chmod(target, mode); err != nil {
+				return result, fmt.Errorf("failed to set mode of %s: %w", path, err)
+			}
+			result.Changed = true
+		}
+		if owner != nil && !sameOwner(info, owner) {
+			if err := chown(target, owner.UID, owner.GID); err != nil {
+				return result, fmt.Errorf("failed to set owner of %s: %w", path, err)
+			}
+			result.Changed = true
+		}
+		return result, record(path, content)
+	}
+
+	if info != nil {
+		recorded, err := recordedChecksum(path)
+		if err != nil {
+			return result, err
+		}
+		result.Drifted = recorded != "" && recorded != Checksum(existing)
+
+		result.Backup, err = backup(path, existing, info.Mode().Perm())
+		if err != nil {
+			return result, err
+		}
+		if result.Drifted {
+			log.Warn("%s was modified outside phanes; previous content saved to %s", path, result.Backup)
+		}
+	}
+
+	if err := writeAtomic(target, content, mode, owner); err != nil {
+		return result, err
+	}
+	result.Changed = true
+
+	return result, record(path, content)
+}
+
+// Drifted reports whether the file at path differs from the content phanes
+// last wrote to it. Files phanes never wrote, and missing files, have not
+// drifted.
+func Drifted(path string) (bool, error) {
+	path, err := resolve(path)
+	if err != nil {
+		return false, err
+	}
+
+	recorded, err := recordedChecksum(path)
+	if err != nil || recorded == "" {
+		return false, err
+	}
+
+	content, info, err := read(exec.Path(path))
+	if err != nil || info == nil {
+		return false, err
+	}
+	return Checksum(content) != recorded, nil
+}
+
+// Backups returns the backups of the file at path, newest first.
+func Backups(path string) ([]string, error) {
+	path, err := resolve(path)
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := filepath.Glob(backupPrefix(path) + "*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups of %s: %w", path, err)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(matches)))
+	return matches, nil
+}
+
+// Restore atomically writes the content of backupPath (as returned by Write
+// or Backups) back to path, keeping the current mode and owner of path.
+func Restore(path, backupPath string) error {
+	content, err := os.ReadFile(backupPath)
+	if err != nil {
+		return fmt.Errorf("failed to read backup %s: %w", backupPath, err)
+	}
+	if _, err := Write(path, content, Options{}); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", path, err)
+	}
+	return nil
+}
+
+// resolve returns the absolute path of the file at path with symbolic links
+// resolved under the alternate root, so that a link is written through to the
+// file it points to instead of being replaced by a regular file.
+func resolve(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	return exec.Resolve(abs)
+}
+
+// read returns the content and file info of path, or nil info if it does not exist.
+func read(path string) ([]byte, os.FileInfo, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, nil, fmt.Errorf("%s is not a regular file", path)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return content, info, nil
+}
+
+// writeAtomic writes content to a temporary file next to path and renames it
+// over path.
+func writeAtomic(path string, content []byte, mode os.FileMode, owner *Owner) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".phanes-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for %s: %w", path, err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set mode of %s: %w", path, err)
+	}
+	if owner != nil {
+		if err := chown(tmpPath, owner.UID, owner.GID); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to set owner of %s: %w", path, err)
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", path, err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+
+	// Persist the rename itself
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// statePath returns the location of a file in the state directory, which lives
+// under the alternate root when one is set.
+func statePath(elem ...string) string {
+	return exec.Path(filepath.Join(append([]string{stateDir}, elem...)...))
+}
+
+// backupPrefix returns the common prefix of the backups of path.
+func backupPrefix(path string) string {
+	return statePath(backupsDir, strings.TrimPrefix(path, "/")) + "."
+}
+
+// backup saves content as a timestamped backup of path and prunes old backups.
+func backup(path string, content []byte, mode os.FileMode) (string, error) {
+	backupPath := backupPrefix(path) + now().UTC().Format(backupTimeFormat)
+	if err := os.MkdirAll(filepath.Dir(backupPath), stateDirPerm); err != nil {
+		return "", fmt.Errorf("failed to create backup directory for %s: %w", path, err)
+	}
+	if err := os.WriteFile(backupPath, content, mode); err != nil {
+		return "", fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+
+	backups, err := Backups(path)
+	if err != nil {
+		return "", err
+	}
+	for i := DefaultBackups; i < len(backups); i++ {
+		if err := os.Remove(backups[i]); err != nil {
+			return "", fmt.Errorf("failed to prune backup %s: %w", backups[i], err)
+		}
+	}
+	return backupPath, nil
+}
+
+// loadChecksums reads the checksum database. The caller must hold mu.
+func loadChecksums() (map[string]string, error) {
+	checksums := make(map[string]string)
+	data, err := os.ReadFile(statePath(checksumsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return checksums, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file checksums: %w", err)
+	}
+	if err := json.Unmarshal(data, &checksums); err != nil {
+		return nil, fmt.Errorf("failed to parse file checksums: %w", err)
+	}
+	return checksums, nil
+}
+
+// recordedChecksum returns the checksum recorded for path, or "" if none.
+func recordedChecksum(path string) (string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	checksums, err := loadChecksums()
+	if err != nil {
+		return "", err
+	}
+	return checksums[path], nil
+}
+
+// record stores the checksum of content for path.
+func record(path string, content []byte) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	checksums, err := loadChecksums()
+	if err != nil {
+		return err
+	}
+	sum := Checksum(content)
+	if checksums[path] == sum {
+		return nil
+	}
+	checksums[path] = sum
+
+	data, err := json.MarshalIndent(checksums, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode file checksums: %w", err)
+	}
+	if err := os.MkdirAll(statePath(), stateDirPerm); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if err := writeAtomic(statePath(checksumsFile), append(data, '\n'), 0600, nil); err != nil {
+		return fmt.Errorf("failed to record checksum of %s: %w", path, err)
+	}
+	return nil
+}
+
+// ownerOf returns the owner of an existing file, or nil if unknown.
+func ownerOf(info os.FileInfo) *Owner {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	return &Owner{UID: int(st.Uid), GID: int(st.Gid)}
+}
+
+// sameOwner reports whether info is owned by owner.
+func sameOwner(info os.FileInfo, owner *Owner) bool {
+	current := ownerOf(info)
+	return current == nil || *current == *owner
+}
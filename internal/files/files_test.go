@@ -0,0 +1,256 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setup points the state directory at a temporary directory, makes chown a
+// recorder and returns a directory for managed files.
+func setup(t *testing.T) (dir string, chowned *[]Owner) {
+	t.Helper()
+
+	origStateDir, origNow, origChown := stateDir, now, chown
+	t.Cleanup(func() {
+		stateDir, now, chown = origStateDir, origNow, origChown
+	})
+
+	stateDir = t.TempDir()
+	clock := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+	calls := []Owner{}
+	chown = func(_ string, uid, gid int) error {
+		calls = append(calls, Owner{UID: uid, GID: gid})
+		return nil
+	}
+	return t.TempDir(), &calls
+}
+
+func TestWriteNewFile(t *testing.T) {
+	dir, _ := setup(t)
+	path := filepath.Join(dir, "app.conf")
+
+	result, err := Write(path, []byte("a=1\n"), Options{})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if !result.Changed || result.Backup != "" || result.Drifted {
+		t.Errorf("Write() = %+v, want changed without backup or drift", result)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Mode().Perm() != DefaultMode {
+		t.Errorf("mode = %v, want %v", info.Mode().Perm(), DefaultMode)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries, want only the written file", len(entries))
+	}
+}
+
+func TestWriteKeepsModeAndBacksUp(t *testing.T) {
+	dir, _ := setup(t)
+	path := filepath.Join(dir, "sshd_config")
+	if err := os.WriteFile(path, []byte("Port 22\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Write(path, []byte("Port 2222\n"), Options{})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if !result.Changed || result.Backup == "" {
+		t.Fatalf("Write() = %+v, want changed with backup", result)
+	}
+	if result.Drifted {
+		t.Error("file phanes never wrote should not be reported as drifted")
+	}
+
+	backup, err := os.ReadFile(result.Backup)
+	if err != nil || string(backup) != "Port 22\n" {
+		t.Errorf("backup = %q, %v, want previous content", backup, err)
+	}
+	if !strings.HasPrefix(result.Backup, stateDir) {
+		t.Errorf("backup %s is not under the state directory", result.Backup)
+	}
+
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want existing mode 0600 to be kept", info.Mode().Perm())
+	}
+}
+
+func TestWriteSetsModeAndOwner(t *testing.T) {
+	dir, chowned := setup(t)
+	path := filepath.Join(dir, "authorized_keys")
+
+	owner := &Owner{UID: 1000, GID: 1000}
+	if _, err := Write(path, []byte("ssh-ed25519 AAAA\n"), Options{Mode: 0600, Owner: owner}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+	if len(*chowned) != 1 || (*chowned)[0] != *owner {
+		t.Errorf("chown calls = %v, want [%v]", *chowned, *owner)
+	}
+
+	// Same content with a different mode only fixes the mode
+	result, err := Write(path, []byte("ssh-ed25519 AAAA\n"), Options{Mode: 0640})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if !result.Changed || result.Backup != "" {
+		t.Errorf("Write() = %+v, want changed without backup", result)
+	}
+	info, _ = os.Stat(path)
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("mode = %v, want 0640", info.Mode().Perm())
+	}
+}
+
+func TestWriteUnchanged(t *testing.T) {
+	dir, _ := setup(t)
+	path := filepath.Join(dir, "app.conf")
+
+	if _, err := Write(path, []byte("a=1\n"), Options{}); err != nil {
+		t.Fatal(err)
+	}
+	result, err := Write(path, []byte("a=1\n"), Options{})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if result.Changed || result.Backup != "" {
+		t.Errorf("Write() = %+v, want unchanged", result)
+	}
+}
+
+func TestDrift(t *testing.T) {
+	dir, _ := setup(t)
+	path := filepath.Join(dir, "redis.conf")
+
+	if drifted, err := Drifted(path); err != nil || drifted {
+		t.Errorf("Drifted() on unmanaged file = %v, %v, want false", drifted, err)
+	}
+
+	if _, err := Write(path, []byte("maxmemory 256mb\n"), Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if drifted, err := Drifted(path); err != nil || drifted {
+		t.Errorf("Drifted() after write = %v, %v, want false", drifted, err)
+	}
+
+	if err := os.WriteFile(path, []byte("maxmemory 1gb\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if drifted, err := Drifted(path); err != nil || !drifted {
+		t.Errorf("Drifted() after hand edit = %v, %v, want true", drifted, err)
+	}
+
+	result, err := Write(path, []byte("maxmemory 512mb\n"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Drifted {
+		t.Error("Write() over a hand-edited file should report drift")
+	}
+	if drifted, _ := Drifted(path); drifted {
+		t.Error("Drifted() after rewrite should be false")
+	}
+}
+
+func TestBackupsArePruned(t *testing.T) {
+	dir, _ := setup(t)
+	path := filepath.Join(dir, "fstab")
+
+	for i := 0; i < DefaultBackups+3; i++ {
+		if _, err := Write(path, []byte(strings.Repeat("x", i+1)), Options{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := Backups(path)
+	if err != nil {
+		t.Fatalf("Backups() error = %v", err)
+	}
+	if len(backups) != DefaultBackups {
+		t.Fatalf("got %d backups, want %d", len(backups), DefaultBackups)
+	}
+
+	// Newest first: the latest backup holds the content before the last write
+	newest, _ := os.ReadFile(backups[0])
+	if want := strings.Repeat("x", DefaultBackups+2); string(newest) != want {
+		t.Errorf("newest backup = %q, want %q", newest, want)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	dir, _ := setup(t)
+	path := filepath.Join(dir, "sshd_config")
+
+	if _, err := Write(path, []byte("Port 22\n"), Options{}); err != nil {
+		t.Fatal(err)
+	}
+	result, err := Write(path, []byte("Port 2222\n"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Restore(path, result.Backup); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	content, _ := os.ReadFile(path)
+	if string(content) != "Port 22\n" {
+		t.Errorf("restored content = %q, want %q", content, "Port 22\n")
+	}
+}
+
+func TestWriteRejectsDirectory(t *testing.T) {
+	dir, _ := setup(t)
+	if _, err := Write(dir, []byte("x"), Options{}); err == nil {
+		t.Error("Write() to a directory should fail")
+	}
+}
+
+func TestWriteFollowsSymlink(t *testing.T) {
+	dir, _ := setup(t)
+	target := filepath.Join(dir, "real.conf")
+	link := filepath.Join(dir, "app.conf")
+	if err := os.WriteFile(target, []byte("a=1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("real.conf", link); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Write(link, []byte("a=2\n"), Options{})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if !result.Changed || result.Backup == "" {
+		t.Errorf("Write() = %+v, want changed with a backup", result)
+	}
+
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("link was replaced: %v, %v", info, err)
+	}
+	content, err := os.ReadFile(target)
+	if err != nil || string(content) != "a=2\n" {
+		t.Errorf("target content = %q, %v, want %q", content, err, "a=2\n")
+	}
+	if info, _ := os.Stat(target); info.Mode().Perm() != 0600 {
+		t.Errorf("target mode = %v, want 0600", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,22 @@
+// Package fleet provisions many hosts from an inventory concurrently.
+//
+// Apply runs hosts in rolling batches. Within a batch, up to Parallel hosts
+// are provisioned at the same time; the next batch starts once the batch has
+// finished. When more than MaxFailures hosts have failed, no further batches
+// are started and the remaining hosts are reported as skipped.
+//
+// The output of concurrently running hosts is interleaved line by line, each
+// line prefixed with the host name. PrintSummary prints an aggregated
+// per-host summary afterwards.
+//
+// Usage:
+//
+//	results := fleet.Apply(hosts, fleet.Options{
+//	    Parallel:    5,
+//	    BatchSize:   10,
+//	    MaxFailures: 1,
+//	    Output:      os.Stdout,
+//	    Provision:   provisionHost,
+//	})
+//	fleet.PrintSummary(os.Stdout, results)
+package fleet
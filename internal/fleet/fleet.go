@@ -0,0 +1,253 @@
+package fleet
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/stwalsh4118/phanes/internal/inventory"
+	"github.com/stwalsh4118/phanes/internal/report"
+	"github.com/stwalsh4118/phanes/internal/runner"
+)
+
+// Status is the outcome of a host.
+type Status string
+
+const (
+	// StatusSucceeded indicates every module of the host succeeded.
+	StatusSucceeded Status = "succeeded"
+
+	// StatusFailed indicates the host could not be provisioned or a module failed.
+	StatusFailed Status = "failed"
+
+	// StatusSkipped indicates the host was not started because the failure
+	// threshold was reached.
+	StatusSkipped Status = "skipped"
+)
+
+// ProvisionFunc provisions a single host, writing its output to out.
+type ProvisionFunc func(host inventory.Host, out io.Writer) (*report.Results, error)
+
+// Options controls a fleet run.
+type Options struct {
+	// Parallel is the maximum number of hosts provisioned at the same time
+	// (minimum 1).
+	Parallel int
+
+	// BatchSize is the number of hosts per rolling batch. 0 runs all hosts in
+	// a single batch.
+	BatchSize int
+
+	// MaxFailures is the number of failed hosts tolerated. Once more hosts
+	// have failed, no further batches are started.
+	MaxFailures int
+
+	// Output receives the output of all hosts, prefixed with the host name.
+	Output io.Writer
+
+	// Provision provisions a single host.
+	Provision ProvisionFunc
+}
+
+// Result is the outcome of a single host.
+type Result struct {
+	// Host is the host name.
+	Host string
+
+	// Status is the outcome of the host.
+	Status Status
+
+	// Results are the results of the phanes run on the host, if it produced any.
+	Results *report.Results
+
+	// Err is why the host failed or was skipped.
+	Err error
+
+	// Duration is how long the host took.
+	Duration time.Duration
+}
+
+// Apply provisions hosts in rolling batches and returns their results in the
+// order of hosts.
+func Apply(hosts []inventory.Host, opts Options) []Result {
+	parallel := opts.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+	batchSize := opts.BatchSize
+	if batchSize < 1 {
+		batchSize = len(hosts)
+	}
+
+	var outMu sync.Mutex
+	results := make([]Result, len(hosts))
+	failures := 0
+
+	for start := 0; start < len(hosts); start += batchSize {
+		end := min(start+batchSize, len(hosts))
+
+		if failures > opts.MaxFailures {
+			for i := start; i < len(hosts); i++ {
+				results[i] = Result{
+					Host:   hosts[i].Name,
+					Status: StatusSkipped,
+					Err:    fmt.Errorf("stopped after %d failed hosts", failures),
+				}
+			}
+			break
+		}
+
+		sem := make(chan struct{}, parallel)
+		var wg sync.WaitGroup
+		for i := start; i < end; i++ {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int) {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				out := &prefixWriter{prefix: "[" + hosts[i].Name + "] ", out: opts.Output, mu: &outMu}
+				results[i] = provision(hosts[i], opts.Provision, out)
+				out.Flush()
+			}(i)
+		}
+		wg.Wait()
+
+		for i := start; i < end; i++ {
+			if results[i].Status == StatusFailed {
+				failures++
+			}
+		}
+	}
+
+	return results
+}
+
+// provision runs a single host and converts its outcome into a Result.
+func provision(host inventory.Host, fn ProvisionFunc, out io.Writer) Result {
+	started := time.Now()
+	results, err := fn(host, out)
+	result := Result{
+		Host:     host.Name,
+		Status:   StatusSucceeded,
+		Results:  results,
+		Err:      err,
+		Duration: time.Since(started),
+	}
+	if err != nil || (results != nil && !results.Success) {
+		result.Status = StatusFailed
+	}
+	return result
+}
+
+// Failed returns the number of failed hosts.
+func Failed(results []Result) int {
+	n := 0
+	for _, r := range results {
+		if r.Status == StatusFailed {
+			n++
+		}
+	}
+	return n
+}
+
+// PrintSummary prints a table with the outcome of every host and the number
+// of modules installed, skipped and failed on it.
+func PrintSummary(w io.Writer, results []Result) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "HOST\tSTATUS\tINSTALLED\tSKIPPED\tFAILED\tDURATION\tDETAILS")
+
+	counts := make(map[Status]int)
+	for _, r := range results {
+		counts[r.Status]++
+		installed, skipped, failed := "-", "-", "-"
+		if r.Results != nil {
+			i, s, f := countModules(r.Results)
+			installed, skipped, failed = fmt.Sprint(i), fmt.Sprint(s), fmt.Sprint(f)
+		}
+		duration := "-"
+		if r.Status != StatusSkipped {
+			duration = r.Duration.Round(time.Second).String()
+		}
+		details := ""
+		if r.Err != nil {
+			details = r.Err.Error()
+		} else if r.Results != nil {
+			details = firstError(r.Results)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Host, r.Status, installed, skipped, failed, duration, details)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\n%d hosts: %d succeeded, %d failed, %d skipped\n",
+		len(results), counts[StatusSucceeded], counts[StatusFailed], counts[StatusSkipped])
+}
+
+// countModules returns the number of installed (or would-install), skipped
+// and failed modules of a run. Declined, deferred and not run modules count as
+// skipped, and modules whose dependency failed as failed, as in the report.
+func countModules(results *report.Results) (installed, skipped, failed int) {
+	for _, r := range results.Results {
+		switch runner.ModuleStatus(r.Status) {
+		case runner.StatusInstalled, runner.StatusWouldInstall:
+			installed++
+		case runner.StatusSkipped, runner.StatusDeclined, runner.StatusDeferred, runner.StatusNotRun:
+			skipped++
+		case runner.StatusFailed, runner.StatusError, runner.StatusDependencyFailed:
+			failed++
+		}
+	}
+	return installed, skipped, failed
+}
+
+// firstError returns the error of the first failed module, if any.
+func firstError(results *report.Results) string {
+	for _, r := range results.Results {
+		if r.Error != "" {
+			return r.Name + ": " + r.Error
+		}
+	}
+	return ""
+}
+
+// prefixWriter writes complete lines to out, each prefixed with prefix, so
+// the output of concurrent hosts does not mix within a line.
+type prefixWriter struct {
+	prefix string
+	out    io.Writer
+	mu     *sync.Mutex
+	buf    bytes.Buffer
+}
+
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.buf.Write(data)
+	for {
+		i := bytes.IndexByte(p.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(data), nil
+		}
+		line := p.buf.Next(i + 1)
+		p.writeLine(line)
+	}
+}
+
+// Flush writes a trailing incomplete line.
+func (p *prefixWriter) Flush() {
+	if p.buf.Len() > 0 {
+		p.writeLine(append(p.buf.Bytes(), '\n'))
+		p.buf.Reset()
+	}
+}
+
+func (p *prefixWriter) writeLine(line []byte) {
+	if p.out == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	io.WriteString(p.out, p.prefix)
+	p.out.Write(line)
+}
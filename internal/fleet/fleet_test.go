@@ -0,0 +1,178 @@
+package fleet
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stwalsh4118/phanes/internal/inventory"
+	"github.com/stwalsh4118/phanes/internal/report"
+)
+
+func hosts(names ...string) []inventory.Host {
+	result := make([]inventory.Host, len(names))
+	for i, name := range names {
+		result[i] = inventory.Host{Name: name, Address: name}
+	}
+	return result
+}
+
+func TestApply_AllSucceed(t *testing.T) {
+	var out bytes.Buffer
+	results := Apply(hosts("a", "b", "c"), Options{
+		Parallel: 2,
+		Output:   &out,
+		Provision: func(host inventory.Host, w io.Writer) (*report.Results, error) {
+			fmt.Fprintf(w, "hello from %s\npartial", host.Name)
+			return &report.Results{Success: true}, nil
+		},
+	})
+
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	for i, name := range []string{"a", "b", "c"} {
+		if results[i].Host != name || results[i].Status != StatusSucceeded {
+			t.Errorf("results[%d] = %s %s, want %s succeeded", i, results[i].Host, results[i].Status, name)
+		}
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		if !strings.Contains(out.String(), "["+name+"] hello from "+name+"\n") {
+			t.Errorf("output missing prefixed line for %s:\n%s", name, out.String())
+		}
+		if !strings.Contains(out.String(), "["+name+"] partial\n") {
+			t.Errorf("output missing flushed partial line for %s:\n%s", name, out.String())
+		}
+	}
+	if Failed(results) != 0 {
+		t.Errorf("Failed() = %d, want 0", Failed(results))
+	}
+}
+
+func TestApply_Parallelism(t *testing.T) {
+	var running, peak int32
+	Apply(hosts("a", "b", "c", "d", "e", "f"), Options{
+		Parallel: 2,
+		Provision: func(host inventory.Host, w io.Writer) (*report.Results, error) {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return &report.Results{Success: true}, nil
+		},
+	})
+
+	if peak > 2 {
+		t.Errorf("peak concurrency = %d, want at most 2", peak)
+	}
+}
+
+func TestApply_FailureThreshold(t *testing.T) {
+	var mu sync.Mutex
+	var started []string
+	results := Apply(hosts("a", "b", "c", "d", "e"), Options{
+		Parallel:    1,
+		BatchSize:   2,
+		MaxFailures: 0,
+		Provision: func(host inventory.Host, w io.Writer) (*report.Results, error) {
+			mu.Lock()
+			started = append(started, host.Name)
+			mu.Unlock()
+			switch host.Name {
+			case "a":
+				return nil, errors.New("connection refused")
+			case "b":
+				return &report.Results{Success: false}, nil
+			}
+			return &report.Results{Success: true}, nil
+		},
+	})
+
+	want := []Status{StatusFailed, StatusFailed, StatusSkipped, StatusSkipped, StatusSkipped}
+	for i, status := range want {
+		if results[i].Status != status {
+			t.Errorf("results[%d].Status = %s, want %s", i, results[i].Status, status)
+		}
+	}
+	if len(started) != 2 {
+		t.Errorf("started %v, want only the first batch", started)
+	}
+	if Failed(results) != 2 {
+		t.Errorf("Failed() = %d, want 2", Failed(results))
+	}
+}
+
+func TestApply_ToleratesFailures(t *testing.T) {
+	results := Apply(hosts("a", "b", "c"), Options{
+		BatchSize:   1,
+		MaxFailures: 1,
+		Provision: func(host inventory.Host, w io.Writer) (*report.Results, error) {
+			if host.Name == "a" {
+				return nil, errors.New("boom")
+			}
+			return &report.Results{Success: true}, nil
+		},
+	})
+
+	want := []Status{StatusFailed, StatusSucceeded, StatusSucceeded}
+	for i, status := range want {
+		if results[i].Status != status {
+			t.Errorf("results[%d].Status = %s, want %s", i, results[i].Status, status)
+		}
+	}
+}
+
+func TestPrintSummary(t *testing.T) {
+	var out bytes.Buffer
+	PrintSummary(&out, []Result{
+		{
+			Host:   "web-1",
+			Status: StatusSucceeded,
+			Results: &report.Results{Success: true, Results: []report.ModuleResult{
+				{Name: "baseline", Status: "installed"},
+				{Name: "user", Status: "skipped"},
+				{Name: "updates", Status: "deferred"},
+				{Name: "docker", Status: "declined"},
+				{Name: "coolify", Status: "not_run"},
+			}},
+			Duration: 42 * time.Second,
+		},
+		{
+			Host:   "web-2",
+			Status: StatusFailed,
+			Results: &report.Results{Results: []report.ModuleResult{
+				{Name: "baseline", Status: "installed"},
+				{Name: "docker", Status: "failed", Error: "apt-get failed"},
+				{Name: "coolify", Status: "dependency_failed"},
+			}},
+		},
+		{Host: "db-1", Status: StatusFailed, Err: errors.New("connection refused")},
+		{Host: "db-2", Status: StatusSkipped, Err: errors.New("stopped after 2 failed hosts")},
+	})
+
+	got := out.String()
+	for _, want := range []string{
+		"HOST",
+		"web-1  succeeded  1          4        0       42s",
+		"web-2  failed     1          0        2",
+		"docker: apt-get failed",
+		"connection refused",
+		"db-2   skipped    -          -        -       -         stopped after 2 failed hosts",
+		"4 hosts: 1 succeeded, 2 failed, 1 skipped",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("summary missing %q:\n%s", want, got)
+		}
+	}
+}
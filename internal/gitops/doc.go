@@ -0,0 +1,25 @@
+// Package gitops keeps a server converged to configuration stored in a git
+// repository. Repo clones or fetches a branch into a checkout below the state
+// directory, optionally verifies the signature of its head commit, and
+// resolves the configuration file inside it. The state records, per
+// repository, branch and path, the commit and a fingerprint of the
+// configuration that were last applied, so unchanged configuration is not
+// applied again.
+//
+// Any git remote works, including local paths and bare repositories. git must
+// be installed.
+//
+// Usage:
+//
+//	repo := gitops.NewRepo(url, "main")
+//	commit, err := repo.Sync()
+//	if err := repo.Verify(commit, allowedSigners); err != nil { ... }
+//	configPath, err := repo.File("hosts/web1.yaml")
+//
+//	key := gitops.Key(url, "main", "hosts/web1.yaml")
+//	last, ok, err := gitops.Last(key)
+//	if !ok || last.Fingerprint != fingerprint {
+//	    // apply, then:
+//	    gitops.Record(key, gitops.Applied{Commit: commit, Fingerprint: fingerprint})
+//	}
+package gitops
@@ -0,0 +1,158 @@
+package gitops
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const checkoutDirPerm = 0700
+
+// command runs git. It is a variable so tests can inspect the invocations.
+var command = exec.Command
+
+// Repo is a branch of a git repository and its local checkout.
+type Repo struct {
+	// URL is the git remote (URL or local path).
+	URL string
+
+	// Branch is the branch to follow.
+	Branch string
+
+	// Dir is the local checkout.
+	Dir string
+}
+
+// NewRepo returns the repository with its checkout below the state directory.
+// Each URL gets its own checkout.
+func NewRepo(url, branch string) *Repo {
+	sum := sha256.Sum256([]byte(url))
+	return &Repo{
+		URL:    url,
+		Branch: branch,
+		Dir:    filepath.Join(stateDir, "repos", hex.EncodeToString(sum[:8])),
+	}
+}
+
+// Sync clones the branch or fetches its latest commit, checks it out, and
+// returns the commit SHA. Local changes in the checkout are discarded.
+func (r *Repo) Sync() (string, error) {
+	// git would read a leading "-" as an option
+	if r.Branch == "" || strings.HasPrefix(r.Branch, "-") {
+		return "", fmt.Errorf("invalid branch %q", r.Branch)
+	}
+
+	if _, err := os.Stat(filepath.Join(r.Dir, ".git")); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(r.Dir), checkoutDirPerm); err != nil {
+			return "", fmt.Errorf("failed to create %s: %w", filepath.Dir(r.Dir), err)
+		}
+		if _, err := git("", "clone", "--quiet", "--no-checkout", "--origin", "origin", "--", r.URL, r.Dir); err != nil {
+			return "", fmt.Errorf("failed to clone %s: %w", r.URL, err)
+		}
+	} else if _, err := git(r.Dir, "remote", "set-url", "--", "origin", r.URL); err != nil {
+		return "", fmt.Errorf("failed to set remote of %s: %w", r.Dir, err)
+	}
+
+	ref := "refs/remotes/origin/" + r.Branch
+	if _, err := git(r.Dir, "fetch", "--quiet", "origin", "+refs/heads/"+r.Branch+":"+ref); err != nil {
+		return "", fmt.Errorf("failed to fetch branch %s from %s: %w", r.Branch, r.URL, err)
+	}
+	if _, err := git(r.Dir, "checkout", "--quiet", "--force", "--detach", ref); err != nil {
+		return "", fmt.Errorf("failed to check out %s: %w", r.Branch, err)
+	}
+	if _, err := git(r.Dir, "clean", "--quiet", "-d", "--force", "-x"); err != nil {
+		return "", fmt.Errorf("failed to clean %s: %w", r.Dir, err)
+	}
+
+	commit, err := git(r.Dir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve commit: %w", err)
+	}
+	return commit, nil
+}
+
+// Verify checks the GPG or SSH signature of commit with git verify-commit. SSH
+// signatures are checked against allowedSigners (an allowed signers file) if
+// set, otherwise against git's configuration.
+func (r *Repo) Verify(commit, allowedSigners string) error {
+	args := []string{"verify-commit", commit}
+	if allowedSigners != "" {
+		path, err := filepath.Abs(allowedSigners)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", allowedSigners, err)
+		}
+		args = append([]string{"-c", "gpg.ssh.allowedSignersFile=" + path}, args...)
+	}
+	if _, err := git(r.Dir, args...); err != nil {
+		return fmt.Errorf("commit %s has no valid signature: %w", Short(commit), err)
+	}
+	return nil
+}
+
+// Subject returns the first line of the message of commit.
+func (r *Repo) Subject(commit string) (string, error) {
+	return git(r.Dir, "log", "-1", "--format=%s", commit)
+}
+
+// File returns the path of a file in the checkout. path must be relative and
+// stay inside the repository, also after resolving symbolic links.
+func (r *Repo) File(path string) (string, error) {
+	clean := filepath.Clean(path)
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid path %s: must be relative to the repository root", path)
+	}
+
+	// A committed symlink could point anywhere on the server, so the path is
+	// resolved and must stay inside the checkout
+	resolved, err := filepath.EvalSymlinks(filepath.Join(r.Dir, clean))
+	if err != nil {
+		return "", fmt.Errorf("%s not found in branch %s of %s", path, r.Branch, r.URL)
+	}
+	dir, err := filepath.EvalSymlinks(r.Dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", r.Dir, err)
+	}
+	if rel, err := filepath.Rel(dir, resolved); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s points outside the repository", path)
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return "", fmt.Errorf("%s not found in branch %s of %s", path, r.Branch, r.URL)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory", path)
+	}
+	return resolved, nil
+}
+
+// Short abbreviates a commit SHA for display.
+func Short(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
+
+// git runs git in dir (if set) without prompting for credentials and returns
+// its trimmed output.
+func git(dir string, args ...string) (string, error) {
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	cmd := command("git", args...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,202 @@
+package gitops
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// setup points the state directory at a temporary directory and returns a
+// bare repository with a working copy to commit from.
+func setup(t *testing.T) (bare, work string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	orig := stateDir
+	stateDir = filepath.Join(t.TempDir(), "pull")
+	t.Cleanup(func() { stateDir = orig })
+
+	root := t.TempDir()
+	bare = filepath.Join(root, "origin.git")
+	work = filepath.Join(root, "work")
+	runGit(t, "", "init", "--quiet", "--bare", bare)
+	runGit(t, "", "init", "--quiet", "--initial-branch", "main", work)
+	runGit(t, work, "remote", "add", "origin", bare)
+	return bare, work
+}
+
+// commit writes a file in work, commits and pushes it.
+func commit(t *testing.T, work, path, content string) string {
+	t.Helper()
+	full := filepath.Join(work, path)
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, work, "add", "-A")
+	runGit(t, work, "commit", "--quiet", "-m", "update "+path)
+	runGit(t, work, "push", "--quiet", "origin", "main")
+	return runGit(t, work, "rev-parse", "HEAD")
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false"}, args...)
+	out, err := git(dir, args...)
+	if err != nil {
+		t.Fatalf("git %v: %v", args, err)
+	}
+	return out
+}
+
+func TestSync(t *testing.T) {
+	bare, work := setup(t)
+	first := commit(t, work, "hosts/web1.yaml", "user:\n  username: alice\n")
+
+	repo := NewRepo(bare, "main")
+	got, err := repo.Sync()
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if got != first {
+		t.Errorf("Sync() = %s, want %s", got, first)
+	}
+
+	path, err := repo.File("hosts/web1.yaml")
+	if err != nil {
+		t.Fatalf("File() error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "user:\n  username: alice\n" {
+		t.Errorf("config = %q", data)
+	}
+
+	// Local changes are discarded and new commits are fetched
+	os.WriteFile(path, []byte("edited"), 0644)
+	os.WriteFile(filepath.Join(repo.Dir, "stray"), []byte("x"), 0644)
+	second := commit(t, work, "hosts/web1.yaml", "user:\n  username: bob\n")
+
+	got, err = repo.Sync()
+	if err != nil {
+		t.Fatalf("second Sync() error = %v", err)
+	}
+	if got != second {
+		t.Errorf("second Sync() = %s, want %s", got, second)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "user:\n  username: bob\n" {
+		t.Errorf("config after update = %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(repo.Dir, "stray")); !os.IsNotExist(err) {
+		t.Error("untracked file should have been removed")
+	}
+
+	subject, err := repo.Subject(second)
+	if err != nil || subject != "update hosts/web1.yaml" {
+		t.Errorf("Subject() = %q, %v", subject, err)
+	}
+}
+
+func TestSync_UnknownBranch(t *testing.T) {
+	bare, work := setup(t)
+	commit(t, work, "config.yaml", "{}\n")
+
+	if _, err := NewRepo(bare, "release").Sync(); err == nil {
+		t.Error("Sync() of a missing branch should fail")
+	}
+}
+
+func TestSync_InvalidBranch(t *testing.T) {
+	bare, _ := setup(t)
+	repo := NewRepo(bare, "--orphan")
+	if _, err := repo.Sync(); err == nil {
+		t.Error("Sync() of an option-like branch should fail")
+	}
+	if _, err := os.Stat(repo.Dir); !os.IsNotExist(err) {
+		t.Error("nothing should be cloned for an invalid branch")
+	}
+}
+
+func TestVerify_Unsigned(t *testing.T) {
+	bare, work := setup(t)
+	sha := commit(t, work, "config.yaml", "{}\n")
+
+	repo := NewRepo(bare, "main")
+	if _, err := repo.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Verify(sha, ""); err == nil {
+		t.Error("Verify() of an unsigned commit should fail")
+	}
+}
+
+func TestFile_Invalid(t *testing.T) {
+	bare, work := setup(t)
+	commit(t, work, "hosts/web1.yaml", "{}\n")
+
+	repo := NewRepo(bare, "main")
+	if _, err := repo.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	for link, target := range map[string]string{"hosts/passwd.yaml": "/etc/passwd", "hosts/up.yaml": "../../state.json", "hosts/ok.yaml": "web1.yaml"} {
+		if err := os.Symlink(target, filepath.Join(work, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commit(t, work, "config.yaml", "{}\n")
+	if _, err := repo.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.File("hosts/ok.yaml"); err != nil {
+		t.Errorf("File() of a link inside the repository error = %v", err)
+	}
+
+	for _, path := range []string{"../state.json", "/etc/passwd", "hosts", "hosts/missing.yaml", "hosts/passwd.yaml", "hosts/up.yaml"} {
+		if _, err := repo.File(path); err == nil {
+			t.Errorf("File(%q) should fail", path)
+		}
+	}
+}
+
+func TestState(t *testing.T) {
+	orig := stateDir
+	stateDir = filepath.Join(t.TempDir(), "pull")
+	t.Cleanup(func() { stateDir = orig })
+
+	key := Key("https://git.example.com/infra.git", "main", "./hosts/web1.yaml")
+	if key != "https://git.example.com/infra.git#main:hosts/web1.yaml" {
+		t.Errorf("Key() = %s", key)
+	}
+
+	if _, ok, err := Last(key); ok || err != nil {
+		t.Fatalf("Last() before Record = %v, %v", ok, err)
+	}
+
+	fingerprint := Fingerprint([]byte("config"), "web", "")
+	if err := Record(key, Applied{Commit: "abc", Fingerprint: fingerprint}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	applied, ok, err := Last(key)
+	if err != nil || !ok || applied.Commit != "abc" || applied.Fingerprint != fingerprint || applied.AppliedAt.IsZero() {
+		t.Errorf("Last() = %+v, %v, %v", applied, ok, err)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	base := Fingerprint([]byte("config"), "web", "docker")
+	if base != Fingerprint([]byte("config"), "web", "docker") {
+		t.Error("Fingerprint() should be deterministic")
+	}
+	for _, other := range []string{
+		Fingerprint([]byte("config2"), "web", "docker"),
+		Fingerprint([]byte("config"), "dev", "docker"),
+		Fingerprint([]byte("config"), "webdocker", ""),
+	} {
+		if other == base {
+			t.Error("different input should change the fingerprint")
+		}
+	}
+}
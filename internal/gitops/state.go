@@ -0,0 +1,106 @@
+package gitops
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	stateFile     = "state.json"
+	stateFilePerm = 0600
+)
+
+// stateDir holds the checkouts and the state. It is a variable so tests can
+// use a temporary directory.
+var stateDir = "/var/lib/phanes/pull"
+
+// Applied is the last applied configuration of a repository, branch and path.
+type Applied struct {
+	// Commit is the commit SHA that was applied.
+	Commit string `json:"commit"`
+
+	// Fingerprint identifies the configuration and selection that was applied.
+	Fingerprint string `json:"fingerprint"`
+
+	// AppliedAt is when the run finished.
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// Key identifies a configuration file on a branch of a repository.
+func Key(url, branch, path string) string {
+	return url + "#" + branch + ":" + filepath.Clean(path)
+}
+
+// Fingerprint returns a checksum of the configuration and the arguments it is
+// applied with (e.g., profile and modules), so that changing either is
+// detected.
+func Fingerprint(config []byte, args ...string) string {
+	h := sha256.New()
+	h.Write(config)
+	for _, arg := range args {
+		h.Write([]byte{0})
+		h.Write([]byte(arg))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Last returns the last applied configuration for key.
+func Last(key string) (Applied, bool, error) {
+	state, err := load()
+	if err != nil {
+		return Applied{}, false, err
+	}
+	applied, ok := state[key]
+	return applied, ok, nil
+}
+
+// Record stores the applied configuration for key.
+func Record(key string, applied Applied) error {
+	state, err := load()
+	if err != nil {
+		return err
+	}
+	if applied.AppliedAt.IsZero() {
+		applied.AppliedAt = time.Now().UTC()
+	}
+	state[key] = applied
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode pull state: %w", err)
+	}
+	if err := os.MkdirAll(stateDir, checkoutDirPerm); err != nil {
+		return fmt.Errorf("failed to create %s: %w", stateDir, err)
+	}
+	path := filepath.Join(stateDir, stateFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), stateFilePerm); err != nil {
+		return fmt.Errorf("failed to write pull state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write pull state: %w", err)
+	}
+	return nil
+}
+
+// load reads the state, which is empty if it was never written.
+func load() (map[string]Applied, error) {
+	state := make(map[string]Applied)
+	data, err := os.ReadFile(filepath.Join(stateDir, stateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pull state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse pull state: %w", err)
+	}
+	return state, nil
+}
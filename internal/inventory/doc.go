@@ -0,0 +1,41 @@
+// Package inventory reads inventory files describing a fleet of hosts for
+// "phanes fleet".
+//
+// An inventory lists hosts with their SSH address, groups they belong to, and
+// settings: the base configuration file, a profile, extra modules, and a
+// configuration overlay. Settings are applied in layers: defaults, then each
+// group in the order the host lists them, then the host itself. Later layers
+// override the base config file and profile, add modules, and deep-merge
+// their overlay into the configuration (maps are merged, other values are
+// replaced).
+//
+// Example:
+//
+//	defaults:
+//	  config: config.yaml
+//	  profile: minimal
+//	groups:
+//	  web:
+//	    profile: web
+//	    overlay:
+//	      caddy: {enabled: true}
+//	  vpn:
+//	    modules: [tailscale]
+//	hosts:
+//	  web-1:
+//	    address: root@203.0.113.10
+//	    groups: [web, vpn]
+//	  web-2:
+//	    address: deploy@203.0.113.11:2222
+//	    groups: [web]
+//	    overlay:
+//	      system: {timezone: Europe/Berlin}
+//
+// Usage:
+//
+//	inv, err := inventory.Load("inventory.yaml")
+//	if err != nil {
+//	    return err
+//	}
+//	hosts, err := inv.Select([]string{"web"})
+package inventory
@@ -0,0 +1,239 @@
+package inventory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/stwalsh4118/phanes/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Inventory is a parsed inventory file.
+type Inventory struct {
+	// Defaults apply to every host.
+	Defaults Settings `yaml:"defaults"`
+
+	// Groups maps group names to the settings of their members.
+	Groups map[string]Settings `yaml:"groups"`
+
+	// Hosts maps host names to their entries.
+	Hosts map[string]Entry `yaml:"hosts"`
+
+	// dir is the directory of the inventory file; relative config paths are
+	// resolved against it.
+	dir string
+}
+
+// Settings are the settings of defaults, groups and hosts.
+type Settings struct {
+	// Config is the base configuration file, relative to the inventory.
+	Config string `yaml:"config"`
+
+	// Profile is the profile to run.
+	Profile string `yaml:"profile"`
+
+	// Modules are modules to run in addition to the profile.
+	Modules []string `yaml:"modules"`
+
+	// Overlay is merged into the configuration.
+	Overlay map[string]interface{} `yaml:"overlay"`
+}
+
+// Entry is a host in the inventory file.
+type Entry struct {
+	Settings `yaml:",inline"`
+
+	// Address is the SSH destination ("[user@]host[:port]"). Default: the
+	// host name.
+	Address string `yaml:"address"`
+
+	// Groups are the groups the host belongs to, in the order their settings
+	// apply.
+	Groups []string `yaml:"groups"`
+}
+
+// Host is a host with its settings resolved.
+type Host struct {
+	// Name is the host name in the inventory.
+	Name string
+
+	// Address is the SSH destination.
+	Address string
+
+	// Groups are the groups the host belongs to.
+	Groups []string
+
+	// Profile is the profile to run (may be empty if Modules is not).
+	Profile string
+
+	// Modules are the modules to run in addition to the profile.
+	Modules []string
+
+	// Config is the host's validated configuration as YAML.
+	Config []byte
+}
+
+// Load reads and validates an inventory file.
+func Load(path string) (*Inventory, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inventory: %w", err)
+	}
+
+	var inv Inventory
+	if err := yaml.Unmarshal(data, &inv); err != nil {
+		return nil, fmt.Errorf("failed to parse inventory %s: %w", path, err)
+	}
+	inv.dir = filepath.Dir(path)
+
+	if err := inv.validate(); err != nil {
+		return nil, fmt.Errorf("invalid inventory %s: %w", path, err)
+	}
+	return &inv, nil
+}
+
+// validate checks that hosts exist and reference defined groups.
+func (inv *Inventory) validate() error {
+	if len(inv.Hosts) == 0 {
+		return fmt.Errorf("no hosts defined")
+	}
+	for name, entry := range inv.Hosts {
+		if _, ok := inv.Groups[name]; ok {
+			return fmt.Errorf("%s is both a host and a group", name)
+		}
+		for _, group := range entry.Groups {
+			if _, ok := inv.Groups[group]; !ok {
+				return fmt.Errorf("host %s: unknown group %s", name, group)
+			}
+		}
+	}
+	return nil
+}
+
+// Select resolves the hosts named in limit, where each name is a host or a
+// group. An empty limit selects all hosts. Hosts are returned sorted by name.
+func (inv *Inventory) Select(limit []string) ([]Host, error) {
+	selected := make(map[string]bool)
+	if len(limit) == 0 {
+		for name := range inv.Hosts {
+			selected[name] = true
+		}
+	}
+	for _, name := range limit {
+		if _, ok := inv.Hosts[name]; ok {
+			selected[name] = true
+			continue
+		}
+		if _, ok := inv.Groups[name]; !ok {
+			return nil, fmt.Errorf("unknown host or group: %s", name)
+		}
+		for host, entry := range inv.Hosts {
+			for _, group := range entry.Groups {
+				if group == name {
+					selected[host] = true
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(selected))
+	for name := range selected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hosts := make([]Host, 0, len(names))
+	for _, name := range names {
+		host, err := inv.resolve(name)
+		if err != nil {
+			return nil, fmt.Errorf("host %s: %w", name, err)
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
+
+// resolve applies the layered settings of a host.
+func (inv *Inventory) resolve(name string) (Host, error) {
+	entry := inv.Hosts[name]
+	host := Host{Name: name, Address: entry.Address, Groups: entry.Groups}
+	if host.Address == "" {
+		host.Address = name
+	}
+
+	layers := []Settings{inv.Defaults}
+	for _, group := range entry.Groups {
+		layers = append(layers, inv.Groups[group])
+	}
+	layers = append(layers, entry.Settings)
+
+	configPath := ""
+	seen := make(map[string]bool)
+	for _, layer := range layers {
+		if layer.Config != "" {
+			configPath = layer.Config
+		}
+		if layer.Profile != "" {
+			host.Profile = layer.Profile
+		}
+		for _, mod := range layer.Modules {
+			if !seen[mod] {
+				host.Modules = append(host.Modules, mod)
+				seen[mod] = true
+			}
+		}
+	}
+	if host.Profile == "" && len(host.Modules) == 0 {
+		return host, fmt.Errorf("no profile or modules")
+	}
+
+	values := make(map[string]interface{})
+	if configPath != "" {
+		if !filepath.IsAbs(configPath) {
+			configPath = filepath.Join(inv.dir, configPath)
+		}
+		data, err := os.ReadFile(configPath)
+		if err != nil {
+			return host, fmt.Errorf("failed to read config: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return host, fmt.Errorf("failed to parse %s: %w", configPath, err)
+		}
+		if values == nil {
+			values = make(map[string]interface{})
+		}
+	}
+	for _, layer := range layers {
+		merge(values, layer.Overlay)
+	}
+
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return host, fmt.Errorf("failed to encode config: %w", err)
+	}
+	if _, err := config.Parse(data); err != nil {
+		return host, err
+	}
+	host.Config = data
+	return host, nil
+}
+
+// merge deep-merges overlay into dst. Nested maps are merged; other values,
+// including lists, replace the existing value.
+func merge(dst, overlay map[string]interface{}) {
+	for key, value := range overlay {
+		if src, ok := value.(map[string]interface{}); ok {
+			if existing, ok := dst[key].(map[string]interface{}); ok {
+				merge(existing, src)
+				continue
+			}
+			copied := make(map[string]interface{}, len(src))
+			merge(copied, src)
+			dst[key] = copied
+			continue
+		}
+		dst[key] = value
+	}
+}
@@ -0,0 +1,190 @@
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stwalsh4118/phanes/internal/config"
+)
+
+const baseConfig = `user:
+  username: deploy
+  ssh_public_key: ssh-ed25519 AAAA
+system:
+  timezone: UTC
+redis:
+  enabled: false
+  bind_address: 127.0.0.1
+`
+
+const testInventory = `defaults:
+  config: config.yaml
+  profile: minimal
+groups:
+  web:
+    profile: web
+    overlay:
+      redis:
+        enabled: true
+  vpn:
+    modules: [tailscale]
+    overlay:
+      tailscale:
+        enabled: true
+        skip_auth: true
+hosts:
+  web-1:
+    address: root@203.0.113.10
+    groups: [web, vpn]
+  web-2:
+    groups: [web]
+    modules: [docker]
+    overlay:
+      system:
+        timezone: Europe/Berlin
+      redis:
+        bind_address: 0.0.0.0
+  db-1:
+    address: deploy@10.0.0.5:2222
+`
+
+// write writes the test inventory and base config and returns the inventory path.
+func write(t *testing.T, inventory string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(baseConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "inventory.yaml")
+	if err := os.WriteFile(path, []byte(inventory), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSelectAll(t *testing.T) {
+	inv, err := Load(write(t, testInventory))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	hosts, err := inv.Select(nil)
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+
+	var names []string
+	for _, h := range hosts {
+		names = append(names, h.Name)
+	}
+	if want := []string{"db-1", "web-1", "web-2"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("hosts = %q, want %q", names, want)
+	}
+
+	db, web1, web2 := hosts[0], hosts[1], hosts[2]
+	if db.Address != "deploy@10.0.0.5:2222" || db.Profile != "minimal" || db.Modules != nil {
+		t.Errorf("db-1 = %+v", db)
+	}
+	if web1.Profile != "web" || !reflect.DeepEqual(web1.Modules, []string{"tailscale"}) {
+		t.Errorf("web-1 = %+v", web1)
+	}
+	if web2.Address != "web-2" || !reflect.DeepEqual(web2.Modules, []string{"docker"}) {
+		t.Errorf("web-2 = %+v", web2)
+	}
+
+	cfg := parse(t, web2.Config)
+	if cfg.System.Timezone != "Europe/Berlin" || !cfg.Redis.Enabled || cfg.Redis.BindAddress != "0.0.0.0" || cfg.User.Username != "deploy" {
+		t.Errorf("web-2 config = %+v %+v %+v", cfg.System, cfg.Redis, cfg.User)
+	}
+	cfg = parse(t, web1.Config)
+	if !cfg.Tailscale.Enabled || !cfg.Redis.Enabled || cfg.Redis.BindAddress != "127.0.0.1" || cfg.System.Timezone != "UTC" {
+		t.Errorf("web-1 config = %+v %+v %+v", cfg.Tailscale, cfg.Redis, cfg.System)
+	}
+	if cfg := parse(t, db.Config); cfg.Redis.Enabled {
+		t.Error("db-1 should not get the web group's overlay")
+	}
+}
+
+func parse(t *testing.T, data []byte) *config.Config {
+	t.Helper()
+	cfg, err := config.Parse(data)
+	if err != nil {
+		t.Fatalf("config.Parse() error = %v", err)
+	}
+	return cfg
+}
+
+func TestSelectLimit(t *testing.T) {
+	inv, err := Load(write(t, testInventory))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hosts, err := inv.Select([]string{"vpn", "db-1"})
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	if len(hosts) != 2 || hosts[0].Name != "db-1" || hosts[1].Name != "web-1" {
+		t.Errorf("Select(vpn, db-1) = %+v", hosts)
+	}
+
+	if _, err := inv.Select([]string{"nope"}); err == nil {
+		t.Error("Select() with an unknown name should fail")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		inventory string
+		wantErr   string
+	}{
+		{"no hosts", "defaults:\n  profile: web\n", "no hosts"},
+		{"unknown group", "hosts:\n  a:\n    groups: [web]\n", "unknown group web"},
+		{"host and group", "groups:\n  a: {}\nhosts:\n  a:\n    profile: web\n", "both a host and a group"},
+		{"invalid yaml", "hosts: [", "failed to parse"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(write(t, tt.inventory)); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSelectInvalidHost(t *testing.T) {
+	tests := []struct {
+		name      string
+		inventory string
+		wantErr   string
+	}{
+		{"no profile or modules", "defaults:\n  config: config.yaml\nhosts:\n  a: {}\n", "no profile or modules"},
+		{"invalid config", "defaults:\n  config: config.yaml\n  profile: web\nhosts:\n  a:\n    overlay:\n      user:\n        username: \"\"\n", "user.username is required"},
+		{"missing config", "defaults:\n  config: missing.yaml\n  profile: web\nhosts:\n  a: {}\n", "failed to read config"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv, err := Load(write(t, tt.inventory))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := inv.Select(nil); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Select() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMergeDoesNotAliasOverlays(t *testing.T) {
+	overlay := map[string]interface{}{"redis": map[string]interface{}{"enabled": true}}
+	a := map[string]interface{}{}
+	merge(a, overlay)
+	merge(a, map[string]interface{}{"redis": map[string]interface{}{"password": "x"}})
+
+	if _, ok := overlay["redis"].(map[string]interface{})["password"]; ok {
+		t.Error("merge() modified the overlay")
+	}
+}
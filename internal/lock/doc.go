@@ -0,0 +1,15 @@
+// Package lock provides exclusive locks between phanes processes.
+//
+// Acquire takes an advisory lock (flock) on a file. The lock is released when
+// the returned function is called or the process exits, so a crashed run never
+// leaves a stale lock behind. The holder's PID is written to the file for
+// error messages.
+//
+// Usage:
+//
+//	release, err := lock.Acquire("/var/lib/phanes/run.lock")
+//	if errors.Is(err, lock.ErrLocked) {
+//	    log.Error("Another run is in progress (pid %d)", lock.Holder("/var/lib/phanes/run.lock"))
+//	}
+//	defer release()
+package lock
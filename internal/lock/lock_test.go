@@ -0,0 +1,38 @@
+package lock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "run.lock")
+
+	release, err := Acquire(path)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	if got := Holder(path); got != os.Getpid() {
+		t.Errorf("Holder() = %d, want %d", got, os.Getpid())
+	}
+
+	// flock locks conflict between file descriptors, even in one process
+	if _, err := Acquire(path); !errors.Is(err, ErrLocked) {
+		t.Errorf("second Acquire() error = %v, want ErrLocked", err)
+	}
+
+	release()
+	release, err = Acquire(path)
+	if err != nil {
+		t.Fatalf("Acquire() after release error = %v", err)
+	}
+	release()
+}
+
+func TestHolder_Unknown(t *testing.T) {
+	if got := Holder(filepath.Join(t.TempDir(), "missing.lock")); got != 0 {
+		t.Errorf("Holder() of a missing file = %d, want 0", got)
+	}
+}
@@ -0,0 +1,41 @@
+package module
+
+import (
+	"github.com/stwalsh4118/phanes/internal/config"
+	"github.com/stwalsh4118/phanes/internal/download"
+	"github.com/stwalsh4118/phanes/internal/pkgmgr"
+)
+
+// Requirements lists what a module downloads during installation.
+type Requirements struct {
+	// Packages are the system packages the module installs (Debian names).
+	Packages []string
+
+	// Repositories are the third-party package repositories the packages
+	// come from. Their signing keys are included in bundles.
+	Repositories []pkgmgr.Repository
+
+	// Artifacts are the files the module downloads with download.Fetch.
+	Artifacts []download.Artifact
+
+	// Documents are the URLs the module reads with download.Get
+	// (e.g., published checksum files).
+	Documents []string
+
+	// NeedsNetwork explains why installation still needs network access when
+	// everything above is available offline (e.g., an install script that
+	// downloads further files). Empty if the module can install offline.
+	NeedsNetwork string
+}
+
+// Bundler is an optional interface implemented by modules that download
+// packages or files during installation.
+//
+// `phanes bundle` calls Requirements() on a connected machine to fetch
+// everything in advance, so the module can later be installed from the bundle
+// without network access. Modules should only report what Install() would
+// download with cfg (e.g., nothing when the module is disabled).
+type Bundler interface {
+	// Requirements returns what Install() downloads with cfg.
+	Requirements(cfg *config.Config) (Requirements, error)
+}
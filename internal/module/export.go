@@ -0,0 +1,18 @@
+package module
+
+import (
+	"github.com/stwalsh4118/phanes/internal/config"
+	"github.com/stwalsh4118/phanes/internal/export"
+)
+
+// Exporter is an optional interface implemented by modules that can write
+// their installation as a shell script.
+//
+// `phanes export` calls Export() to add the checks and commands Install()
+// would perform with cfg to a standalone script. Export must not inspect or
+// change the system: every check belongs in the script. Secrets must be
+// referenced with Script.Secret instead of being written into the script.
+type Exporter interface {
+	// Export adds the steps of Install() with cfg to s.
+	Export(cfg *config.Config, s *export.Script) error
+}
@@ -0,0 +1,60 @@
+package module
+
+// ConfigKey describes a configuration key a module reads.
+type ConfigKey struct {
+	// Key is the dotted YAML path of the key.
+	// Example: "postgres.version"
+	Key string `json:"key"`
+
+	// Description explains what the key controls.
+	Description string `json:"description"`
+}
+
+// Port describes a network port a module opens or listens on.
+type Port struct {
+	// Number is the port number.
+	Number int `json:"number"`
+
+	// Protocol is the transport protocol ("tcp" or "udp").
+	Protocol string `json:"protocol"`
+
+	// Description explains what is served on the port.
+	Description string `json:"description"`
+}
+
+// Metadata describes what a module reads, manages, and talks to.
+// Paths may contain placeholders in braces (e.g., "{user.username}") for
+// values that depend on the configuration.
+type Metadata struct {
+	// ConfigKeys are the configuration keys the module reads.
+	ConfigKeys []ConfigKey `json:"config_keys"`
+
+	// Files are the files and directories the module creates or modifies.
+	Files []string `json:"files"`
+
+	// Services are the system services the module enables, starts, or reloads.
+	Services []string `json:"services"`
+
+	// Ports are the ports the module opens in the firewall or listens on.
+	Ports []Port `json:"ports"`
+
+	// Packages are the system packages the module installs.
+	Packages []string `json:"packages"`
+
+	// URLs are the remote URLs the module downloads from or adds as repositories.
+	URLs []string `json:"urls"`
+
+	// Requires are the modules that must be installed first. If one of them
+	// fails in the same run, the module is skipped (see runner.ErrorPolicy).
+	Requires []string `json:"requires"`
+}
+
+// Describer is an optional interface implemented by modules that expose
+// detailed metadata about what they manage.
+//
+// Metadata is static: it must not inspect the system or depend on the
+// configuration, so it can be shown without root privileges (phanes describe).
+type Describer interface {
+	// Metadata returns the module's metadata.
+	Metadata() Metadata
+}
@@ -0,0 +1,17 @@
+package module
+
+import "github.com/stwalsh4118/phanes/internal/config"
+
+// BootRequirer is an optional interface implemented by modules that cannot
+// install correctly while a reboot is pending (e.g., modules loading kernel
+// modules, which must match the running kernel after a kernel update).
+//
+// Before calling Install(), the runner asks RequiresBoot() whether a pending
+// reboot (see the reboot package) must happen first. With --reboot-if-needed
+// the server reboots and the module is installed after boot; otherwise the
+// runner warns and installs it anyway.
+type BootRequirer interface {
+	// RequiresBoot reports whether Install() with cfg needs the server to run
+	// the latest kernel and libraries.
+	RequiresBoot(cfg *config.Config) bool
+}
@@ -0,0 +1,55 @@
+package module
+
+import "github.com/stwalsh4118/phanes/internal/config"
+
+// RiskLevel indicates how dangerous a planned action is.
+type RiskLevel int
+
+const (
+	// RiskMedium indicates an action that may disrupt services or connectivity
+	// but is usually recoverable (e.g., enabling a firewall).
+	RiskMedium RiskLevel = iota + 1
+
+	// RiskHigh indicates an action that can lock the operator out of the server
+	// (e.g., changing the SSH port or disabling password authentication).
+	RiskHigh
+)
+
+// String returns a human-readable name for the risk level.
+func (l RiskLevel) String() string {
+	switch l {
+	case RiskMedium:
+		return "medium"
+	case RiskHigh:
+		return "high"
+	default:
+		return "unknown"
+	}
+}
+
+// Risk describes a single potentially dangerous action a module is about to perform.
+type Risk struct {
+	// Level is the severity of the action.
+	Level RiskLevel
+
+	// Action is a short description of what will be changed.
+	// Example: "Change SSH port from 22 to 2222"
+	Action string
+
+	// Reason explains what can go wrong and how to avoid it.
+	// Example: "Existing sessions and firewall rules on port 22 will stop working"
+	Reason string
+}
+
+// RiskAssessor is an optional interface implemented by modules whose installation
+// can lock the operator out of the server or otherwise disrupt access.
+//
+// The runner calls Risks() after IsInstalled() returns false and before Install().
+// If any risks are returned, the runner requires confirmation (interactively or
+// via --yes) before calling Install(). Modules should only report risks for
+// changes that will actually be made given the current system state.
+type RiskAssessor interface {
+	// Risks returns the dangerous actions Install() would perform with cfg.
+	// Returns an empty slice if nothing risky would be changed.
+	Risks(cfg *config.Config) ([]Risk, error)
+}
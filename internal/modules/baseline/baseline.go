@@ -6,8 +6,11 @@ import (
 
 	"github.com/stwalsh4118/phanes/internal/config"
 	"github.com/stwalsh4118/phanes/internal/exec"
+	"github.com/stwalsh4118/phanes/internal/export"
+	"github.com/stwalsh4118/phanes/internal/files"
 	"github.com/stwalsh4118/phanes/internal/log"
 	"github.com/stwalsh4118/phanes/internal/module"
+	"github.com/stwalsh4118/phanes/internal/pkgmgr"
 )
 
 const (
@@ -15,7 +18,7 @@ const (
 )
 
 // BaselineModule implements the Module interface for baseline server configuration.
-// It configures timezone, locale, and runs apt update.
+// It configures timezone, locale, and refreshes the package indexes.
 type BaselineModule struct{}
 
 // Name returns the unique name identifier for this module.
@@ -25,7 +28,21 @@ func (m *BaselineModule) Name() string {
 
 // Description returns a human-readable description of what this module does.
 func (m *BaselineModule) Description() string {
-	return "Sets timezone, locale, and runs apt update"
+	return "Sets timezone, locale, and refreshes package indexes"
+}
+
+// Metadata returns the configuration keys, files, services, ports, packages,
+// and URLs this module uses.
+func (m *BaselineModule) Metadata() module.Metadata {
+	return module.Metadata{
+		ConfigKeys: []module.ConfigKey{
+			{Key: "system.timezone", Description: "System timezone set with timedatectl"},
+		},
+		Files: []string{
+			"/etc/timezone",
+			"/etc/default/locale",
+		},
+	}
 }
 
 // IsInstalled checks if the baseline configuration is already applied.
@@ -101,7 +118,7 @@ func (m *BaselineModule) IsInstalled() (bool, error) {
 	return true, nil
 }
 
-// Install configures the timezone, locale, and runs apt update.
+// Install configures the timezone, locale, and refreshes the package indexes.
 // It uses the provided config to get the timezone setting.
 func (m *BaselineModule) Install(cfg *config.Config) error {
 	timezone := cfg.System.Timezone
@@ -117,7 +134,7 @@ func (m *BaselineModule) Install(cfg *config.Config) error {
 		// timedatectl not available (e.g., in Docker containers without systemd)
 		// Fallback to writing /etc/timezone and creating symlink
 		log.Info("timedatectl not available, using /etc/timezone method")
-		if err := exec.WriteFile("/etc/timezone", []byte(timezone+"\n"), 0644); err != nil {
+		if _, err := files.Write("/etc/timezone", []byte(timezone+"\n"), files.Options{Mode: 0644}); err != nil {
 			return fmt.Errorf("failed to set timezone: %w", err)
 		}
 		// Note: Creating /etc/localtime symlink requires the timezone data files
@@ -180,15 +197,48 @@ func (m *BaselineModule) Install(cfg *config.Config) error {
 		}
 	}
 
-	// Run apt update
-	log.Info("Running apt-get update")
-	if err := exec.Run("apt-get", "update"); err != nil {
-		return fmt.Errorf("failed to run apt-get update: %w", err)
+	// Refresh package indexes
+	pm, err := pkgmgr.Detect()
+	if err != nil {
+		return err
+	}
+	log.Info("Refreshing package indexes (%s)", pm.Name())
+	if err := pm.Refresh(); err != nil {
+		return fmt.Errorf("failed to refresh package indexes: %w", err)
+	}
+	log.Success("Package indexes refreshed")
+
+	return nil
+}
+
+// Export adds the timezone, locale, and package index steps to s.
+func (m *BaselineModule) Export(cfg *config.Config, s *export.Script) error {
+	timezone := cfg.System.Timezone
+	if timezone == "" {
+		timezone = "UTC"
 	}
-	log.Success("apt-get update completed successfully")
 
+	// timedatectl needs systemd; containers fall back to /etc/timezone
+	s.IfElse("timedatectl show >/dev/null 2>&1", func() {
+		s.If(fmt.Sprintf(`[ "$(timedatectl show -p Timezone --value)" != %s ]`, export.Quote(timezone)), func() {
+			s.Log("Setting timezone to %s", timezone)
+			s.Run("timedatectl", "set-timezone", timezone)
+		})
+	}, func() {
+		s.WriteFile("/etc/timezone", timezone+"\n", export.FileOptions{Mode: 0644})
+	})
+
+	s.If(fmt.Sprintf("! grep -qx %s /etc/default/locale 2>/dev/null", export.Quote("LANG="+defaultLocale)), func() {
+		s.Log("Configuring locale %s", defaultLocale)
+		s.Run("locale-gen", defaultLocale)
+		s.Run("update-locale", fmt.Sprintf("LANG=%s", defaultLocale))
+	})
+
+	s.RefreshPackages()
 	return nil
 }
 
-// Ensure BaselineModule implements the Module interface
+// Ensure BaselineModule implements the Module, Describer, and Exporter interfaces
 var _ module.Module = (*BaselineModule)(nil)
+var _ module.Describer = (*BaselineModule)(nil)
+var _ module.Exporter = (*BaselineModule)(nil)
@@ -19,7 +19,7 @@ func TestBaselineModule_Name(t *testing.T) {
 
 func TestBaselineModule_Description(t *testing.T) {
 	mod := &BaselineModule{}
-	want := "Sets timezone, locale, and runs apt update"
+	want := "Sets timezone, locale, and refreshes package indexes"
 	if got := mod.Description(); got != want {
 		t.Errorf("Description() = %q, want %q", got, want)
 	}
@@ -3,12 +3,17 @@ package caddy
 import (
 	"fmt"
 	"os"
-	"strings"
 
+	"github.com/stwalsh4118/phanes/internal/aptrepo"
 	"github.com/stwalsh4118/phanes/internal/config"
 	"github.com/stwalsh4118/phanes/internal/exec"
+	"github.com/stwalsh4118/phanes/internal/files"
 	"github.com/stwalsh4118/phanes/internal/log"
 	"github.com/stwalsh4118/phanes/internal/module"
+	"github.com/stwalsh4118/phanes/internal/osinfo"
+	"github.com/stwalsh4118/phanes/internal/pkgmgr"
+	"github.com/stwalsh4118/phanes/internal/sockets"
+	"github.com/stwalsh4118/phanes/internal/svcmgr"
 )
 
 const (
@@ -18,14 +23,18 @@ const (
 	caddyConfigDir          = "/etc/caddy"
 	caddyfilePath           = "/etc/caddy/Caddyfile"
 	caddyGPGKeyURL          = "https://dl.cloudsmith.io/public/caddy/stable/gpg.key"
-	caddyRepositoryURL       = "https://dl.cloudsmith.io/public/caddy/stable/debian.deb.txt"
-	caddyGPGKeyringPath     = "/usr/share/keyrings/caddy-stable-archive-keyring.gpg"
-	caddyAptSourcesPath     = "/etc/apt/sources.list.d/caddy-stable.list"
+	caddyAptRepositoryURL   = "https://dl.cloudsmith.io/public/caddy/stable/deb/debian"
+	caddyCoprURL            = "https://download.copr.fedorainfracloud.org/results/@caddy/caddy/"
+	caddyRepoName           = "caddy-stable"
+	caddyDnfRepoPath        = "/etc/yum.repos.d/caddy-stable.repo"
 	defaultCaddyfileContent = `localhost {
 	respond "Caddy is running!"
 }`
 )
 
+// caddyKeyFingerprint is the fingerprint of the Caddy stable repository signing key.
+const caddyKeyFingerprint = "65760C51EDEA2017CEA2CA15155B6D79CA56EA34"
+
 // CaddyModule implements the Module interface for Caddy web server installation.
 type CaddyModule struct{}
 
@@ -39,15 +48,96 @@ func (m *CaddyModule) Description() string {
 	return "Installs and configures Caddy web server with automatic HTTPS"
 }
 
+// Metadata returns the configuration keys, files, services, ports, packages,
+// and URLs this module uses.
+func (m *CaddyModule) Metadata() module.Metadata {
+	return module.Metadata{
+		ConfigKeys: []module.ConfigKey{
+			{Key: "caddy.enabled", Description: "Whether to install Caddy"},
+		},
+		Files: []string{
+			caddyfilePath,
+			aptrepo.KeyringPath(caddyRepoName),
+			aptrepo.SourcesPath(caddyRepoName),
+			caddyDnfRepoPath,
+		},
+		Services: []string{caddyServiceName},
+		Ports: []module.Port{
+			{Number: caddyDefaultPort, Protocol: "tcp", Description: "HTTP"},
+			{Number: 443, Protocol: "tcp", Description: "HTTPS with automatic certificates"},
+		},
+		Packages: []string{"caddy", "debian-keyring", "debian-archive-keyring", "apt-transport-https"},
+		URLs: []string{
+			caddyGPGKeyURL,
+			caddyAptRepositoryURL,
+			caddyCoprURL,
+		},
+	}
+}
+
+// Requirements returns the Caddy packages and the repository they come from.
+func (m *CaddyModule) Requirements(cfg *config.Config) (module.Requirements, error) {
+	if !cfg.Caddy.Enabled {
+		return module.Requirements{}, nil
+	}
+
+	pm, err := pkgmgr.Detect()
+	if err != nil {
+		return module.Requirements{}, err
+	}
+	repo, err := caddyRepository(pm)
+	if err != nil {
+		return module.Requirements{}, err
+	}
+
+	reqs := module.Requirements{Packages: []string{"debian-keyring", "debian-archive-keyring", "apt-transport-https", "curl", "caddy"}}
+	if repo != nil {
+		reqs.Repositories = []pkgmgr.Repository{*repo}
+	}
+	return reqs, nil
+}
+
+// caddyRepository returns Caddy's package repository for the detected OS, or
+// nil if Caddy is installed from the distribution's own repositories (Alpine).
+func caddyRepository(pm pkgmgr.Manager) (*pkgmgr.Repository, error) {
+	switch pm.Name() {
+	case pkgmgr.NameApt:
+		return &pkgmgr.Repository{
+			Name:        caddyRepoName,
+			URL:         caddyAptRepositoryURL,
+			KeyURL:      caddyGPGKeyURL,
+			Fingerprint: caddyKeyFingerprint,
+			Suite:       "any-version",
+			Components:  []string{"main"},
+		}, nil
+	case pkgmgr.NameDnf:
+		info, err := osinfo.Detect()
+		if err != nil {
+			return nil, fmt.Errorf("failed to detect operating system: %w", err)
+		}
+		// The COPR builds are published as fedora-* and epel-* (RHEL rebuilds)
+		chroot := "epel"
+		if info.Is("fedora") && !info.Is("rhel") {
+			chroot = "fedora"
+		}
+		return &pkgmgr.Repository{
+			Name:   caddyRepoName,
+			URL:    caddyCoprURL + chroot + "-$releasever-$basearch/",
+			KeyURL: caddyCoprURL + "pubkey.gpg",
+		}, nil
+	default:
+		return nil, nil
+	}
+}
+
 // caddyInstalled checks if Caddy is installed by checking if the binary exists.
 func caddyInstalled() (bool, error) {
 	if exec.FileExists(caddyBinaryPath) {
 		return true, nil
 	}
 	// Also check if service exists as fallback
-	output, err := exec.RunWithOutput("systemctl", "list-unit-files", "--type=service", "--no-pager")
-	if err == nil {
-		if strings.Contains(output, caddyServiceName+".service") {
+	if svc, err := svcmgr.Detect(); err == nil {
+		if exists, _ := svc.Exists(caddyServiceName); exists {
 			return true, nil
 		}
 	}
@@ -63,71 +153,38 @@ func caddyInstalled() (bool, error) {
 
 // caddyServiceRunning checks if Caddy service is running.
 func caddyServiceRunning() (bool, error) {
-	output, err := exec.RunWithOutput("systemctl", "is-active", caddyServiceName)
+	svc, err := svcmgr.Detect()
 	if err != nil {
-		return false, nil
+		return false, err
 	}
-	return strings.TrimSpace(output) == "active", nil
+	return svc.IsActive(caddyServiceName)
 }
 
 // caddyServiceEnabled checks if Caddy service is enabled.
 func caddyServiceEnabled() (bool, error) {
-	output, err := exec.RunWithOutput("systemctl", "is-enabled", caddyServiceName)
+	svc, err := svcmgr.Detect()
 	if err != nil {
-		return false, nil
+		return false, err
 	}
-	status := strings.TrimSpace(output)
-	return status == "enabled" || status == "enabled-runtime", nil
+	return svc.IsEnabled(caddyServiceName)
 }
 
 // caddyPortAccessible checks if Caddy is listening on port 80.
 func caddyPortAccessible() (bool, error) {
-	// Try ss first (more modern), fallback to netstat
-	output, err := exec.RunWithOutput("ss", "-tlnp")
+	listening, err := sockets.ListeningAs(caddyDefaultPort, "caddy")
 	if err != nil {
-		// Fallback to netstat
-		output, err = exec.RunWithOutput("netstat", "-tlnp")
-		if err != nil {
-			return false, fmt.Errorf("failed to check port accessibility: %w", err)
-		}
+		return false, fmt.Errorf("failed to check port accessibility: %w", err)
 	}
-
-	// Check if port 80 is in the output
-	portStr := fmt.Sprintf(":%d", caddyDefaultPort)
-	if strings.Contains(output, portStr) {
-		return true, nil
-	}
-
-	return false, nil
+	return listening, nil
 }
 
-// port80InUse checks if port 80 is already in use by another service (not Caddy).
-func port80InUse() (bool, error) {
-	// Try ss first (more modern), fallback to netstat
-	output, err := exec.RunWithOutput("ss", "-tlnp")
+// port80Conflicts returns the listeners on port 80 that are not Caddy.
+func port80Conflicts() ([]sockets.Listener, error) {
+	conflicts, err := sockets.Conflicts(caddyDefaultPort, "caddy")
 	if err != nil {
-		// Fallback to netstat
-		output, err = exec.RunWithOutput("netstat", "-tlnp")
-		if err != nil {
-			return false, fmt.Errorf("failed to check port 80 usage: %w", err)
-		}
+		return nil, fmt.Errorf("failed to check port 80 usage: %w", err)
 	}
-
-	// Check if port 80 is in the output
-	portStr := fmt.Sprintf(":%d", caddyDefaultPort)
-	if !strings.Contains(output, portStr) {
-		return false, nil
-	}
-
-	// Port 80 is in use, check if it's Caddy
-	// Check if caddy is mentioned in the output
-	if strings.Contains(strings.ToLower(output), caddyServiceName) {
-		// It's Caddy, so port is not in use by another service
-		return false, nil
-	}
-
-	// Port 80 is in use by another service
-	return true, nil
+	return conflicts, nil
 }
 
 // caddyfileExists checks if the Caddyfile exists.
@@ -138,13 +195,13 @@ func caddyfileExists() (bool, error) {
 // createDefaultCaddyfile creates the default Caddyfile if it doesn't exist.
 func createDefaultCaddyfile() error {
 	// Create config directory if it doesn't exist
-	if err := os.MkdirAll(caddyConfigDir, 0755); err != nil {
+	if err := os.MkdirAll(exec.Path(caddyConfigDir), 0755); err != nil {
 		return fmt.Errorf("failed to create Caddy config directory: %w", err)
 	}
 
 	// Write default Caddyfile content
 	content := []byte(defaultCaddyfileContent)
-	if err := exec.WriteFile(caddyfilePath, content, 0644); err != nil {
+	if _, err := files.Write(caddyfilePath, content, files.Options{Mode: 0644}); err != nil {
 		return fmt.Errorf("failed to create default Caddyfile: %w", err)
 	}
 
@@ -196,12 +253,12 @@ func (m *CaddyModule) Install(cfg *config.Config) error {
 	}
 
 	// Check if port 80 is in use by another service
-	inUse, err := port80InUse()
+	conflicts, err := port80Conflicts()
 	if err != nil {
-		return fmt.Errorf("failed to check port 80 usage: %w", err)
+		return err
 	}
-	if inUse {
-		log.Warn("Port 80 is already in use. Caddy may not be able to bind to this port.")
+	for _, listener := range conflicts {
+		log.Warn("Port 80 is already in use by %s. Caddy may not be able to bind to this port.", listener)
 	}
 
 	// Check if Caddy is already installed
@@ -211,6 +268,15 @@ func (m *CaddyModule) Install(cfg *config.Config) error {
 	}
 
 	if !installed {
+		pm, err := pkgmgr.Detect()
+		if err != nil {
+			return err
+		}
+		repo, err := caddyRepository(pm)
+		if err != nil {
+			return err
+		}
+
 		if dryRun {
 			log.Info("Would install Caddy web server")
 		} else {
@@ -218,38 +284,29 @@ func (m *CaddyModule) Install(cfg *config.Config) error {
 
 			// Install prerequisites
 			log.Info("Installing prerequisites")
-			if err := exec.Run("apt-get", "update"); err != nil {
-				return fmt.Errorf("failed to update package list: %w", err)
+			if err := pm.Refresh(); err != nil {
+				return fmt.Errorf("failed to refresh package indexes: %w", err)
 			}
-			if err := exec.Run("apt-get", "install", "-y", "debian-keyring", "debian-archive-keyring", "apt-transport-https", "curl"); err != nil {
+			if err := pm.Install("debian-keyring", "debian-archive-keyring", "apt-transport-https", "curl"); err != nil {
 				return fmt.Errorf("failed to install prerequisites: %w", err)
 			}
 
-			// Download and install GPG key
-			log.Info("Adding Caddy GPG key")
-			// Download GPG key and pipe to gpg --dearmor
-			// Using curl to download and pipe to gpg
-			cmd := fmt.Sprintf("curl -1sLf '%s' | gpg --dearmor -o %s", caddyGPGKeyURL, caddyGPGKeyringPath)
-			if err := exec.Run("bash", "-c", cmd); err != nil {
-				return fmt.Errorf("failed to add Caddy GPG key: %w", err)
-			}
-
-			// Add Caddy repository
-			log.Info("Adding Caddy repository")
-			cmd = fmt.Sprintf("curl -1sLf '%s' | tee %s", caddyRepositoryURL, caddyAptSourcesPath)
-			if err := exec.Run("bash", "-c", cmd); err != nil {
-				return fmt.Errorf("failed to add Caddy repository: %w", err)
-			}
-
-			// Update apt package list
-			log.Info("Updating package list")
-			if err := exec.Run("apt-get", "update"); err != nil {
-				return fmt.Errorf("failed to update package list: %w", err)
+			// Add Caddy repository and signing key
+			if repo != nil {
+				log.Info("Adding Caddy repository")
+				if err := pm.AddRepository(*repo); err != nil {
+					return fmt.Errorf("failed to add Caddy repository: %w", err)
+				}
+
+				log.Info("Refreshing package indexes")
+				if err := pm.Refresh(); err != nil {
+					return fmt.Errorf("failed to refresh package indexes: %w", err)
+				}
 			}
 
 			// Install caddy
 			log.Info("Installing caddy package")
-			if err := exec.Run("apt-get", "install", "-y", "caddy"); err != nil {
+			if err := pm.Install("caddy"); err != nil {
 				return fmt.Errorf("failed to install caddy: %w", err)
 			}
 
@@ -284,6 +341,11 @@ func (m *CaddyModule) Install(cfg *config.Config) error {
 		log.Skip("Caddyfile already exists")
 	}
 
+	svc, err := svcmgr.Detect()
+	if err != nil {
+		return err
+	}
+
 	// Configure service to start on boot
 	enabled, err := caddyServiceEnabled()
 	if err != nil {
@@ -295,7 +357,7 @@ func (m *CaddyModule) Install(cfg *config.Config) error {
 			log.Info("Would enable Caddy service to start on boot")
 		} else {
 			log.Info("Enabling Caddy service to start on boot")
-			if err := exec.Run("systemctl", "enable", caddyServiceName); err != nil {
+			if err := svc.Enable(caddyServiceName); err != nil {
 				return fmt.Errorf("failed to enable Caddy service: %w", err)
 			}
 			log.Success("Caddy service enabled")
@@ -315,17 +377,13 @@ func (m *CaddyModule) Install(cfg *config.Config) error {
 			log.Info("Would start Caddy service")
 		} else {
 			log.Info("Starting Caddy service")
-			if err := exec.Run("systemctl", "start", caddyServiceName); err != nil {
+			if err := svc.Start(caddyServiceName); err != nil {
 				return fmt.Errorf("failed to start Caddy service: %w", err)
 			}
 
-			// Verify service is running
-			running, err := caddyServiceRunning()
-			if err != nil {
-				return fmt.Errorf("failed to verify Caddy service status: %w", err)
-			}
-			if !running {
-				return fmt.Errorf("Caddy service is not running after start")
+			// Wait for the service to become active
+			if err := svc.WaitActive(caddyServiceName, svcmgr.DefaultWaitTimeout); err != nil {
+				return fmt.Errorf("Caddy service is not running after start: %w", err)
 			}
 
 			log.Success("Caddy service started")
@@ -361,7 +419,9 @@ func (m *CaddyModule) Install(cfg *config.Config) error {
 	return nil
 }
 
-// Ensure CaddyModule implements the Module interface
+// Ensure CaddyModule implements the Module and Describer interfaces
 var _ module.Module = (*CaddyModule)(nil)
+var _ module.Describer = (*CaddyModule)(nil)
+var _ module.Bundler = (*CaddyModule)(nil)
 
 
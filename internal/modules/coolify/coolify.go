@@ -5,13 +5,19 @@ import (
 	"strings"
 
 	"github.com/stwalsh4118/phanes/internal/config"
+	"github.com/stwalsh4118/phanes/internal/download"
 	"github.com/stwalsh4118/phanes/internal/exec"
 	"github.com/stwalsh4118/phanes/internal/log"
 	"github.com/stwalsh4118/phanes/internal/module"
+	"github.com/stwalsh4118/phanes/internal/timing"
+	"github.com/stwalsh4118/phanes/internal/svcmgr"
 )
 
 const (
 	coolifyInstallScript = "https://cdn.coollabs.io/coolify/install.sh"
+
+	// coolifyInstallArtifact names the install script in downloads.checksums.
+	coolifyInstallArtifact = "coolify-install"
 )
 
 // CoolifyModule implements the Module interface for Coolify installation.
@@ -27,6 +33,33 @@ func (m *CoolifyModule) Description() string {
 	return "Installs and configures Coolify self-hosted PaaS"
 }
 
+// Metadata returns the configuration keys, files, services, ports, packages,
+// and URLs this module uses.
+func (m *CoolifyModule) Metadata() module.Metadata {
+	return module.Metadata{
+		ConfigKeys: []module.ConfigKey{
+			{Key: "coolify.enabled", Description: "Whether to install Coolify"},
+		},
+		Files: []string{"/data/coolify"},
+		Ports: []module.Port{
+			{Number: 8000, Protocol: "tcp", Description: "Coolify dashboard"},
+		},
+		URLs:     []string{coolifyInstallScript},
+		Requires: []string{"docker"},
+	}
+}
+
+// Requirements returns the Coolify install script.
+func (m *CoolifyModule) Requirements(cfg *config.Config) (module.Requirements, error) {
+	if !cfg.Coolify.Enabled {
+		return module.Requirements{}, nil
+	}
+	return module.Requirements{
+		Artifacts:    []download.Artifact{{Name: coolifyInstallArtifact, URL: coolifyInstallScript, Script: true}},
+		NeedsNetwork: "the Coolify install script downloads Coolify's container images",
+	}, nil
+}
+
 // dockerInstalled checks if Docker is installed by running docker --version.
 func dockerInstalled() (bool, error) {
 	err := exec.Run("docker", "--version")
@@ -38,11 +71,11 @@ func dockerInstalled() (bool, error) {
 
 // dockerServiceRunning checks if Docker service is running.
 func dockerServiceRunning() (bool, error) {
-	output, err := exec.RunWithOutput("systemctl", "is-active", "docker")
+	svc, err := svcmgr.Detect()
 	if err != nil {
-		return false, nil
+		return false, err
 	}
-	return strings.TrimSpace(output) == "active", nil
+	return svc.IsActive("docker")
 }
 
 // checkDockerDependency checks if Docker is installed and running.
@@ -131,15 +164,24 @@ func (m *CoolifyModule) Install(cfg *config.Config) error {
 
 	if dryRun {
 		log.Info("Would install Coolify using official install script")
-		log.Info("Would run: curl -fsSL %s | bash", coolifyInstallScript)
+		log.Info("Would download and verify %s, then run it with bash", coolifyInstallScript)
 		log.Info("Would verify Coolify containers are running after installation")
 		return nil
 	}
 
 	// Install Coolify using official install script
 	log.Info("Installing Coolify using official install script")
-	installCmd := fmt.Sprintf("curl -fsSL %s | bash", coolifyInstallScript)
-	if err := exec.Run("sh", "-c", installCmd); err != nil {
+	script, err := download.Fetch(download.Artifact{
+		Name:   coolifyInstallArtifact,
+		URL:    coolifyInstallScript,
+		Script: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to download Coolify install script: %w", err)
+	}
+	if err := timing.Track("run Coolify install script", func() error {
+		return exec.Run("bash", script)
+	}); err != nil {
 		return fmt.Errorf("failed to install Coolify: %w", err)
 	}
 
@@ -162,6 +204,8 @@ func (m *CoolifyModule) Install(cfg *config.Config) error {
 	return nil
 }
 
-// Ensure CoolifyModule implements the Module interface
+// Ensure CoolifyModule implements the Module and Describer interfaces
 var _ module.Module = (*CoolifyModule)(nil)
+var _ module.Describer = (*CoolifyModule)(nil)
+var _ module.Bundler = (*CoolifyModule)(nil)
 
@@ -180,3 +180,26 @@ func TestCoolifyModule_ModuleInterface(t *testing.T) {
 	} = &CoolifyModule{}
 }
 
+
+func TestCoolifyModule_Requirements(t *testing.T) {
+	mod := &CoolifyModule{}
+	cfg := config.DefaultConfig()
+
+	cfg.Coolify.Enabled = false
+	reqs, err := mod.Requirements(cfg)
+	if err != nil || len(reqs.Artifacts) != 0 {
+		t.Errorf("Requirements() when disabled = %+v, %v, want none", reqs, err)
+	}
+
+	cfg.Coolify.Enabled = true
+	reqs, err = mod.Requirements(cfg)
+	if err != nil {
+		t.Fatalf("Requirements() error = %v", err)
+	}
+	if len(reqs.Artifacts) != 1 || reqs.Artifacts[0].Name != coolifyInstallArtifact || reqs.Artifacts[0].URL != coolifyInstallScript {
+		t.Errorf("Requirements().Artifacts = %+v, want the install script", reqs.Artifacts)
+	}
+	if reqs.NeedsNetwork == "" {
+		t.Error("Requirements() should explain that Coolify needs network access")
+	}
+}
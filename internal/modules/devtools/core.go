@@ -2,11 +2,11 @@ package devtools
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/stwalsh4118/phanes/internal/config"
 	"github.com/stwalsh4118/phanes/internal/exec"
 	"github.com/stwalsh4118/phanes/internal/log"
+	"github.com/stwalsh4118/phanes/internal/pkgmgr"
 )
 
 const (
@@ -17,6 +17,17 @@ const (
 	packageCaCertificates = "ca-certificates"
 )
 
+// packageInstalled reports whether pkg is installed according to the system
+// package manager. Returns false if the package manager cannot be detected.
+func packageInstalled(pkg string) bool {
+	pm, err := pkgmgr.Detect()
+	if err != nil {
+		return false
+	}
+	installed, _, err := pm.IsInstalled(pkg)
+	return err == nil && installed
+}
+
 // gitInstalled checks if Git is installed.
 func gitInstalled() (bool, error) {
 	return exec.CommandExists("git"), nil
@@ -25,18 +36,11 @@ func gitInstalled() (bool, error) {
 // buildEssentialInstalled checks if build-essential package is installed.
 // This checks for the package itself and verifies gcc and make are available.
 func buildEssentialInstalled() (bool, error) {
-	// Check if build-essential package is installed via dpkg
-	if exec.CommandExists("dpkg") {
-		output, err := exec.RunWithOutput("dpkg", "-l", packageBuildEssential)
-		if err == nil {
-			// dpkg -l returns 0 even if package is not installed, but output will indicate status
-			// Look for "ii" which means installed and configured
-			if strings.Contains(output, packageBuildEssential) && strings.Contains(output, "ii") {
-				// Also verify gcc and make are available (they come with build-essential)
-				if exec.CommandExists("gcc") && exec.CommandExists("make") {
-					return true, nil
-				}
-			}
+	// Check if build-essential package is installed via the package manager
+	if packageInstalled(packageBuildEssential) {
+		// Also verify gcc and make are available (they come with build-essential)
+		if exec.CommandExists("gcc") && exec.CommandExists("make") {
+			return true, nil
 		}
 	}
 
@@ -60,16 +64,9 @@ func wgetInstalled() (bool, error) {
 
 // caCertificatesInstalled checks if ca-certificates package is installed.
 func caCertificatesInstalled() (bool, error) {
-	// Check if ca-certificates package is installed via dpkg
-	if exec.CommandExists("dpkg") {
-		output, err := exec.RunWithOutput("dpkg", "-l", packageCaCertificates)
-		if err == nil {
-			// dpkg -l returns 0 even if package is not installed, but output will indicate status
-			// Look for "ii" which means installed and configured
-			if strings.Contains(output, packageCaCertificates) && strings.Contains(output, "ii") {
-				return true, nil
-			}
-		}
+	// Check if ca-certificates package is installed via the package manager
+	if packageInstalled(packageCaCertificates) {
+		return true, nil
 	}
 
 	// Fallback: check if update-ca-certificates command exists (comes with ca-certificates)
@@ -126,7 +123,7 @@ func coreToolsInstalled() (bool, error) {
 	return true, nil
 }
 
-// installCoreTools installs core development tools via apt.
+// installCoreTools installs core development tools via the system package manager.
 // Installs: git, build-essential, curl, wget, ca-certificates
 func installCoreTools(cfg *config.Config) error {
 	dryRun := log.IsDryRun()
@@ -149,15 +146,20 @@ func installCoreTools(cfg *config.Config) error {
 
 	log.Info("Installing core development tools")
 
-	// Update apt package list
-	log.Info("Updating apt package list")
-	if err := exec.Run("apt-get", "update"); err != nil {
-		return fmt.Errorf("failed to update apt: %w", err)
+	pm, err := pkgmgr.Detect()
+	if err != nil {
+		return err
+	}
+
+	// Refresh package indexes
+	log.Info("Refreshing package indexes")
+	if err := pm.Refresh(); err != nil {
+		return fmt.Errorf("failed to refresh package indexes: %w", err)
 	}
 
 	// Install all packages in one command
 	log.Info("Installing packages: git, build-essential, curl, wget, ca-certificates")
-	if err := exec.Run("apt-get", "install", "-y", packageGit, packageBuildEssential, packageCurl, packageWget, packageCaCertificates); err != nil {
+	if err := pm.Install(packageGit, packageBuildEssential, packageCurl, packageWget, packageCaCertificates); err != nil {
 		return fmt.Errorf("failed to install core development tools: %w", err)
 	}
 
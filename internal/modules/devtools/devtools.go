@@ -2,10 +2,14 @@ package devtools
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/stwalsh4118/phanes/internal/config"
+	"github.com/stwalsh4118/phanes/internal/download"
+	"github.com/stwalsh4118/phanes/internal/exec"
 	"github.com/stwalsh4118/phanes/internal/log"
 	"github.com/stwalsh4118/phanes/internal/module"
+	"github.com/stwalsh4118/phanes/internal/proxy"
 )
 
 // DevToolsModule implements the Module interface for development tools installation.
@@ -22,6 +26,87 @@ func (m *DevToolsModule) Description() string {
 	return "Installs development tools (Git, build-essential, Node.js, Python, Go)"
 }
 
+// Metadata returns the configuration keys, files, services, ports, packages,
+// and URLs this module uses.
+func (m *DevToolsModule) Metadata() module.Metadata {
+	return module.Metadata{
+		ConfigKeys: []module.ConfigKey{
+			{Key: "devtools.enabled", Description: "Whether to install development tools"},
+			{Key: "devtools.node_version", Description: "Node.js version installed with nvm"},
+			{Key: "devtools.python_version", Description: "Python version to install"},
+			{Key: "devtools.go_version", Description: "Go version installed to /usr/local/go"},
+			{Key: "devtools.install_uv", Description: "Whether to install the uv Python package manager"},
+			{Key: "user.username", Description: "User that receives nvm, uv, and shell PATH configuration"},
+		},
+		Files: []string{
+			goInstallDir,
+			"/home/{user.username}/" + nvmDirName,
+			"/home/{user.username}/" + uvBinDir + "/" + uvBinName,
+			"/home/{user.username}/.bashrc",
+			"/home/{user.username}/.zshrc",
+		},
+		Packages: []string{
+			packageGit,
+			packageBuildEssential,
+			packageCurl,
+			packageWget,
+			packageCaCertificates,
+			packagePython3,
+			packagePython3Venv,
+			packagePython3Pip,
+		},
+		URLs: []string{
+			"https://go.dev/dl/go{devtools.go_version}.linux-{arch}.tar.gz",
+			nvmInstallURL,
+			uvInstallURL,
+		},
+		// Tools are installed in the user's home directory
+		Requires: []string{"user"},
+	}
+}
+
+// Requirements returns the development packages, the nvm and uv install
+// scripts, and the Go release tarball with its published checksum unless the
+// tarball is pinned.
+func (m *DevToolsModule) Requirements(cfg *config.Config) (module.Requirements, error) {
+	if !cfg.DevTools.Enabled {
+		return module.Requirements{}, nil
+	}
+
+	reqs := module.Requirements{
+		Packages: []string{
+			packageGit, packageBuildEssential, packageCurl, packageWget, packageCaCertificates,
+			packagePython3, packagePython3Venv, packagePython3Pip,
+		},
+	}
+
+	// nvm and uv are installed for the configured user only
+	if cfg.User.Username != "" {
+		reqs.Artifacts = append(reqs.Artifacts, download.Artifact{Name: nvmInstallArtifact, URL: nvmInstallURL, SHA256: nvmInstallSHA256, Script: true})
+		reqs.NeedsNetwork = "nvm downloads Node.js"
+		if cfg.DevTools.InstallUv {
+			reqs.Artifacts = append(reqs.Artifacts, download.Artifact{Name: uvInstallArtifact, URL: uvInstallURL, Script: true})
+			reqs.NeedsNetwork += ", and the uv install script downloads uv"
+		}
+	}
+
+	systemArch, err := getSystemArch()
+	if err != nil {
+		return module.Requirements{}, fmt.Errorf("failed to detect system architecture: %w", err)
+	}
+	tarball, err := goArtifact(cfg, mapArchToGoArch(systemArch))
+	if err != nil {
+		return module.Requirements{}, err
+	}
+	reqs.Artifacts = append(reqs.Artifacts, tarball)
+	if tarball.Published != "" {
+		// Offline runs of an unpinned tarball need its published checksum
+		reqs.Documents = append(reqs.Documents, tarball.URL+".sha256")
+	}
+
+	return reqs, nil
+}
+
 // IsInstalled checks if development tools are already installed.
 // Returns true if all enabled components are installed.
 // Note: Since IsInstalled() doesn't receive config, it checks if the core tools
@@ -83,6 +168,30 @@ func (m *DevToolsModule) Install(cfg *config.Config) error {
 	return nil
 }
 
-// Ensure DevToolsModule implements the Module interface
+// runInstallScript downloads and verifies an install script, then runs it with
+// shell as the given user so it installs into the user's home directory.
+func runInstallScript(username, shell string, artifact download.Artifact) error {
+	script, err := download.Fetch(artifact)
+	if err != nil {
+		return err
+	}
+	return exec.Run("su", suArgs(username, shell+" "+shellQuote(script))...)
+}
+
+// suArgs returns the su arguments that run cmd in a login shell of username.
+// The login shell starts with a clean environment, so the proxy settings are
+// explicitly kept.
+func suArgs(username, cmd string) []string {
+	return append(proxy.SuFlags(), "-", username, "-c", cmd)
+}
+
+// shellQuote quotes s as a single word for sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Ensure DevToolsModule implements the Module and Describer interfaces
 var _ module.Module = (*DevToolsModule)(nil)
+var _ module.Describer = (*DevToolsModule)(nil)
+var _ module.Bundler = (*DevToolsModule)(nil)
 
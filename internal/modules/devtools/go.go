@@ -3,21 +3,75 @@ package devtools
 import (
 	"fmt"
 	"os"
-	"os/user"
 	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/stwalsh4118/phanes/internal/config"
+	"github.com/stwalsh4118/phanes/internal/download"
 	"github.com/stwalsh4118/phanes/internal/exec"
+	"github.com/stwalsh4118/phanes/internal/files"
 	"github.com/stwalsh4118/phanes/internal/log"
+	"github.com/stwalsh4118/phanes/internal/timing"
 )
 
 const (
 	goInstallDir = "/usr/local/go"
 	goBinDir     = "/usr/local/go/bin"
+
+	// defaultGoVersion is installed when devtools.go_version is not set.
+	// Go release downloads require the full version including the patch number.
+	defaultGoVersion = "1.25.5"
 )
 
+// configuredGoVersion returns the Go version to install.
+func configuredGoVersion(cfg *config.Config) string {
+	if cfg.DevTools.GoVersion == "" {
+		return defaultGoVersion
+	}
+	return cfg.DevTools.GoVersion
+}
+
+// goTarball returns the artifact name and download URL of a Go release tarball.
+func goTarball(version, goArch string) (string, string) {
+	name := fmt.Sprintf("go%s.linux-%s", version, goArch)
+	return name, fmt.Sprintf("https://go.dev/dl/%s.tar.gz", name)
+}
+
+// goChecksum returns the SHA-256 checksum published alongside a Go release
+// tarball at its URL with a .sha256 suffix.
+func goChecksum(tarballURL string) (string, error) {
+	body, err := download.Get(tarballURL + ".sha256")
+	if err != nil {
+		return "", fmt.Errorf("failed to get published checksum (Go requires a full version like '1.24.0', not just '1.24'): %w", err)
+	}
+	fields := strings.Fields(string(body))
+	if len(fields) == 0 || len(fields[0]) != 64 {
+		return "", fmt.Errorf("invalid published checksum for %s", tarballURL)
+	}
+	return fields[0], nil
+}
+
+// goArtifact returns the Go release tarball for goArch. Unless the tarball is
+// pinned in downloads.checksums, the checksum published next to it is fetched
+// to verify the download. It comes from the same host as the tarball, so it is
+// not a pin, and it is not fetched when require_checksums would refuse the
+// tarball anyway.
+func goArtifact(cfg *config.Config, goArch string) (download.Artifact, error) {
+	name, url := goTarball(configuredGoVersion(cfg), goArch)
+	artifact := download.Artifact{Name: name, URL: url}
+	if cfg.Downloads.Checksums[name] != "" || cfg.Downloads.RequireChecksums {
+		return artifact, nil
+	}
+
+	published, err := goChecksum(url)
+	if err != nil {
+		return download.Artifact{}, err
+	}
+	artifact.Published = published
+	return artifact, nil
+}
+
 // goPathScript returns the PATH export script that should be added to shell profiles for Go.
 func goPathScript() string {
 	return `export PATH=$PATH:/usr/local/go/bin
@@ -110,7 +164,7 @@ func shellProfileHasGoPath(profilePath string) (bool, error) {
 		return false, nil
 	}
 
-	content, err := os.ReadFile(profilePath)
+	content, err := os.ReadFile(exec.Path(profilePath))
 	if err != nil {
 		return false, fmt.Errorf("failed to read shell profile: %w", err)
 	}
@@ -140,7 +194,7 @@ func configureShellProfileForGo(profilePath string, userUID, userGID int) error
 	// Read existing content
 	var content []byte
 	if exec.FileExists(profilePath) {
-		existingContent, err := os.ReadFile(profilePath)
+		existingContent, err := os.ReadFile(exec.Path(profilePath))
 		if err != nil {
 			return fmt.Errorf("failed to read shell profile: %w", err)
 		}
@@ -155,16 +209,12 @@ func configureShellProfileForGo(profilePath string, userUID, userGID int) error
 	content = append(content, []byte("\n# Go PATH configuration\n")...)
 	content = append(content, []byte(goPathScript())...)
 
-	// Write file
-	if err := exec.WriteFile(profilePath, content, 0644); err != nil {
+	// Write file owned by the user
+	owner := &files.Owner{UID: userUID, GID: userGID}
+	if _, err := files.Write(profilePath, content, files.Options{Owner: owner}); err != nil {
 		return fmt.Errorf("failed to write shell profile: %w", err)
 	}
 
-	// Set ownership to the user
-	if err := os.Chown(profilePath, userUID, userGID); err != nil {
-		return fmt.Errorf("failed to set shell profile ownership: %w", err)
-	}
-
 	log.Success("Configured shell profile: %s", profilePath)
 	return nil
 }
@@ -175,10 +225,7 @@ func installGo(cfg *config.Config) error {
 	dryRun := log.IsDryRun()
 
 	// Get Go version from config
-	goVersion := cfg.DevTools.GoVersion
-	if goVersion == "" {
-		goVersion = "1.25.5" // Default version - must include patch number
-	}
+	goVersion := configuredGoVersion(cfg)
 
 	// Check if Go is already installed with correct version
 	goOk, err := goInstalled(goVersion)
@@ -202,7 +249,7 @@ func installGo(cfg *config.Config) error {
 	// Get user info for file ownership
 	var userUID, userGID int
 	if !dryRun && username != "" {
-		userInfo, err := user.Lookup(username)
+		userInfo, err := exec.LookupUser(username)
 		if err != nil {
 			return fmt.Errorf("failed to look up user %s: %w", username, err)
 		}
@@ -226,11 +273,10 @@ func installGo(cfg *config.Config) error {
 	log.Info("Detected architecture: %s (Go arch: %s)", systemArch, goArch)
 
 	// Build download URL
-	downloadURL := fmt.Sprintf("https://go.dev/dl/go%s.linux-%s.tar.gz", goVersion, goArch)
-	tarballPath := fmt.Sprintf("/tmp/go%s.linux-%s.tar.gz", goVersion, goArch)
+	_, downloadURL := goTarball(goVersion, goArch)
 
 	if dryRun {
-		log.Info("Would download Go %s from %s", goVersion, downloadURL)
+		log.Info("Would download Go %s from %s and verify its SHA-256 checksum", goVersion, downloadURL)
 		log.Info("Would extract to %s", goInstallDir)
 		if username != "" {
 			log.Info("Would configure shell profiles (.bashrc and .zshrc) for Go")
@@ -238,55 +284,37 @@ func installGo(cfg *config.Config) error {
 		return nil
 	}
 
-	// Always remove old installation to ensure clean state
-	log.Info("Removing existing Go installation at %s (if exists)", goInstallDir)
-	if err := exec.Run("rm", "-rf", goInstallDir); err != nil {
-		return fmt.Errorf("failed to remove old Go installation: %w", err)
-	}
-
-	// Download Go tarball
+	// Download and verify the tarball before touching the existing installation
 	log.Info("Downloading Go %s from %s", goVersion, downloadURL)
-	if err := exec.Run("curl", "-L", "-o", tarballPath, downloadURL); err != nil {
+	var tarballPath string
+	if err := timing.Track("download Go tarball", func() error {
+		artifact, err := goArtifact(cfg, goArch)
+		if err != nil {
+			return err
+		}
+		tarballPath, err = download.Fetch(artifact)
+		return err
+	}); err != nil {
 		return fmt.Errorf("failed to download Go tarball: %w", err)
 	}
 
-	// Ensure tarball was downloaded
-	if !exec.FileExists(tarballPath) {
-		return fmt.Errorf("Go tarball was not downloaded: %s", tarballPath)
-	}
-
-	// Validate the downloaded file is actually a gzip archive
-	fileInfo, err := os.Stat(tarballPath)
-	if err != nil {
-		return fmt.Errorf("failed to stat downloaded tarball: %w", err)
-	}
-	if fileInfo.Size() < 1000000 { // Go tarball should be at least 1MB
-		// Read file content to see what was downloaded (likely an error page)
-		content, _ := os.ReadFile(tarballPath)
-		contentPreview := string(content)
-		if len(contentPreview) > 500 {
-			contentPreview = contentPreview[:500]
-		}
-		_ = exec.Run("rm", "-f", tarballPath)
-		return fmt.Errorf("downloaded file is too small (%d bytes) - Go version %s may not exist or URL is incorrect. Expected tarball from: %s. Note: Go requires full version like '1.24.0' not just '1.24'. Preview: %s", fileInfo.Size(), goVersion, downloadURL, contentPreview)
+	// Always remove old installation to ensure clean state
+	log.Info("Removing existing Go installation at %s (if exists)", goInstallDir)
+	if err := exec.Run("rm", "-rf", goInstallDir); err != nil {
+		return fmt.Errorf("failed to remove old Go installation: %w", err)
 	}
 
 	// Extract tarball to /usr/local
 	log.Info("Extracting Go to %s", goInstallDir)
-	if err := exec.Run("tar", "-C", "/usr/local", "-xzf", tarballPath); err != nil {
-		// Clean up tarball on error
-		_ = exec.Run("rm", "-f", tarballPath)
+	if err := timing.Track("extract Go tarball", func() error {
+		return exec.Run("tar", "-C", "/usr/local", "-xzf", tarballPath)
+	}); err != nil {
 		return fmt.Errorf("failed to extract Go tarball: %w", err)
 	}
 
 	// Sync filesystem to ensure extraction is complete
 	_ = exec.Run("sync")
 
-	// Clean up tarball
-	if err := exec.Run("rm", "-f", tarballPath); err != nil {
-		log.Warn("Failed to remove temporary tarball: %s", tarballPath)
-	}
-
 	// Verify Go binary exists after installation
 	// Note: We don't check version immediately after extraction as the OS may cache stale data
 	if !exec.FileExists(goBinaryPath) {
@@ -3,20 +3,27 @@ package devtools
 import (
 	"fmt"
 	"os"
-	"os/user"
 	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/stwalsh4118/phanes/internal/config"
+	"github.com/stwalsh4118/phanes/internal/download"
 	"github.com/stwalsh4118/phanes/internal/exec"
+	"github.com/stwalsh4118/phanes/internal/files"
 	"github.com/stwalsh4118/phanes/internal/log"
+	"github.com/stwalsh4118/phanes/internal/timing"
 )
 
 const (
-	nvmVersion   = "v0.40.0"
-	nvmInstallURL = "https://raw.githubusercontent.com/nvm-sh/nvm/v0.40.0/install.sh"
-	nvmDirName   = ".nvm"
+	nvmVersion    = "v0.40.3"
+	nvmInstallURL = "https://raw.githubusercontent.com/nvm-sh/nvm/" + nvmVersion + "/install.sh"
+	nvmDirName    = ".nvm"
+
+	// nvmInstallArtifact names the nvm install script in downloads.checksums,
+	// and nvmInstallSHA256 is the checksum of the script of nvmVersion.
+	nvmInstallArtifact = "nvm-install"
+	nvmInstallSHA256   = "2d8359a64a3cb07c02389ad88ceecd43f2fa469c06104f92f98df5b6f315275f"
 )
 
 // nvmInitScript returns the nvm initialization script that should be added to shell profiles.
@@ -60,7 +67,7 @@ func nodeInstalled(username, version string) (bool, error) {
 	// Check if Node.js version is installed via nvm
 	// Use su to run as the user with nvm sourced
 	cmd := fmt.Sprintf("source ~/.nvm/nvm.sh && nvm which %s >/dev/null 2>&1", version)
-	output, err := exec.RunWithOutput("su", "-", username, "-c", cmd)
+	output, err := exec.RunWithOutput("su", suArgs(username, cmd)...)
 	if err != nil {
 		// If command fails, version is not installed
 		return false, nil
@@ -80,7 +87,7 @@ func shellProfileHasNvm(profilePath string) (bool, error) {
 		return false, nil
 	}
 
-	content, err := os.ReadFile(profilePath)
+	content, err := os.ReadFile(exec.Path(profilePath))
 	if err != nil {
 		return false, fmt.Errorf("failed to read shell profile: %w", err)
 	}
@@ -110,7 +117,7 @@ func configureShellProfile(profilePath string, userUID, userGID int) error {
 	// Read existing content
 	var content []byte
 	if exec.FileExists(profilePath) {
-		existingContent, err := os.ReadFile(profilePath)
+		existingContent, err := os.ReadFile(exec.Path(profilePath))
 		if err != nil {
 			return fmt.Errorf("failed to read shell profile: %w", err)
 		}
@@ -125,16 +132,12 @@ func configureShellProfile(profilePath string, userUID, userGID int) error {
 	content = append(content, []byte("\n# nvm initialization\n")...)
 	content = append(content, []byte(nvmInitScript())...)
 
-	// Write file
-	if err := exec.WriteFile(profilePath, content, 0644); err != nil {
+	// Write file owned by the user
+	owner := &files.Owner{UID: userUID, GID: userGID}
+	if _, err := files.Write(profilePath, content, files.Options{Owner: owner}); err != nil {
 		return fmt.Errorf("failed to write shell profile: %w", err)
 	}
 
-	// Set ownership to the user
-	if err := os.Chown(profilePath, userUID, userGID); err != nil {
-		return fmt.Errorf("failed to set shell profile ownership: %w", err)
-	}
-
 	log.Success("Configured shell profile: %s", profilePath)
 	return nil
 }
@@ -158,7 +161,7 @@ func installNodeJS(cfg *config.Config) error {
 	// Get user info for file ownership
 	var userUID, userGID int
 	if !dryRun {
-		userInfo, err := user.Lookup(username)
+		userInfo, err := exec.LookupUser(username)
 		if err != nil {
 			return fmt.Errorf("failed to look up user %s: %w", username, err)
 		}
@@ -189,8 +192,14 @@ func installNodeJS(cfg *config.Config) error {
 			log.Info("Installing nvm for user: %s", username)
 			// Install nvm using the official install script
 			// Run as the user to ensure it's installed in their home directory
-			installCmd := fmt.Sprintf("curl -o- %s | bash", nvmInstallURL)
-			if err := exec.Run("su", "-", username, "-c", installCmd); err != nil {
+			if err := timing.Track("install nvm", func() error {
+				return runInstallScript(username, "bash", download.Artifact{
+					Name:   nvmInstallArtifact,
+					URL:    nvmInstallURL,
+					SHA256: nvmInstallSHA256,
+					Script: true,
+				})
+			}); err != nil {
 				return fmt.Errorf("failed to install nvm: %w", err)
 			}
 
@@ -252,19 +261,21 @@ func installNodeJS(cfg *config.Config) error {
 
 			// Install Node.js via nvm
 			installCmd := fmt.Sprintf("source ~/.nvm/nvm.sh && nvm install %s", nodeVersion)
-			if err := exec.Run("su", "-", username, "-c", installCmd); err != nil {
+			if err := timing.Track("nvm install "+nodeVersion, func() error {
+				return exec.Run("su", suArgs(username, installCmd)...)
+			}); err != nil {
 				return fmt.Errorf("failed to install Node.js: %w", err)
 			}
 
 			// Set as default version
 			aliasCmd := fmt.Sprintf("source ~/.nvm/nvm.sh && nvm alias default %s", nodeVersion)
-			if err := exec.Run("su", "-", username, "-c", aliasCmd); err != nil {
+			if err := exec.Run("su", suArgs(username, aliasCmd)...); err != nil {
 				return fmt.Errorf("failed to set Node.js default version: %w", err)
 			}
 
 			// Verify installation
 			verifyCmd := "source ~/.nvm/nvm.sh && node --version && npm --version"
-			output, err := exec.RunWithOutput("su", "-", username, "-c", verifyCmd)
+			output, err := exec.RunWithOutput("su", suArgs(username, verifyCmd)...)
 			if err != nil {
 				return fmt.Errorf("failed to verify Node.js installation: %w", err)
 			}
@@ -3,20 +3,25 @@ package devtools
 import (
 	"fmt"
 	"os"
-	"os/user"
 	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/stwalsh4118/phanes/internal/config"
+	"github.com/stwalsh4118/phanes/internal/download"
 	"github.com/stwalsh4118/phanes/internal/exec"
+	"github.com/stwalsh4118/phanes/internal/files"
 	"github.com/stwalsh4118/phanes/internal/log"
+	"github.com/stwalsh4118/phanes/internal/pkgmgr"
 )
 
 const (
 	uvInstallURL = "https://astral.sh/uv/install.sh"
 	uvBinDir     = ".local/bin"
 	uvBinName    = "uv"
+
+	// uvInstallArtifact names the uv install script in downloads.checksums.
+	uvInstallArtifact = "uv-install"
 )
 
 const (
@@ -56,7 +61,7 @@ func shellProfileHasUvPath(profilePath string) (bool, error) {
 		return false, nil
 	}
 
-	content, err := os.ReadFile(profilePath)
+	content, err := os.ReadFile(exec.Path(profilePath))
 	if err != nil {
 		return false, fmt.Errorf("failed to read shell profile: %w", err)
 	}
@@ -86,7 +91,7 @@ func configureShellProfileForUv(profilePath string, userUID, userGID int) error
 	// Read existing content
 	var content []byte
 	if exec.FileExists(profilePath) {
-		existingContent, err := os.ReadFile(profilePath)
+		existingContent, err := os.ReadFile(exec.Path(profilePath))
 		if err != nil {
 			return fmt.Errorf("failed to read shell profile: %w", err)
 		}
@@ -101,16 +106,12 @@ func configureShellProfileForUv(profilePath string, userUID, userGID int) error
 	content = append(content, []byte("\n# uv PATH configuration\n")...)
 	content = append(content, []byte(uvPathScript())...)
 
-	// Write file
-	if err := exec.WriteFile(profilePath, content, 0644); err != nil {
+	// Write file owned by the user
+	owner := &files.Owner{UID: userUID, GID: userGID}
+	if _, err := files.Write(profilePath, content, files.Options{Owner: owner}); err != nil {
 		return fmt.Errorf("failed to write shell profile: %w", err)
 	}
 
-	// Set ownership to the user
-	if err := os.Chown(profilePath, userUID, userGID); err != nil {
-		return fmt.Errorf("failed to set shell profile ownership: %w", err)
-	}
-
 	log.Success("Configured shell profile: %s", profilePath)
 	return nil
 }
@@ -131,15 +132,20 @@ func installPython(cfg *config.Config) error {
 		} else {
 			log.Info("Installing Python 3")
 
-			// Update apt package list
-			log.Info("Updating apt package list")
-			if err := exec.Run("apt-get", "update"); err != nil {
-				return fmt.Errorf("failed to update apt: %w", err)
+			pm, err := pkgmgr.Detect()
+			if err != nil {
+				return err
+			}
+
+			// Refresh package indexes
+			log.Info("Refreshing package indexes")
+			if err := pm.Refresh(); err != nil {
+				return fmt.Errorf("failed to refresh package indexes: %w", err)
 			}
 
 			// Install Python 3 and related packages
 			log.Info("Installing packages: python3, python3-venv, python3-pip")
-			if err := exec.Run("apt-get", "install", "-y", packagePython3, packagePython3Venv, packagePython3Pip); err != nil {
+			if err := pm.Install(packagePython3, packagePython3Venv, packagePython3Pip); err != nil {
 				return fmt.Errorf("failed to install Python 3: %w", err)
 			}
 
@@ -171,7 +177,7 @@ func installPython(cfg *config.Config) error {
 		// Get user info for file ownership
 		var userUID, userGID int
 		if !dryRun {
-			userInfo, err := user.Lookup(username)
+			userInfo, err := exec.LookupUser(username)
 			if err != nil {
 				return fmt.Errorf("failed to look up user %s: %w", username, err)
 			}
@@ -202,8 +208,11 @@ func installPython(cfg *config.Config) error {
 				log.Info("Installing uv for user: %s", username)
 				// Install uv using the official install script
 				// Run as the user to ensure it's installed in their home directory
-				installCmd := fmt.Sprintf("curl -LsSf %s | sh", uvInstallURL)
-				if err := exec.Run("su", "-", username, "-c", installCmd); err != nil {
+				if err := runInstallScript(username, "sh", download.Artifact{
+					Name:   uvInstallArtifact,
+					URL:    uvInstallURL,
+					Script: true,
+				}); err != nil {
 					return fmt.Errorf("failed to install uv: %w", err)
 				}
 
@@ -250,7 +259,7 @@ func installPython(cfg *config.Config) error {
 
 			// Verify uv installation
 			verifyCmd := "~/.local/bin/uv --version"
-			output, err := exec.RunWithOutput("su", "-", username, "-c", verifyCmd)
+			output, err := exec.RunWithOutput("su", suArgs(username, verifyCmd)...)
 			if err != nil {
 				return fmt.Errorf("failed to verify uv installation: %w", err)
 			}
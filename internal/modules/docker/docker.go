@@ -1,24 +1,53 @@
 package docker
 
 import (
-	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/stwalsh4118/phanes/internal/aptrepo"
 	"github.com/stwalsh4118/phanes/internal/config"
 	"github.com/stwalsh4118/phanes/internal/exec"
+	"github.com/stwalsh4118/phanes/internal/files"
 	"github.com/stwalsh4118/phanes/internal/log"
 	"github.com/stwalsh4118/phanes/internal/module"
+	"github.com/stwalsh4118/phanes/internal/osinfo"
+	"github.com/stwalsh4118/phanes/internal/pkgmgr"
+	"github.com/stwalsh4118/phanes/internal/proxy"
+	"github.com/stwalsh4118/phanes/internal/svcmgr"
 )
 
 const (
-	dockerGPGKeyURL      = "https://download.docker.com/linux/ubuntu/gpg"
-	dockerGPGKeyringPath = "/usr/share/keyrings/docker-archive-keyring.gpg"
-	dockerAptSourcesPath = "/etc/apt/sources.list.d/docker.list"
-	dockerRepoURL        = "https://download.docker.com/linux/ubuntu"
+	dockerAptRepoName = "docker"
+	dockerDnfRepoPath = "/etc/yum.repos.d/docker-ce-stable.repo"
+
+	// dockerKeyFingerprint is the fingerprint of Docker's release signing key.
+	dockerKeyFingerprint = "9DC858229FC7DD38854AE2D88D81803C0EBFCD88"
+
+	dockerServiceName = "docker"
+
+	// dockerRepoBaseURL is followed by the distribution
+	// ("ubuntu", "debian", "fedora", "rhel", or "centos").
+	dockerRepoBaseURL = "https://download.docker.com/linux/"
+)
+
+// dockerKernelModules are the kernel modules loaded by Docker's default
+// storage driver (overlay2) and bridge networking.
+var dockerKernelModules = []string{"overlay", "br_netfilter"}
+
+// kernelReleasePath, loadedModulesDir and kernelModulesDir locate the running
+// kernel's release, its loaded modules and the installed modules. They are
+// variables so tests can use temporary files.
+var (
+	kernelReleasePath = "/proc/sys/kernel/osrelease"
+	loadedModulesDir  = "/sys/module"
+	kernelModulesDir  = "/lib/modules"
 )
 
+// dockerPackages are the Docker packages (Debian names; pkgmgr maps them per distribution).
+var dockerPackages = []string{"docker-ce", "docker-ce-cli", "containerd.io", "docker-buildx-plugin", "docker-compose-plugin"}
+
 // DockerModule implements the Module interface for Docker CE and Docker Compose installation.
 type DockerModule struct{}
 
@@ -32,6 +61,94 @@ func (m *DockerModule) Description() string {
 	return "Installs Docker CE and Docker Compose"
 }
 
+// Metadata returns the configuration keys, files, services, ports, packages,
+// and URLs this module uses.
+func (m *DockerModule) Metadata() module.Metadata {
+	return module.Metadata{
+		ConfigKeys: []module.ConfigKey{
+			{Key: "docker.install_compose", Description: "Whether to install the Docker Compose plugin"},
+			{Key: "user.username", Description: "User added to the docker group"},
+			{Key: "network.proxy.https", Description: "Proxy the Docker daemon uses to pull images"},
+		},
+		Files: []string{
+			aptrepo.KeyringPath(dockerAptRepoName),
+			aptrepo.SourcesPath(dockerAptRepoName),
+			dockerDnfRepoPath,
+			proxy.DockerDropInPath,
+		},
+		Services: []string{dockerServiceName},
+		Packages: dockerPackages,
+		URLs: []string{
+			dockerRepoBaseURL + "{distro}/gpg",
+			dockerRepoBaseURL + "{distro}",
+		},
+		// The user is added to the docker group
+		Requires: []string{"user"},
+	}
+}
+
+// Requirements returns the Docker packages and the repository they come from.
+func (m *DockerModule) Requirements(cfg *config.Config) (module.Requirements, error) {
+	pm, err := pkgmgr.Detect()
+	if err != nil {
+		return module.Requirements{}, err
+	}
+	repo, err := dockerRepository(pm)
+	if err != nil {
+		return module.Requirements{}, err
+	}
+
+	reqs := module.Requirements{Packages: append([]string{"ca-certificates", "curl"}, dockerPackages...)}
+	if repo != nil {
+		reqs.Repositories = []pkgmgr.Repository{*repo}
+	}
+	return reqs, nil
+}
+
+// dockerRepository returns Docker's package repository for the detected OS, or
+// nil if Docker is installed from the distribution's own repositories (Alpine).
+// Docker publishes a separate repository per distribution.
+func dockerRepository(pm pkgmgr.Manager) (*pkgmgr.Repository, error) {
+	info, err := osinfo.Detect()
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect operating system: %w", err)
+	}
+
+	switch pm.Name() {
+	case pkgmgr.NameApt:
+		distro, codename, err := info.AptDistro()
+		if err != nil {
+			return nil, err
+		}
+		url := dockerRepoBaseURL + distro
+		return &pkgmgr.Repository{
+			Name:        dockerAptRepoName,
+			URL:         url,
+			KeyURL:      url + "/gpg",
+			Fingerprint: dockerKeyFingerprint,
+			Suite:       codename,
+			Components:  []string{"stable"},
+			Arch:        info.Arch,
+		}, nil
+	case pkgmgr.NameDnf:
+		// Rocky, Alma and other RHEL rebuilds use the CentOS repository
+		family := "centos"
+		switch {
+		case info.Is("fedora") && !info.Is("rhel"):
+			family = "fedora"
+		case info.ID == "rhel":
+			family = "rhel"
+		}
+		return &pkgmgr.Repository{
+			Name:   "docker-ce-stable",
+			URL:    dockerRepoBaseURL + family + "/$releasever/$basearch/stable",
+			KeyURL: dockerRepoBaseURL + family + "/gpg",
+		}, nil
+	default:
+		return nil, nil
+	}
+}
+
 // dockerInstalled checks if Docker is installed by running docker --version.
 func dockerInstalled() (bool, error) {
 	err := exec.Run("docker", "--version")
@@ -43,11 +160,11 @@ func dockerInstalled() (bool, error) {
 
 // dockerServiceRunning checks if Docker service is running.
 func dockerServiceRunning() (bool, error) {
-	output, err := exec.RunWithOutput("systemctl", "is-active", "docker")
+	svc, err := svcmgr.Detect()
 	if err != nil {
-		return false, nil
+		return false, err
 	}
-	return strings.TrimSpace(output) == "active", nil
+	return svc.IsActive(dockerServiceName)
 }
 
 // dockerComposeInstalled checks if Docker Compose v2 is installed.
@@ -91,69 +208,22 @@ func userInDockerGroup(username string) (bool, error) {
 	return false, nil
 }
 
-// getDistributionCodename gets the distribution codename (e.g., "jammy", "focal").
-// Tries lsb_release first, then falls back to reading /etc/os-release.
-func getDistributionCodename() (string, error) {
-	// Try lsb_release first
-	output, err := exec.RunWithOutput("lsb_release", "-cs")
-	if err == nil {
-		codename := strings.TrimSpace(output)
-		if codename != "" {
-			return codename, nil
-		}
-	}
-
-	// Fallback to /etc/os-release
-	if !exec.FileExists("/etc/os-release") {
-		return "", fmt.Errorf("cannot determine distribution codename: lsb_release failed and /etc/os-release not found")
-	}
-
-	file, err := os.Open("/etc/os-release")
-	if err != nil {
-		return "", fmt.Errorf("failed to open /etc/os-release: %w", err)
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		// Look for VERSION_CODENAME or VERSION_ID
-		if strings.HasPrefix(line, "VERSION_CODENAME=") {
-			codename := strings.TrimPrefix(line, "VERSION_CODENAME=")
-			codename = strings.Trim(codename, `"`)
-			if codename != "" {
-				return codename, nil
-			}
-		}
-		// Fallback to VERSION_ID for Ubuntu versions
-		if strings.HasPrefix(line, "VERSION_ID=") {
-			versionID := strings.TrimPrefix(line, "VERSION_ID=")
-			versionID = strings.Trim(versionID, `"`)
-			// Map common Ubuntu version IDs to codenames
-			// This is a fallback, lsb_release should work in most cases
-			versionMap := map[string]string{
-				"22.04": "jammy",
-				"20.04": "focal",
-				"18.04": "bionic",
-				"16.04": "xenial",
-			}
-			if codename, ok := versionMap[versionID]; ok {
-				return codename, nil
-			}
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		return "", fmt.Errorf("failed to read /etc/os-release: %w", err)
-	}
-
-	return "", fmt.Errorf("cannot determine distribution codename from /etc/os-release")
-}
-
-// IsInstalled checks if Docker is already installed and configured.
+// IsInstalled checks if Docker is already installed and configured, including
+// the daemon's proxy settings (network.proxy).
 // Since IsInstalled() doesn't receive config, it performs generic checks.
 // Install() performs specific checks with config and is fully idempotent.
 func (m *DockerModule) IsInstalled() (bool, error) {
+	ready, err := dockerReady()
+	if err != nil || !ready {
+		return false, err
+	}
+	return daemonProxyUpToDate()
+}
+
+// dockerReady checks that Docker is installed and running, and that Docker
+// Compose is installed. It is a variable so tests can simulate an installed
+// Docker.
+var dockerReady = func() (bool, error) {
 	// Check if Docker is installed
 	installed, err := dockerInstalled()
 	if err != nil {
@@ -184,6 +254,79 @@ func (m *DockerModule) IsInstalled() (bool, error) {
 	return true, nil
 }
 
+// usesSystemd reports whether services are managed by systemd, the only
+// service manager the daemon proxy is configured for. It is a variable so
+// tests can simulate systemd.
+var usesSystemd = func() (bool, error) {
+	svc, err := svcmgr.Detect()
+	if err != nil {
+		return false, err
+	}
+	return svc.Name() == svcmgr.NameSystemd, nil
+}
+
+// daemonProxyUpToDate reports whether the Docker daemon's proxy drop-in matches
+// the proxy settings: it has the content of proxy.DockerDropIn, or is absent if
+// no proxy is configured.
+func daemonProxyUpToDate() (bool, error) {
+	systemd, err := usesSystemd()
+	if err != nil || !systemd {
+		return true, err
+	}
+	if !proxy.Enabled() {
+		return !exec.FileExists(proxy.DockerDropInPath), nil
+	}
+	content, err := os.ReadFile(exec.Path(proxy.DockerDropInPath))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", proxy.DockerDropInPath, err)
+	}
+	return string(content) == proxy.DockerDropIn(), nil
+}
+
+// RequiresBoot reports whether a kernel module Docker needs is neither loaded
+// nor available for the running kernel. A kernel update can remove the modules
+// of the running kernel, and then they can only be loaded after a reboot.
+func (m *DockerModule) RequiresBoot(cfg *config.Config) bool {
+	release, err := os.ReadFile(kernelReleasePath)
+	if err != nil {
+		return false
+	}
+	dir := filepath.Join(kernelModulesDir, strings.TrimSpace(string(release)))
+	for _, name := range dockerKernelModules {
+		if !kernelModuleAvailable(dir, name) {
+			log.Info("Kernel module %s is not available for the running kernel", name)
+			return true
+		}
+	}
+	return false
+}
+
+// kernelModuleAvailable reports whether the kernel module name is loaded, or
+// is built into or can be loaded into the kernel whose modules are in dir.
+func kernelModuleAvailable(dir, name string) bool {
+	if _, err := os.Stat(filepath.Join(loadedModulesDir, name)); err == nil {
+		return true
+	}
+	for _, index := range []string{"modules.builtin", "modules.dep"} {
+		content, err := os.ReadFile(filepath.Join(dir, index))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(content), "\n") {
+			path, _, _ := strings.Cut(line, ":")
+			// Modules may be compressed (e.g., overlay.ko.zst)
+			base := filepath.Base(path)
+			if base == name+".ko" || strings.HasPrefix(base, name+".ko.") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // Install installs Docker CE and Docker Compose v2, and adds the user to the docker group.
 func (m *DockerModule) Install(cfg *config.Config) error {
 	dryRun := log.IsDryRun()
@@ -200,57 +343,49 @@ func (m *DockerModule) Install(cfg *config.Config) error {
 	}
 
 	if !dockerInstalled {
+		pm, err := pkgmgr.Detect()
+		if err != nil {
+			return err
+		}
+		repo, err := dockerRepository(pm)
+		if err != nil {
+			return err
+		}
+
 		if dryRun {
-			log.Info("Would install Docker CE and Docker Compose")
+			if repo != nil {
+				log.Info("Would install Docker CE and Docker Compose from %s", repo.URL)
+			} else {
+				log.Info("Would install Docker and Docker Compose from the distribution repositories")
+			}
 		} else {
 			log.Info("Installing Docker CE and Docker Compose")
 
 			// Install prerequisites
 			log.Info("Installing prerequisites")
-			if err := exec.Run("apt-get", "update"); err != nil {
-				return fmt.Errorf("failed to update apt: %w", err)
+			if err := pm.Refresh(); err != nil {
+				return fmt.Errorf("failed to refresh package indexes: %w", err)
 			}
-			if err := exec.Run("apt-get", "install", "-y", "ca-certificates", "curl"); err != nil {
+			if err := pm.Install("ca-certificates", "curl"); err != nil {
 				return fmt.Errorf("failed to install prerequisites: %w", err)
 			}
 
-			// Download and add Docker GPG key
-			log.Info("Adding Docker GPG key")
-			// Download GPG key and pipe through gpg --dearmor to create keyring
-			// Use curl to download and pipe to gpg --dearmor
-			if err := exec.Run("sh", "-c", fmt.Sprintf("curl -fsSL %s | gpg --dearmor -o %s", dockerGPGKeyURL, dockerGPGKeyringPath)); err != nil {
-				return fmt.Errorf("failed to add Docker GPG key: %w", err)
-			}
-
-			// Get distribution codename
-			codename, err := getDistributionCodename()
-			if err != nil {
-				return fmt.Errorf("failed to get distribution codename: %w", err)
-			}
-
-			// Get architecture
-			arch, err := exec.RunWithOutput("dpkg", "--print-architecture")
-			if err != nil {
-				return fmt.Errorf("failed to get architecture: %w", err)
-			}
-			arch = strings.TrimSpace(arch)
-
-			// Add Docker repository
-			log.Info("Adding Docker repository")
-			repoLine := fmt.Sprintf("deb [arch=%s signed-by=%s] %s %s stable\n", arch, dockerGPGKeyringPath, dockerRepoURL, codename)
-			if err := exec.WriteFile(dockerAptSourcesPath, []byte(repoLine), 0644); err != nil {
-				return fmt.Errorf("failed to add Docker repository: %w", err)
-			}
+			// Add Docker repository and signing key
+			if repo != nil {
+				log.Info("Adding Docker repository")
+				if err := pm.AddRepository(*repo); err != nil {
+					return fmt.Errorf("failed to add Docker repository: %w", err)
+				}
 
-			// Update apt package list
-			log.Info("Updating apt package list")
-			if err := exec.Run("apt-get", "update"); err != nil {
-				return fmt.Errorf("failed to update apt after adding Docker repository: %w", err)
+				log.Info("Refreshing package indexes")
+				if err := pm.Refresh(); err != nil {
+					return fmt.Errorf("failed to refresh package indexes after adding Docker repository: %w", err)
+				}
 			}
 
 			// Install Docker CE packages
 			log.Info("Installing Docker CE packages")
-			if err := exec.Run("apt-get", "install", "-y", "docker-ce", "docker-ce-cli", "containerd.io", "docker-buildx-plugin", "docker-compose-plugin"); err != nil {
+			if err := pm.Install(dockerPackages...); err != nil {
 				return fmt.Errorf("failed to install Docker packages: %w", err)
 			}
 
@@ -262,17 +397,20 @@ func (m *DockerModule) Install(cfg *config.Config) error {
 
 			// Start and enable Docker service
 			log.Info("Starting Docker service")
-			if err := exec.Run("systemctl", "enable", "--now", "docker"); err != nil {
+			svc, err := svcmgr.Detect()
+			if err != nil {
+				return err
+			}
+			if err := svc.Enable(dockerServiceName); err != nil {
+				return fmt.Errorf("failed to enable Docker service: %w", err)
+			}
+			if err := svc.Start(dockerServiceName); err != nil {
 				return fmt.Errorf("failed to start Docker service: %w", err)
 			}
 
-			// Verify Docker service is running
-			running, err := dockerServiceRunning()
-			if err != nil {
-				return fmt.Errorf("failed to verify Docker service status: %w", err)
-			}
-			if !running {
-				return fmt.Errorf("Docker service is not running after start")
+			// Wait for the service to become active
+			if err := svc.WaitActive(dockerServiceName, svcmgr.DefaultWaitTimeout); err != nil {
+				return fmt.Errorf("Docker service is not running after start: %w", err)
 			}
 
 			log.Success("Docker CE installed and started")
@@ -281,6 +419,11 @@ func (m *DockerModule) Install(cfg *config.Config) error {
 		log.Skip("Docker is already installed")
 	}
 
+	// Route image pulls through the proxy
+	if err := configureDaemonProxy(dryRun); err != nil {
+		return err
+	}
+
 	// Verify Docker Compose if enabled
 	if cfg.Docker.InstallCompose {
 		composeInstalled, err := dockerComposeInstalled()
@@ -339,5 +482,70 @@ func (m *DockerModule) Install(cfg *config.Config) error {
 	return nil
 }
 
-// Ensure DockerModule implements the Module interface
+// configureDaemonProxy writes the Docker daemon's proxy drop-in, or removes
+// it if no proxy is configured, and restarts a running daemon on changes.
+func configureDaemonProxy(dryRun bool) error {
+	svc, err := svcmgr.Detect()
+	if err != nil {
+		return err
+	}
+	if svc.Name() != svcmgr.NameSystemd {
+		if proxy.Enabled() {
+			log.Warn("The Docker daemon proxy is only configured with systemd; configure it for %s manually", svc.Name())
+		}
+		return nil
+	}
+
+	changed := false
+	if proxy.Enabled() {
+		content := []byte(proxy.DockerDropIn())
+		if dryRun {
+			if _, err := files.PreviewMasked(proxy.DockerDropInPath, content, proxy.MaskCredentials); err != nil {
+				return fmt.Errorf("failed to preview %s: %w", proxy.DockerDropInPath, err)
+			}
+			return nil
+		}
+		if err := os.MkdirAll(exec.Path(filepath.Dir(proxy.DockerDropInPath)), 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", filepath.Dir(proxy.DockerDropInPath), err)
+		}
+		result, err := files.Write(proxy.DockerDropInPath, content, files.Options{Mode: 0644})
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", proxy.DockerDropInPath, err)
+		}
+		changed = result.Changed
+	} else if exec.FileExists(proxy.DockerDropInPath) {
+		if dryRun {
+			log.Info("Would remove %s (no proxy configured)", proxy.DockerDropInPath)
+			return nil
+		}
+		if err := os.Remove(exec.Path(proxy.DockerDropInPath)); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", proxy.DockerDropInPath, err)
+		}
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	running, err := svc.IsActive(dockerServiceName)
+	if err != nil {
+		return fmt.Errorf("failed to check Docker service status: %w", err)
+	}
+	if running {
+		log.Info("Restarting Docker to apply the proxy settings")
+		if err := exec.Run("systemctl", "daemon-reload"); err != nil {
+			return fmt.Errorf("failed to reload systemd: %w", err)
+		}
+		if err := svc.Restart(dockerServiceName); err != nil {
+			return fmt.Errorf("failed to restart Docker service: %w", err)
+		}
+	}
+	log.Success("Updated Docker daemon proxy in %s", proxy.DockerDropInPath)
+	return nil
+}
+
+// Ensure DockerModule implements the Module and Describer interfaces
 var _ module.Module = (*DockerModule)(nil)
+var _ module.Describer = (*DockerModule)(nil)
+var _ module.Bundler = (*DockerModule)(nil)
+var _ module.BootRequirer = (*DockerModule)(nil)
@@ -2,13 +2,15 @@ package monitoring
 
 import (
 	"fmt"
-	"os"
-	"strings"
 
 	"github.com/stwalsh4118/phanes/internal/config"
+	"github.com/stwalsh4118/phanes/internal/download"
 	"github.com/stwalsh4118/phanes/internal/exec"
 	"github.com/stwalsh4118/phanes/internal/log"
 	"github.com/stwalsh4118/phanes/internal/module"
+	"github.com/stwalsh4118/phanes/internal/sockets"
+	"github.com/stwalsh4118/phanes/internal/svcmgr"
+	"github.com/stwalsh4118/phanes/internal/timing"
 )
 
 const (
@@ -16,7 +18,9 @@ const (
 	netdataDefaultPort  = 19999
 	netdataServiceName  = "netdata"
 	netdataBinaryPath   = "/usr/sbin/netdata"
-	kickstartScriptPath = "/tmp/netdata-kickstart.sh"
+
+	// netdataKickstartArtifact names the kickstart script in downloads.checksums.
+	netdataKickstartArtifact = "netdata-kickstart"
 )
 
 // MonitoringModule implements the Module interface for Netdata monitoring installation.
@@ -32,15 +36,35 @@ func (m *MonitoringModule) Description() string {
 	return "Installs and configures Netdata monitoring"
 }
 
+// Metadata returns the configuration keys, files, services, ports, packages,
+// and URLs this module uses.
+func (m *MonitoringModule) Metadata() module.Metadata {
+	return module.Metadata{
+		Services: []string{netdataServiceName},
+		Ports: []module.Port{
+			{Number: netdataDefaultPort, Protocol: "tcp", Description: "Netdata dashboard"},
+		},
+		Packages: []string{"netdata"},
+		URLs:     []string{netdataKickstartURL},
+	}
+}
+
+// Requirements returns the Netdata kickstart script.
+func (m *MonitoringModule) Requirements(cfg *config.Config) (module.Requirements, error) {
+	return module.Requirements{
+		Artifacts:    []download.Artifact{{Name: netdataKickstartArtifact, URL: netdataKickstartURL, Script: true}},
+		NeedsNetwork: "the Netdata kickstart script downloads Netdata itself",
+	}, nil
+}
+
 // netdataInstalled checks if Netdata is installed by checking if the binary exists.
 func netdataInstalled() (bool, error) {
 	if exec.FileExists(netdataBinaryPath) {
 		return true, nil
 	}
 	// Also check if service exists as fallback
-	output, err := exec.RunWithOutput("systemctl", "list-unit-files", "--type=service", "--no-pager")
-	if err == nil {
-		if strings.Contains(output, netdataServiceName+".service") {
+	if svc, err := svcmgr.Detect(); err == nil {
+		if exists, _ := svc.Exists(netdataServiceName); exists {
 			return true, nil
 		}
 	}
@@ -49,42 +73,29 @@ func netdataInstalled() (bool, error) {
 
 // netdataServiceRunning checks if Netdata service is running.
 func netdataServiceRunning() (bool, error) {
-	output, err := exec.RunWithOutput("systemctl", "is-active", netdataServiceName)
+	svc, err := svcmgr.Detect()
 	if err != nil {
-		return false, nil
+		return false, err
 	}
-	return strings.TrimSpace(output) == "active", nil
+	return svc.IsActive(netdataServiceName)
 }
 
 // netdataServiceEnabled checks if Netdata service is enabled.
 func netdataServiceEnabled() (bool, error) {
-	output, err := exec.RunWithOutput("systemctl", "is-enabled", netdataServiceName)
+	svc, err := svcmgr.Detect()
 	if err != nil {
-		return false, nil
+		return false, err
 	}
-	status := strings.TrimSpace(output)
-	return status == "enabled" || status == "enabled-runtime", nil
+	return svc.IsEnabled(netdataServiceName)
 }
 
 // netdataPortAccessible checks if Netdata is listening on port 19999.
 func netdataPortAccessible() (bool, error) {
-	// Try ss first (more modern), fallback to netstat
-	output, err := exec.RunWithOutput("ss", "-tlnp")
+	listening, err := sockets.ListeningAs(netdataDefaultPort, "netdata")
 	if err != nil {
-		// Fallback to netstat
-		output, err = exec.RunWithOutput("netstat", "-tlnp")
-		if err != nil {
-			return false, fmt.Errorf("failed to check port accessibility: %w", err)
-		}
-	}
-
-	// Check if port 19999 is in the output
-	portStr := fmt.Sprintf(":%d", netdataDefaultPort)
-	if strings.Contains(output, portStr) {
-		return true, nil
+		return false, fmt.Errorf("failed to check port accessibility: %w", err)
 	}
-
-	return false, nil
+	return listening, nil
 }
 
 // IsInstalled checks if Netdata is already installed and configured.
@@ -137,37 +148,40 @@ func (m *MonitoringModule) Install(cfg *config.Config) error {
 		} else {
 			log.Info("Installing Netdata monitoring")
 
-			// Download kickstart script
-			log.Info("Downloading Netdata kickstart script")
-			if err := exec.Run("curl", "-fsSL", netdataKickstartURL, "-o", kickstartScriptPath); err != nil {
+			// Download and verify kickstart script
+			var script string
+			if err := timing.Track("download Netdata kickstart script", func() error {
+				var err error
+				script, err = download.Fetch(download.Artifact{
+					Name:   netdataKickstartArtifact,
+					URL:    netdataKickstartURL,
+					Script: true,
+				})
+				return err
+			}); err != nil {
 				return fmt.Errorf("failed to download Netdata kickstart script: %w", err)
 			}
 
-			// Make script executable
-			if err := exec.Run("chmod", "+x", kickstartScriptPath); err != nil {
-				return fmt.Errorf("failed to make kickstart script executable: %w", err)
-			}
-
 			// Run kickstart script in non-interactive mode
 			log.Info("Running Netdata kickstart script (this may take a few minutes)")
 			// The kickstart script provides its own progress output, so we let it stream to stdout/stderr
-			if err := exec.Run("bash", kickstartScriptPath, "--non-interactive"); err != nil {
-				// Clean up script even on error
-				os.Remove(kickstartScriptPath)
+			if err := timing.Track("run Netdata kickstart script", func() error {
+				return exec.Run("bash", script, "--non-interactive")
+			}); err != nil {
 				return fmt.Errorf("failed to run Netdata kickstart script: %w", err)
 			}
 
-			// Clean up kickstart script after successful installation
-			if err := os.Remove(kickstartScriptPath); err != nil {
-				log.Warn("Failed to remove kickstart script: %v", err)
-			}
-
 			log.Success("Netdata installed successfully")
 		}
 	} else {
 		log.Skip("Netdata is already installed")
 	}
 
+	svc, err := svcmgr.Detect()
+	if err != nil {
+		return err
+	}
+
 	// Configure service to start on boot
 	enabled, err := netdataServiceEnabled()
 	if err != nil {
@@ -179,7 +193,7 @@ func (m *MonitoringModule) Install(cfg *config.Config) error {
 			log.Info("Would enable Netdata service to start on boot")
 		} else {
 			log.Info("Enabling Netdata service to start on boot")
-			if err := exec.Run("systemctl", "enable", netdataServiceName); err != nil {
+			if err := svc.Enable(netdataServiceName); err != nil {
 				return fmt.Errorf("failed to enable Netdata service: %w", err)
 			}
 			log.Success("Netdata service enabled")
@@ -199,17 +213,13 @@ func (m *MonitoringModule) Install(cfg *config.Config) error {
 			log.Info("Would start Netdata service")
 		} else {
 			log.Info("Starting Netdata service")
-			if err := exec.Run("systemctl", "start", netdataServiceName); err != nil {
+			if err := svc.Start(netdataServiceName); err != nil {
 				return fmt.Errorf("failed to start Netdata service: %w", err)
 			}
 
-			// Verify service is running
-			running, err := netdataServiceRunning()
-			if err != nil {
-				return fmt.Errorf("failed to verify Netdata service status: %w", err)
-			}
-			if !running {
-				return fmt.Errorf("Netdata service is not running after start")
+			// Wait for the service to become active
+			if err := svc.WaitActive(netdataServiceName, svcmgr.DefaultWaitTimeout); err != nil {
+				return fmt.Errorf("Netdata service is not running after start: %w", err)
 			}
 
 			log.Success("Netdata service started")
@@ -244,7 +254,9 @@ func (m *MonitoringModule) Install(cfg *config.Config) error {
 	return nil
 }
 
-// Ensure MonitoringModule implements the Module interface
+// Ensure MonitoringModule implements the Module and Describer interfaces
 var _ module.Module = (*MonitoringModule)(nil)
+var _ module.Describer = (*MonitoringModule)(nil)
+var _ module.Bundler = (*MonitoringModule)(nil)
 
 
@@ -78,7 +78,7 @@ func TestNetdataPortAccessible(t *testing.T) {
 	// It may return false if Netdata port is not accessible
 	accessible, err := netdataPortAccessible()
 	if err != nil {
-		t.Logf("netdataPortAccessible() returned error (may be expected if Netdata not running or ss/netstat not available): %v", err)
+		t.Logf("netdataPortAccessible() returned error (may be expected if Netdata not running or /proc/net not readable): %v", err)
 	}
 
 	// Verify return type
@@ -175,12 +175,12 @@ func TestMonitoringModule_Install_Idempotency(t *testing.T) {
 }
 
 func TestNetdataPortAccessible_EdgeCases(t *testing.T) {
-	// Test that netdataPortAccessible() handles missing ss/netstat gracefully
+	// Test that netdataPortAccessible() handles an unreadable /proc/net gracefully
 	// This is tested implicitly in TestNetdataPortAccessible, but we can verify
-	// the function doesn't panic even if both commands fail
+	// the function doesn't panic even if /proc/net cannot be read
 	accessible, err := netdataPortAccessible()
 	if err != nil {
-		// Error is acceptable if ss and netstat are both unavailable
+		// Error is acceptable if /proc/net cannot be read
 		if !strings.Contains(err.Error(), "failed to check port accessibility") {
 			t.Errorf("netdataPortAccessible() should return descriptive error, got: %v", err)
 		}
@@ -2,12 +2,14 @@ package nginx
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/stwalsh4118/phanes/internal/config"
 	"github.com/stwalsh4118/phanes/internal/exec"
 	"github.com/stwalsh4118/phanes/internal/log"
 	"github.com/stwalsh4118/phanes/internal/module"
+	"github.com/stwalsh4118/phanes/internal/pkgmgr"
+	"github.com/stwalsh4118/phanes/internal/sockets"
+	"github.com/stwalsh4118/phanes/internal/svcmgr"
 )
 
 const (
@@ -29,15 +31,38 @@ func (m *NginxModule) Description() string {
 	return "Installs and configures Nginx web server"
 }
 
+// Metadata returns the configuration keys, files, services, ports, packages,
+// and URLs this module uses.
+func (m *NginxModule) Metadata() module.Metadata {
+	return module.Metadata{
+		ConfigKeys: []module.ConfigKey{
+			{Key: "nginx.enabled", Description: "Whether to install Nginx"},
+		},
+		Files:    []string{"/etc/nginx"},
+		Services: []string{nginxServiceName},
+		Ports: []module.Port{
+			{Number: nginxDefaultPort, Protocol: "tcp", Description: "HTTP"},
+		},
+		Packages: []string{"nginx"},
+	}
+}
+
+// Requirements returns the Nginx package.
+func (m *NginxModule) Requirements(cfg *config.Config) (module.Requirements, error) {
+	if !cfg.Nginx.Enabled {
+		return module.Requirements{}, nil
+	}
+	return module.Requirements{Packages: []string{"nginx"}}, nil
+}
+
 // nginxInstalled checks if Nginx is installed by checking if the binary exists.
 func nginxInstalled() (bool, error) {
 	if exec.FileExists(nginxBinaryPath) {
 		return true, nil
 	}
 	// Also check if service exists as fallback
-	output, err := exec.RunWithOutput("systemctl", "list-unit-files", "--type=service", "--no-pager")
-	if err == nil {
-		if strings.Contains(output, nginxServiceName+".service") {
+	if svc, err := svcmgr.Detect(); err == nil {
+		if exists, _ := svc.Exists(nginxServiceName); exists {
 			return true, nil
 		}
 	}
@@ -53,71 +78,38 @@ func nginxInstalled() (bool, error) {
 
 // nginxServiceRunning checks if Nginx service is running.
 func nginxServiceRunning() (bool, error) {
-	output, err := exec.RunWithOutput("systemctl", "is-active", nginxServiceName)
+	svc, err := svcmgr.Detect()
 	if err != nil {
-		return false, nil
+		return false, err
 	}
-	return strings.TrimSpace(output) == "active", nil
+	return svc.IsActive(nginxServiceName)
 }
 
 // nginxServiceEnabled checks if Nginx service is enabled.
 func nginxServiceEnabled() (bool, error) {
-	output, err := exec.RunWithOutput("systemctl", "is-enabled", nginxServiceName)
+	svc, err := svcmgr.Detect()
 	if err != nil {
-		return false, nil
+		return false, err
 	}
-	status := strings.TrimSpace(output)
-	return status == "enabled" || status == "enabled-runtime", nil
+	return svc.IsEnabled(nginxServiceName)
 }
 
 // nginxPortAccessible checks if Nginx is listening on port 80.
 func nginxPortAccessible() (bool, error) {
-	// Try ss first (more modern), fallback to netstat
-	output, err := exec.RunWithOutput("ss", "-tlnp")
+	listening, err := sockets.ListeningAs(nginxDefaultPort, "nginx")
 	if err != nil {
-		// Fallback to netstat
-		output, err = exec.RunWithOutput("netstat", "-tlnp")
-		if err != nil {
-			return false, fmt.Errorf("failed to check port accessibility: %w", err)
-		}
-	}
-
-	// Check if port 80 is in the output
-	portStr := fmt.Sprintf(":%d", nginxDefaultPort)
-	if strings.Contains(output, portStr) {
-		return true, nil
+		return false, fmt.Errorf("failed to check port accessibility: %w", err)
 	}
-
-	return false, nil
+	return listening, nil
 }
 
-// port80InUse checks if port 80 is already in use by another service (not Nginx).
-func port80InUse() (bool, error) {
-	// Try ss first (more modern), fallback to netstat
-	output, err := exec.RunWithOutput("ss", "-tlnp")
+// port80Conflicts returns the listeners on port 80 that are not Nginx.
+func port80Conflicts() ([]sockets.Listener, error) {
+	conflicts, err := sockets.Conflicts(nginxDefaultPort, "nginx")
 	if err != nil {
-		// Fallback to netstat
-		output, err = exec.RunWithOutput("netstat", "-tlnp")
-		if err != nil {
-			return false, fmt.Errorf("failed to check port 80 usage: %w", err)
-		}
-	}
-
-	// Check if port 80 is in the output
-	portStr := fmt.Sprintf(":%d", nginxDefaultPort)
-	if !strings.Contains(output, portStr) {
-		return false, nil
+		return nil, fmt.Errorf("failed to check port 80 usage: %w", err)
 	}
-
-	// Port 80 is in use, check if it's Nginx
-	// Check if nginx is mentioned in the output
-	if strings.Contains(strings.ToLower(output), nginxServiceName) {
-		// It's Nginx, so port is not in use by another service
-		return false, nil
-	}
-
-	// Port 80 is in use by another service
-	return true, nil
+	return conflicts, nil
 }
 
 // IsInstalled checks if Nginx is already installed and configured.
@@ -165,12 +157,12 @@ func (m *NginxModule) Install(cfg *config.Config) error {
 	}
 
 	// Check if port 80 is in use by another service
-	inUse, err := port80InUse()
+	conflicts, err := port80Conflicts()
 	if err != nil {
-		return fmt.Errorf("failed to check port 80 usage: %w", err)
+		return err
 	}
-	if inUse {
-		log.Warn("Port 80 is already in use. Nginx may not be able to bind to this port.")
+	for _, listener := range conflicts {
+		log.Warn("Port 80 is already in use by %s. Nginx may not be able to bind to this port.", listener)
 	}
 
 	// Check if Nginx is already installed
@@ -180,20 +172,25 @@ func (m *NginxModule) Install(cfg *config.Config) error {
 	}
 
 	if !installed {
+		pm, err := pkgmgr.Detect()
+		if err != nil {
+			return err
+		}
+
 		if dryRun {
 			log.Info("Would install Nginx web server")
 		} else {
 			log.Info("Installing Nginx web server")
 
-			// Update apt package list
-			log.Info("Updating package list")
-			if err := exec.Run("apt-get", "update"); err != nil {
-				return fmt.Errorf("failed to update package list: %w", err)
+			// Refresh package indexes
+			log.Info("Refreshing package indexes")
+			if err := pm.Refresh(); err != nil {
+				return fmt.Errorf("failed to refresh package indexes: %w", err)
 			}
 
 			// Install nginx
 			log.Info("Installing nginx package")
-			if err := exec.Run("apt-get", "install", "-y", "nginx"); err != nil {
+			if err := pm.Install("nginx"); err != nil {
 				return fmt.Errorf("failed to install nginx: %w", err)
 			}
 
@@ -208,6 +205,11 @@ func (m *NginxModule) Install(cfg *config.Config) error {
 		log.Skip("Nginx is already installed")
 	}
 
+	svc, err := svcmgr.Detect()
+	if err != nil {
+		return err
+	}
+
 	// Configure service to start on boot
 	enabled, err := nginxServiceEnabled()
 	if err != nil {
@@ -219,7 +221,7 @@ func (m *NginxModule) Install(cfg *config.Config) error {
 			log.Info("Would enable Nginx service to start on boot")
 		} else {
 			log.Info("Enabling Nginx service to start on boot")
-			if err := exec.Run("systemctl", "enable", nginxServiceName); err != nil {
+			if err := svc.Enable(nginxServiceName); err != nil {
 				return fmt.Errorf("failed to enable Nginx service: %w", err)
 			}
 			log.Success("Nginx service enabled")
@@ -239,17 +241,13 @@ func (m *NginxModule) Install(cfg *config.Config) error {
 			log.Info("Would start Nginx service")
 		} else {
 			log.Info("Starting Nginx service")
-			if err := exec.Run("systemctl", "start", nginxServiceName); err != nil {
+			if err := svc.Start(nginxServiceName); err != nil {
 				return fmt.Errorf("failed to start Nginx service: %w", err)
 			}
 
-			// Verify service is running
-			running, err := nginxServiceRunning()
-			if err != nil {
-				return fmt.Errorf("failed to verify Nginx service status: %w", err)
-			}
-			if !running {
-				return fmt.Errorf("Nginx service is not running after start")
+			// Wait for the service to become active
+			if err := svc.WaitActive(nginxServiceName, svcmgr.DefaultWaitTimeout); err != nil {
+				return fmt.Errorf("Nginx service is not running after start: %w", err)
 			}
 
 			log.Success("Nginx service started")
@@ -284,8 +282,10 @@ func (m *NginxModule) Install(cfg *config.Config) error {
 	return nil
 }
 
-// Ensure NginxModule implements the Module interface
+// Ensure NginxModule implements the Module and Describer interfaces
 var _ module.Module = (*NginxModule)(nil)
+var _ module.Describer = (*NginxModule)(nil)
+var _ module.Bundler = (*NginxModule)(nil)
 
 
 
@@ -1,30 +1,36 @@
 package postgres
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	osexec "os/exec"
 	"strings"
 
+	"github.com/stwalsh4118/phanes/internal/aptrepo"
 	"github.com/stwalsh4118/phanes/internal/config"
 	"github.com/stwalsh4118/phanes/internal/exec"
 	"github.com/stwalsh4118/phanes/internal/log"
 	"github.com/stwalsh4118/phanes/internal/module"
+	"github.com/stwalsh4118/phanes/internal/osinfo"
+	"github.com/stwalsh4118/phanes/internal/pkgmgr"
+	"github.com/stwalsh4118/phanes/internal/sockets"
+	"github.com/stwalsh4118/phanes/internal/svcmgr"
 )
 
 const (
-	postgresGPGKeyURL      = "https://www.postgresql.org/media/keys/ACCC4CF8.asc"
-	postgresGPGKeyringPath = "/usr/share/keyrings/postgresql-archive-keyring.gpg"
-	postgresAptSourcesPath = "/etc/apt/sources.list.d/pgdg.list"
-	postgresRepoBaseURL    = "http://apt.postgresql.org/pub/repos/apt/"
-	postgresServiceName    = "postgresql"
-	postgresDefaultPort    = 5432
-	defaultVersion         = "16"
-	defaultDatabase        = "phanes"
-	defaultUser            = "phanes"
+	postgresGPGKeyURL   = "https://www.postgresql.org/media/keys/ACCC4CF8.asc"
+	postgresRepoName    = "pgdg"
+	postgresRepoBaseURL = "http://apt.postgresql.org/pub/repos/apt/"
+	postgresServiceName = "postgresql"
+	postgresDefaultPort = 5432
+	defaultVersion      = "16"
+	defaultDatabase     = "phanes"
+	defaultUser         = "phanes"
 )
 
+// postgresKeyFingerprint is the fingerprint of the PGDG repository signing key.
+const postgresKeyFingerprint = "B97B0AFCAA1A47F044F244A07FCC7D46ACCC4CF8"
+
 // PostgresModule implements the Module interface for PostgreSQL installation.
 type PostgresModule struct{}
 
@@ -38,62 +44,70 @@ func (m *PostgresModule) Description() string {
 	return "Installs and configures PostgreSQL database server"
 }
 
-// getDistributionCodename gets the distribution codename (e.g., "jammy", "focal").
-// Tries lsb_release first, then falls back to reading /etc/os-release.
-func getDistributionCodename() (string, error) {
-	// Try lsb_release first
-	output, err := exec.RunWithOutput("lsb_release", "-cs")
-	if err == nil {
-		codename := strings.TrimSpace(output)
-		if codename != "" {
-			return codename, nil
-		}
+// Metadata returns the configuration keys, files, services, ports, packages,
+// and URLs this module uses.
+func (m *PostgresModule) Metadata() module.Metadata {
+	return module.Metadata{
+		ConfigKeys: []module.ConfigKey{
+			{Key: "postgres.enabled", Description: "Whether to install PostgreSQL"},
+			{Key: "postgres.version", Description: "PostgreSQL major version installed from the PGDG repository"},
+			{Key: "postgres.password", Description: "Password set for the database user"},
+			{Key: "postgres.database", Description: "Initial database to create"},
+			{Key: "postgres.user", Description: "Database user to create"},
+		},
+		Files: []string{
+			aptrepo.KeyringPath(postgresRepoName),
+			aptrepo.SourcesPath(postgresRepoName),
+			"/etc/postgresql/{postgres.version}/main/",
+		},
+		Services: []string{postgresServiceName},
+		Ports: []module.Port{
+			{Number: postgresDefaultPort, Protocol: "tcp", Description: "PostgreSQL"},
+		},
+		Packages: []string{"postgresql-{postgres.version}"},
+		URLs: []string{
+			postgresGPGKeyURL,
+			postgresRepoBaseURL,
+		},
 	}
+}
 
-	// Fallback to /etc/os-release
-	if !exec.FileExists("/etc/os-release") {
-		return "", fmt.Errorf("cannot determine distribution codename: lsb_release failed and /etc/os-release not found")
+// Requirements returns the PostgreSQL packages and the PGDG repository they
+// come from.
+func (m *PostgresModule) Requirements(cfg *config.Config) (module.Requirements, error) {
+	if !cfg.Postgres.Enabled {
+		return module.Requirements{}, nil
 	}
 
-	file, err := os.Open("/etc/os-release")
+	version := cfg.Postgres.Version
+	if version == "" {
+		version = defaultVersion
+	}
+	info, err := osinfo.Detect()
 	if err != nil {
-		return "", fmt.Errorf("failed to open /etc/os-release: %w", err)
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		// Look for VERSION_CODENAME or VERSION_ID
-		if strings.HasPrefix(line, "VERSION_CODENAME=") {
-			codename := strings.TrimPrefix(line, "VERSION_CODENAME=")
-			codename = strings.Trim(codename, `"`)
-			if codename != "" {
-				return codename, nil
-			}
-		}
-		// Fallback to VERSION_ID for Ubuntu versions
-		if strings.HasPrefix(line, "VERSION_ID=") {
-			versionID := strings.TrimPrefix(line, "VERSION_ID=")
-			versionID = strings.Trim(versionID, `"`)
-			// Map common Ubuntu version IDs to codenames
-			versionMap := map[string]string{
-				"22.04": "jammy",
-				"20.04": "focal",
-				"18.04": "bionic",
-				"16.04": "xenial",
-			}
-			if codename, ok := versionMap[versionID]; ok {
-				return codename, nil
-			}
-		}
+		return module.Requirements{}, fmt.Errorf("failed to detect operating system: %w", err)
 	}
-
-	if err := scanner.Err(); err != nil {
-		return "", fmt.Errorf("failed to read /etc/os-release: %w", err)
+	_, codename, err := info.AptDistro()
+	if err != nil {
+		return module.Requirements{}, err
 	}
 
-	return "", fmt.Errorf("cannot determine distribution codename from /etc/os-release")
+	return module.Requirements{
+		Packages:     []string{"curl", "ca-certificates", "postgresql-" + version},
+		Repositories: []pkgmgr.Repository{postgresRepository(codename)},
+	}, nil
+}
+
+// postgresRepository returns the PGDG repository for a Debian or Ubuntu release.
+func postgresRepository(codename string) pkgmgr.Repository {
+	return pkgmgr.Repository{
+		Name:        postgresRepoName,
+		URL:         postgresRepoBaseURL,
+		KeyURL:      postgresGPGKeyURL,
+		Fingerprint: postgresKeyFingerprint,
+		Suite:       codename + "-pgdg",
+		Components:  []string{"main"},
+	}
 }
 
 // postgresInstalled checks if PostgreSQL is installed by running psql --version.
@@ -107,42 +121,29 @@ func postgresInstalled() (bool, error) {
 
 // postgresServiceRunning checks if PostgreSQL service is running.
 func postgresServiceRunning() (bool, error) {
-	output, err := exec.RunWithOutput("systemctl", "is-active", postgresServiceName)
+	svc, err := svcmgr.Detect()
 	if err != nil {
-		return false, nil
+		return false, err
 	}
-	return strings.TrimSpace(output) == "active", nil
+	return svc.IsActive(postgresServiceName)
 }
 
 // postgresServiceEnabled checks if PostgreSQL service is enabled.
 func postgresServiceEnabled() (bool, error) {
-	output, err := exec.RunWithOutput("systemctl", "is-enabled", postgresServiceName)
+	svc, err := svcmgr.Detect()
 	if err != nil {
-		return false, nil
+		return false, err
 	}
-	status := strings.TrimSpace(output)
-	return status == "enabled" || status == "enabled-runtime", nil
+	return svc.IsEnabled(postgresServiceName)
 }
 
 // postgresPortAccessible checks if PostgreSQL is listening on port 5432.
 func postgresPortAccessible() (bool, error) {
-	// Try ss first (more modern), fallback to netstat
-	output, err := exec.RunWithOutput("ss", "-tlnp")
+	listening, err := sockets.ListeningAs(postgresDefaultPort, "postgres")
 	if err != nil {
-		// Fallback to netstat
-		output, err = exec.RunWithOutput("netstat", "-tlnp")
-		if err != nil {
-			return false, fmt.Errorf("failed to check port accessibility: %w", err)
-		}
+		return false, fmt.Errorf("failed to check port accessibility: %w", err)
 	}
-
-	// Check if port 5432 is in the output
-	portStr := fmt.Sprintf(":%d", postgresDefaultPort)
-	if strings.Contains(output, portStr) {
-		return true, nil
-	}
-
-	return false, nil
+	return listening, nil
 }
 
 // runPsqlCommand runs a psql command with optional PGPASSWORD environment variable.
@@ -294,50 +295,54 @@ func (m *PostgresModule) Install(cfg *config.Config) error {
 	}
 
 	if !installed {
+		// The module relies on the Debian packaging of PostgreSQL (pg_ctlcluster,
+		// /etc/postgresql/<version>/main) and the PGDG apt repository
+		pm, err := pkgmgr.Detect()
+		if err != nil {
+			return err
+		}
+		if err := pkgmgr.Require(pm, "the postgres module", pkgmgr.NameApt); err != nil {
+			return err
+		}
+
+		// The PGDG repository is published per Debian/Ubuntu release
+		info, err := osinfo.Detect()
+		if err != nil {
+			return fmt.Errorf("failed to detect operating system: %w", err)
+		}
+		_, codename, err := info.AptDistro()
+		if err != nil {
+			return err
+		}
+
 		if dryRun {
-			log.Info("Would install PostgreSQL %s", version)
+			log.Info("Would install PostgreSQL %s from the PGDG repository (%s-pgdg)", version, codename)
 		} else {
 			log.Info("Installing PostgreSQL %s", version)
 
 			// Install prerequisites
 			log.Info("Installing prerequisites")
-			if err := exec.Run("apt-get", "update"); err != nil {
-				return fmt.Errorf("failed to update apt: %w", err)
+			if err := pm.Refresh(); err != nil {
+				return fmt.Errorf("failed to refresh package indexes: %w", err)
 			}
-			if err := exec.Run("apt-get", "install", "-y", "wget", "ca-certificates"); err != nil {
+			if err := pm.Install("curl", "ca-certificates"); err != nil {
 				return fmt.Errorf("failed to install prerequisites: %w", err)
 			}
 
-			// Download and add PostgreSQL GPG key
-			log.Info("Adding PostgreSQL GPG key")
-			cmd := fmt.Sprintf("wget --quiet -O - %s | gpg --dearmor -o %s", postgresGPGKeyURL, postgresGPGKeyringPath)
-			if err := exec.Run("bash", "-c", cmd); err != nil {
-				return fmt.Errorf("failed to add PostgreSQL GPG key: %w", err)
-			}
-
-			// Get distribution codename
-			codename, err := getDistributionCodename()
-			if err != nil {
-				return fmt.Errorf("failed to get distribution codename: %w", err)
-			}
-
-			// Add PostgreSQL repository
+			// Add PostgreSQL repository and signing key
 			log.Info("Adding PostgreSQL repository")
-			repoLine := fmt.Sprintf("deb [signed-by=%s] %s %s-pgdg main\n", postgresGPGKeyringPath, postgresRepoBaseURL, codename)
-			if err := exec.WriteFile(postgresAptSourcesPath, []byte(repoLine), 0644); err != nil {
+			if err := pm.AddRepository(postgresRepository(codename)); err != nil {
 				return fmt.Errorf("failed to add PostgreSQL repository: %w", err)
 			}
 
-			// Update apt package list
-			log.Info("Updating apt package list")
-			if err := exec.Run("apt-get", "update"); err != nil {
-				return fmt.Errorf("failed to update apt after adding PostgreSQL repository: %w", err)
+			log.Info("Refreshing package indexes")
+			if err := pm.Refresh(); err != nil {
+				return fmt.Errorf("failed to refresh package indexes after adding PostgreSQL repository: %w", err)
 			}
 
 			// Install PostgreSQL
 			log.Info("Installing PostgreSQL %s", version)
-			packageName := fmt.Sprintf("postgresql-%s", version)
-			if err := exec.Run("apt-get", "install", "-y", packageName); err != nil {
+			if err := pm.Install(fmt.Sprintf("postgresql-%s", version)); err != nil {
 				return fmt.Errorf("failed to install PostgreSQL: %w", err)
 			}
 
@@ -353,6 +358,11 @@ func (m *PostgresModule) Install(cfg *config.Config) error {
 		log.Skip("PostgreSQL is already installed")
 	}
 
+	svc, err := svcmgr.Detect()
+	if err != nil {
+		return err
+	}
+
 	// Configure service to start on boot
 	enabled, err := postgresServiceEnabled()
 	if err != nil {
@@ -364,7 +374,7 @@ func (m *PostgresModule) Install(cfg *config.Config) error {
 			log.Info("Would enable PostgreSQL service to start on boot")
 		} else {
 			log.Info("Enabling PostgreSQL service to start on boot")
-			if err := exec.Run("systemctl", "enable", postgresServiceName); err != nil {
+			if err := svc.Enable(postgresServiceName); err != nil {
 				return fmt.Errorf("failed to enable PostgreSQL service: %w", err)
 			}
 			log.Success("PostgreSQL service enabled")
@@ -384,17 +394,13 @@ func (m *PostgresModule) Install(cfg *config.Config) error {
 			log.Info("Would start PostgreSQL service")
 		} else {
 			log.Info("Starting PostgreSQL service")
-			if err := exec.Run("systemctl", "start", postgresServiceName); err != nil {
+			if err := svc.Start(postgresServiceName); err != nil {
 				return fmt.Errorf("failed to start PostgreSQL service: %w", err)
 			}
 
-			// Verify service is running
-			running, err := postgresServiceRunning()
-			if err != nil {
-				return fmt.Errorf("failed to verify PostgreSQL service status: %w", err)
-			}
-			if !running {
-				return fmt.Errorf("PostgreSQL service is not running after start")
+			// Wait for the service to become active
+			if err := svc.WaitActive(postgresServiceName, svcmgr.DefaultWaitTimeout); err != nil {
+				return fmt.Errorf("PostgreSQL service is not running after start: %w", err)
 			}
 
 			log.Success("PostgreSQL service started")
@@ -485,5 +491,7 @@ func (m *PostgresModule) Install(cfg *config.Config) error {
 	return nil
 }
 
-// Ensure PostgresModule implements the Module interface
+// Ensure PostgresModule implements the Module and Describer interfaces
 var _ module.Module = (*PostgresModule)(nil)
+var _ module.Describer = (*PostgresModule)(nil)
+var _ module.Bundler = (*PostgresModule)(nil)
@@ -8,8 +8,13 @@ import (
 
 	"github.com/stwalsh4118/phanes/internal/config"
 	"github.com/stwalsh4118/phanes/internal/exec"
+	"github.com/stwalsh4118/phanes/internal/export"
+	"github.com/stwalsh4118/phanes/internal/files"
 	"github.com/stwalsh4118/phanes/internal/log"
 	"github.com/stwalsh4118/phanes/internal/module"
+	"github.com/stwalsh4118/phanes/internal/pkgmgr"
+	"github.com/stwalsh4118/phanes/internal/sockets"
+	"github.com/stwalsh4118/phanes/internal/svcmgr"
 )
 
 const (
@@ -33,6 +38,32 @@ func (m *RedisModule) Description() string {
 	return "Installs and configures Redis in-memory data store"
 }
 
+// Metadata returns the configuration keys, files, services, ports, packages,
+// and URLs this module uses.
+func (m *RedisModule) Metadata() module.Metadata {
+	return module.Metadata{
+		ConfigKeys: []module.ConfigKey{
+			{Key: "redis.enabled", Description: "Whether to install Redis"},
+			{Key: "redis.password", Description: "Password required by Redis clients"},
+			{Key: "redis.bind_address", Description: "Address Redis listens on"},
+		},
+		Files:    []string{redisConfigPath},
+		Services: []string{redisServiceName},
+		Ports: []module.Port{
+			{Number: redisDefaultPort, Protocol: "tcp", Description: "Redis"},
+		},
+		Packages: []string{redisPackageName},
+	}
+}
+
+// Requirements returns the Redis package.
+func (m *RedisModule) Requirements(cfg *config.Config) (module.Requirements, error) {
+	if !cfg.Redis.Enabled {
+		return module.Requirements{}, nil
+	}
+	return module.Requirements{Packages: []string{redisPackageName}}, nil
+}
+
 // redisInstalled checks if Redis is installed by running redis-cli --version.
 func redisInstalled() (bool, error) {
 	err := exec.Run("redis-cli", "--version")
@@ -44,42 +75,29 @@ func redisInstalled() (bool, error) {
 
 // redisServiceRunning checks if Redis service is running.
 func redisServiceRunning() (bool, error) {
-	output, err := exec.RunWithOutput("systemctl", "is-active", redisServiceName)
+	svc, err := svcmgr.Detect()
 	if err != nil {
-		return false, nil
+		return false, err
 	}
-	return strings.TrimSpace(output) == "active", nil
+	return svc.IsActive(redisServiceName)
 }
 
 // redisServiceEnabled checks if Redis service is enabled.
 func redisServiceEnabled() (bool, error) {
-	output, err := exec.RunWithOutput("systemctl", "is-enabled", redisServiceName)
+	svc, err := svcmgr.Detect()
 	if err != nil {
-		return false, nil
+		return false, err
 	}
-	status := strings.TrimSpace(output)
-	return status == "enabled" || status == "enabled-runtime", nil
+	return svc.IsEnabled(redisServiceName)
 }
 
 // redisPortAccessible checks if Redis is listening on port 6379.
 func redisPortAccessible() (bool, error) {
-	// Try ss first (more modern), fallback to netstat
-	output, err := exec.RunWithOutput("ss", "-tlnp")
+	listening, err := sockets.ListeningAs(redisDefaultPort, "redis-server")
 	if err != nil {
-		// Fallback to netstat
-		output, err = exec.RunWithOutput("netstat", "-tlnp")
-		if err != nil {
-			return false, fmt.Errorf("failed to check port accessibility: %w", err)
-		}
+		return false, fmt.Errorf("failed to check port accessibility: %w", err)
 	}
-
-	// Check if port 6379 is in the output
-	portStr := fmt.Sprintf(":%d", redisDefaultPort)
-	if strings.Contains(output, portStr) {
-		return true, nil
-	}
-
-	return false, nil
+	return listening, nil
 }
 
 // redisRespondsToPing checks if Redis responds to ping command.
@@ -111,7 +129,7 @@ func getRedisConfigValue(key string) (string, bool, error) {
 		return "", false, nil
 	}
 
-	file, err := os.Open(redisConfigPath)
+	file, err := os.Open(exec.Path(redisConfigPath))
 	if err != nil {
 		return "", false, fmt.Errorf("failed to open Redis config file: %w", err)
 	}
@@ -153,7 +171,7 @@ func updateRedisConfig(key, value string) error {
 	}
 
 	// Read current config
-	file, err := os.Open(redisConfigPath)
+	file, err := os.Open(exec.Path(redisConfigPath))
 	if err != nil {
 		return fmt.Errorf("failed to open Redis config file: %w", err)
 	}
@@ -197,7 +215,7 @@ func updateRedisConfig(key, value string) error {
 
 	// Write updated config back
 	content := strings.Join(lines, "\n") + "\n"
-	if err := exec.WriteFile(redisConfigPath, []byte(content), 0644); err != nil {
+	if _, err := files.Write(redisConfigPath, []byte(content), files.Options{}); err != nil {
 		return fmt.Errorf("failed to write Redis config file: %w", err)
 	}
 
@@ -217,23 +235,18 @@ func configureRedisPassword(password string) error {
 
 // reloadRedisConfig reloads or restarts Redis to apply config changes.
 func reloadRedisConfig() error {
-	// Try reload first
-	err := exec.Run("systemctl", "reload", redisServiceName)
+	svc, err := svcmgr.Detect()
 	if err != nil {
-		// If reload fails, restart
-		log.Info("Reload failed, restarting Redis service")
-		if err := exec.Run("systemctl", "restart", redisServiceName); err != nil {
-			return fmt.Errorf("failed to restart Redis service: %w", err)
-		}
+		return err
 	}
 
-	// Verify service is still running
-	running, err := redisServiceRunning()
-	if err != nil {
-		return fmt.Errorf("failed to verify Redis service status: %w", err)
+	if err := svc.ReloadOrRestart(redisServiceName); err != nil {
+		return fmt.Errorf("failed to reload Redis service: %w", err)
 	}
-	if !running {
-		return fmt.Errorf("Redis service is not running after reload/restart")
+
+	// Verify service is still running
+	if err := svc.WaitActive(redisServiceName, svcmgr.DefaultWaitTimeout); err != nil {
+		return fmt.Errorf("Redis service is not running after reload/restart: %w", err)
 	}
 
 	return nil
@@ -312,20 +325,25 @@ func (m *RedisModule) Install(cfg *config.Config) error {
 	}
 
 	if !installed {
+		pm, err := pkgmgr.Detect()
+		if err != nil {
+			return err
+		}
+
 		if dryRun {
 			log.Info("Would install Redis")
 		} else {
 			log.Info("Installing Redis")
 
-			// Update apt package list
-			log.Info("Updating apt package list")
-			if err := exec.Run("apt-get", "update"); err != nil {
-				return fmt.Errorf("failed to update apt: %w", err)
+			// Refresh package indexes
+			log.Info("Refreshing package indexes")
+			if err := pm.Refresh(); err != nil {
+				return fmt.Errorf("failed to refresh package indexes: %w", err)
 			}
 
 			// Install Redis
 			log.Info("Installing Redis package")
-			if err := exec.Run("apt-get", "install", "-y", redisPackageName); err != nil {
+			if err := pm.Install(redisPackageName); err != nil {
 				return fmt.Errorf("failed to install Redis: %w", err)
 			}
 
@@ -412,6 +430,11 @@ func (m *RedisModule) Install(cfg *config.Config) error {
 		log.Success("Redis configuration reloaded")
 	}
 
+	svc, err := svcmgr.Detect()
+	if err != nil {
+		return err
+	}
+
 	// Configure service to start on boot
 	enabled, err := redisServiceEnabled()
 	if err != nil {
@@ -423,7 +446,7 @@ func (m *RedisModule) Install(cfg *config.Config) error {
 			log.Info("Would enable Redis service to start on boot")
 		} else {
 			log.Info("Enabling Redis service to start on boot")
-			if err := exec.Run("systemctl", "enable", redisServiceName); err != nil {
+			if err := svc.Enable(redisServiceName); err != nil {
 				return fmt.Errorf("failed to enable Redis service: %w", err)
 			}
 			log.Success("Redis service enabled")
@@ -443,17 +466,13 @@ func (m *RedisModule) Install(cfg *config.Config) error {
 			log.Info("Would start Redis service")
 		} else {
 			log.Info("Starting Redis service")
-			if err := exec.Run("systemctl", "start", redisServiceName); err != nil {
+			if err := svc.Start(redisServiceName); err != nil {
 				return fmt.Errorf("failed to start Redis service: %w", err)
 			}
 
-			// Verify service is running
-			running, err := redisServiceRunning()
-			if err != nil {
-				return fmt.Errorf("failed to verify Redis service status: %w", err)
-			}
-			if !running {
-				return fmt.Errorf("Redis service is not running after start")
+			// Wait for the service to become active
+			if err := svc.WaitActive(redisServiceName, svcmgr.DefaultWaitTimeout); err != nil {
+				return fmt.Errorf("Redis service is not running after start: %w", err)
 			}
 
 			log.Success("Redis service started")
@@ -505,6 +524,42 @@ func (m *RedisModule) Install(cfg *config.Config) error {
 	return nil
 }
 
-// Ensure RedisModule implements the Module interface
+// Export adds the Redis installation and configuration steps to s. The
+// password is read from REDIS_PASSWORD when the script runs.
+func (m *RedisModule) Export(cfg *config.Config, s *export.Script) error {
+	if !cfg.Redis.Enabled {
+		s.Comment("Redis module is disabled in configuration")
+		return nil
+	}
+
+	bindAddress := cfg.Redis.BindAddress
+	if bindAddress == "" {
+		bindAddress = defaultBindAddress
+	}
+	password := ""
+	if cfg.Redis.Password != "" {
+		password = s.Secret("REDIS_PASSWORD", "redis.password")
+	} else if isBindingToAllInterfaces(bindAddress) {
+		s.Comment("Warning: Redis binds to all interfaces without a password. This is insecure.")
+	}
+
+	s.If("! command -v redis-cli >/dev/null 2>&1", func() {
+		s.RefreshPackages()
+		s.InstallPackages(redisPackageName)
+	})
+
+	s.SetDirective(redisConfigPath, "bind", bindAddress)
+	s.SetDirective(redisConfigPath, "requirepass", password)
+	s.EnableService(redisServiceName)
+	s.IfChanged(func() {
+		s.ReloadService(redisServiceName)
+	})
+	return nil
+}
+
+// Ensure RedisModule implements the Module, Describer, Bundler, and Exporter interfaces
 var _ module.Module = (*RedisModule)(nil)
+var _ module.Describer = (*RedisModule)(nil)
+var _ module.Bundler = (*RedisModule)(nil)
+var _ module.Exporter = (*RedisModule)(nil)
 
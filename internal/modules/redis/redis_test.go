@@ -176,3 +176,20 @@ func TestIsInstalled(t *testing.T) {
 	_ = err
 }
 
+
+func TestRedisModule_Requirements(t *testing.T) {
+	mod := &RedisModule{}
+	cfg := config.DefaultConfig()
+
+	cfg.Redis.Enabled = false
+	reqs, err := mod.Requirements(cfg)
+	if err != nil || len(reqs.Packages) != 0 {
+		t.Errorf("Requirements() when disabled = %+v, %v, want none", reqs, err)
+	}
+
+	cfg.Redis.Enabled = true
+	reqs, err = mod.Requirements(cfg)
+	if err != nil || len(reqs.Packages) != 1 || reqs.Packages[0] != redisPackageName {
+		t.Errorf("Requirements() = %+v, %v, want the %s package", reqs, err, redisPackageName)
+	}
+}
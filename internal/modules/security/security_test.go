@@ -199,6 +199,135 @@ func TestNormalizeSSHConfig(t *testing.T) {
 	}
 }
 
+func TestParseSSHSettings(t *testing.T) {
+	tests := []struct {
+		name             string
+		input            string
+		wantPort         int
+		wantPasswordAuth bool
+	}{
+		{
+			name:             "empty config uses OpenSSH defaults",
+			input:            "",
+			wantPort:         22,
+			wantPasswordAuth: true,
+		},
+		{
+			name:             "custom port and password auth disabled",
+			input:            "Port 2222\nPasswordAuthentication no\n",
+			wantPort:         2222,
+			wantPasswordAuth: false,
+		},
+		{
+			name:             "commented directives are ignored",
+			input:            "#Port 2222\n#PasswordAuthentication no\n",
+			wantPort:         22,
+			wantPasswordAuth: true,
+		},
+		{
+			name:             "directives are case-insensitive",
+			input:            "port 2200\npasswordauthentication yes\n",
+			wantPort:         2200,
+			wantPasswordAuth: true,
+		},
+		{
+			name:             "first value wins",
+			input:            "PasswordAuthentication no\nPort 2222\nPasswordAuthentication yes\nPort 22\n",
+			wantPort:         2222,
+			wantPasswordAuth: false,
+		},
+		{
+			name:             "match blocks are ignored",
+			input:            "Port 2222\nMatch User deploy\n  PasswordAuthentication no\n",
+			wantPort:         2222,
+			wantPasswordAuth: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port, passwordAuth := parseSSHSettings(tt.input)
+			if port != tt.wantPort {
+				t.Errorf("parseSSHSettings() port = %d, want %d", port, tt.wantPort)
+			}
+			if passwordAuth != tt.wantPasswordAuth {
+				t.Errorf("parseSSHSettings() passwordAuth = %v, want %v", passwordAuth, tt.wantPasswordAuth)
+			}
+		})
+	}
+}
+
+func TestParseSSHSettings_Include(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"50-cloud-init.conf": "PasswordAuthentication no\n",
+		"60-custom.conf":     "PasswordAuthentication yes\nPort 2200\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Included files are read in order where the Include appears
+	port, passwordAuth := parseSSHSettings("Include " + dir + "/*.conf\nPort 2222\nPasswordAuthentication yes\n")
+	if port != 2200 || passwordAuth {
+		t.Errorf("parseSSHSettings() = %d, %v, want 2200, false", port, passwordAuth)
+	}
+}
+
+func TestSecurityModule_Risks(t *testing.T) {
+	// Probe an empty tree instead of the host: the SSH config is written
+	// per case, and ufw is missing, so the firewall counts as disabled
+	root := t.TempDir()
+	if err := exec.SetRoot(root); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { exec.SetRoot("") })
+	sshdConfig := filepath.Join(root, "etc/ssh/sshd_config")
+	if err := os.MkdirAll(filepath.Dir(sshdConfig), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Security.SSHPort = 2222
+
+	tests := []struct {
+		name       string
+		sshdConfig string
+		want       []string
+	}{
+		{
+			name:       "defaults",
+			sshdConfig: "",
+			want:       []string{"Change SSH port from 22 to 2222", "Disable SSH password authentication", "Enable UFW firewall allowing only 2222/tcp, 80/tcp and 443/tcp"},
+		},
+		{
+			name:       "already hardened",
+			sshdConfig: "Port 2222\nPasswordAuthentication no\n",
+			want:       []string{"Enable UFW firewall allowing only 2222/tcp, 80/tcp and 443/tcp"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(sshdConfig, []byte(tt.sshdConfig), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			risks, err := (&SecurityModule{}).Risks(cfg)
+			if err != nil {
+				t.Fatalf("Risks() error = %v", err)
+			}
+			var got []string
+			for _, risk := range risks {
+				got = append(got, risk.Action)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Risks() actions = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestSecurityModule_IsInstalled(t *testing.T) {
 	mod := &SecurityModule{}
 
@@ -2,6 +2,7 @@ package swap
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
@@ -9,8 +10,11 @@ import (
 
 	"github.com/stwalsh4118/phanes/internal/config"
 	"github.com/stwalsh4118/phanes/internal/exec"
+	"github.com/stwalsh4118/phanes/internal/export"
+	"github.com/stwalsh4118/phanes/internal/files"
 	"github.com/stwalsh4118/phanes/internal/log"
 	"github.com/stwalsh4118/phanes/internal/module"
+	"github.com/stwalsh4118/phanes/internal/timing"
 )
 
 const (
@@ -33,6 +37,22 @@ func (m *SwapModule) Description() string {
 	return "Creates and configures swap file"
 }
 
+// Metadata returns the configuration keys, files, services, ports, packages,
+// and URLs this module uses.
+func (m *SwapModule) Metadata() module.Metadata {
+	return module.Metadata{
+		ConfigKeys: []module.ConfigKey{
+			{Key: "swap.enabled", Description: "Whether to create a swap file"},
+			{Key: "swap.size", Description: "Size of the swap file (e.g., 2G)"},
+		},
+		Files: []string{
+			defaultSwapFilePath,
+			fstabPath,
+			swappinessConfigPath,
+		},
+	}
+}
+
 // parseSwapSize parses a size string (e.g., "2G", "512M", "1T") and returns the size in bytes.
 // Supports formats: G/g (gigabytes), M/m (megabytes), T/t (terabytes).
 // Returns an error if the format is invalid.
@@ -113,7 +133,7 @@ func swapIsActive() (bool, error) {
 
 	// Fallback: read /proc/swaps
 	if exec.FileExists("/proc/swaps") {
-		content, err := os.ReadFile("/proc/swaps")
+		content, err := os.ReadFile(exec.Path("/proc/swaps"))
 		if err != nil {
 			return false, fmt.Errorf("failed to read /proc/swaps: %w", err)
 		}
@@ -138,7 +158,7 @@ func fstabContainsSwap(swapPath string) (bool, error) {
 		return false, nil
 	}
 
-	file, err := os.Open(fstabPath)
+	file, err := os.Open(exec.Path(fstabPath))
 	if err != nil {
 		return false, fmt.Errorf("failed to open %s: %w", fstabPath, err)
 	}
@@ -175,7 +195,7 @@ func fstabContainsSwap(swapPath string) (bool, error) {
 func getSwappiness() (int, error) {
 	// Try reading from /proc/sys/vm/swappiness first (most reliable)
 	if exec.FileExists("/proc/sys/vm/swappiness") {
-		content, err := os.ReadFile("/proc/sys/vm/swappiness")
+		content, err := os.ReadFile(exec.Path("/proc/sys/vm/swappiness"))
 		if err != nil {
 			return 0, fmt.Errorf("failed to read swappiness: %w", err)
 		}
@@ -284,7 +304,9 @@ func (m *SwapModule) Install(cfg *config.Config) error {
 
 			// Try fallocate first (faster and more efficient)
 			if exec.CommandExists("fallocate") {
-				if err := exec.Run("fallocate", "-l", fmt.Sprintf("%d", sizeBytes), defaultSwapFilePath); err != nil {
+				if err := timing.Track("allocate swap file", func() error {
+					return exec.Run("fallocate", "-l", fmt.Sprintf("%d", sizeBytes), defaultSwapFilePath)
+				}); err != nil {
 					// Fallback to dd if fallocate fails
 					log.Info("fallocate failed, using dd as fallback")
 					// Calculate size in MB for dd
@@ -292,7 +314,9 @@ func (m *SwapModule) Install(cfg *config.Config) error {
 					if sizeMB == 0 {
 						sizeMB = 1 // At least 1MB
 					}
-					if err := exec.Run("dd", "if=/dev/zero", fmt.Sprintf("of=%s", defaultSwapFilePath), "bs=1M", fmt.Sprintf("count=%d", sizeMB)); err != nil {
+					if err := timing.Track("allocate swap file (dd)", func() error {
+						return exec.Run("dd", "if=/dev/zero", fmt.Sprintf("of=%s", defaultSwapFilePath), "bs=1M", fmt.Sprintf("count=%d", sizeMB))
+					}); err != nil {
 						return fmt.Errorf("failed to create swap file: %w", err)
 					}
 				}
@@ -302,7 +326,9 @@ func (m *SwapModule) Install(cfg *config.Config) error {
 				if sizeMB == 0 {
 					sizeMB = 1
 				}
-				if err := exec.Run("dd", "if=/dev/zero", fmt.Sprintf("of=%s", defaultSwapFilePath), "bs=1M", fmt.Sprintf("count=%d", sizeMB)); err != nil {
+				if err := timing.Track("allocate swap file (dd)", func() error {
+					return exec.Run("dd", "if=/dev/zero", fmt.Sprintf("of=%s", defaultSwapFilePath), "bs=1M", fmt.Sprintf("count=%d", sizeMB))
+				}); err != nil {
 					return fmt.Errorf("failed to create swap file: %w", err)
 				}
 			}
@@ -321,7 +347,8 @@ func (m *SwapModule) Install(cfg *config.Config) error {
 
 			// Enable swap
 			log.Info("Enabling swap")
-			if err := exec.Run("swapon", defaultSwapFilePath); err != nil {
+			// Under an alternate root the swap file is only activated on boot
+			if err := exec.Run("swapon", defaultSwapFilePath); err != nil && !errors.Is(err, exec.ErrSkipped) {
 				return fmt.Errorf("failed to enable swap: %w", err)
 			}
 
@@ -346,7 +373,7 @@ func (m *SwapModule) Install(cfg *config.Config) error {
 			// Read existing fstab
 			var existingContent []byte
 			if exec.FileExists(fstabPath) {
-				existingContent, err = os.ReadFile(fstabPath)
+				existingContent, err = os.ReadFile(exec.Path(fstabPath))
 				if err != nil {
 					return fmt.Errorf("failed to read %s: %w", fstabPath, err)
 				}
@@ -361,7 +388,7 @@ func (m *SwapModule) Install(cfg *config.Config) error {
 			newContent += swapEntry
 
 			// Write back to fstab
-			if err := exec.WriteFile(fstabPath, []byte(newContent), 0644); err != nil {
+			if _, err := files.Write(fstabPath, []byte(newContent), files.Options{}); err != nil {
 				return fmt.Errorf("failed to write %s: %w", fstabPath, err)
 			}
 
@@ -384,13 +411,13 @@ func (m *SwapModule) Install(cfg *config.Config) error {
 			log.Info("Setting swappiness to %d", defaultSwappiness)
 
 			// Set runtime value
-			if err := exec.Run("sysctl", fmt.Sprintf("vm.swappiness=%d", defaultSwappiness)); err != nil {
+			if err := exec.Run("sysctl", fmt.Sprintf("vm.swappiness=%d", defaultSwappiness)); err != nil && !errors.Is(err, exec.ErrSkipped) {
 				return fmt.Errorf("failed to set swappiness: %w", err)
 			}
 
 			// Make persistent
 			swappinessConfig := fmt.Sprintf("vm.swappiness=%d\n", defaultSwappiness)
-			if err := exec.WriteFile(swappinessConfigPath, []byte(swappinessConfig), 0644); err != nil {
+			if _, err := files.Write(swappinessConfigPath, []byte(swappinessConfig), files.Options{Mode: 0644}); err != nil {
 				return fmt.Errorf("failed to write swappiness config: %w", err)
 			}
 
@@ -407,5 +434,48 @@ func (m *SwapModule) Install(cfg *config.Config) error {
 	return nil
 }
 
-// Ensure SwapModule implements the Module interface
+// Export adds the swap file, fstab, and swappiness steps to s.
+func (m *SwapModule) Export(cfg *config.Config, s *export.Script) error {
+	if !cfg.Swap.Enabled {
+		s.Comment("Swap is disabled in configuration")
+		return nil
+	}
+
+	swapSize := cfg.Swap.Size
+	if swapSize == "" {
+		swapSize = "2G" // Use default
+	}
+	sizeBytes, err := parseSwapSize(swapSize)
+	if err != nil {
+		return fmt.Errorf("failed to parse swap size: %w", err)
+	}
+	sizeMB := sizeBytes / (1024 * 1024)
+	if sizeMB == 0 {
+		sizeMB = 1 // At least 1MB
+	}
+
+	s.If(fmt.Sprintf(`[ ! -e %s ] || [ -z "$(swapon --show 2>/dev/null)" ]`, defaultSwapFilePath), func() {
+		s.Log("Creating swap file of size %s", swapSize)
+		s.Shell(fmt.Sprintf("fallocate -l %d %s || dd if=/dev/zero of=%s bs=1M count=%d",
+			sizeBytes, defaultSwapFilePath, defaultSwapFilePath, sizeMB))
+		s.Run("chmod", "600", defaultSwapFilePath)
+		s.Run("mkswap", defaultSwapFilePath)
+		s.Run("swapon", defaultSwapFilePath)
+	})
+
+	s.If(fmt.Sprintf(`! awk '$1 == "%s" && $3 == "swap" { found = 1 } END { exit !found }' %s 2>/dev/null`,
+		defaultSwapFilePath, fstabPath), func() {
+		s.EnsureLine(fstabPath, fmt.Sprintf("%s none swap sw 0 0", defaultSwapFilePath))
+	})
+
+	s.If(fmt.Sprintf(`[ "$(sysctl -n vm.swappiness)" -ne %d ]`, defaultSwappiness), func() {
+		s.Run("sysctl", fmt.Sprintf("vm.swappiness=%d", defaultSwappiness))
+		s.WriteFile(swappinessConfigPath, fmt.Sprintf("vm.swappiness=%d\n", defaultSwappiness), export.FileOptions{Mode: 0644})
+	})
+	return nil
+}
+
+// Ensure SwapModule implements the Module, Describer, and Exporter interfaces
 var _ module.Module = (*SwapModule)(nil)
+var _ module.Describer = (*SwapModule)(nil)
+var _ module.Exporter = (*SwapModule)(nil)
@@ -5,14 +5,20 @@ import (
 	"strings"
 
 	"github.com/stwalsh4118/phanes/internal/config"
+	"github.com/stwalsh4118/phanes/internal/download"
 	"github.com/stwalsh4118/phanes/internal/exec"
 	"github.com/stwalsh4118/phanes/internal/log"
 	"github.com/stwalsh4118/phanes/internal/module"
+	"github.com/stwalsh4118/phanes/internal/timing"
+	"github.com/stwalsh4118/phanes/internal/svcmgr"
 )
 
 const (
 	tailscaleInstallScript = "https://tailscale.com/install.sh"
 	tailscaleServiceName    = "tailscaled"
+
+	// tailscaleInstallArtifact names the install script in downloads.checksums.
+	tailscaleInstallArtifact = "tailscale-install"
 )
 
 // TailscaleModule implements the Module interface for Tailscale VPN installation.
@@ -28,6 +34,35 @@ func (m *TailscaleModule) Description() string {
 	return "Installs and configures Tailscale VPN"
 }
 
+// Metadata returns the configuration keys, files, services, ports, packages,
+// and URLs this module uses.
+func (m *TailscaleModule) Metadata() module.Metadata {
+	return module.Metadata{
+		ConfigKeys: []module.ConfigKey{
+			{Key: "tailscale.enabled", Description: "Whether to install Tailscale"},
+			{Key: "tailscale.auth_key", Description: "Auth key used to join the tailnet"},
+			{Key: "tailscale.skip_auth", Description: "Install without authenticating (run tailscale up manually)"},
+		},
+		Services: []string{tailscaleServiceName},
+		Ports: []module.Port{
+			{Number: 41641, Protocol: "udp", Description: "WireGuard traffic between tailnet nodes"},
+		},
+		Packages: []string{"tailscale"},
+		URLs:     []string{tailscaleInstallScript},
+	}
+}
+
+// Requirements returns the Tailscale install script.
+func (m *TailscaleModule) Requirements(cfg *config.Config) (module.Requirements, error) {
+	if !cfg.Tailscale.Enabled {
+		return module.Requirements{}, nil
+	}
+	return module.Requirements{
+		Artifacts:    []download.Artifact{{Name: tailscaleInstallArtifact, URL: tailscaleInstallScript, Script: true}},
+		NeedsNetwork: "the Tailscale install script installs Tailscale from its package repository, and joining a tailnet needs network access",
+	}, nil
+}
+
 // tailscaleInstalled checks if Tailscale is installed by checking if the tailscale command exists.
 func tailscaleInstalled() (bool, error) {
 	return exec.CommandExists("tailscale"), nil
@@ -43,13 +78,13 @@ func tailscaleConnected() (bool, error) {
 	return true, nil
 }
 
-// tailscaleServiceEnabled checks if the tailscaled systemd service is enabled.
+// tailscaleServiceEnabled checks if the tailscaled service is enabled.
 func tailscaleServiceEnabled() (bool, error) {
-	output, err := exec.RunWithOutput("systemctl", "is-enabled", tailscaleServiceName)
+	svc, err := svcmgr.Detect()
 	if err != nil {
-		return false, nil
+		return false, err
 	}
-	return strings.TrimSpace(output) == "enabled", nil
+	return svc.IsEnabled(tailscaleServiceName)
 }
 
 // IsInstalled checks if Tailscale is already installed, authenticated, and the service is enabled.
@@ -112,7 +147,7 @@ func (m *TailscaleModule) Install(cfg *config.Config) error {
 
 	if dryRun {
 		log.Info("Would install Tailscale using official install script")
-		log.Info("Would run: curl -fsSL %s | sh", tailscaleInstallScript)
+		log.Info("Would download and verify %s, then run it with sh", tailscaleInstallScript)
 		if cfg.Tailscale.SkipAuth {
 			log.Info("Would skip authentication (manual login enabled)")
 			log.Info("Would enable and start tailscaled systemd service")
@@ -127,8 +162,17 @@ func (m *TailscaleModule) Install(cfg *config.Config) error {
 
 	// Install Tailscale using official install script
 	log.Info("Installing Tailscale using official install script")
-	installCmd := fmt.Sprintf("curl -fsSL %s | sh", tailscaleInstallScript)
-	if err := exec.Run("sh", "-c", installCmd); err != nil {
+	script, err := download.Fetch(download.Artifact{
+		Name:   tailscaleInstallArtifact,
+		URL:    tailscaleInstallScript,
+		Script: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to download Tailscale install script: %w", err)
+	}
+	if err := timing.Track("run Tailscale install script", func() error {
+		return exec.Run("sh", script)
+	}); err != nil {
 		return fmt.Errorf("failed to install Tailscale: %w", err)
 	}
 
@@ -146,9 +190,16 @@ func (m *TailscaleModule) Install(cfg *config.Config) error {
 
 	// Enable and start tailscaled service
 	log.Info("Enabling and starting tailscaled service")
-	if err := exec.Run("systemctl", "enable", "--now", tailscaleServiceName); err != nil {
+	svc, err := svcmgr.Detect()
+	if err != nil {
+		return err
+	}
+	if err := svc.Enable(tailscaleServiceName); err != nil {
 		return fmt.Errorf("failed to enable Tailscale service: %w", err)
 	}
+	if err := svc.Start(tailscaleServiceName); err != nil {
+		return fmt.Errorf("failed to start Tailscale service: %w", err)
+	}
 
 	// Display Tailscale status (only if authenticated)
 	if !cfg.Tailscale.SkipAuth {
@@ -180,6 +231,8 @@ func (m *TailscaleModule) Install(cfg *config.Config) error {
 	return nil
 }
 
-// Ensure TailscaleModule implements the Module interface
+// Ensure TailscaleModule implements the Module and Describer interfaces
 var _ module.Module = (*TailscaleModule)(nil)
+var _ module.Describer = (*TailscaleModule)(nil)
+var _ module.Bundler = (*TailscaleModule)(nil)
 
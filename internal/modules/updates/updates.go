@@ -7,8 +7,11 @@ import (
 
 	"github.com/stwalsh4118/phanes/internal/config"
 	"github.com/stwalsh4118/phanes/internal/exec"
+	"github.com/stwalsh4118/phanes/internal/export"
+	"github.com/stwalsh4118/phanes/internal/files"
 	"github.com/stwalsh4118/phanes/internal/log"
 	"github.com/stwalsh4118/phanes/internal/module"
+	"github.com/stwalsh4118/phanes/internal/pkgmgr"
 )
 
 const (
@@ -29,16 +32,32 @@ func (m *UpdatesModule) Description() string {
 	return "Configures automatic security updates"
 }
 
+// Metadata returns the configuration keys, files, services, ports, packages,
+// and URLs this module uses.
+func (m *UpdatesModule) Metadata() module.Metadata {
+	return module.Metadata{
+		Files: []string{
+			unattendedUpgradesConfigPath,
+			autoUpgradesConfigPath,
+		},
+		Packages: []string{"unattended-upgrades"},
+	}
+}
+
+// Requirements returns the unattended-upgrades package.
+func (m *UpdatesModule) Requirements(cfg *config.Config) (module.Requirements, error) {
+	return module.Requirements{Packages: []string{"unattended-upgrades"}}, nil
+}
+
 // unattendedUpgradesInstalled checks if the unattended-upgrades package is installed.
 func unattendedUpgradesInstalled() (bool, error) {
-	// Try dpkg -l first (most reliable for Debian/Ubuntu)
-	if exec.CommandExists("dpkg") {
-		output, err := exec.RunWithOutput("dpkg", "-l", "unattended-upgrades")
-		if err == nil {
-			// dpkg -l returns 0 even if package is not installed, but output will indicate status
-			// Look for "ii" which means installed and configured
-			return strings.Contains(output, "unattended-upgrades") && strings.Contains(output, "ii"), nil
+	// Ask the package manager first (most reliable for Debian/Ubuntu)
+	if pm, err := pkgmgr.Detect(); err == nil && pm.Name() == pkgmgr.NameApt {
+		installed, _, err := pm.IsInstalled("unattended-upgrades")
+		if err != nil {
+			return false, fmt.Errorf("failed to query unattended-upgrades package: %w", err)
 		}
+		return installed, nil
 	}
 
 	// Fallback: check if command exists in PATH
@@ -156,7 +175,7 @@ func configFileMatches(filePath string, expectedContent string) (bool, error) {
 	}
 
 	// Read current config
-	currentContent, err := os.ReadFile(filePath)
+	currentContent, err := os.ReadFile(exec.Path(filePath))
 	if err != nil {
 		return false, fmt.Errorf("failed to read %s: %w", filePath, err)
 	}
@@ -250,6 +269,15 @@ func (m *UpdatesModule) IsInstalled() (bool, error) {
 func (m *UpdatesModule) Install(cfg *config.Config) error {
 	dryRun := log.IsDryRun()
 
+	// unattended-upgrades and its configuration are specific to apt
+	pm, err := pkgmgr.Detect()
+	if err != nil {
+		return err
+	}
+	if err := pkgmgr.Require(pm, "the updates module", pkgmgr.NameApt); err != nil {
+		return err
+	}
+
 	// Install unattended-upgrades package
 	installed, err := unattendedUpgradesInstalled()
 	if err != nil {
@@ -261,7 +289,7 @@ func (m *UpdatesModule) Install(cfg *config.Config) error {
 			log.Info("Would install unattended-upgrades package")
 		} else {
 			log.Info("Installing unattended-upgrades package")
-			if err := exec.Run("apt-get", "install", "-y", "unattended-upgrades"); err != nil {
+			if err := pm.Install("unattended-upgrades"); err != nil {
 				return fmt.Errorf("failed to install unattended-upgrades: %w", err)
 			}
 			log.Success("unattended-upgrades package installed")
@@ -279,10 +307,12 @@ func (m *UpdatesModule) Install(cfg *config.Config) error {
 
 	if !matches50 {
 		if dryRun {
-			log.Info("Would configure %s", unattendedUpgradesConfigPath)
+			if _, err := files.Preview(unattendedUpgradesConfigPath, []byte(config50)); err != nil {
+				return fmt.Errorf("failed to preview %s: %w", unattendedUpgradesConfigPath, err)
+			}
 		} else {
 			log.Info("Configuring %s", unattendedUpgradesConfigPath)
-			if err := exec.WriteFile(unattendedUpgradesConfigPath, []byte(config50), 0644); err != nil {
+			if _, err := files.Write(unattendedUpgradesConfigPath, []byte(config50), files.Options{Mode: 0644}); err != nil {
 				return fmt.Errorf("failed to write %s: %w", unattendedUpgradesConfigPath, err)
 			}
 			log.Success("Configured %s", unattendedUpgradesConfigPath)
@@ -300,10 +330,12 @@ func (m *UpdatesModule) Install(cfg *config.Config) error {
 
 	if !matches20 {
 		if dryRun {
-			log.Info("Would configure %s", autoUpgradesConfigPath)
+			if _, err := files.Preview(autoUpgradesConfigPath, []byte(config20)); err != nil {
+				return fmt.Errorf("failed to preview %s: %w", autoUpgradesConfigPath, err)
+			}
 		} else {
 			log.Info("Configuring %s", autoUpgradesConfigPath)
-			if err := exec.WriteFile(autoUpgradesConfigPath, []byte(config20), 0644); err != nil {
+			if _, err := files.Write(autoUpgradesConfigPath, []byte(config20), files.Options{Mode: 0644}); err != nil {
 				return fmt.Errorf("failed to write %s: %w", autoUpgradesConfigPath, err)
 			}
 			log.Success("Configured %s", autoUpgradesConfigPath)
@@ -331,5 +363,19 @@ func (m *UpdatesModule) Install(cfg *config.Config) error {
 	return nil
 }
 
-// Ensure UpdatesModule implements the Module interface
+// Export adds the unattended-upgrades steps to s.
+func (m *UpdatesModule) Export(cfg *config.Config, s *export.Script) error {
+	s.InstallPackages("unattended-upgrades")
+	s.WriteFile(unattendedUpgradesConfigPath, generate50UnattendedUpgrades(), export.FileOptions{Mode: 0644})
+	s.WriteFile(autoUpgradesConfigPath, generate20AutoUpgrades(), export.FileOptions{Mode: 0644})
+
+	// Verification errors are expected on some systems and only reported
+	s.Shell("unattended-upgrades --dry-run --debug >/dev/null || log 'unattended-upgrades verification returned an error (this may be expected)'")
+	return nil
+}
+
+// Ensure UpdatesModule implements the Module, Describer, Bundler, and Exporter interfaces
 var _ module.Module = (*UpdatesModule)(nil)
+var _ module.Describer = (*UpdatesModule)(nil)
+var _ module.Bundler = (*UpdatesModule)(nil)
+var _ module.Exporter = (*UpdatesModule)(nil)
@@ -10,6 +10,8 @@ import (
 
 	"github.com/stwalsh4118/phanes/internal/config"
 	"github.com/stwalsh4118/phanes/internal/exec"
+	"github.com/stwalsh4118/phanes/internal/export"
+	"github.com/stwalsh4118/phanes/internal/files"
 	"github.com/stwalsh4118/phanes/internal/log"
 	"github.com/stwalsh4118/phanes/internal/module"
 )
@@ -34,6 +36,21 @@ func (m *UserModule) Description() string {
 	return "Creates user and sets up SSH keys"
 }
 
+// Metadata returns the configuration keys, files, services, ports, packages,
+// and URLs this module uses.
+func (m *UserModule) Metadata() module.Metadata {
+	return module.Metadata{
+		ConfigKeys: []module.ConfigKey{
+			{Key: "user.username", Description: "Linux user to create with passwordless sudo"},
+			{Key: "user.ssh_public_key", Description: "Public key added to the user's authorized_keys"},
+		},
+		Files: []string{
+			"/home/{user.username}/.ssh/authorized_keys",
+			"/etc/sudoers.d/{user.username}",
+		},
+	}
+}
+
 // validateSSHKey checks if the SSH public key has a valid format.
 // Valid formats include: ssh-rsa, ssh-ed25519, ecdsa-sha2-*, ssh-dss
 func validateSSHKey(key string) error {
@@ -60,7 +77,7 @@ func validateSSHKey(key string) error {
 
 // userExists checks if a user exists on the system.
 func userExists(username string) (bool, error) {
-	_, err := user.Lookup(username)
+	_, err := exec.LookupUser(username)
 	if err != nil {
 		if _, ok := err.(user.UnknownUserError); ok {
 			return false, nil
@@ -76,7 +93,7 @@ func sshKeyExists(authorizedKeysPath string, key string) (bool, error) {
 		return false, nil
 	}
 
-	content, err := os.ReadFile(authorizedKeysPath)
+	content, err := os.ReadFile(exec.Path(authorizedKeysPath))
 	if err != nil {
 		return false, fmt.Errorf("failed to read authorized_keys file: %w", err)
 	}
@@ -125,7 +142,7 @@ func (m *UserModule) IsInstalled() (bool, error) {
 	// Try to list files in sudoers.d to see if any exist
 	// We can't check for a specific username without config, so we just
 	// check if the directory exists and appears to be set up
-	entries, err := os.ReadDir(sudoersDir)
+	entries, err := os.ReadDir(exec.Path(sudoersDir))
 	if err != nil {
 		return false, fmt.Errorf("failed to read sudoers.d directory: %w", err)
 	}
@@ -197,7 +214,7 @@ func (m *UserModule) Install(cfg *config.Config) error {
 	// Look up user info to get UID/GID for file ownership (required for OpenSSH StrictModes)
 	// This must happen after user creation to ensure we have the correct UID/GID
 	if !dryRun {
-		userInfo, err := user.Lookup(username)
+		userInfo, err := exec.LookupUser(username)
 		if err != nil {
 			return fmt.Errorf("failed to look up user %s: %w", username, err)
 		}
@@ -217,20 +234,20 @@ func (m *UserModule) Install(cfg *config.Config) error {
 	} else {
 		if !exec.FileExists(sshDir) {
 			log.Info("Creating SSH directory: %s", sshDir)
-			if err := os.MkdirAll(sshDir, sshDirPerm); err != nil {
+			if err := os.MkdirAll(exec.Path(sshDir), sshDirPerm); err != nil {
 				return fmt.Errorf("failed to create SSH directory: %w", err)
 			}
-			if err := os.Chmod(sshDir, sshDirPerm); err != nil {
+			if err := os.Chmod(exec.Path(sshDir), sshDirPerm); err != nil {
 				return fmt.Errorf("failed to set SSH directory permissions: %w", err)
 			}
 			// Set ownership to the user (required by OpenSSH StrictModes)
-			if err := os.Chown(sshDir, userUID, userGID); err != nil {
+			if err := os.Chown(exec.Path(sshDir), userUID, userGID); err != nil {
 				return fmt.Errorf("failed to set SSH directory ownership: %w", err)
 			}
 			log.Success("Created SSH directory: %s", sshDir)
 		} else {
 			// Directory exists, but ensure correct ownership
-			if err := os.Chown(sshDir, userUID, userGID); err != nil {
+			if err := os.Chown(exec.Path(sshDir), userUID, userGID); err != nil {
 				return fmt.Errorf("failed to set SSH directory ownership: %w", err)
 			}
 			log.Skip("SSH directory already exists: %s", sshDir)
@@ -250,7 +267,7 @@ func (m *UserModule) Install(cfg *config.Config) error {
 			log.Info("Adding SSH key to authorized_keys")
 			var content []byte
 			if exec.FileExists(authorizedKeysPath) {
-				existingContent, err := os.ReadFile(authorizedKeysPath)
+				existingContent, err := os.ReadFile(exec.Path(authorizedKeysPath))
 				if err != nil {
 					return fmt.Errorf("failed to read authorized_keys file: %w", err)
 				}
@@ -263,15 +280,13 @@ func (m *UserModule) Install(cfg *config.Config) error {
 			content = append(content, []byte(sshKey)...)
 			content = append(content, '\n')
 
-			if err := exec.WriteFile(authorizedKeysPath, content, authorizedKeysPerm); err != nil {
-				return fmt.Errorf("failed to write authorized_keys file: %w", err)
-			}
-			if err := os.Chmod(authorizedKeysPath, authorizedKeysPerm); err != nil {
-				return fmt.Errorf("failed to set authorized_keys permissions: %w", err)
+			// Owned by the user with restricted permissions (required by OpenSSH StrictModes)
+			opts := files.Options{
+				Mode:  authorizedKeysPerm,
+				Owner: &files.Owner{UID: userUID, GID: userGID},
 			}
-			// Set ownership to the user (required by OpenSSH StrictModes)
-			if err := os.Chown(authorizedKeysPath, userUID, userGID); err != nil {
-				return fmt.Errorf("failed to set authorized_keys ownership: %w", err)
+			if _, err := files.Write(authorizedKeysPath, content, opts); err != nil {
+				return fmt.Errorf("failed to write authorized_keys file: %w", err)
 			}
 			log.Success("Added SSH key to authorized_keys")
 		}
@@ -283,11 +298,11 @@ func (m *UserModule) Install(cfg *config.Config) error {
 		} else {
 			// Ensure file exists before trying to fix ownership
 			if exec.FileExists(authorizedKeysPath) {
-				if err := os.Chmod(authorizedKeysPath, authorizedKeysPerm); err != nil {
+				if err := os.Chmod(exec.Path(authorizedKeysPath), authorizedKeysPerm); err != nil {
 					return fmt.Errorf("failed to set authorized_keys permissions: %w", err)
 				}
 				// Set ownership to the user (required by OpenSSH StrictModes)
-				if err := os.Chown(authorizedKeysPath, userUID, userGID); err != nil {
+				if err := os.Chown(exec.Path(authorizedKeysPath), userUID, userGID); err != nil {
 					return fmt.Errorf("failed to set authorized_keys ownership: %w", err)
 				}
 			}
@@ -304,7 +319,7 @@ func (m *UserModule) Install(cfg *config.Config) error {
 		// Check if sudoers file already exists and is correct
 		needsUpdate := true
 		if exec.FileExists(sudoersPath) {
-			existingContent, err := os.ReadFile(sudoersPath)
+			existingContent, err := os.ReadFile(exec.Path(sudoersPath))
 			if err == nil {
 				if strings.TrimSpace(string(existingContent)) == strings.TrimSpace(sudoersContent) {
 					needsUpdate = false
@@ -314,18 +329,22 @@ func (m *UserModule) Install(cfg *config.Config) error {
 
 		if needsUpdate {
 			log.Info("Configuring passwordless sudo for user: %s", username)
-			if err := exec.WriteFile(sudoersPath, []byte(sudoersContent), sudoersPerm); err != nil {
+			result, err := files.Write(sudoersPath, []byte(sudoersContent), files.Options{Mode: sudoersPerm})
+			if err != nil {
 				return fmt.Errorf("failed to write sudoers file: %w", err)
 			}
-			if err := os.Chmod(sudoersPath, sudoersPerm); err != nil {
-				return fmt.Errorf("failed to set sudoers file permissions: %w", err)
-			}
 
 			// Validate sudoers file
 			log.Info("Validating sudoers file")
 			if err := exec.Run("visudo", "-c", "-f", sudoersPath); err != nil {
-				// If validation fails, remove the file we just created
-				os.Remove(sudoersPath)
+				// If validation fails, restore the previous file or remove the one we just created
+				if result.Backup != "" {
+					if restoreErr := files.Restore(sudoersPath, result.Backup); restoreErr != nil {
+						return fmt.Errorf("sudoers file validation failed and restore failed: %w (restore error: %v)", err, restoreErr)
+					}
+				} else {
+					os.Remove(exec.Path(sudoersPath))
+				}
 				return fmt.Errorf("sudoers file validation failed: %w", err)
 			}
 			log.Success("Configured passwordless sudo for user: %s", username)
@@ -341,5 +360,43 @@ func (m *UserModule) Install(cfg *config.Config) error {
 	return nil
 }
 
-// Ensure UserModule implements the Module interface
+// Export adds the user, SSH key, and sudo steps to s.
+func (m *UserModule) Export(cfg *config.Config, s *export.Script) error {
+	if cfg.User.Username == "" {
+		return fmt.Errorf("username is required")
+	}
+	if err := validateSSHKey(cfg.User.SSHPublicKey); err != nil {
+		return fmt.Errorf("invalid SSH public key: %w", err)
+	}
+
+	username := cfg.User.Username
+	sshKey := strings.TrimSpace(cfg.User.SSHPublicKey)
+	sshDir := filepath.Join("/home", username, ".ssh")
+	authorizedKeysPath := filepath.Join(sshDir, "authorized_keys")
+	// "user:" is the user's login group
+	owner := username + ":"
+
+	s.If(fmt.Sprintf("! id -u %s >/dev/null 2>&1", export.Quote(username)), func() {
+		s.Log("Creating user %s", username)
+		s.Run("useradd", "-m", "-s", "/bin/bash", username)
+	})
+
+	// Ownership and permissions are required by OpenSSH StrictModes
+	s.Run("mkdir", "-p", sshDir)
+	s.Run("chmod", fmt.Sprintf("%o", sshDirPerm), sshDir)
+	s.Run("chown", owner, sshDir)
+	s.EnsureLine(authorizedKeysPath, sshKey)
+	s.Run("chmod", fmt.Sprintf("%o", authorizedKeysPerm), authorizedKeysPath)
+	s.Run("chown", owner, authorizedKeysPath)
+
+	s.WriteFile(filepath.Join("/etc/sudoers.d", username), fmt.Sprintf("%s ALL=(ALL) NOPASSWD:ALL\n", username), export.FileOptions{
+		Mode:     sudoersPerm,
+		Validate: []string{"visudo", "-c", "-f"},
+	})
+	return nil
+}
+
+// Ensure UserModule implements the Module, Describer, and Exporter interfaces
 var _ module.Module = (*UserModule)(nil)
+var _ module.Describer = (*UserModule)(nil)
+var _ module.Exporter = (*UserModule)(nil)
@@ -0,0 +1,23 @@
+// Package notify delivers run completion notifications to webhooks.
+// Each configured webhook receives a JSON summary of the run: the module
+// results, host identity, and duration.
+//
+// Key Features:
+//   - Generic JSON payloads plus Slack- and Discord-shaped presets
+//   - Per-webhook trigger (always or only on failure)
+//   - Per-attempt timeout with retries and exponential backoff
+//   - Custom HTTP headers (e.g., Authorization)
+//
+// Usage:
+//
+//	run := notify.Run{
+//	    Version:    version,
+//	    Host:       report.CollectHostFacts(),
+//	    StartedAt:  startedAt,
+//	    FinishedAt: time.Now(),
+//	    Results:    results,
+//	}
+//	if err := notify.Send(cfg.Notifications.Webhooks, run); err != nil {
+//	    log.Warn("Some notifications could not be delivered: %v", err)
+//	}
+package notify
@@ -0,0 +1,186 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/stwalsh4118/phanes/internal/config"
+	"github.com/stwalsh4118/phanes/internal/log"
+	"github.com/stwalsh4118/phanes/internal/proxy"
+	"github.com/stwalsh4118/phanes/internal/report"
+	"github.com/stwalsh4118/phanes/internal/runner"
+)
+
+const (
+	contentTypeJSON = "application/json"
+	userAgent       = "phanes"
+
+	// maxErrorBodyLen limits how much of an error response body is included in errors.
+	maxErrorBodyLen = 200
+)
+
+// retryBackoff is the delay before the first retry; it doubles on each further retry.
+// It is a variable so tests can shorten it.
+var retryBackoff = time.Second
+
+// Run describes a finished provisioning run.
+type Run struct {
+	// Version is the phanes version that executed the run.
+	Version string
+	// Profile is the selected profile name (empty if only --modules was used).
+	Profile string
+	// Modules is the ordered list of modules that were requested.
+	Modules []string
+	// Host identifies the machine the run was executed on.
+	Host report.HostFacts
+	// StartedAt is when module execution started.
+	StartedAt time.Time
+	// FinishedAt is when module execution finished.
+	FinishedAt time.Time
+	// Results are the per-module execution results.
+	Results []runner.ModuleResult
+}
+
+// Succeeded reports whether no module failed, errored, or was declined.
+func (r Run) Succeeded() bool {
+	for _, result := range r.Results {
+		if failed(result) {
+			return false
+		}
+	}
+	return true
+}
+
+// Send delivers the run summary to every webhook whose trigger matches the
+// run outcome. A failing webhook does not prevent delivery to the others.
+// Returns an error describing every webhook that could not be notified.
+func Send(webhooks []config.Webhook, run Run) error {
+	var errs []error
+	for _, webhook := range webhooks {
+		name := webhookName(webhook)
+
+		if webhook.On == config.WebhookOnFailure && run.Succeeded() {
+			log.Skip("Skipping webhook %s: run succeeded and it only fires on failure", name)
+			continue
+		}
+
+		if err := deliver(webhook, run); err != nil {
+			log.Warn("Failed to notify webhook %s: %v", name, err)
+			errs = append(errs, fmt.Errorf("webhook %s: %w", name, err))
+			continue
+		}
+		log.Success("Notified webhook %s", name)
+	}
+
+	return errors.Join(errs...)
+}
+
+// deliver POSTs the payload for run to a single webhook, retrying transient
+// failures with exponential backoff.
+func deliver(webhook config.Webhook, run Run) error {
+	body, err := encodePayload(webhook.Format, run)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: webhook.Timeout, Transport: proxy.Transport()}
+	backoff := retryBackoff
+
+	var lastErr error
+	for attempt := 0; attempt <= webhook.Retries; attempt++ {
+		if attempt > 0 {
+			log.Info("Retrying webhook %s in %s (attempt %d of %d)", webhookName(webhook), backoff, attempt+1, webhook.Retries+1)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		retryable, err := post(client, webhook, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retryable {
+			break
+		}
+	}
+
+	return lastErr
+}
+
+// post sends a single request. It reports whether a failure is worth retrying:
+// network errors, 429, and 5xx responses are retried, other responses are not.
+func post(client *http.Client, webhook config.Webhook, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentTypeJSON)
+	req.Header.Set("User-Agent", userAgent)
+	for name, value := range webhook.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to send request: %w", redactURL(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyLen))
+	retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retryable, fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(snippet))
+}
+
+// encodePayload builds the JSON body for the given webhook format.
+func encodePayload(format string, run Run) ([]byte, error) {
+	var payload interface{}
+	switch format {
+	case config.WebhookFormatSlack:
+		payload = buildSlack(run)
+	case config.WebhookFormatDiscord:
+		payload = buildDiscord(run)
+	case config.WebhookFormatGeneric, "":
+		payload = buildGeneric(run)
+	default:
+		return nil, fmt.Errorf("unknown webhook format %q", format)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode payload: %w", err)
+	}
+	return body, nil
+}
+
+// webhookName returns the name used for a webhook in logs. The full URL is
+// never logged because it often contains a secret token.
+func webhookName(webhook config.Webhook) string {
+	if webhook.Name != "" {
+		return webhook.Name
+	}
+	if parsed, err := url.Parse(webhook.URL); err == nil && parsed.Host != "" {
+		return parsed.Host
+	}
+	return "(unnamed)"
+}
+
+// redactURL strips the request URL from net/http client errors so webhook
+// tokens do not end up in logs.
+func redactURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
@@ -0,0 +1,263 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stwalsh4118/phanes/internal/config"
+	"github.com/stwalsh4118/phanes/internal/report"
+	"github.com/stwalsh4118/phanes/internal/runner"
+)
+
+func init() {
+	retryBackoff = time.Millisecond
+}
+
+func testRun(success bool) Run {
+	started := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	run := Run{
+		Version:    "1.2.3",
+		Profile:    "web",
+		Modules:    []string{"baseline", "docker"},
+		Host:       report.HostFacts{Hostname: "web-1", OS: "Ubuntu 24.04 LTS", Arch: "amd64"},
+		StartedAt:  started,
+		FinishedAt: started.Add(90 * time.Second),
+		Results: []runner.ModuleResult{
+			{Name: "baseline", Status: runner.StatusInstalled, Duration: 10 * time.Second},
+			{Name: "docker", Status: runner.StatusInstalled, Duration: 80 * time.Second},
+		},
+	}
+	if !success {
+		run.Results[1] = runner.ModuleResult{
+			Name:   "docker",
+			Status: runner.StatusFailed,
+			Error:  errors.New("module docker: apt-get failed"),
+		}
+	}
+	return run
+}
+
+func testWebhook(url string) config.Webhook {
+	return config.Webhook{
+		URL:     url,
+		Format:  config.WebhookFormatGeneric,
+		On:      config.WebhookOnAlways,
+		Timeout: time.Second,
+		Retries: 2,
+	}
+}
+
+func TestSend_GenericPayload(t *testing.T) {
+	var got genericPayload
+	var gotHeader, gotContentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get("Authorization")
+		gotContentType = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	webhook := testWebhook(server.URL)
+	webhook.Headers = map[string]string{"Authorization": "Bearer token"}
+
+	if err := Send([]config.Webhook{webhook}, testRun(false)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if gotHeader != "Bearer token" {
+		t.Errorf("Authorization header = %q, want %q", gotHeader, "Bearer token")
+	}
+	if gotContentType != contentTypeJSON {
+		t.Errorf("Content-Type = %q, want %q", gotContentType, contentTypeJSON)
+	}
+	if got.Event != eventRunFailed || got.Success {
+		t.Errorf("payload event = %q success = %v, want %q false", got.Event, got.Success, eventRunFailed)
+	}
+	if got.Host.Hostname != "web-1" {
+		t.Errorf("payload host = %q, want web-1", got.Host.Hostname)
+	}
+	if got.DurationSeconds != 90 {
+		t.Errorf("payload duration = %v, want 90", got.DurationSeconds)
+	}
+	if len(got.Results) != 2 || got.Results[1].Error != "module docker: apt-get failed" {
+		t.Errorf("payload results = %+v", got.Results)
+	}
+}
+
+func TestSend_ChatFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		field  string
+		want   string
+	}{
+		{format: config.WebhookFormatSlack, field: "text", want: "*phanes run FAILED on web-1*"},
+		{format: config.WebhookFormatDiscord, field: "content", want: "**phanes run FAILED on web-1**"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var got map[string]string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("failed to decode payload: %v", err)
+				}
+			}))
+			defer server.Close()
+
+			webhook := testWebhook(server.URL)
+			webhook.Format = tt.format
+			if err := Send([]config.Webhook{webhook}, testRun(false)); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			message := got[tt.field]
+			if !strings.Contains(message, tt.want) {
+				t.Errorf("%s = %q, want it to contain %q", tt.field, message, tt.want)
+			}
+			if !strings.Contains(message, "apt-get failed") {
+				t.Errorf("%s = %q, want it to contain the module error", tt.field, message)
+			}
+		})
+	}
+}
+
+func TestSend_OnFailureOnly(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer server.Close()
+
+	webhook := testWebhook(server.URL)
+	webhook.On = config.WebhookOnFailure
+
+	if err := Send([]config.Webhook{webhook}, testRun(true)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("failure-only webhook was called %d times for a successful run", calls)
+	}
+
+	if err := Send([]config.Webhook{webhook}, testRun(false)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("failure-only webhook was called %d times for a failed run, want 1", calls)
+	}
+}
+
+func TestSend_RetriesServerErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	if err := Send([]config.Webhook{testWebhook(server.URL)}, testRun(true)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("webhook was called %d times, want 3", calls)
+	}
+}
+
+func TestSend_GivesUpAfterRetries(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = io.WriteString(w, "boom")
+	}))
+	defer server.Close()
+
+	err := Send([]config.Webhook{testWebhook(server.URL)}, testRun(true))
+	if err == nil {
+		t.Fatal("Send() expected error")
+	}
+	if !strings.Contains(err.Error(), "500") || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Send() error = %v, want status and body", err)
+	}
+	if calls != 3 {
+		t.Errorf("webhook was called %d times, want 3 (1 attempt + 2 retries)", calls)
+	}
+}
+
+func TestSend_DoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	if err := Send([]config.Webhook{testWebhook(server.URL)}, testRun(true)); err == nil {
+		t.Fatal("Send() expected error")
+	}
+	if calls != 1 {
+		t.Errorf("webhook was called %d times, want 1", calls)
+	}
+}
+
+func TestSend_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	webhook := testWebhook(server.URL + "/secret-token")
+	webhook.Timeout = 20 * time.Millisecond
+	webhook.Retries = 0
+
+	err := Send([]config.Webhook{webhook}, testRun(true))
+	if err == nil {
+		t.Fatal("Send() expected timeout error")
+	}
+	if strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("Send() error leaked the webhook URL: %v", err)
+	}
+}
+
+func TestSend_ContinuesAfterFailure(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer server.Close()
+
+	broken := testWebhook("http://127.0.0.1:1")
+	broken.Name = "broken"
+	broken.Retries = 0
+
+	err := Send([]config.Webhook{broken, testWebhook(server.URL)}, testRun(true))
+	if err == nil || !strings.Contains(err.Error(), "webhook broken") {
+		t.Errorf("Send() error = %v, want error for webhook broken", err)
+	}
+	if calls != 1 {
+		t.Errorf("second webhook was called %d times, want 1", calls)
+	}
+}
+
+func TestBuildDiscord_Truncates(t *testing.T) {
+	run := testRun(false)
+	run.Results[1].Error = errors.New(strings.Repeat("é", 3000))
+
+	payload := buildDiscord(run)
+	if n := len([]rune(payload.Content)); n > discordContentLimit {
+		t.Errorf("Discord content has %d characters, want at most %d", n, discordContentLimit)
+	}
+}
@@ -0,0 +1,145 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/stwalsh4118/phanes/internal/runner"
+)
+
+const (
+	eventRunSucceeded = "run.succeeded"
+	eventRunFailed    = "run.failed"
+
+	// discordContentLimit is the maximum length of a Discord message.
+	discordContentLimit = 2000
+)
+
+// hostPayload identifies the host the run was executed on.
+type hostPayload struct {
+	Hostname string `json:"hostname"`
+	OS       string `json:"os,omitempty"`
+	Kernel   string `json:"kernel,omitempty"`
+	Arch     string `json:"arch"`
+}
+
+// resultPayload is the JSON form of a runner.ModuleResult.
+type resultPayload struct {
+	Name            string  `json:"name"`
+	Status          string  `json:"status"`
+	Error           string  `json:"error,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// genericPayload is the body sent to generic webhooks.
+type genericPayload struct {
+	Event           string          `json:"event"`
+	Success         bool            `json:"success"`
+	Version         string          `json:"version"`
+	Profile         string          `json:"profile,omitempty"`
+	Modules         []string        `json:"modules"`
+	Host            hostPayload     `json:"host"`
+	StartedAt       time.Time       `json:"started_at"`
+	FinishedAt      time.Time       `json:"finished_at"`
+	DurationSeconds float64         `json:"duration_seconds"`
+	Results         []resultPayload `json:"results"`
+}
+
+// slackPayload is the body sent to Slack incoming webhooks.
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+// discordPayload is the body sent to Discord webhooks.
+type discordPayload struct {
+	Content string `json:"content"`
+}
+
+// buildGeneric converts a run into the generic JSON payload.
+func buildGeneric(run Run) genericPayload {
+	payload := genericPayload{
+		Event:   eventRunSucceeded,
+		Success: run.Succeeded(),
+		Version: run.Version,
+		Profile: run.Profile,
+		Modules: run.Modules,
+		Host: hostPayload{
+			Hostname: run.Host.Hostname,
+			OS:       run.Host.OS,
+			Kernel:   run.Host.Kernel,
+			Arch:     run.Host.Arch,
+		},
+		StartedAt:       run.StartedAt,
+		FinishedAt:      run.FinishedAt,
+		DurationSeconds: run.FinishedAt.Sub(run.StartedAt).Seconds(),
+		Results:         make([]resultPayload, 0, len(run.Results)),
+	}
+	if !payload.Success {
+		payload.Event = eventRunFailed
+	}
+
+	for _, result := range run.Results {
+		entry := resultPayload{
+			Name:            result.Name,
+			Status:          string(result.Status),
+			DurationSeconds: result.Duration.Seconds(),
+		}
+		if result.Error != nil {
+			entry.Error = result.Error.Error()
+		}
+		payload.Results = append(payload.Results, entry)
+	}
+
+	return payload
+}
+
+// buildMessage renders a short human-readable summary of the run for chat webhooks.
+// Markup uses the *bold* and `code` syntax understood by both Slack and Discord.
+func buildMessage(run Run, bold string) string {
+	var b strings.Builder
+
+	outcome := "succeeded"
+	if !run.Succeeded() {
+		outcome = "FAILED"
+	}
+	fmt.Fprintf(&b, "%sphanes run %s on %s%s", bold, outcome, run.Host.Hostname, bold)
+	if run.Profile != "" {
+		fmt.Fprintf(&b, " (profile %s)", run.Profile)
+	}
+	fmt.Fprintf(&b, " in %s\n", run.FinishedAt.Sub(run.StartedAt).Round(time.Second))
+
+	for _, result := range run.Results {
+		fmt.Fprintf(&b, "• `%s` %s", result.Name, result.Status)
+		if result.Error != nil {
+			fmt.Fprintf(&b, ": %s", result.Error.Error())
+		}
+		b.WriteString("\n")
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// buildSlack converts a run into a Slack incoming webhook payload.
+func buildSlack(run Run) slackPayload {
+	return slackPayload{Text: buildMessage(run, "*")}
+}
+
+// buildDiscord converts a run into a Discord webhook payload.
+func buildDiscord(run Run) discordPayload {
+	content := buildMessage(run, "**")
+	if runes := []rune(content); len(runes) > discordContentLimit {
+		content = string(runes[:discordContentLimit-3]) + "..."
+	}
+	return discordPayload{Content: content}
+}
+
+// failed reports whether a result counts as a failure of the run.
+func failed(result runner.ModuleResult) bool {
+	switch result.Status {
+	case runner.StatusFailed, runner.StatusError, runner.StatusDeclined, runner.StatusDependencyFailed:
+		return true
+	default:
+		return result.Error != nil
+	}
+}
@@ -0,0 +1,26 @@
+// Package osinfo detects the operating system phanes is running on.
+// It parses /etc/os-release once and exposes the distribution identity so
+// modules can pick distro-correct package repositories or fail early on
+// unsupported systems.
+//
+// Key Features:
+//   - Parses ID, ID_LIKE, VERSION_ID, VERSION_CODENAME, and PRETTY_NAME
+//   - Fills in missing codenames for known Ubuntu and Debian releases
+//   - Resolves derivatives (e.g., Linux Mint) to their upstream distribution
+//   - Reports the Debian-style CPU architecture (amd64, arm64, ...)
+//   - Caches detection results for the lifetime of the process
+//
+// Usage:
+//
+//	info, err := osinfo.Detect()
+//	if err != nil {
+//	    return fmt.Errorf("failed to detect operating system: %w", err)
+//	}
+//
+//	// Pick the upstream apt repository for this distribution
+//	distro, codename, err := info.AptDistro()
+//	if err != nil {
+//	    return err // unsupported OS
+//	}
+//	repoURL := fmt.Sprintf("https://download.docker.com/linux/%s", distro)
+package osinfo
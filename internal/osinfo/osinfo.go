@@ -0,0 +1,235 @@
+package osinfo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/stwalsh4118/phanes/internal/exec"
+)
+
+// Distribution IDs as reported in os-release.
+const (
+	IDUbuntu = "ubuntu"
+	IDDebian = "debian"
+)
+
+// osReleasePaths are the locations of the os-release file, in order of
+// precedence: /usr/lib/os-release is used when /etc/os-release is missing. It
+// is a variable so tests can override it.
+var osReleasePaths = []string{"/etc/os-release", "/usr/lib/os-release"}
+
+// codenames maps VERSION_ID to release codenames for distributions whose
+// os-release may lack VERSION_CODENAME (e.g., minimal images).
+var codenames = map[string]map[string]string{
+	IDUbuntu: {
+		"16.04": "xenial",
+		"18.04": "bionic",
+		"20.04": "focal",
+		"22.04": "jammy",
+		"24.04": "noble",
+		"24.10": "oracular",
+		"25.04": "plucky",
+	},
+	IDDebian: {
+		"10": "buster",
+		"11": "bullseye",
+		"12": "bookworm",
+		"13": "trixie",
+	},
+}
+
+// debianArch maps Go architectures to Debian architecture names.
+var debianArch = map[string]string{
+	"amd64":   "amd64",
+	"arm64":   "arm64",
+	"arm":     "armhf",
+	"386":     "i386",
+	"ppc64le": "ppc64el",
+	"s390x":   "s390x",
+	"riscv64": "riscv64",
+}
+
+// Info describes the detected operating system.
+type Info struct {
+	// ID is the lower-case distribution ID (e.g., "ubuntu", "debian", "fedora").
+	ID string
+	// IDLike lists the distributions this one is derived from (e.g., ["ubuntu", "debian"]).
+	IDLike []string
+	// VersionID is the release version (e.g., "24.04", "12").
+	VersionID string
+	// Codename is the release codename (e.g., "noble", "bookworm").
+	// It is derived from VersionID for known releases if os-release omits it.
+	Codename string
+	// UbuntuCodename is the codename of the Ubuntu release a derivative is based on.
+	UbuntuCodename string
+	// PrettyName is the human-readable OS name (e.g., "Ubuntu 24.04 LTS").
+	PrettyName string
+	// Arch is the Debian-style CPU architecture (e.g., "amd64", "arm64").
+	Arch string
+}
+
+// UnsupportedError indicates that a feature does not support the detected OS.
+type UnsupportedError struct {
+	// OS is the human-readable name of the detected OS.
+	OS string
+	// Reason explains what is required.
+	Reason string
+}
+
+// Error returns the error message.
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("unsupported OS %q: %s", e.OS, e.Reason)
+}
+
+var (
+	mu       sync.Mutex
+	detected *Info
+)
+
+// Detect returns information about the running operating system.
+// The result is read from /etc/os-release (or /usr/lib/os-release if it is
+// missing) on first use and cached. Under an
+// alternate root (exec.SetRoot), the root's os-release is read.
+func Detect() (*Info, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if detected != nil {
+		return detected, nil
+	}
+
+	var file *os.File
+	var err error
+	for _, path := range osReleasePaths {
+		file, err = os.Open(exec.Path(path))
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to open %s: %w", path, err)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find os-release in %s: %w", strings.Join(osReleasePaths, " or "), err)
+	}
+	defer file.Close()
+
+	info, err := Parse(file)
+	if err != nil {
+		return nil, err
+	}
+	info.Arch = goArchToDebian(runtime.GOARCH)
+
+	detected = info
+	return detected, nil
+}
+
+// Parse reads os-release formatted content.
+// Arch is not set since it is not part of os-release.
+func Parse(r io.Reader) (*Info, error) {
+	values := make(map[string]string)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		values[key] = unquote(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read os-release: %w", err)
+	}
+
+	info := &Info{
+		ID:             strings.ToLower(values["ID"]),
+		IDLike:         strings.Fields(strings.ToLower(values["ID_LIKE"])),
+		VersionID:      values["VERSION_ID"],
+		Codename:       values["VERSION_CODENAME"],
+		UbuntuCodename: values["UBUNTU_CODENAME"],
+		PrettyName:     values["PRETTY_NAME"],
+	}
+	if info.ID == "" {
+		return nil, fmt.Errorf("os-release does not contain an ID")
+	}
+	if info.Codename == "" {
+		info.Codename = codenames[info.ID][info.VersionID]
+	}
+	if info.PrettyName == "" {
+		info.PrettyName = strings.TrimSpace(values["NAME"] + " " + info.VersionID)
+	}
+
+	return info, nil
+}
+
+// Is reports whether the OS is one of the given distributions, either
+// directly (ID) or as a derivative (ID_LIKE).
+func (i *Info) Is(ids ...string) bool {
+	for _, id := range ids {
+		if i.ID == id {
+			return true
+		}
+		for _, like := range i.IDLike {
+			if like == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AptDistro returns the upstream distribution ("ubuntu" or "debian") and its
+// codename, as used by third-party apt repositories. Derivatives resolve to
+// the distribution they are based on.
+// Returns an UnsupportedError for non-Debian systems or unknown releases.
+func (i *Info) AptDistro() (string, string, error) {
+	switch {
+	case i.ID == IDUbuntu || i.ID == IDDebian:
+		if i.Codename == "" {
+			return "", "", &UnsupportedError{OS: i.PrettyName, Reason: "cannot determine the release codename"}
+		}
+		return i.ID, i.Codename, nil
+	case i.Is(IDUbuntu) && i.UbuntuCodename != "":
+		return IDUbuntu, i.UbuntuCodename, nil
+	case i.Is(IDDebian) && i.Codename != "":
+		return IDDebian, i.Codename, nil
+	default:
+		return "", "", &UnsupportedError{OS: i.PrettyName, Reason: "a Debian or Ubuntu based distribution is required"}
+	}
+}
+
+// goArchToDebian converts a Go architecture name to its Debian equivalent.
+func goArchToDebian(goarch string) string {
+	if arch, ok := debianArch[goarch]; ok {
+		return arch
+	}
+	return goarch
+}
+
+// unquote strips matching single or double quotes from an os-release value.
+func unquote(value string) string {
+	if len(value) >= 2 {
+		first, last := value[0], value[len(value)-1]
+		if (first == '"' || first == '\'') && first == last {
+			return value[1 : len(value)-1]
+		}
+	}
+	return value
+}
+
+// reset clears the cached detection result. Used by tests.
+func reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	detected = nil
+}
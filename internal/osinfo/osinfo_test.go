@@ -0,0 +1,280 @@
+package osinfo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	ubuntu2404 = `PRETTY_NAME="Ubuntu 24.04.1 LTS"
+NAME="Ubuntu"
+VERSION_ID="24.04"
+VERSION="24.04.1 LTS (Noble Numbat)"
+VERSION_CODENAME=noble
+ID=ubuntu
+ID_LIKE=debian
+UBUNTU_CODENAME=noble
+`
+	debian13 = `PRETTY_NAME="Debian GNU/Linux 13 (trixie)"
+NAME="Debian GNU/Linux"
+VERSION_ID="13"
+VERSION_CODENAME=trixie
+ID=debian
+`
+	// Minimal images may omit VERSION_CODENAME
+	debian12NoCodename = `NAME="Debian GNU/Linux"
+VERSION_ID="12"
+ID=debian
+`
+	linuxMint = `NAME="Linux Mint"
+VERSION_ID="21.3"
+VERSION_CODENAME=virginia
+ID=linuxmint
+ID_LIKE="ubuntu debian"
+PRETTY_NAME="Linux Mint 21.3"
+UBUNTU_CODENAME=jammy
+`
+	raspbian = `PRETTY_NAME="Raspbian GNU/Linux 12 (bookworm)"
+ID=raspbian
+ID_LIKE=debian
+VERSION_ID="12"
+VERSION_CODENAME=bookworm
+`
+	fedora = `NAME="Fedora Linux"
+VERSION_ID=40
+ID=fedora
+PRETTY_NAME="Fedora Linux 40 (Server Edition)"
+`
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Info
+		wantErr bool
+	}{
+		{
+			name:    "ubuntu 24.04",
+			content: ubuntu2404,
+			want: Info{
+				ID:             "ubuntu",
+				IDLike:         []string{"debian"},
+				VersionID:      "24.04",
+				Codename:       "noble",
+				UbuntuCodename: "noble",
+				PrettyName:     "Ubuntu 24.04.1 LTS",
+			},
+		},
+		{
+			name:    "debian 13",
+			content: debian13,
+			want: Info{
+				ID:         "debian",
+				IDLike:     []string{},
+				VersionID:  "13",
+				Codename:   "trixie",
+				PrettyName: "Debian GNU/Linux 13 (trixie)",
+			},
+		},
+		{
+			name:    "codename derived from version",
+			content: debian12NoCodename,
+			want: Info{
+				ID:         "debian",
+				IDLike:     []string{},
+				VersionID:  "12",
+				Codename:   "bookworm",
+				PrettyName: "Debian GNU/Linux 12",
+			},
+		},
+		{
+			name:    "comments, blank lines, and single quotes",
+			content: "# comment\n\nID='ubuntu'\nVERSION_ID='22.04'\n",
+			want: Info{
+				ID:         "ubuntu",
+				IDLike:     []string{},
+				VersionID:  "22.04",
+				Codename:   "jammy",
+				PrettyName: "22.04",
+			},
+		},
+		{
+			name:    "missing ID",
+			content: "NAME=Unknown\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.content))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Parse() expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+			if got.IDLike == nil {
+				got.IDLike = []string{}
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAptDistro(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		wantDistro   string
+		wantCodename string
+		wantErr      bool
+	}{
+		{name: "ubuntu", content: ubuntu2404, wantDistro: "ubuntu", wantCodename: "noble"},
+		{name: "debian", content: debian13, wantDistro: "debian", wantCodename: "trixie"},
+		{name: "debian without codename", content: debian12NoCodename, wantDistro: "debian", wantCodename: "bookworm"},
+		{name: "ubuntu derivative", content: linuxMint, wantDistro: "ubuntu", wantCodename: "jammy"},
+		{name: "debian derivative", content: raspbian, wantDistro: "debian", wantCodename: "bookworm"},
+		{name: "unknown ubuntu release", content: "ID=ubuntu\nVERSION_ID=99.04\n", wantErr: true},
+		{name: "non-debian", content: fedora, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := Parse(strings.NewReader(tt.content))
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+
+			distro, codename, err := info.AptDistro()
+			if tt.wantErr {
+				var unsupported *UnsupportedError
+				if !errors.As(err, &unsupported) {
+					t.Errorf("AptDistro() error = %v, want UnsupportedError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AptDistro() unexpected error: %v", err)
+			}
+			if distro != tt.wantDistro || codename != tt.wantCodename {
+				t.Errorf("AptDistro() = %s %s, want %s %s", distro, codename, tt.wantDistro, tt.wantCodename)
+			}
+		})
+	}
+}
+
+func TestUnsupportedError(t *testing.T) {
+	info, err := Parse(strings.NewReader(fedora))
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+
+	_, _, err = info.AptDistro()
+	if err == nil || !strings.Contains(err.Error(), `unsupported OS "Fedora Linux 40 (Server Edition)"`) {
+		t.Errorf("AptDistro() error = %v, want it to name the OS", err)
+	}
+}
+
+func TestIs(t *testing.T) {
+	info, err := Parse(strings.NewReader(linuxMint))
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+
+	if !info.Is("linuxmint") || !info.Is(IDUbuntu) || !info.Is("fedora", IDDebian) {
+		t.Error("Is() should match ID and ID_LIKE entries")
+	}
+	if info.Is("fedora", "alpine") {
+		t.Error("Is() should not match unrelated distributions")
+	}
+}
+
+func TestDetect_Caches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "os-release")
+	if err := os.WriteFile(path, []byte(ubuntu2404), 0644); err != nil {
+		t.Fatalf("Failed to write os-release: %v", err)
+	}
+
+	originalPaths := osReleasePaths
+	osReleasePaths = []string{path}
+	reset()
+	defer func() {
+		osReleasePaths = originalPaths
+		reset()
+	}()
+
+	info, err := Detect()
+	if err != nil {
+		t.Fatalf("Detect() unexpected error: %v", err)
+	}
+	if info.ID != IDUbuntu || info.Arch == "" {
+		t.Errorf("Detect() = %+v, want ubuntu with an architecture", info)
+	}
+
+	// Changes to the file are not picked up once detected
+	if err := os.WriteFile(path, []byte(fedora), 0644); err != nil {
+		t.Fatalf("Failed to write os-release: %v", err)
+	}
+	again, err := Detect()
+	if err != nil {
+		t.Fatalf("Detect() unexpected error: %v", err)
+	}
+	if again != info {
+		t.Error("Detect() should return the cached result")
+	}
+}
+
+func TestDetect_Fallback(t *testing.T) {
+	dir := t.TempDir()
+	fallback := filepath.Join(dir, "usr-lib-os-release")
+	if err := os.WriteFile(fallback, []byte(fedora), 0644); err != nil {
+		t.Fatalf("Failed to write os-release: %v", err)
+	}
+
+	originalPaths := osReleasePaths
+	osReleasePaths = []string{filepath.Join(dir, "etc-os-release"), fallback}
+	reset()
+	defer func() {
+		osReleasePaths = originalPaths
+		reset()
+	}()
+
+	info, err := Detect()
+	if err != nil {
+		t.Fatalf("Detect() unexpected error: %v", err)
+	}
+	if info.ID != "fedora" {
+		t.Errorf("Detect() ID = %q, want fedora from the fallback", info.ID)
+	}
+
+	reset()
+	osReleasePaths = osReleasePaths[:1]
+	if _, err := Detect(); err == nil {
+		t.Error("Detect() without an os-release file should fail")
+	}
+}
+
+func TestGoArchToDebian(t *testing.T) {
+	tests := map[string]string{
+		"amd64":   "amd64",
+		"arm":     "armhf",
+		"ppc64le": "ppc64el",
+		"mips":    "mips",
+	}
+	for goarch, want := range tests {
+		if got := goArchToDebian(goarch); got != want {
+			t.Errorf("goArchToDebian(%q) = %q, want %q", goarch, got, want)
+		}
+	}
+}
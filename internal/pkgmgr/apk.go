@@ -0,0 +1,148 @@
+package pkgmgr
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/stwalsh4118/phanes/internal/exec"
+)
+
+const apkKeysDir = "/etc/apk/keys"
+
+// apkRepositoriesPath is a variable so tests can use a temporary file.
+var apkRepositoriesPath = "/etc/apk/repositories"
+
+// apk manages packages on Alpine Linux.
+type apk struct{}
+
+func (a *apk) Name() string {
+	return NameApk
+}
+
+func (a *apk) Refresh() error {
+	return track(NameApk, "update", nil, func() error {
+		return runCommand("apk", "update")
+	})
+}
+
+func (a *apk) Install(packages ...string) error {
+	names := resolve(NameApk, packages)
+	if len(names) == 0 {
+		return nil
+	}
+	return track(NameApk, "add", names, func() error {
+		return runCommand("apk", append([]string{"add"}, names...)...)
+	})
+}
+
+func (a *apk) Remove(packages ...string) error {
+	names := resolve(NameApk, packages)
+	if len(names) == 0 {
+		return nil
+	}
+	return track(NameApk, "del", names, func() error {
+		return runCommand("apk", append([]string{"del"}, names...)...)
+	})
+}
+
+func (a *apk) IsInstalled(pkg string) (bool, string, error) {
+	var version string
+	for i, name := range resolve(NameApk, []string{pkg}) {
+		output, err := runOutput("apk", "list", "--installed", name)
+		if err != nil {
+			return false, "", fmt.Errorf("failed to query package %s: %w", name, err)
+		}
+		installed, v := parseApkList(output, name)
+		if !installed {
+			return false, "", nil
+		}
+		if i == 0 {
+			version = v
+		}
+	}
+	return true, version, nil
+}
+
+func (a *apk) AddRepository(repo Repository) error {
+	if repo.KeyURL != "" {
+		keyPath := apkKeysDir + "/" + path.Base(repo.KeyURL)
+		if err := track(NameApk, "add key", []string{repo.Name}, func() error {
+			return runCommand("curl", "-fsSL", "-o", keyPath, repo.KeyURL)
+		}); err != nil {
+			return fmt.Errorf("failed to add %s signing key: %w", repo.Name, err)
+		}
+	}
+
+	content, err := os.ReadFile(exec.Path(apkRepositoriesPath))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", apkRepositoriesPath, err)
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.TrimSpace(line) == repo.URL {
+			return nil
+		}
+	}
+
+	file, err := os.OpenFile(exec.Path(apkRepositoriesPath), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", apkRepositoriesPath, err)
+	}
+	defer file.Close()
+
+	entry := repo.URL + "\n"
+	if len(content) > 0 && !strings.HasSuffix(string(content), "\n") {
+		entry = "\n" + entry
+	}
+	if _, err := file.WriteString(entry); err != nil {
+		return fmt.Errorf("failed to write %s: %w", apkRepositoriesPath, err)
+	}
+	return nil
+}
+
+func (a *apk) RemoveRepository(repo Repository) error {
+	content, err := os.ReadFile(exec.Path(apkRepositoriesPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read %s: %w", apkRepositoriesPath, err)
+	}
+
+	var kept []string
+	for _, line := range strings.Split(strings.TrimSuffix(string(content), "\n"), "\n") {
+		if strings.TrimSpace(line) != repo.URL {
+			kept = append(kept, line)
+		}
+	}
+	if err := os.WriteFile(exec.Path(apkRepositoriesPath), []byte(strings.Join(kept, "\n")+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", apkRepositoriesPath, err)
+	}
+
+	if repo.KeyURL != "" {
+		keyPath := apkKeysDir + "/" + path.Base(repo.KeyURL)
+		if err := os.Remove(exec.Path(keyPath)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", keyPath, err)
+		}
+	}
+	return nil
+}
+
+// parseApkList finds pkg in `apk list --installed` output
+// (e.g., "nginx-1.24.0-r6 x86_64 {nginx} (BSD-2-Clause) [installed]")
+// and returns its version.
+func parseApkList(output, pkg string) (bool, string) {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || !strings.Contains(line, "[installed]") {
+			continue
+		}
+		version, ok := strings.CutPrefix(fields[0], pkg+"-")
+		// Versions start with a digit; this skips e.g. "nginx-mod-http" for "nginx"
+		if ok && version != "" && version[0] >= '0' && version[0] <= '9' {
+			return true, version
+		}
+	}
+	return false, ""
+}
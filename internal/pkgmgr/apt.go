@@ -0,0 +1,112 @@
+package pkgmgr
+
+import (
+	"strings"
+
+	"github.com/stwalsh4118/phanes/internal/aptrepo"
+)
+
+// apt manages packages on Debian and Ubuntu based systems.
+type apt struct{}
+
+func (a *apt) Name() string {
+	return NameApt
+}
+
+func (a *apt) Refresh() error {
+	return track(NameApt, "update", nil, func() error {
+		return runAptGet(append(aptOptions(), "update")...)
+	})
+}
+
+func (a *apt) Install(packages ...string) error {
+	names := resolve(NameApt, packages)
+	if len(names) == 0 {
+		return nil
+	}
+	return track(NameApt, "install", names, func() error {
+		return runAptGet(append(append(aptOptions(), "install", "-y"), names...)...)
+	})
+}
+
+func (a *apt) Remove(packages ...string) error {
+	names := resolve(NameApt, packages)
+	if len(names) == 0 {
+		return nil
+	}
+	return track(NameApt, "remove", names, func() error {
+		return runAptGet(append(append(aptOptions(), "remove", "-y"), names...)...)
+	})
+}
+
+// runAptGet runs apt-get once no other process holds the apt and dpkg locks.
+// apt-get waits for the rest of the lock timeout if a lock is taken again
+// before it starts.
+func runAptGet(args ...string) error {
+	remaining, err := waitForAptLock()
+	if err != nil {
+		return err
+	}
+	return runCommand("apt-get", append(lockTimeoutOption(remaining), args...)...)
+}
+
+func (a *apt) IsInstalled(pkg string) (bool, string, error) {
+	var version string
+	for i, name := range resolve(NameApt, []string{pkg}) {
+		// dpkg-query exits non-zero for unknown packages
+		output, err := runOutput("dpkg-query", "-W", "-f=${Status} ${Version}", name)
+		if err != nil {
+			return false, "", nil
+		}
+		installed, v := parseDpkgStatus(output)
+		if !installed {
+			return false, "", nil
+		}
+		if i == 0 {
+			version = v
+		}
+	}
+	return true, version, nil
+}
+
+func (a *apt) AddRepository(repo Repository) error {
+	aptRepo := toAptRepo(repo)
+	return track(NameApt, "add repository", []string{repo.Name}, func() error {
+		_, err := aptrepo.Add(aptRepo)
+		return err
+	})
+}
+
+func (a *apt) RemoveRepository(repo Repository) error {
+	_, err := aptrepo.Remove(repo.Name)
+	return err
+}
+
+// toAptRepo converts repo to an apt repository definition.
+func toAptRepo(repo Repository) aptrepo.Repo {
+	aptRepo := aptrepo.Repo{
+		Name:        repo.Name,
+		URL:         repo.URL,
+		Suites:      []string{repo.Suite},
+		Components:  repo.Components,
+		KeyURL:      repo.KeyURL,
+		Fingerprint: repo.Fingerprint,
+	}
+	if repo.Arch != "" {
+		aptRepo.Architectures = []string{repo.Arch}
+	}
+	return aptRepo
+}
+
+// parseDpkgStatus parses dpkg-query output in the format "${Status} ${Version}"
+// (e.g., "install ok installed 1.24.0-2ubuntu7").
+func parseDpkgStatus(output string) (bool, string) {
+	fields := strings.Fields(output)
+	if len(fields) < 3 || fields[2] != "installed" {
+		return false, ""
+	}
+	if len(fields) > 3 {
+		return true, fields[3]
+	}
+	return true, ""
+}
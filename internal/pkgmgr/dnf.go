@@ -0,0 +1,91 @@
+package pkgmgr
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/stwalsh4118/phanes/internal/exec"
+)
+
+const dnfReposDir = "/etc/yum.repos.d"
+
+// dnf manages packages on Fedora and RHEL based systems (RHEL, Rocky, Alma, CentOS).
+type dnf struct{}
+
+func (d *dnf) Name() string {
+	return NameDnf
+}
+
+func (d *dnf) Refresh() error {
+	return track(NameDnf, "makecache", nil, func() error {
+		return runCommand("dnf", "makecache")
+	})
+}
+
+func (d *dnf) Install(packages ...string) error {
+	names := resolve(NameDnf, packages)
+	if len(names) == 0 {
+		return nil
+	}
+	return track(NameDnf, "install", names, func() error {
+		return runCommand("dnf", append([]string{"install", "-y"}, names...)...)
+	})
+}
+
+func (d *dnf) Remove(packages ...string) error {
+	names := resolve(NameDnf, packages)
+	if len(names) == 0 {
+		return nil
+	}
+	return track(NameDnf, "remove", names, func() error {
+		return runCommand("dnf", append([]string{"remove", "-y"}, names...)...)
+	})
+}
+
+func (d *dnf) IsInstalled(pkg string) (bool, string, error) {
+	var version string
+	for i, name := range resolve(NameDnf, []string{pkg}) {
+		// rpm -q exits non-zero if the package is not installed
+		output, err := runOutput("rpm", "-q", "--qf", "%{VERSION}-%{RELEASE}", name)
+		if err != nil {
+			return false, "", nil
+		}
+		if i == 0 {
+			version = strings.TrimSpace(output)
+		}
+	}
+	return true, version, nil
+}
+
+func (d *dnf) AddRepository(repo Repository) error {
+	path := fmt.Sprintf("%s/%s.repo", dnfReposDir, repo.Name)
+	if err := exec.WriteFile(path, []byte(dnfRepoFile(repo)), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+func (d *dnf) RemoveRepository(repo Repository) error {
+	path := fmt.Sprintf("%s/%s.repo", dnfReposDir, repo.Name)
+	if err := os.Remove(exec.Path(path)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return nil
+}
+
+// dnfRepoFile builds the contents of a .repo file for repo.
+func dnfRepoFile(repo Repository) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s]\n", repo.Name)
+	fmt.Fprintf(&b, "name=%s\n", repo.Name)
+	fmt.Fprintf(&b, "baseurl=%s\n", repo.URL)
+	b.WriteString("enabled=1\n")
+	if repo.KeyURL != "" {
+		b.WriteString("gpgcheck=1\n")
+		fmt.Fprintf(&b, "gpgkey=%s\n", repo.KeyURL)
+	} else {
+		b.WriteString("gpgcheck=0\n")
+	}
+	return b.String()
+}
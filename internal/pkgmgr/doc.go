@@ -0,0 +1,33 @@
+// Package pkgmgr provides a package manager abstraction over apt, dnf, and apk.
+// The backend is selected from the detected operating system, so modules can
+// install packages without hard-coding apt-get.
+//
+// Package names are given using their Debian/Ubuntu names. Each backend maps
+// them to the distribution's equivalent (e.g., "build-essential" becomes
+// "gcc gcc-c++ make" on dnf and "build-base" on apk). Names without a mapping
+// are used unchanged.
+//
+// Key Features:
+//   - Refresh package indexes, install and remove packages
+//   - Installed checks that also report the installed version
+//   - Third-party repositories (deb822 apt sources with pinned signing keys,
+//     yum repo files, apk repositories)
+//   - Per-distro package name mappings
+//   - Package operations are recorded as timing steps
+//
+// Usage:
+//
+//	pm, err := pkgmgr.Detect()
+//	if err != nil {
+//	    return err // unsupported OS
+//	}
+//
+//	if err := pm.Refresh(); err != nil {
+//	    return fmt.Errorf("failed to refresh package indexes: %w", err)
+//	}
+//	if err := pm.Install("nginx"); err != nil {
+//	    return fmt.Errorf("failed to install nginx: %w", err)
+//	}
+//
+//	installed, version, err := pm.IsInstalled("nginx")
+package pkgmgr
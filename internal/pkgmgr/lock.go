@@ -0,0 +1,115 @@
+package pkgmgr
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/stwalsh4118/phanes/internal/config"
+	"github.com/stwalsh4118/phanes/internal/exec"
+	"github.com/stwalsh4118/phanes/internal/log"
+)
+
+// aptLockFiles are the files apt and dpkg lock while they change packages or
+// package indexes.
+var aptLockFiles = []string{
+	"/var/lib/dpkg/lock-frontend",
+	"/var/lib/dpkg/lock",
+	"/var/lib/apt/lists/lock",
+	"/var/cache/apt/archives/lock",
+}
+
+var (
+	// lockTimeout is how long package operations wait for locks held by
+	// other processes.
+	lockTimeout = config.DefaultPackageLockTimeout
+
+	// lockPollInterval is the time between lock checks, and
+	// lockProgressInterval the time between progress messages while waiting.
+	lockPollInterval     = 2 * time.Second
+	lockProgressInterval = 30 * time.Second
+
+	// aptLockHolder finds a held lock. It is a variable so tests can simulate
+	// other processes.
+	aptLockHolder = heldAptLock
+)
+
+// SetLockTimeout sets how long package operations wait for package manager
+// locks held by other processes (e.g., unattended-upgrades or another apt-get)
+// before failing. It should be called once before modules run.
+func SetLockTimeout(timeout time.Duration) {
+	lockTimeout = timeout
+}
+
+// waitForAptLock waits until no other process holds an apt or dpkg lock,
+// logging progress, and returns how much of the lock timeout is left. It fails
+// if a lock is still held after the lock timeout.
+func waitForAptLock() (time.Duration, error) {
+	start := time.Now()
+	deadline := start.Add(lockTimeout)
+	path, pid := aptLockHolder()
+	if path == "" {
+		return time.Until(deadline), nil
+	}
+
+	var lastProgress time.Time
+	for path != "" {
+		holder := processName(pid)
+		if !time.Now().Before(deadline) {
+			return 0, fmt.Errorf("timed out after %s waiting for %s held by %s", lockTimeout, path, holder)
+		}
+		if time.Since(lastProgress) >= lockProgressInterval {
+			log.Info("Waiting for %s held by %s (waited %s, timeout %s)", path, holder, time.Since(start).Round(time.Second), lockTimeout)
+			lastProgress = time.Now()
+		}
+		time.Sleep(lockPollInterval)
+		path, pid = aptLockHolder()
+	}
+	log.Info("Package manager lock released after %s", time.Since(start).Round(time.Second))
+	return time.Until(deadline), nil
+}
+
+// lockTimeoutOption returns the apt-get option that makes apt and dpkg wait up
+// to remaining for their locks. Another process can take a lock between the
+// last check and apt-get starting, so apt-get keeps waiting itself.
+func lockTimeoutOption(remaining time.Duration) []string {
+	seconds := int(math.Ceil(remaining.Seconds()))
+	if seconds < 0 {
+		seconds = 0
+	}
+	return []string{"-o", fmt.Sprintf("DPkg::Lock::Timeout=%d", seconds)}
+}
+
+// heldAptLock returns the first apt or dpkg lock file held by another process
+// and the process ID, or an empty path if none is held. dpkg and apt use
+// fcntl record locks, which F_GETLK reports without taking them.
+func heldAptLock() (string, int) {
+	for _, path := range aptLockFiles {
+		f, err := os.Open(exec.Path(path))
+		if err != nil {
+			continue
+		}
+		lk := syscall.Flock_t{Type: syscall.F_WRLCK}
+		err = syscall.FcntlFlock(f.Fd(), syscall.F_GETLK, &lk)
+		f.Close()
+		if err == nil && lk.Type != syscall.F_UNLCK {
+			return path, int(lk.Pid)
+		}
+	}
+	return "", 0
+}
+
+// processName describes the process pid (e.g., "unattended-upgr (pid 812)").
+func processName(pid int) string {
+	if pid <= 0 {
+		return "another process"
+	}
+	comm, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pid))
+	if err != nil {
+		return fmt.Sprintf("pid %d", pid)
+	}
+	return fmt.Sprintf("%s (pid %d)", strings.TrimSpace(string(comm)), pid)
+}
@@ -0,0 +1,133 @@
+package pkgmgr
+
+import (
+	"bufio"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// useLockTimings shortens the lock timings for the duration of a test.
+func useLockTimings(t *testing.T, timeout time.Duration) {
+	t.Helper()
+	origTimeout, origPoll, origHolder := lockTimeout, lockPollInterval, aptLockHolder
+	lockTimeout = timeout
+	lockPollInterval = time.Millisecond
+	t.Cleanup(func() {
+		lockTimeout, lockPollInterval, aptLockHolder = origTimeout, origPoll, origHolder
+	})
+}
+
+func TestWaitForAptLock(t *testing.T) {
+	useLockTimings(t, time.Minute)
+	checks := 0
+	aptLockHolder = func() (string, int) {
+		checks++
+		if checks < 3 {
+			return "/var/lib/dpkg/lock-frontend", 812
+		}
+		return "", 0
+	}
+
+	remaining, err := waitForAptLock()
+	if err != nil {
+		t.Fatalf("waitForAptLock() error = %v", err)
+	}
+	if remaining <= 0 || remaining > time.Minute {
+		t.Errorf("waitForAptLock() remaining = %s, want part of the timeout", remaining)
+	}
+	if checks != 3 {
+		t.Errorf("lock checked %d times, want 3", checks)
+	}
+}
+
+func TestWaitForAptLock_Timeout(t *testing.T) {
+	useLockTimings(t, 10*time.Millisecond)
+	aptLockHolder = func() (string, int) { return "/var/lib/dpkg/lock-frontend", 0 }
+
+	_, err := waitForAptLock()
+	if err == nil || !strings.Contains(err.Error(), "timed out") || !strings.Contains(err.Error(), "/var/lib/dpkg/lock-frontend") {
+		t.Errorf("waitForAptLock() error = %v, want a timeout naming the lock", err)
+	}
+}
+
+func TestRunAptGet_WaitsForLock(t *testing.T) {
+	r := record(t, nil)
+	useLockTimings(t, 10*time.Millisecond)
+	aptLockHolder = func() (string, int) { return "/var/lib/apt/lists/lock", 0 }
+
+	if err := (&apt{}).Refresh(); err == nil {
+		t.Error("Refresh() with a held lock should fail")
+	}
+	if len(r.commands) != 0 {
+		t.Errorf("apt-get ran while the lock was held: %v", r.commands)
+	}
+}
+
+// TestHeldAptLock_Helper holds a record lock on PHANES_TEST_LOCK until stdin
+// is closed. It only runs as a subprocess of TestHeldAptLock, since record
+// locks never conflict within one process.
+func TestHeldAptLock_Helper(t *testing.T) {
+	path := os.Getenv("PHANES_TEST_LOCK")
+	if path == "" {
+		t.Skip("helper process")
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lk := syscall.Flock_t{Type: syscall.F_WRLCK}
+	if err := syscall.FcntlFlock(f.Fd(), syscall.F_SETLK, &lk); err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout.WriteString("locked\n")
+	bufio.NewReader(os.Stdin).ReadString('\n')
+}
+
+func TestHeldAptLock(t *testing.T) {
+	dir := t.TempDir()
+	free := filepath.Join(dir, "lock-frontend")
+	held := filepath.Join(dir, "lock")
+	for _, path := range []string{free, held} {
+		if err := os.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	orig := aptLockFiles
+	aptLockFiles = []string{free, filepath.Join(dir, "missing"), held}
+	t.Cleanup(func() { aptLockFiles = orig })
+
+	if path, _ := heldAptLock(); path != "" {
+		t.Fatalf("heldAptLock() without holders = %s, want none", path)
+	}
+
+	cmd := osexec.Command(os.Args[0], "-test.run=^TestHeldAptLock_Helper$")
+	cmd.Env = append(os.Environ(), "PHANES_TEST_LOCK="+held)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		stdin.Close()
+		cmd.Wait()
+	}()
+	if line, _ := bufio.NewReader(stdout).ReadString('\n'); line != "locked\n" {
+		t.Fatalf("helper did not take the lock: %q", line)
+	}
+
+	path, pid := heldAptLock()
+	if path != held || pid != cmd.Process.Pid {
+		t.Errorf("heldAptLock() = %s, %d, want %s, %d", path, pid, held, cmd.Process.Pid)
+	}
+}
@@ -0,0 +1,231 @@
+package pkgmgr
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/stwalsh4118/phanes/internal/aptrepo"
+	"github.com/stwalsh4118/phanes/internal/exec"
+)
+
+// localListName is the sources list written into a local repository directory.
+const localListName = "phanes-local.list"
+
+var (
+	// System apt sources copied by DownloadPackages. They are variables so
+	// tests can use a temporary directory.
+	aptSourceList  = "/etc/apt/sources.list"
+	aptSourceParts = "/etc/apt/sources.list.d"
+
+	// localRepoList is the sources list apt is restricted to while a local
+	// repository is in use.
+	localRepoList string
+)
+
+// UseLocalRepository makes apt refresh and install packages only from the flat
+// repository in dir (.deb files with a Packages index, see WriteIndex), e.g.
+// from an extracted offline bundle. The system's own sources are ignored but
+// left unchanged. An empty dir restores the system's sources.
+func UseLocalRepository(dir string) error {
+	if dir == "" {
+		localRepoList = ""
+		return nil
+	}
+
+	// The packages were verified against their repositories' signatures when
+	// they were downloaded, and the Packages index pins their checksums
+	list := filepath.Join(dir, localListName)
+	entry := fmt.Sprintf("deb [trusted=yes] file:%s ./\n", dir)
+	if err := exec.WriteFile(list, []byte(entry), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", list, err)
+	}
+	localRepoList = list
+	return nil
+}
+
+// aptOptions returns the apt-get options for the active sources.
+func aptOptions() []string {
+	if localRepoList == "" {
+		return nil
+	}
+	return []string{
+		"-o", "Dir::Etc::SourceList=" + localRepoList,
+		"-o", "Dir::Etc::SourceParts=-",
+		// Keep the package lists of the ignored sources for later online runs
+		"-o", "APT::Get::List-Cleanup=0",
+	}
+}
+
+// DownloadPackages downloads packages (Debian names) and all their
+// dependencies as .deb files into dir. Packages come from the system's apt
+// sources plus repos. Nothing is installed and the system's apt state is not
+// changed.
+//
+// Dependencies are resolved as if nothing were installed, so the packages can
+// be installed on any system with the same release and architecture.
+func DownloadPackages(dir string, repos []Repository, packages ...string) error {
+	if !exec.CommandExists("apt-get") {
+		return fmt.Errorf("downloading packages requires apt-get (Debian or Ubuntu)")
+	}
+	names := resolve(NameApt, packages)
+	if len(names) == 0 {
+		return nil
+	}
+
+	work, err := os.MkdirTemp("", "phanes-apt-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(work)
+
+	sourceList := filepath.Join(work, "sources.list")
+	sourceParts := filepath.Join(work, "sources.list.d")
+	keyrings := filepath.Join(work, "keyrings")
+	for _, d := range []string{sourceParts, keyrings, filepath.Join(work, "state", "lists", "partial"), filepath.Join(work, "cache"), filepath.Join(dir, "partial")} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", d, err)
+		}
+	}
+
+	if err := copySystemSources(sourceList, sourceParts, repos); err != nil {
+		return err
+	}
+	for _, repo := range repos {
+		aptRepo := toAptRepo(repo)
+		keyring := filepath.Join(keyrings, repo.Name+".gpg")
+		if repo.KeyURL != "" {
+			data, err := aptrepo.FetchKeyring(aptRepo)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(keyring, data, 0644); err != nil {
+				return fmt.Errorf("failed to write %s signing key: %w", repo.Name, err)
+			}
+		}
+		sources := aptrepo.RenderWithKeyring(aptRepo, keyring)
+		if err := os.WriteFile(filepath.Join(sourceParts, repo.Name+".sources"), []byte(sources), 0644); err != nil {
+			return fmt.Errorf("failed to write %s sources: %w", repo.Name, err)
+		}
+	}
+
+	// An empty status file makes apt download every dependency
+	status := filepath.Join(work, "status")
+	if err := os.WriteFile(status, nil, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", status, err)
+	}
+
+	options := []string{
+		"-o", "Dir::Etc::SourceList=" + sourceList,
+		"-o", "Dir::Etc::SourceParts=" + sourceParts,
+		"-o", "Dir::State=" + filepath.Join(work, "state"),
+		"-o", "Dir::State::status=" + status,
+		"-o", "Dir::Cache=" + filepath.Join(work, "cache"),
+		"-o", "Dir::Cache::archives=" + dir,
+		"-o", "APT::Sandbox::User=root",
+	}
+	if err := track(NameApt, "update", nil, func() error {
+		return runAptGet(append(options, "update")...)
+	}); err != nil {
+		return fmt.Errorf("failed to update package lists: %w", err)
+	}
+	if err := track(NameApt, "download", names, func() error {
+		return runAptGet(append(append(options, "install", "--download-only", "-y"), names...)...)
+	}); err != nil {
+		return fmt.Errorf("failed to download packages: %w", err)
+	}
+
+	// Leftovers of apt's download directory
+	os.RemoveAll(filepath.Join(dir, "partial"))
+	os.Remove(filepath.Join(dir, "lock"))
+	return nil
+}
+
+// copySystemSources copies the system's apt sources into sourceList and
+// sourceParts, except those of repos, which are written separately.
+func copySystemSources(sourceList, sourceParts string, repos []Repository) error {
+	content, err := os.ReadFile(aptSourceList)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", aptSourceList, err)
+	}
+	if err := os.WriteFile(sourceList, content, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", sourceList, err)
+	}
+
+	skip := make(map[string]bool)
+	for _, repo := range repos {
+		skip[repo.Name+".list"] = true
+		skip[repo.Name+".sources"] = true
+	}
+
+	entries, err := os.ReadDir(aptSourceParts)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", aptSourceParts, err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || skip[name] || (!strings.HasSuffix(name, ".list") && !strings.HasSuffix(name, ".sources")) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(aptSourceParts, name))
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(sourceParts, name), content, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// WriteIndex writes the Packages index of the .deb files in dir, making dir a
+// flat apt repository.
+func WriteIndex(dir string) error {
+	debs, err := filepath.Glob(filepath.Join(dir, "*.deb"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(debs)
+
+	var b strings.Builder
+	for _, deb := range debs {
+		control, err := runOutput("dpkg-deb", "--field", deb)
+		if err != nil {
+			return fmt.Errorf("failed to read control fields of %s: %w", filepath.Base(deb), err)
+		}
+		size, sum, err := checksumFile(deb)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", deb, err)
+		}
+
+		b.WriteString(strings.TrimRight(control, "\n"))
+		fmt.Fprintf(&b, "\nFilename: ./%s\nSize: %d\nSHA256: %s\n\n", filepath.Base(deb), size, sum)
+	}
+
+	path := filepath.Join(dir, "Packages")
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// checksumFile returns the size and hex-encoded SHA-256 checksum of a file.
+func checksumFile(path string) (int64, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,132 @@
+package pkgmgr
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUseLocalRepository(t *testing.T) {
+	dir := t.TempDir()
+	if err := UseLocalRepository(dir); err != nil {
+		t.Fatalf("UseLocalRepository() error = %v", err)
+	}
+	t.Cleanup(func() { UseLocalRepository("") })
+
+	list := filepath.Join(dir, localListName)
+	content, err := os.ReadFile(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "deb [trusted=yes] file:" + dir + " ./\n"; string(content) != want {
+		t.Errorf("sources list = %q, want %q", content, want)
+	}
+
+	r := record(t, nil)
+	pm := &apt{}
+	if err := pm.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if err := pm.Install("git"); err != nil {
+		t.Fatal(err)
+	}
+	options := "-o DPkg::Lock::Timeout=600 -o Dir::Etc::SourceList=" + list + " -o Dir::Etc::SourceParts=- -o APT::Get::List-Cleanup=0"
+	want := []string{
+		"apt-get " + options + " update",
+		"apt-get " + options + " install -y git",
+	}
+	if !reflect.DeepEqual(r.commands, want) {
+		t.Errorf("commands = %q, want %q", r.commands, want)
+	}
+
+	UseLocalRepository("")
+	if options := aptOptions(); options != nil {
+		t.Errorf("aptOptions() after reset = %q, want none", options)
+	}
+}
+
+func TestCopySystemSources(t *testing.T) {
+	system := t.TempDir()
+	origList, origParts := aptSourceList, aptSourceParts
+	aptSourceList = filepath.Join(system, "sources.list")
+	aptSourceParts = filepath.Join(system, "sources.list.d")
+	t.Cleanup(func() { aptSourceList, aptSourceParts = origList, origParts })
+
+	if err := os.MkdirAll(aptSourceParts, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"ubuntu.sources":  "Types: deb\n",
+		"docker.sources":  "Types: deb\n",
+		"docker.list":     "deb https://old\n",
+		"notes.txt":       "not a source\n",
+		"ppa.list":        "deb https://ppa\n",
+		"ppa.list.save":   "deb https://ppa\n",
+		"ubuntu.sources~": "backup\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(aptSourceParts, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	work := t.TempDir()
+	parts := filepath.Join(work, "sources.list.d")
+	if err := os.MkdirAll(parts, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := copySystemSources(filepath.Join(work, "sources.list"), parts, []Repository{{Name: "docker"}}); err != nil {
+		t.Fatalf("copySystemSources() error = %v", err)
+	}
+
+	entries, err := os.ReadDir(parts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if want := []string{"ppa.list", "ubuntu.sources"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("copied sources = %q, want %q", names, want)
+	}
+
+	// A missing sources.list is copied as an empty file
+	if content, err := os.ReadFile(filepath.Join(work, "sources.list")); err != nil || len(content) != 0 {
+		t.Errorf("sources.list = %q, %v, want empty", content, err)
+	}
+}
+
+func TestWriteIndex(t *testing.T) {
+	dir := t.TempDir()
+	deb := filepath.Join(dir, "hello_1.0_amd64.deb")
+	if err := os.WriteFile(deb, []byte("deb"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	record(t, map[string]string{
+		"dpkg-deb --field " + deb: "Package: hello\nVersion: 1.0\nArchitecture: amd64\n",
+	})
+
+	if err := WriteIndex(dir); err != nil {
+		t.Fatalf("WriteIndex() error = %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "Packages"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"Package: hello",
+		"Version: 1.0",
+		"Architecture: amd64",
+		"Filename: ./hello_1.0_amd64.deb",
+		"Size: 3",
+		// sha256 of "deb"
+		"SHA256: 9cfa1468c93fc18652e34a000f0c6614b0fa18f6f4887477ad9b0d36ca6a7eaa",
+		"", "",
+	}, "\n")
+	if string(content) != want {
+		t.Errorf("Packages =\n%s\nwant\n%s", content, want)
+	}
+}
@@ -0,0 +1,193 @@
+package pkgmgr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/stwalsh4118/phanes/internal/exec"
+	"github.com/stwalsh4118/phanes/internal/osinfo"
+	"github.com/stwalsh4118/phanes/internal/timing"
+)
+
+// Backend names returned by Manager.Name().
+const (
+	NameApt = "apt"
+	NameDnf = "dnf"
+	NameApk = "apk"
+)
+
+// Command runners. They are variables so tests can record commands instead of running them.
+var (
+	runCommand = exec.Run
+	runOutput  = exec.RunWithOutput
+)
+
+// Repository describes a third-party package repository.
+// Which fields are used depends on the backend.
+type Repository struct {
+	// Name identifies the repository. It is used for file names
+	// (e.g., /etc/apt/sources.list.d/<name>.sources, /etc/yum.repos.d/<name>.repo).
+	Name string
+
+	// URL is the repository base URL.
+	URL string
+
+	// KeyURL is the URL of the repository signing key.
+	KeyURL string
+
+	// Fingerprint is the pinned fingerprint of the signing key. apt only,
+	// where it is required when KeyURL is set; dnf verifies packages against
+	// the key at KeyURL.
+	Fingerprint string
+
+	// Suite is the apt distribution (e.g., "noble", "bookworm-pgdg"). apt only.
+	Suite string
+
+	// Components are the apt components (e.g., ["main"], ["stable"]). apt only.
+	Components []string
+
+	// Arch restricts an apt repository to an architecture (e.g., "amd64"). apt only.
+	Arch string
+}
+
+// Manager installs and queries system packages.
+type Manager interface {
+	// Name returns the backend name ("apt", "dnf", or "apk").
+	Name() string
+
+	// Refresh updates the package indexes.
+	Refresh() error
+
+	// Install installs the given packages (Debian names; mapped per backend).
+	Install(packages ...string) error
+
+	// Remove removes the given packages (Debian names; mapped per backend).
+	Remove(packages ...string) error
+
+	// IsInstalled reports whether pkg (Debian name; mapped per backend) is
+	// installed, and its installed version. If pkg maps to several packages,
+	// all must be installed and the version of the first is returned.
+	IsInstalled(pkg string) (bool, string, error)
+
+	// AddRepository configures a third-party repository.
+	// Callers should Refresh() afterwards.
+	AddRepository(repo Repository) error
+
+	// RemoveRepository removes a repository added with AddRepository,
+	// including its signing key.
+	RemoveRepository(repo Repository) error
+}
+
+// aliases maps Debian package names to their equivalents on other backends.
+// An empty slice means the package is not needed on that backend
+// (its functionality is part of another package).
+var aliases = map[string]map[string][]string{
+	"build-essential": {
+		NameDnf: {"gcc", "gcc-c++", "make"},
+		NameApk: {"build-base"},
+	},
+	"redis-server": {
+		NameDnf: {"redis"},
+		NameApk: {"redis"},
+	},
+	"python3-venv": {
+		NameDnf: {},
+		NameApk: {},
+	},
+	"python3-pip": {
+		NameApk: {"py3-pip"},
+	},
+	"docker-ce": {
+		NameApk: {"docker"},
+	},
+	"docker-ce-cli": {
+		NameApk: {},
+	},
+	"containerd.io": {
+		NameApk: {},
+	},
+	"docker-buildx-plugin": {
+		NameApk: {"docker-cli-buildx"},
+	},
+	"docker-compose-plugin": {
+		NameApk: {"docker-cli-compose"},
+	},
+	// apt-only helpers for third-party repositories
+	"debian-keyring": {
+		NameDnf: {},
+		NameApk: {},
+	},
+	"debian-archive-keyring": {
+		NameDnf: {},
+		NameApk: {},
+	},
+	"apt-transport-https": {
+		NameDnf: {},
+		NameApk: {},
+	},
+}
+
+// resolve maps Debian package names to the names used by backend.
+func resolve(backend string, packages []string) []string {
+	resolved := make([]string, 0, len(packages))
+	for _, pkg := range packages {
+		if names, ok := aliases[pkg][backend]; ok {
+			resolved = append(resolved, names...)
+			continue
+		}
+		resolved = append(resolved, pkg)
+	}
+	return resolved
+}
+
+// Detect returns the package manager for the running operating system.
+// Returns an osinfo.UnsupportedError if no backend supports the OS.
+func Detect() (Manager, error) {
+	info, err := osinfo.Detect()
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect operating system: %w", err)
+	}
+	return ForOS(info)
+}
+
+// ForOS returns the package manager for the given operating system.
+// Returns an osinfo.UnsupportedError if no backend supports the OS.
+func ForOS(info *osinfo.Info) (Manager, error) {
+	switch {
+	case info.Is(osinfo.IDDebian, osinfo.IDUbuntu):
+		return &apt{}, nil
+	case info.Is("fedora", "rhel", "centos", "rocky", "almalinux"):
+		return &dnf{}, nil
+	case info.Is("alpine"):
+		return &apk{}, nil
+	default:
+		return nil, &osinfo.UnsupportedError{OS: info.PrettyName, Reason: "no supported package manager (apt, dnf, or apk)"}
+	}
+}
+
+// Require returns an UnsupportedError unless pm is one of the given backends.
+// Modules use it to fail early when they rely on backend-specific behavior.
+func Require(pm Manager, feature string, backends ...string) error {
+	for _, backend := range backends {
+		if pm.Name() == backend {
+			return nil
+		}
+	}
+	osName := pm.Name()
+	if info, err := osinfo.Detect(); err == nil {
+		osName = info.PrettyName
+	}
+	return &osinfo.UnsupportedError{
+		OS:     osName,
+		Reason: fmt.Sprintf("%s requires %s", feature, strings.Join(backends, " or ")),
+	}
+}
+
+// track runs a package operation as a named timing step.
+func track(backend, action string, packages []string, fn func() error) error {
+	name := backend + " " + action
+	if len(packages) > 0 {
+		name += " " + strings.Join(packages, " ")
+	}
+	return timing.Track(name, fn)
+}
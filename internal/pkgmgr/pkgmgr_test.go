@@ -0,0 +1,306 @@
+package pkgmgr
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stwalsh4118/phanes/internal/osinfo"
+)
+
+// recorder replaces runCommand and runOutput for the duration of a test and
+// records every command line. Package manager locks are never held. Outputs maps a command line to its output;
+// command lines without an entry fail.
+type recorder struct {
+	commands []string
+	outputs  map[string]string
+}
+
+func record(t *testing.T, outputs map[string]string) *recorder {
+	t.Helper()
+	r := &recorder{outputs: outputs}
+	origRun, origOutput, origHolder := runCommand, runOutput, aptLockHolder
+	aptLockHolder = func() (string, int) { return "", 0 }
+	runCommand = func(name string, args ...string) error {
+		r.commands = append(r.commands, strings.Join(append([]string{name}, args...), " "))
+		return nil
+	}
+	runOutput = func(name string, args ...string) (string, error) {
+		line := strings.Join(append([]string{name}, args...), " ")
+		r.commands = append(r.commands, line)
+		if output, ok := r.outputs[line]; ok {
+			return output, nil
+		}
+		return "", errors.New("exit status 1")
+	}
+	t.Cleanup(func() {
+		runCommand, runOutput, aptLockHolder = origRun, origOutput, origHolder
+	})
+	return r
+}
+
+func TestForOS(t *testing.T) {
+	tests := []struct {
+		name    string
+		info    osinfo.Info
+		want    string
+		wantErr bool
+	}{
+		{"ubuntu", osinfo.Info{ID: "ubuntu"}, NameApt, false},
+		{"debian derivative", osinfo.Info{ID: "raspbian", IDLike: []string{"debian"}}, NameApt, false},
+		{"rocky", osinfo.Info{ID: "rocky", IDLike: []string{"rhel", "centos", "fedora"}}, NameDnf, false},
+		{"almalinux", osinfo.Info{ID: "almalinux"}, NameDnf, false},
+		{"fedora", osinfo.Info{ID: "fedora"}, NameDnf, false},
+		{"alpine", osinfo.Info{ID: "alpine"}, NameApk, false},
+		{"arch", osinfo.Info{ID: "arch", PrettyName: "Arch Linux"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm, err := ForOS(&tt.info)
+			if tt.wantErr {
+				var unsupported *osinfo.UnsupportedError
+				if !errors.As(err, &unsupported) {
+					t.Fatalf("ForOS() error = %v, want UnsupportedError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ForOS() error = %v", err)
+			}
+			if pm.Name() != tt.want {
+				t.Errorf("ForOS().Name() = %q, want %q", pm.Name(), tt.want)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		backend  string
+		packages []string
+		want     []string
+	}{
+		{NameApt, []string{"build-essential", "redis-server"}, []string{"build-essential", "redis-server"}},
+		{NameDnf, []string{"build-essential", "git"}, []string{"gcc", "gcc-c++", "make", "git"}},
+		{NameApk, []string{"build-essential", "python3-pip"}, []string{"build-base", "py3-pip"}},
+		{NameDnf, []string{"python3", "python3-venv"}, []string{"python3"}},
+		{NameApk, []string{"redis-server"}, []string{"redis"}},
+	}
+
+	for _, tt := range tests {
+		got := resolve(tt.backend, tt.packages)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("resolve(%s, %v) = %v, want %v", tt.backend, tt.packages, got, tt.want)
+		}
+	}
+}
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		pm   Manager
+		run  func(Manager) error
+		want []string
+	}{
+		{"apt refresh", &apt{}, Manager.Refresh, []string{"apt-get -o DPkg::Lock::Timeout=600 update"}},
+		{"dnf refresh", &dnf{}, Manager.Refresh, []string{"dnf makecache"}},
+		{"apk refresh", &apk{}, Manager.Refresh, []string{"apk update"}},
+		{
+			"apt install", &apt{},
+			func(pm Manager) error { return pm.Install("git", "build-essential") },
+			[]string{"apt-get -o DPkg::Lock::Timeout=600 install -y git build-essential"},
+		},
+		{
+			"dnf install", &dnf{},
+			func(pm Manager) error { return pm.Install("git", "build-essential") },
+			[]string{"dnf install -y git gcc gcc-c++ make"},
+		},
+		{
+			"apk install", &apk{},
+			func(pm Manager) error { return pm.Install("git", "build-essential") },
+			[]string{"apk add git build-base"},
+		},
+		{
+			"apt remove", &apt{},
+			func(pm Manager) error { return pm.Remove("nginx") },
+			[]string{"apt-get -o DPkg::Lock::Timeout=600 remove -y nginx"},
+		},
+		{
+			"dnf remove", &dnf{},
+			func(pm Manager) error { return pm.Remove("nginx") },
+			[]string{"dnf remove -y nginx"},
+		},
+		{
+			"apk remove", &apk{},
+			func(pm Manager) error { return pm.Remove("nginx") },
+			[]string{"apk del nginx"},
+		},
+		{
+			"install with nothing to do", &dnf{},
+			func(pm Manager) error { return pm.Install("python3-venv") },
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := record(t, nil)
+			if err := tt.run(tt.pm); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(r.commands, tt.want) {
+				t.Errorf("commands = %q, want %q", r.commands, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsInstalled(t *testing.T) {
+	tests := []struct {
+		name          string
+		pm            Manager
+		pkg           string
+		outputs       map[string]string
+		wantInstalled bool
+		wantVersion   string
+	}{
+		{
+			name:          "apt installed",
+			pm:            &apt{},
+			pkg:           "nginx",
+			outputs:       map[string]string{"dpkg-query -W -f=${Status} ${Version} nginx": "install ok installed 1.24.0-2ubuntu7"},
+			wantInstalled: true,
+			wantVersion:   "1.24.0-2ubuntu7",
+		},
+		{
+			name:    "apt removed but config remains",
+			pm:      &apt{},
+			pkg:     "nginx",
+			outputs: map[string]string{"dpkg-query -W -f=${Status} ${Version} nginx": "deinstall ok config-files 1.24.0-2ubuntu7"},
+		},
+		{
+			name: "apt unknown package",
+			pm:   &apt{},
+			pkg:  "nginx",
+		},
+		{
+			name:          "dnf installed",
+			pm:            &dnf{},
+			pkg:           "redis-server",
+			outputs:       map[string]string{"rpm -q --qf %{VERSION}-%{RELEASE} redis": "7.2.5-1.el9"},
+			wantInstalled: true,
+			wantVersion:   "7.2.5-1.el9",
+		},
+		{
+			name: "dnf not installed",
+			pm:   &dnf{},
+			pkg:  "redis-server",
+		},
+		{
+			name: "dnf partially installed",
+			pm:   &dnf{},
+			pkg:  "build-essential",
+			outputs: map[string]string{
+				"rpm -q --qf %{VERSION}-%{RELEASE} gcc":     "11.4.1-3.el9",
+				"rpm -q --qf %{VERSION}-%{RELEASE} gcc-c++": "11.4.1-3.el9",
+			},
+		},
+		{
+			name:          "dnf not needed",
+			pm:            &dnf{},
+			pkg:           "python3-venv",
+			wantInstalled: true,
+		},
+		{
+			name: "apk installed",
+			pm:   &apk{},
+			pkg:  "nginx",
+			outputs: map[string]string{"apk list --installed nginx": "nginx-mod-http-lua-1.24.0-r6 x86_64 {nginx} (BSD-2-Clause) [installed]\n" +
+				"nginx-1.24.0-r6 x86_64 {nginx} (BSD-2-Clause) [installed]\n"},
+			wantInstalled: true,
+			wantVersion:   "1.24.0-r6",
+		},
+		{
+			name:    "apk not installed",
+			pm:      &apk{},
+			pkg:     "nginx",
+			outputs: map[string]string{"apk list --installed nginx": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record(t, tt.outputs)
+			installed, version, err := tt.pm.IsInstalled(tt.pkg)
+			if err != nil {
+				t.Fatalf("IsInstalled() error = %v", err)
+			}
+			if installed != tt.wantInstalled || version != tt.wantVersion {
+				t.Errorf("IsInstalled() = (%v, %q), want (%v, %q)", installed, version, tt.wantInstalled, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestDnfRepoFile(t *testing.T) {
+	repo := Repository{
+		Name:   "docker-ce-stable",
+		URL:    "https://download.docker.com/linux/centos/$releasever/$basearch/stable",
+		KeyURL: "https://download.docker.com/linux/centos/gpg",
+	}
+	want := `[docker-ce-stable]
+name=docker-ce-stable
+baseurl=https://download.docker.com/linux/centos/$releasever/$basearch/stable
+enabled=1
+gpgcheck=1
+gpgkey=https://download.docker.com/linux/centos/gpg
+`
+	if got := dnfRepoFile(repo); got != want {
+		t.Errorf("dnfRepoFile() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestApkRepository(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "repositories")
+	if err := os.WriteFile(path, []byte("https://dl-cdn.alpinelinux.org/alpine/v3.20/main"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	orig := apkRepositoriesPath
+	apkRepositoriesPath = path
+	t.Cleanup(func() { apkRepositoriesPath = orig })
+
+	record(t, nil)
+	repo := Repository{Name: "community", URL: "https://dl-cdn.alpinelinux.org/alpine/v3.20/community"}
+	for i := 0; i < 2; i++ {
+		if err := (&apk{}).AddRepository(repo); err != nil {
+			t.Fatalf("AddRepository() error = %v", err)
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "https://dl-cdn.alpinelinux.org/alpine/v3.20/main\nhttps://dl-cdn.alpinelinux.org/alpine/v3.20/community\n"
+	if string(content) != want {
+		t.Errorf("repositories = %q, want %q", string(content), want)
+	}
+
+	if err := (&apk{}).RemoveRepository(repo); err != nil {
+		t.Fatalf("RemoveRepository() error = %v", err)
+	}
+	content, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "https://dl-cdn.alpinelinux.org/alpine/v3.20/main\n"
+	if string(content) != want {
+		t.Errorf("repositories after removal = %q, want %q", string(content), want)
+	}
+}
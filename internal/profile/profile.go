@@ -108,3 +108,23 @@ func ProfileExists(name string) bool {
 	return exists
 }
 
+// ProfilesContaining returns a sorted list of the profiles that include the
+// given module. Returns an empty slice if no profile includes it.
+//
+// Example:
+//
+//	profiles := profile.ProfilesContaining("docker")
+//	// profiles = ["coolify", "database", "dev", "web"]
+func ProfilesContaining(moduleName string) []string {
+	names := []string{}
+	for name, modules := range profiles {
+		for _, mod := range modules {
+			if mod == moduleName {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -192,3 +192,36 @@ func TestAllProfilesAreValid(t *testing.T) {
 		}
 	}
 }
+
+func TestProfilesContaining(t *testing.T) {
+	tests := []struct {
+		name       string
+		moduleName string
+		want       []string
+	}{
+		{
+			name:       "module in every profile",
+			moduleName: "baseline",
+			want:       []string{"coolify", "database", "dev", "minimal", "web"},
+		},
+		{
+			name:       "module in some profiles",
+			moduleName: "docker",
+			want:       []string{"coolify", "database", "dev", "web"},
+		},
+		{
+			name:       "module in no profile",
+			moduleName: "tailscale",
+			want:       []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ProfilesContaining(tt.moduleName)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ProfilesContaining(%q) = %v, want %v", tt.moduleName, got, tt.want)
+			}
+		})
+	}
+}
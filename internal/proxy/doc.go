@@ -0,0 +1,25 @@
+// Package proxy applies the network.proxy configuration consistently to
+// everything phanes runs.
+//
+// Configure stores the proxy settings and exports the http_proxy, https_proxy
+// and no_proxy variables (in lower and upper case) to phanes' own environment,
+// so commands run as root, such as the Coolify and Tailscale installers,
+// inherit them. The other consumers need the settings in their own form:
+//
+//   - phanes' own HTTP clients use Transport, which proxies through FromRequest
+//   - apt ignores the environment when run through sudo or from
+//     unattended-upgrades; ConfigureApt writes AptConfigPath
+//   - the Docker daemon is started by systemd; the docker module writes
+//     DockerDropIn to DockerDropInPath
+//   - "su - user -c" starts a login shell that drops the environment;
+//     SuFlags keeps the variables without putting them on the command line
+//
+// Usage:
+//
+//	proxy.Configure(cfg.Network.Proxy)
+//	if err := proxy.ConfigureApt(); err != nil {
+//	    return err
+//	}
+//
+//	exec.Run("su", append(proxy.SuFlags(), "-", username, "-c", "sh install.sh")...)
+package proxy
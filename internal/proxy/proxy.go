@@ -0,0 +1,267 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/stwalsh4118/phanes/internal/config"
+	"github.com/stwalsh4118/phanes/internal/exec"
+	"github.com/stwalsh4118/phanes/internal/files"
+	"github.com/stwalsh4118/phanes/internal/log"
+)
+
+const (
+	// AptConfigPath is the apt configuration file with the proxy settings.
+	AptConfigPath = "/etc/apt/apt.conf.d/95phanes-proxy"
+
+	// DockerDropInPath is the systemd drop-in with the Docker daemon's proxy
+	// environment.
+	DockerDropInPath = "/etc/systemd/system/docker.service.d/http-proxy.conf"
+
+	aptConfigDir = "/etc/apt/apt.conf.d"
+)
+
+var (
+	mu       sync.RWMutex
+	settings config.Proxy
+
+	// setenv is a variable so tests can leave the environment unchanged.
+	setenv = os.Setenv
+)
+
+// Configure applies the proxy settings from the configuration and exports them
+// to phanes' environment. It should be called once before modules run. A
+// disabled proxy leaves the environment unchanged.
+func Configure(proxy config.Proxy) {
+	if proxy.HTTPS == "" {
+		proxy.HTTPS = proxy.HTTP
+	}
+
+	mu.Lock()
+	settings = proxy
+	mu.Unlock()
+
+	if !proxy.Enabled() {
+		return
+	}
+	for _, kv := range Env() {
+		name, value, _ := strings.Cut(kv, "=")
+		setenv(name, value)
+	}
+}
+
+// current returns the active proxy settings.
+func current() config.Proxy {
+	mu.RLock()
+	defer mu.RUnlock()
+	return settings
+}
+
+// Enabled reports whether a proxy is configured.
+func Enabled() bool {
+	return current().Enabled()
+}
+
+// Env returns the proxy environment variables as NAME=value pairs, in lower
+// and upper case since tools disagree on which they read. It returns nil if no
+// proxy is configured.
+func Env() []string {
+	p := current()
+	if !p.Enabled() {
+		return nil
+	}
+
+	var env []string
+	add := func(name, value string) {
+		if value != "" {
+			env = append(env, name+"="+value, strings.ToUpper(name)+"="+value)
+		}
+	}
+	add("http_proxy", p.HTTP)
+	add("https_proxy", p.HTTPS)
+	add("no_proxy", strings.Join(p.NoProxy, ","))
+	return env
+}
+
+// SuFlags returns the su flags that keep the proxy environment for commands
+// run through "su - user -c", whose login shell otherwise starts with a clean
+// environment. The values are inherited from phanes' environment (see
+// Configure) instead of being passed on the command line, where credentials
+// would show up in ps. It returns nil if no proxy is configured.
+func SuFlags() []string {
+	env := Env()
+	if env == nil {
+		return nil
+	}
+	names := make([]string, len(env))
+	for i, kv := range env {
+		names[i], _, _ = strings.Cut(kv, "=")
+	}
+	return []string{"--whitelist-environment=" + strings.Join(names, ",")}
+}
+
+// FromRequest returns the proxy URL for a request, or nil for a direct
+// connection. Without a configured proxy it falls back to the environment. It
+// is meant for http.Transport.Proxy.
+func FromRequest(req *http.Request) (*url.URL, error) {
+	p := current()
+	if !p.Enabled() {
+		return http.ProxyFromEnvironment(req)
+	}
+
+	proxyURL := p.HTTP
+	if req.URL.Scheme == "https" {
+		proxyURL = p.HTTPS
+	}
+	if proxyURL == "" || bypass(req.URL.Hostname(), p.NoProxy) {
+		return nil, nil
+	}
+	return url.Parse(proxyURL)
+}
+
+// Transport returns an HTTP transport that connects through the proxy.
+func Transport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = FromRequest
+	return t
+}
+
+// bypass reports whether host is reached directly according to noProxy.
+func bypass(host string, noProxy []string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	ip := net.ParseIP(host)
+
+	for _, entry := range noProxy {
+		entry = strings.ToLower(entry)
+		switch {
+		case entry == "*":
+			return true
+		case strings.Contains(entry, "/"):
+			if _, network, err := net.ParseCIDR(entry); err == nil && ip != nil && network.Contains(ip) {
+				return true
+			}
+		case ip != nil:
+			if entryIP := net.ParseIP(entry); entryIP != nil && entryIP.Equal(ip) {
+				return true
+			}
+		default:
+			domain := strings.TrimPrefix(entry, ".")
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AptConfig returns the apt configuration for the proxy. Hosts in the no_proxy
+// list are reached directly; apt does not support domain and CIDR entries.
+func AptConfig() string {
+	p := current()
+
+	var b strings.Builder
+	b.WriteString("// Managed by phanes (network.proxy)\n")
+	if p.HTTP != "" {
+		fmt.Fprintf(&b, "Acquire::http::Proxy %q;\n", p.HTTP)
+	}
+	if p.HTTPS != "" {
+		fmt.Fprintf(&b, "Acquire::https::Proxy %q;\n", p.HTTPS)
+	}
+	for _, entry := range p.NoProxy {
+		host := strings.TrimPrefix(entry, ".")
+		if host == "*" || strings.Contains(host, "/") {
+			continue
+		}
+		fmt.Fprintf(&b, "Acquire::http::Proxy::%s \"DIRECT\";\n", host)
+		fmt.Fprintf(&b, "Acquire::https::Proxy::%s \"DIRECT\";\n", host)
+	}
+	return b.String()
+}
+
+// DockerDropIn returns the systemd drop-in that sets the Docker daemon's proxy
+// environment, used when pulling images.
+func DockerDropIn() string {
+	var b strings.Builder
+	b.WriteString("# Managed by phanes (network.proxy)\n[Service]\n")
+	for _, kv := range Env() {
+		name, _, _ := strings.Cut(kv, "=")
+		if name == strings.ToUpper(name) {
+			fmt.Fprintf(&b, "Environment=%q\n", kv)
+		}
+	}
+	return b.String()
+}
+
+// ConfigureApt writes the proxy settings to AptConfigPath on systems with apt,
+// or removes a file written by an earlier run if no proxy is configured. The
+// file is only readable by root when the proxy URLs contain credentials.
+func ConfigureApt() error {
+	dryRun := log.IsDryRun()
+
+	if !Enabled() {
+		if !exec.FileExists(AptConfigPath) {
+			return nil
+		}
+		if dryRun {
+			log.Info("Would remove %s (no proxy configured)", AptConfigPath)
+			return nil
+		}
+		if err := os.Remove(exec.Path(AptConfigPath)); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", AptConfigPath, err)
+		}
+		log.Success("Removed apt proxy configuration %s", AptConfigPath)
+		return nil
+	}
+
+	if !exec.FileExists(aptConfigDir) {
+		return nil
+	}
+
+	content := []byte(AptConfig())
+	if dryRun {
+		if _, err := files.PreviewMasked(AptConfigPath, content, MaskCredentials); err != nil {
+			return fmt.Errorf("failed to preview %s: %w", AptConfigPath, err)
+		}
+		return nil
+	}
+	// Proxy credentials must not be world-readable. Without them the file
+	// stays readable, since apt fails for users who cannot read its config.
+	mode := os.FileMode(0644)
+	if hasCredentials(current()) {
+		mode = 0600
+	}
+	result, err := files.Write(AptConfigPath, content, files.Options{Mode: mode})
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", AptConfigPath, err)
+	}
+	if result.Changed {
+		log.Success("Configured apt proxy in %s", AptConfigPath)
+	}
+	return nil
+}
+
+// urlPattern matches URLs in configuration files, up to quotes or whitespace.
+var urlPattern = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"']+`)
+
+// MaskCredentials masks the passwords of URLs in text, such as proxy URLs in
+// a preview of a configuration file, as in masked configuration output.
+func MaskCredentials(text string) string {
+	return urlPattern.ReplaceAllStringFunc(text, config.MaskURLPassword)
+}
+
+// hasCredentials reports whether a proxy URL of p contains a user name or
+// password.
+func hasCredentials(p config.Proxy) bool {
+	for _, raw := range []string{p.HTTP, p.HTTPS} {
+		if parsed, err := url.Parse(raw); err == nil && parsed.User != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,18 @@
+// Package reboot tracks whether the server needs a reboot for changes to take
+// effect.
+//
+// A reboot is needed when a package left /var/run/reboot-required behind
+// (Debian and Ubuntu create it for kernel, libc, and systemd updates, listing
+// the packages in /var/run/reboot-required.pkgs). Modules that cannot install
+// while such a reboot is pending implement module.BootRequirer; with
+// --reboot-if-needed the runner then reboots before installing them. These two
+// are the mechanisms modules rely on. Request records a further reason for a
+// change that leaves no such file behind.
+//
+// Usage:
+//
+//	// After the run
+//	if reasons := reboot.Reasons(); len(reasons) > 0 {
+//	    log.Warn("A reboot is required: %s", strings.Join(reasons, ", "))
+//	}
+package reboot
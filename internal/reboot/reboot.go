@@ -0,0 +1,85 @@
+package reboot
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/stwalsh4118/phanes/internal/exec"
+)
+
+// The files packages create when they need a reboot. They are variables so
+// tests can use temporary files.
+var (
+	requiredPath = "/var/run/reboot-required"
+	packagesPath = "/var/run/reboot-required.pkgs"
+)
+
+var (
+	mu       sync.Mutex
+	requests []string
+)
+
+// Request records that a change only takes effect after a reboot. reason
+// describes the change; repeated reasons are recorded once. It is only needed
+// for changes that do not create /var/run/reboot-required; no built-in module
+// makes such a change.
+func Request(reason string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, existing := range requests {
+		if existing == reason {
+			return
+		}
+	}
+	requests = append(requests, reason)
+}
+
+// Reset forgets the requests recorded with Request.
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	requests = nil
+}
+
+// Reasons returns why a reboot is needed: the requests recorded with Request,
+// followed by the packages that created /var/run/reboot-required. Returns
+// nil if no reboot is needed.
+func Reasons() []string {
+	mu.Lock()
+	reasons := append([]string(nil), requests...)
+	mu.Unlock()
+
+	if !exec.FileExists(requiredPath) {
+		return reasons
+	}
+	content, err := os.ReadFile(exec.Path(packagesPath))
+	if err != nil {
+		return append(reasons, fmt.Sprintf("%s exists", requiredPath))
+	}
+	seen := make(map[string]bool)
+	for _, pkg := range strings.Fields(string(content)) {
+		if !seen[pkg] {
+			seen[pkg] = true
+			reasons = append(reasons, fmt.Sprintf("package %s was updated", pkg))
+		}
+	}
+	if len(seen) == 0 {
+		reasons = append(reasons, fmt.Sprintf("%s exists", requiredPath))
+	}
+	return reasons
+}
+
+// Required reports whether a reboot is needed.
+func Required() bool {
+	return len(Reasons()) > 0
+}
+
+// Now reboots the server.
+func Now() error {
+	if exec.CommandExists("systemctl") {
+		return exec.Run("systemctl", "reboot")
+	}
+	return exec.Run("reboot")
+}
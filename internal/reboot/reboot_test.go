@@ -0,0 +1,64 @@
+package reboot
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// useTempFiles points the reboot-required files at a temporary directory.
+func useTempFiles(t *testing.T) (required, packages string) {
+	t.Helper()
+	dir := t.TempDir()
+	origRequired, origPackages := requiredPath, packagesPath
+	requiredPath = filepath.Join(dir, "reboot-required")
+	packagesPath = filepath.Join(dir, "reboot-required.pkgs")
+	t.Cleanup(func() {
+		requiredPath, packagesPath = origRequired, origPackages
+		Reset()
+	})
+	return requiredPath, packagesPath
+}
+
+func TestReasons_Requests(t *testing.T) {
+	useTempFiles(t)
+
+	if Required() {
+		t.Fatal("Required() without requests or reboot-required file should be false")
+	}
+	Request("kernel module changed")
+	Request("kernel module changed")
+	Request("swap file resized")
+
+	want := []string{"kernel module changed", "swap file resized"}
+	if got := Reasons(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Reasons() = %v, want %v", got, want)
+	}
+
+	Reset()
+	if Required() {
+		t.Error("Required() after Reset() should be false")
+	}
+}
+
+func TestReasons_RebootRequiredFile(t *testing.T) {
+	required, packages := useTempFiles(t)
+	if err := os.WriteFile(required, []byte("*** System restart required ***\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{required + " exists"}
+	if got := Reasons(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Reasons() without package list = %v, want %v", got, want)
+	}
+
+	if err := os.WriteFile(packages, []byte("linux-image-6.8.0-50-generic\nlibc6\nlibc6\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	Request("swap file resized")
+	want = []string{"swap file resized", "package linux-image-6.8.0-50-generic was updated", "package libc6 was updated"}
+	if got := Reasons(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Reasons() = %v, want %v", got, want)
+	}
+}
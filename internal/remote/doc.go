@@ -0,0 +1,25 @@
+// Package remote provisions a server over SSH from another machine.
+//
+// Provision detects the server's architecture, uploads a matching phanes
+// binary and the configuration to a private temporary directory, runs phanes
+// there (with sudo unless connecting as root) while streaming its output, fetches
+// the machine-readable results and optional HTML report back, and removes the
+// temporary directory again.
+//
+// Commands and file transfers go through a Transport. SSH implements it with
+// the system's ssh and scp, so ~/.ssh/config, agents and known_hosts work as
+// usual.
+//
+// Usage:
+//
+//	target, err := remote.ParseTarget("root@203.0.113.10")
+//	if err != nil {
+//	    return err
+//	}
+//	results, err := remote.Provision(&remote.SSH{Target: target}, remote.Options{
+//	    Config: "config.yaml",
+//	    Args:   []string{"--profile", "web"},
+//	    Stdout: os.Stdout,
+//	    Stderr: os.Stderr,
+//	})
+package remote
@@ -0,0 +1,245 @@
+package remote
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/stwalsh4118/phanes/internal/log"
+	"github.com/stwalsh4118/phanes/internal/report"
+)
+
+// Files in the temporary directory on the server.
+const (
+	remoteBinary  = "phanes"
+	remoteConfig  = "config.yaml"
+	remoteBundle  = "bundle.tar"
+	remoteResults = "results.json"
+	remoteReport  = "report.html"
+)
+
+// The local binary and platform. They are variables so tests can replace them.
+var (
+	executable = os.Executable
+	hostOS     = runtime.GOOS
+	hostArch   = runtime.GOARCH
+)
+
+// unameArch maps "uname -m" output to Go architectures.
+var unameArch = map[string]string{
+	"x86_64":  "amd64",
+	"amd64":   "amd64",
+	"aarch64": "arm64",
+	"arm64":   "arm64",
+	"armv7l":  "arm",
+	"armv6l":  "arm",
+	"i686":    "386",
+	"i386":    "386",
+	"ppc64le": "ppc64le",
+	"s390x":   "s390x",
+	"riscv64": "riscv64",
+}
+
+// Transport runs commands on and copies files to and from a server.
+type Transport interface {
+	// Run runs a shell command on the server.
+	Run(cmd string, stdin io.Reader, stdout, stderr io.Writer) error
+
+	// Upload copies a local file to path on the server.
+	Upload(local, path string) error
+
+	// Download copies path on the server to a local file.
+	Download(path, local string) error
+}
+
+// Options controls a remote run.
+type Options struct {
+	// Binary is the phanes binary to upload. Empty uses FindBinary.
+	Binary string
+
+	// Config is the local configuration file.
+	Config string
+
+	// Bundle is an optional local offline bundle, uploaded and passed with
+	// --bundle.
+	Bundle string
+
+	// Args are the phanes arguments (e.g., "--profile", "web"). --config,
+	// --bundle, --report and --results-json are added.
+	Args []string
+
+	// Report is an optional local path for the HTML report of the run.
+	Report string
+
+	// ResultsJSON is an optional local path to keep the results of the run.
+	ResultsJSON string
+
+	// Stdin, Stdout and Stderr are connected to the remote phanes process.
+	Stdin          io.Reader
+	Stdout, Stderr io.Writer
+}
+
+// Provision runs phanes on the server behind t and returns the results of the
+// run. If the run fails after producing results, both are returned.
+func Provision(t Transport, opts Options) (results *report.Results, err error) {
+	arch, isRoot, err := probe(t)
+	if err != nil {
+		return nil, err
+	}
+
+	binary := opts.Binary
+	if binary == "" {
+		binary, err = FindBinary(arch)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dir, err := output(t, "mktemp -d /tmp/phanes.XXXXXX")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory on server: %w", err)
+	}
+	defer func() {
+		if cleanupErr := t.Run("rm -rf "+shellQuote(dir), nil, io.Discard, io.Discard); cleanupErr != nil {
+			log.Warn("Failed to remove %s on server: %v", dir, cleanupErr)
+		}
+	}()
+
+	log.Info("Uploading phanes (linux/%s) and %s to %s", arch, opts.Config, dir)
+	uploads := []struct{ local, name string }{
+		{binary, remoteBinary},
+		{opts.Config, remoteConfig},
+	}
+	if opts.Bundle != "" {
+		uploads = append(uploads, struct{ local, name string }{opts.Bundle, remoteBundle})
+	}
+	for _, upload := range uploads {
+		if err := t.Upload(upload.local, path.Join(dir, upload.name)); err != nil {
+			return nil, fmt.Errorf("failed to upload %s: %w", upload.local, err)
+		}
+	}
+	if err := t.Run("chmod 700 "+shellQuote(path.Join(dir, remoteBinary)), nil, io.Discard, opts.Stderr); err != nil {
+		return nil, fmt.Errorf("failed to make phanes executable on server: %w", err)
+	}
+
+	runErr := t.Run(remoteCommand(dir, isRoot, opts), opts.Stdin, opts.Stdout, opts.Stderr)
+
+	results, err = fetchResults(t, dir, opts.ResultsJSON)
+	if err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("remote run failed: %w", runErr)
+		}
+		return nil, err
+	}
+	if opts.Report != "" {
+		if err := t.Download(path.Join(dir, remoteReport), opts.Report); err != nil {
+			log.Warn("Failed to fetch report: %v", err)
+		}
+	}
+
+	if runErr != nil {
+		return results, fmt.Errorf("remote run failed: %w", runErr)
+	}
+	return results, nil
+}
+
+// probe returns the server's Go architecture and whether the login user is
+// root.
+func probe(t Transport) (string, bool, error) {
+	out, err := output(t, "uname -m && id -u")
+	if err != nil {
+		return "", false, fmt.Errorf("failed to connect to server: %w", err)
+	}
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return "", false, fmt.Errorf("unexpected response from server: %q", out)
+	}
+	arch, ok := unameArch[fields[0]]
+	if !ok {
+		return "", false, fmt.Errorf("unsupported server architecture %s", fields[0])
+	}
+	return arch, fields[1] == "0", nil
+}
+
+// remoteCommand returns the shell command that runs the uploaded phanes.
+func remoteCommand(dir string, isRoot bool, opts Options) string {
+	args := append([]string{path.Join(dir, remoteBinary)}, opts.Args...)
+	args = append(args, "--config", path.Join(dir, remoteConfig), "--results-json", path.Join(dir, remoteResults))
+	if opts.Bundle != "" {
+		args = append(args, "--bundle", path.Join(dir, remoteBundle))
+	}
+	if opts.Report != "" {
+		args = append(args, "--report", path.Join(dir, remoteReport))
+	}
+
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	cmd := strings.Join(quoted, " ")
+	if !isRoot {
+		cmd = "sudo " + cmd
+	}
+	return cmd
+}
+
+// fetchResults downloads and parses the results of the run. They are kept at
+// keep if set.
+func fetchResults(t Transport, dir, keep string) (*report.Results, error) {
+	local := keep
+	if local == "" {
+		tmp, err := os.CreateTemp("", "phanes-results-*.json")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temporary file: %w", err)
+		}
+		tmp.Close()
+		local = tmp.Name()
+		defer os.Remove(local)
+	}
+
+	if err := t.Download(path.Join(dir, remoteResults), local); err != nil {
+		return nil, fmt.Errorf("failed to fetch results: %w", err)
+	}
+	return report.ReadResults(local)
+}
+
+// FindBinary returns the phanes binary to upload to a linux/<arch> server: the
+// running binary if it was built for that platform, otherwise
+// phanes-linux-<arch> next to it.
+func FindBinary(arch string) (string, error) {
+	self, err := executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate phanes binary: %w", err)
+	}
+	if hostOS == "linux" && hostArch == arch {
+		return self, nil
+	}
+
+	candidate := filepath.Join(filepath.Dir(self), "phanes-linux-"+arch)
+	if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+		return candidate, nil
+	}
+	return "", fmt.Errorf("no phanes binary for linux/%s: build one with 'GOOS=linux GOARCH=%s go build -o %s' or pass --remote-binary", arch, arch, candidate)
+}
+
+// output runs cmd on the server and returns its trimmed output.
+func output(t Transport, cmd string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	if err := t.Run(cmd, nil, &stdout, &stderr); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// shellQuote quotes s as a single word for sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,239 @@
+package remote
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stwalsh4118/phanes/internal/report"
+	"github.com/stwalsh4118/phanes/internal/runner"
+)
+
+// fakeTransport records commands and transfers and answers like a server.
+type fakeTransport struct {
+	uname, uid string
+	runErr     error // returned by the phanes command
+	noResults  bool
+
+	commands  []string
+	uploads   []string
+	downloads []string
+}
+
+func (f *fakeTransport) Run(cmd string, stdin io.Reader, stdout, stderr io.Writer) error {
+	f.commands = append(f.commands, cmd)
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	switch {
+	case cmd == "uname -m && id -u":
+		fmt.Fprintf(stdout, "%s\n%s\n", f.uname, f.uid)
+	case strings.HasPrefix(cmd, "mktemp"):
+		fmt.Fprintln(stdout, "/tmp/phanes.abc")
+	case strings.Contains(cmd, "--results-json"):
+		fmt.Fprintln(stdout, "running")
+		return f.runErr
+	}
+	return nil
+}
+
+func (f *fakeTransport) Upload(local, path string) error {
+	f.uploads = append(f.uploads, local+" -> "+path)
+	return nil
+}
+
+func (f *fakeTransport) Download(path, local string) error {
+	f.downloads = append(f.downloads, path)
+	if f.noResults {
+		return fmt.Errorf("no such file")
+	}
+	if strings.HasSuffix(path, remoteResults) {
+		started := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+		return report.WriteResults(local, report.Run{
+			Version:    "0.1.0",
+			Modules:    []string{"baseline"},
+			StartedAt:  started,
+			FinishedAt: started.Add(time.Minute),
+			Results:    []runner.ModuleResult{{Name: "baseline", Status: runner.StatusInstalled}},
+		})
+	}
+	return os.WriteFile(local, []byte("<html>"), 0644)
+}
+
+func TestProvision(t *testing.T) {
+	f := &fakeTransport{uname: "x86_64", uid: "1000"}
+	out := filepath.Join(t.TempDir(), "report.html")
+
+	var stdout strings.Builder
+	results, err := Provision(f, Options{
+		Binary: "/build/phanes-linux-amd64",
+		Config: "config.yaml",
+		Bundle: "bundle.tar",
+		Args:   []string{"--profile", "web", "--dry-run"},
+		Report: out,
+		Stdout: &stdout,
+		Stderr: io.Discard,
+	})
+	if err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+	if !results.Success || len(results.Results) != 1 || results.Results[0].Name != "baseline" {
+		t.Errorf("results = %+v", results)
+	}
+	if stdout.String() != "running\n" {
+		t.Errorf("stdout = %q, want the remote output", stdout.String())
+	}
+
+	wantCommands := []string{
+		"uname -m && id -u",
+		"mktemp -d /tmp/phanes.XXXXXX",
+		"chmod 700 '/tmp/phanes.abc/phanes'",
+		"sudo '/tmp/phanes.abc/phanes' '--profile' 'web' '--dry-run' '--config' '/tmp/phanes.abc/config.yaml' '--results-json' '/tmp/phanes.abc/results.json' '--bundle' '/tmp/phanes.abc/bundle.tar' '--report' '/tmp/phanes.abc/report.html'",
+		"rm -rf '/tmp/phanes.abc'",
+	}
+	if !reflect.DeepEqual(f.commands, wantCommands) {
+		t.Errorf("commands =\n%q\nwant\n%q", f.commands, wantCommands)
+	}
+	wantUploads := []string{
+		"/build/phanes-linux-amd64 -> /tmp/phanes.abc/phanes",
+		"config.yaml -> /tmp/phanes.abc/config.yaml",
+		"bundle.tar -> /tmp/phanes.abc/bundle.tar",
+	}
+	if !reflect.DeepEqual(f.uploads, wantUploads) {
+		t.Errorf("uploads = %q, want %q", f.uploads, wantUploads)
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Errorf("report not fetched: %v", err)
+	}
+}
+
+func TestProvisionAsRoot(t *testing.T) {
+	f := &fakeTransport{uname: "aarch64", uid: "0"}
+	if _, err := Provision(f, Options{Binary: "phanes", Config: "config.yaml", Args: []string{"--modules", "baseline"}}); err != nil {
+		t.Fatalf("Provision() error = %v", err)
+	}
+	if cmd := f.commands[3]; strings.HasPrefix(cmd, "sudo") {
+		t.Errorf("command as root = %q, want no sudo", cmd)
+	}
+}
+
+func TestProvisionFailure(t *testing.T) {
+	// A failed run still returns its results
+	f := &fakeTransport{uname: "x86_64", uid: "0", runErr: fmt.Errorf("exit status 1")}
+	results, err := Provision(f, Options{Binary: "phanes", Config: "config.yaml"})
+	if err == nil || results == nil {
+		t.Errorf("Provision() = %v, %v, want results and an error", results, err)
+	}
+
+	// Without results the run error is returned, and the directory is removed
+	f = &fakeTransport{uname: "x86_64", uid: "0", runErr: fmt.Errorf("exit status 2"), noResults: true}
+	if _, err := Provision(f, Options{Binary: "phanes", Config: "config.yaml"}); err == nil || !strings.Contains(err.Error(), "exit status 2") {
+		t.Errorf("Provision() error = %v, want the run error", err)
+	}
+	if last := f.commands[len(f.commands)-1]; !strings.HasPrefix(last, "rm -rf") {
+		t.Errorf("last command = %q, want cleanup", last)
+	}
+
+	// Unsupported architectures fail before anything is uploaded
+	f = &fakeTransport{uname: "mips", uid: "0"}
+	if _, err := Provision(f, Options{Binary: "phanes", Config: "config.yaml"}); err == nil || len(f.uploads) != 0 {
+		t.Errorf("Provision() on mips error = %v, uploads = %q", err, f.uploads)
+	}
+}
+
+func TestFindBinary(t *testing.T) {
+	dir := t.TempDir()
+	self := filepath.Join(dir, "phanes")
+	origExecutable, origOS, origArch := executable, hostOS, hostArch
+	executable = func() (string, error) { return self, nil }
+	hostOS, hostArch = "darwin", "arm64"
+	t.Cleanup(func() { executable, hostOS, hostArch = origExecutable, origOS, origArch })
+
+	if _, err := FindBinary("amd64"); err == nil || !strings.Contains(err.Error(), "GOARCH=amd64") {
+		t.Errorf("FindBinary() without a linux binary error = %v", err)
+	}
+
+	cross := filepath.Join(dir, "phanes-linux-amd64")
+	if err := os.WriteFile(cross, []byte("elf"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := FindBinary("amd64"); err != nil || got != cross {
+		t.Errorf("FindBinary() = %q, %v, want %q", got, err, cross)
+	}
+
+	hostOS, hostArch = "linux", "amd64"
+	if got, err := FindBinary("amd64"); err != nil || got != self {
+		t.Errorf("FindBinary() on linux/amd64 = %q, %v, want the running binary", got, err)
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Target
+		wantErr bool
+	}{
+		{in: "root@203.0.113.10", want: Target{User: "root", Host: "203.0.113.10"}},
+		{in: "deploy@web-1:2222", want: Target{User: "deploy", Host: "web-1", Port: 2222}},
+		{in: "web-1", want: Target{Host: "web-1"}},
+		{in: "root@[2001:db8::1]:22", want: Target{User: "root", Host: "2001:db8::1", Port: 22}},
+		{in: "root@2001:db8::1", want: Target{User: "root", Host: "2001:db8::1"}},
+		{in: "@web-1", wantErr: true},
+		{in: "root@", wantErr: true},
+		{in: "root@web-1:ssh", wantErr: true},
+		{in: "root@web-1:70000", wantErr: true},
+		{in: "-oProxyCommand=sh", wantErr: true},
+		{in: "root@-oProxyCommand=sh", wantErr: true},
+		{in: "-l@web-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTarget(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTarget(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseTarget(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSSHArguments(t *testing.T) {
+	var calls [][]string
+	origCommand := command
+	command = func(name string, args ...string) *exec.Cmd {
+		calls = append(calls, append([]string{name}, args...))
+		return exec.Command("true")
+	}
+	t.Cleanup(func() { command = origCommand })
+
+	s := &SSH{Target: Target{User: "root", Host: "2001:db8::1", Port: 2222}, TTY: true, Options: []string{"-i", "key"}}
+	if err := s.Run("uname -m", nil, io.Discard, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Run("phanes", os.Stdin, io.Discard, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Upload("config.yaml", "/tmp/x/config.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Download("/tmp/x/results.json", "results.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{"ssh", "-i", "key", "-p", "2222", "--", "root@2001:db8::1", "uname -m"},
+		{"ssh", "-i", "key", "-p", "2222", "-t", "--", "root@2001:db8::1", "phanes"},
+		{"scp", "-q", "-i", "key", "-P", "2222", "--", "config.yaml", "root@[2001:db8::1]:/tmp/x/config.yaml"},
+		{"scp", "-q", "-i", "key", "-P", "2222", "--", "root@[2001:db8::1]:/tmp/x/results.json", "results.json"},
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("commands =\n%q\nwant\n%q", calls, want)
+	}
+}
@@ -0,0 +1,144 @@
+package remote
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// command creates the ssh and scp processes. It is a variable so tests can
+// record the arguments instead.
+var command = exec.Command
+
+// Target is an SSH destination.
+type Target struct {
+	// User is the login user. Empty uses ssh's default.
+	User string
+	// Host is the host name or IP address.
+	Host string
+	// Port is the SSH port. 0 uses ssh's default.
+	Port int
+}
+
+// ParseTarget parses "[user@]host[:port]". IPv6 addresses with a port are
+// written in brackets ("root@[2001:db8::1]:2222").
+func ParseTarget(s string) (Target, error) {
+	var t Target
+	hostPort := s
+	if user, rest, ok := strings.Cut(s, "@"); ok {
+		if user == "" {
+			return t, fmt.Errorf("invalid remote %q: empty user", s)
+		}
+		if strings.HasPrefix(user, "-") {
+			return t, fmt.Errorf("invalid remote %q: user must not start with \"-\"", s)
+		}
+		t.User = user
+		hostPort = rest
+	}
+
+	host := hostPort
+	if h, port, err := net.SplitHostPort(hostPort); err == nil {
+		n, err := strconv.Atoi(port)
+		if err != nil || n < 1 || n > 65535 {
+			return t, fmt.Errorf("invalid remote %q: port must be between 1 and 65535", s)
+		}
+		host, t.Port = h, n
+	} else if strings.HasPrefix(hostPort, "[") && strings.HasSuffix(hostPort, "]") {
+		host = strings.Trim(hostPort, "[]")
+	} else if strings.Count(hostPort, ":") == 1 {
+		return t, fmt.Errorf("invalid remote %q: port must be between 1 and 65535", s)
+	}
+
+	if host == "" || strings.ContainsAny(host, " /") {
+		return t, fmt.Errorf("invalid remote %q: expected [user@]host[:port]", s)
+	}
+	// ssh would read a leading "-" as an option (e.g., -oProxyCommand=...)
+	if strings.HasPrefix(host, "-") {
+		return t, fmt.Errorf("invalid remote %q: host must not start with \"-\"", s)
+	}
+	t.Host = host
+	return t, nil
+}
+
+// String returns the target as "[user@]host[:port]".
+func (t Target) String() string {
+	s := t.destination(false)
+	if t.Port != 0 {
+		s = t.destination(true) + ":" + strconv.Itoa(t.Port)
+	}
+	return s
+}
+
+// destination returns "[user@]host" for ssh, or with an IPv6 host in
+// brackets for scp and ports.
+func (t Target) destination(bracketIPv6 bool) string {
+	host := t.Host
+	if bracketIPv6 && strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if t.User == "" {
+		return host
+	}
+	return t.User + "@" + host
+}
+
+// SSH is a Transport using the system's ssh and scp commands.
+type SSH struct {
+	// Target is the server to connect to.
+	Target Target
+
+	// TTY allocates a terminal for commands that are given stdin, so prompts
+	// on the server (sudo password, risky change confirmations) can be
+	// answered locally.
+	TTY bool
+
+	// Options are additional options for both ssh and scp (e.g., "-i", "key").
+	Options []string
+}
+
+// Run runs a shell command on the server.
+func (s *SSH) Run(cmd string, stdin io.Reader, stdout, stderr io.Writer) error {
+	args := append([]string{}, s.Options...)
+	if s.Target.Port != 0 {
+		args = append(args, "-p", strconv.Itoa(s.Target.Port))
+	}
+	if s.TTY && stdin != nil {
+		args = append(args, "-t")
+	}
+	args = append(args, "--", s.Target.destination(false), cmd)
+
+	c := command("ssh", args...)
+	c.Stdin, c.Stdout, c.Stderr = stdin, stdout, stderr
+	return c.Run()
+}
+
+// Upload copies a local file to path on the server.
+func (s *SSH) Upload(local, path string) error {
+	return s.copy(local, s.Target.destination(true)+":"+path)
+}
+
+// Download copies path on the server to a local file.
+func (s *SSH) Download(path, local string) error {
+	return s.copy(s.Target.destination(true)+":"+path, local)
+}
+
+// copy runs scp quietly from src to dst.
+func (s *SSH) copy(src, dst string) error {
+	args := append([]string{"-q"}, s.Options...)
+	if s.Target.Port != 0 {
+		args = append(args, "-P", strconv.Itoa(s.Target.Port))
+	}
+	args = append(args, "--", src, dst)
+
+	output, err := command("scp", args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+// Package report generates self-contained HTML reports of provisioning runs.
+// Reports are intended to be handed over to clients or archived alongside a
+// server's documentation, so they embed all styling and require no network access.
+//
+// A report contains:
+//   - Run metadata (phanes version, profile, modules, start/finish time)
+//   - Host facts (hostname, OS, kernel, architecture, CPUs, memory)
+//   - Per-module results with durations and captured errors
+//   - The effective configuration with secrets masked
+//
+// Usage:
+//
+//	run := report.Run{
+//	    Version:    "0.1.0",
+//	    Profile:    "web",
+//	    StartedAt:  started,
+//	    FinishedAt: time.Now(),
+//	    Host:       report.CollectHostFacts(),
+//	    Results:    results,
+//	    Config:     cfg,
+//	}
+//	if err := report.WriteFile("report.html", run); err != nil {
+//	    log.Error("Failed to write report: %v", err)
+//	}
+package report
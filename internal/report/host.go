@@ -0,0 +1,81 @@
+package report
+
+import (
+	"bufio"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/stwalsh4118/phanes/internal/osinfo"
+)
+
+const (
+	kernelReleasePath = "/proc/sys/kernel/osrelease"
+	meminfoPath       = "/proc/meminfo"
+)
+
+// HostFacts describes the machine a run was executed on.
+type HostFacts struct {
+	// Hostname is the system hostname.
+	Hostname string `json:"hostname"`
+	// OS is the human-readable operating system name (PRETTY_NAME from /etc/os-release).
+	OS string `json:"os,omitempty"`
+	// Kernel is the running kernel release.
+	Kernel string `json:"kernel,omitempty"`
+	// Arch is the CPU architecture phanes was built for (e.g., "amd64", "arm64").
+	Arch string `json:"arch"`
+	// CPUs is the number of logical CPUs.
+	CPUs int `json:"cpus"`
+	// MemoryBytes is the total physical memory in bytes (0 if unknown).
+	MemoryBytes int64 `json:"memory_bytes"`
+}
+
+// CollectHostFacts gathers host facts from the local system.
+// Facts that cannot be determined are left empty rather than returning an error,
+// since a partial report is more useful than none.
+func CollectHostFacts() HostFacts {
+	facts := HostFacts{
+		Arch: runtime.GOARCH,
+		CPUs: runtime.NumCPU(),
+	}
+
+	if hostname, err := os.Hostname(); err == nil {
+		facts.Hostname = hostname
+	}
+
+	if info, err := osinfo.Detect(); err == nil {
+		facts.OS = info.PrettyName
+	}
+
+	if content, err := os.ReadFile(kernelReleasePath); err == nil {
+		facts.Kernel = strings.TrimSpace(string(content))
+	}
+
+	if file, err := os.Open(meminfoPath); err == nil {
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			facts.MemoryBytes = parseMemTotal(scanner.Text())
+			if facts.MemoryBytes > 0 {
+				break
+			}
+		}
+	}
+
+	return facts
+}
+
+// parseMemTotal parses a "MemTotal:  16318480 kB" line from /proc/meminfo.
+// Returns 0 if the line is not a MemTotal line or cannot be parsed.
+func parseMemTotal(line string) int64 {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[0] != "MemTotal:" {
+		return 0
+	}
+	kb, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return kb * 1024
+}
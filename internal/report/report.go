@@ -0,0 +1,200 @@
+package report
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"time"
+
+	"github.com/stwalsh4118/phanes/internal/config"
+	"github.com/stwalsh4118/phanes/internal/runner"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed report.html.tmpl
+var reportTemplate string
+
+const (
+	reportFilePerm = 0644
+	timeLayout     = "2006-01-02 15:04:05 MST"
+)
+
+// Run contains everything needed to render a report for a single provisioning run.
+type Run struct {
+	// Version is the phanes version that executed the run.
+	Version string
+	// Profile is the selected profile name (empty if only --modules was used).
+	Profile string
+	// Modules is the ordered list of modules that were requested.
+	Modules []string
+	// ConfigPath is the path of the configuration file used for the run.
+	ConfigPath string
+	// DryRun indicates whether the run was a dry-run.
+	DryRun bool
+	// StartedAt is when module execution started.
+	StartedAt time.Time
+	// FinishedAt is when module execution finished.
+	FinishedAt time.Time
+	// Host describes the machine the run was executed on.
+	Host HostFacts
+	// Results are the per-module execution results.
+	Results []runner.ModuleResult
+	// Config is the effective configuration. Secrets are masked when rendering.
+	Config *config.Config
+}
+
+// moduleRow is the template view of a single module result.
+type moduleRow struct {
+	Name        string
+	Status      string
+	StatusClass string
+	Duration    string
+	Error       string
+	Steps       []stepRow
+}
+
+// stepRow is the template view of a single timed step within a module.
+type stepRow struct {
+	Name     string
+	Duration string
+}
+
+// templateData is the data passed to the HTML template.
+type templateData struct {
+	Run        Run
+	StartedAt  string
+	FinishedAt string
+	Duration   string
+	Memory     string
+	Outcome    string
+	Rows       []moduleRow
+	ConfigYAML string
+}
+
+// Render writes the HTML report for run to w.
+func Render(w io.Writer, run Run) error {
+	tmpl, err := template.New("report").Parse(reportTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse report template: %w", err)
+	}
+
+	data, err := buildTemplateData(run)
+	if err != nil {
+		return err
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to render report: %w", err)
+	}
+
+	return nil
+}
+
+// WriteFile renders the HTML report for run and writes it to path.
+func WriteFile(path string, run Run) error {
+	var buf bytes.Buffer
+	if err := Render(&buf, run); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), reportFilePerm); err != nil {
+		return fmt.Errorf("failed to write report to %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// buildTemplateData converts a Run into the view model used by the template.
+func buildTemplateData(run Run) (templateData, error) {
+	data := templateData{
+		Run:        run,
+		StartedAt:  formatTime(run.StartedAt),
+		FinishedAt: formatTime(run.FinishedAt),
+		Memory:     formatBytes(run.Host.MemoryBytes),
+		Outcome:    "success",
+	}
+
+	if !run.StartedAt.IsZero() && !run.FinishedAt.IsZero() {
+		data.Duration = formatDuration(run.FinishedAt.Sub(run.StartedAt))
+	}
+
+	for _, result := range run.Results {
+		row := moduleRow{
+			Name:        result.Name,
+			Status:      string(result.Status),
+			StatusClass: statusClass(result.Status),
+			Duration:    formatDuration(result.Duration),
+		}
+		for _, step := range result.Steps {
+			row.Steps = append(row.Steps, stepRow{
+				Name:     step.Name,
+				Duration: formatDuration(step.Duration),
+			})
+		}
+		if result.Error != nil {
+			row.Error = result.Error.Error()
+			data.Outcome = "failure"
+		}
+		data.Rows = append(data.Rows, row)
+	}
+
+	if run.Config != nil {
+		configYAML, err := yaml.Marshal(run.Config.Masked())
+		if err != nil {
+			return templateData{}, fmt.Errorf("failed to serialize config: %w", err)
+		}
+		data.ConfigYAML = string(configYAML)
+	}
+
+	return data, nil
+}
+
+// statusClass maps a module status to a CSS class used for color coding.
+func statusClass(status runner.ModuleStatus) string {
+	switch status {
+	case runner.StatusInstalled, runner.StatusWouldInstall:
+		return "ok"
+	case runner.StatusSkipped, runner.StatusDeclined, runner.StatusDeferred, runner.StatusNotRun:
+		return "skip"
+	case runner.StatusFailed, runner.StatusError, runner.StatusDependencyFailed:
+		return "fail"
+	default:
+		return ""
+	}
+}
+
+// formatTime formats a timestamp for display, returning "-" for zero values.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(timeLayout)
+}
+
+// formatDuration formats a duration for display, returning "-" for zero values.
+func formatDuration(d time.Duration) string {
+	if d <= 0 {
+		return "-"
+	}
+	return d.Round(time.Millisecond).String()
+}
+
+// formatBytes formats a byte count using binary units.
+func formatBytes(n int64) string {
+	if n <= 0 {
+		return "-"
+	}
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
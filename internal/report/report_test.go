@@ -0,0 +1,140 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stwalsh4118/phanes/internal/config"
+	"github.com/stwalsh4118/phanes/internal/runner"
+	"github.com/stwalsh4118/phanes/internal/timing"
+)
+
+func testRun() Run {
+	cfg := config.DefaultConfig()
+	cfg.User.Username = "deploy"
+	cfg.Postgres.Password = "super-secret-password"
+	cfg.Tailscale.AuthKey = "tskey-auth-secret"
+
+	started := time.Date(2025, 1, 27, 10, 0, 0, 0, time.UTC)
+	return Run{
+		Version:    "0.1.0",
+		Profile:    "database",
+		Modules:    []string{"baseline", "postgres"},
+		ConfigPath: "config.yaml",
+		StartedAt:  started,
+		FinishedAt: started.Add(90 * time.Second),
+		Host: HostFacts{
+			Hostname:    "web-1",
+			OS:          "Ubuntu 24.04 LTS",
+			Kernel:      "6.8.0-31-generic",
+			Arch:        "amd64",
+			CPUs:        2,
+			MemoryBytes: 4 * 1024 * 1024 * 1024,
+		},
+		Results: []runner.ModuleResult{
+			{
+				Name:     "baseline",
+				Status:   runner.StatusInstalled,
+				Duration: 12 * time.Second,
+				Steps:    []timing.Step{{Name: "apt-get update", Duration: 9 * time.Second}},
+			},
+			{Name: "postgres", Status: runner.StatusFailed, Error: errors.New("module postgres: <apt> failed")},
+		},
+		Config: cfg,
+	}
+}
+
+func TestRender(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Render(&buf, testRun()); err != nil {
+		t.Fatalf("Render() returned error: %v", err)
+	}
+	html := buf.String()
+
+	wantContains := []string{
+		"<!DOCTYPE html>",
+		"web-1",
+		"Ubuntu 24.04 LTS",
+		"database",
+		"baseline",
+		"12s",
+		"apt-get update: 9s",
+		"1m30s",
+		"4.0 GiB",
+		"outcome-failure",
+		"username: deploy",
+		// Error messages must be escaped
+		"module postgres: &lt;apt&gt; failed",
+	}
+	for _, want := range wantContains {
+		if !strings.Contains(html, want) {
+			t.Errorf("Rendered report missing %q", want)
+		}
+	}
+
+	for _, secret := range []string{"super-secret-password", "tskey-auth-secret"} {
+		if strings.Contains(html, secret) {
+			t.Errorf("Rendered report leaked secret %q", secret)
+		}
+	}
+
+	// Report must be self-contained
+	for _, external := range []string{"<script src", "<link"} {
+		if strings.Contains(html, external) {
+			t.Errorf("Rendered report references external resource %q", external)
+		}
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.html")
+	if err := WriteFile(path, testRun()); err != nil {
+		t.Fatalf("WriteFile() returned error: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read report: %v", err)
+	}
+	if !strings.Contains(string(content), "Provisioning report") {
+		t.Error("Written report is missing title")
+	}
+}
+
+func TestParseMemTotal(t *testing.T) {
+	tests := []struct {
+		line string
+		want int64
+	}{
+		{line: "MemTotal:        2048 kB", want: 2048 * 1024},
+		{line: "MemFree:         1024 kB", want: 0},
+		{line: "MemTotal:", want: 0},
+	}
+	for _, tt := range tests {
+		if got := parseMemTotal(tt.line); got != tt.want {
+			t.Errorf("parseMemTotal(%q) = %d, want %d", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{n: 0, want: "-"},
+		{n: 512, want: "512 B"},
+		{n: 1536, want: "1.5 KiB"},
+		{n: 2 * 1024 * 1024 * 1024, want: "2.0 GiB"},
+	}
+	for _, tt := range tests {
+		if got := formatBytes(tt.n); got != tt.want {
+			t.Errorf("formatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
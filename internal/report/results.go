@@ -0,0 +1,111 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/stwalsh4118/phanes/internal/runner"
+)
+
+// Results is the machine-readable summary of a run, as written by
+// WriteResults. It is read back to show the summary of remote runs.
+type Results struct {
+	Version         string         `json:"version"`
+	Profile         string         `json:"profile,omitempty"`
+	Modules         []string       `json:"modules"`
+	DryRun          bool           `json:"dry_run"`
+	Success         bool           `json:"success"`
+	StartedAt       time.Time      `json:"started_at"`
+	FinishedAt      time.Time      `json:"finished_at"`
+	DurationSeconds float64        `json:"duration_seconds"`
+	Host            HostFacts      `json:"host"`
+	Results         []ModuleResult `json:"results"`
+}
+
+// ModuleResult is the machine-readable result of a single module.
+type ModuleResult struct {
+	Name            string  `json:"name"`
+	Status          string  `json:"status"`
+	Error           string  `json:"error,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// NewResults converts a run into its machine-readable summary.
+func NewResults(run Run) Results {
+	results := Results{
+		Version:         run.Version,
+		Profile:         run.Profile,
+		Modules:         run.Modules,
+		DryRun:          run.DryRun,
+		Success:         true,
+		StartedAt:       run.StartedAt,
+		FinishedAt:      run.FinishedAt,
+		DurationSeconds: run.FinishedAt.Sub(run.StartedAt).Seconds(),
+		Host:            run.Host,
+		Results:         make([]ModuleResult, 0, len(run.Results)),
+	}
+
+	for _, result := range run.Results {
+		entry := ModuleResult{
+			Name:            result.Name,
+			Status:          string(result.Status),
+			DurationSeconds: result.Duration.Seconds(),
+		}
+		if result.Error != nil {
+			entry.Error = result.Error.Error()
+		}
+		switch result.Status {
+		case runner.StatusFailed, runner.StatusError, runner.StatusDeclined, runner.StatusDependencyFailed:
+			results.Success = false
+		}
+		results.Results = append(results.Results, entry)
+	}
+
+	return results
+}
+
+// ModuleResults converts the summary back into runner results, e.g. for
+// runner.PrintSummary. Steps are not part of the summary.
+func (r *Results) ModuleResults() []runner.ModuleResult {
+	results := make([]runner.ModuleResult, 0, len(r.Results))
+	for _, entry := range r.Results {
+		result := runner.ModuleResult{
+			Name:     entry.Name,
+			Status:   runner.ModuleStatus(entry.Status),
+			Duration: time.Duration(entry.DurationSeconds * float64(time.Second)),
+		}
+		if entry.Error != "" {
+			result.Error = errors.New(entry.Error)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// WriteResults writes the machine-readable summary of run to path as JSON.
+func WriteResults(path string, run Run) error {
+	data, err := json.MarshalIndent(NewResults(run), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode results: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), reportFilePerm); err != nil {
+		return fmt.Errorf("failed to write results to %s: %w", path, err)
+	}
+	return nil
+}
+
+// ReadResults reads a summary written by WriteResults.
+func ReadResults(path string) (*Results, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read results: %w", err)
+	}
+	var results Results
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("failed to parse results %s: %w", path, err)
+	}
+	return &results, nil
+}
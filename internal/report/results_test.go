@@ -0,0 +1,46 @@
+package report
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stwalsh4118/phanes/internal/runner"
+)
+
+func TestWriteAndReadResults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.json")
+	if err := WriteResults(path, testRun()); err != nil {
+		t.Fatalf("WriteResults() error = %v", err)
+	}
+
+	results, err := ReadResults(path)
+	if err != nil {
+		t.Fatalf("ReadResults() error = %v", err)
+	}
+	if results.Success {
+		t.Error("Success = true, want false for a run with a failed module")
+	}
+	if results.Profile != "database" || results.Host.Hostname != "web-1" || results.DurationSeconds != 90 {
+		t.Errorf("results = %+v", results)
+	}
+
+	modules := results.ModuleResults()
+	if len(modules) != 2 {
+		t.Fatalf("ModuleResults() returned %d results, want 2", len(modules))
+	}
+	if modules[0].Name != "baseline" || modules[0].Status != runner.StatusInstalled || modules[0].Duration != 12*time.Second || modules[0].Error != nil {
+		t.Errorf("ModuleResults()[0] = %+v", modules[0])
+	}
+	if modules[1].Status != runner.StatusFailed || modules[1].Error == nil || modules[1].Error.Error() != "module postgres: <apt> failed" {
+		t.Errorf("ModuleResults()[1] = %+v", modules[1])
+	}
+}
+
+func TestNewResultsSuccess(t *testing.T) {
+	run := testRun()
+	run.Results = run.Results[:1]
+	if !NewResults(run).Success {
+		t.Error("Success = false, want true when no module failed")
+	}
+}
@@ -0,0 +1,32 @@
+// Package resume continues a run after the server reboots.
+//
+// When a run with --reboot-if-needed has to reboot, Schedule saves the modules
+// that did not run yet together with the results so far, and enables a
+// one-shot systemd service that runs 'phanes resume' once after boot. The
+// resumed run installs the remaining modules and reports the combined result
+// of both parts. Cancel removes the service and the saved state.
+//
+// Every run is also recorded with SaveLastRun, so 'phanes --resume' can rerun
+// the modules of the last run that failed or did not run (see Unfinished).
+//
+// Usage:
+//
+//	err := resume.Schedule(resume.State{
+//	    Config:    "/etc/phanes/config.yaml",
+//	    Remaining: []string{"docker", "monitoring"},
+//	    Results:   report.NewResults(run),
+//	}, "/usr/local/bin/phanes")
+//
+//	// After boot
+//	state, err := resume.Load()
+//	if state != nil {
+//	    err = resume.Cancel()
+//	    // run state.Remaining
+//	}
+//
+//	// phanes --resume
+//	last, err := resume.LoadLastRun()
+//	if last != nil {
+//	    modules := resume.Unfinished(last)
+//	}
+package resume
@@ -0,0 +1,56 @@
+package resume
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/stwalsh4118/phanes/internal/exec"
+	"github.com/stwalsh4118/phanes/internal/report"
+	"github.com/stwalsh4118/phanes/internal/runner"
+)
+
+const lastRunFile = "last-run.json"
+
+// LastRunPath returns the path of the results of the last run.
+func LastRunPath() string {
+	return filepath.Join(stateDir, lastRunFile)
+}
+
+// SaveLastRun records the results of run, so a later run can resume it with
+// Unfinished.
+func SaveLastRun(run report.Run) error {
+	if err := os.MkdirAll(exec.Path(stateDir), stateDirPerm); err != nil {
+		return fmt.Errorf("failed to create %s: %w", stateDir, err)
+	}
+	return report.WriteResults(exec.Path(LastRunPath()), run)
+}
+
+// LoadLastRun returns the results recorded by SaveLastRun, or nil if no run
+// was recorded.
+func LoadLastRun() (*report.Results, error) {
+	if !exec.FileExists(LastRunPath()) {
+		return nil, nil
+	}
+	return report.ReadResults(exec.Path(LastRunPath()))
+}
+
+// Unfinished returns the modules of a run that were not installed or already
+// installed: those that failed, were skipped because of a failure, or did not
+// run. They are returned in the order of the run.
+func Unfinished(results *report.Results) []string {
+	statuses := make(map[string]runner.ModuleStatus)
+	for _, result := range results.Results {
+		statuses[result.Name] = runner.ModuleStatus(result.Status)
+	}
+
+	var unfinished []string
+	for _, name := range results.Modules {
+		switch statuses[name] {
+		case runner.StatusInstalled, runner.StatusSkipped:
+		default:
+			unfinished = append(unfinished, name)
+		}
+	}
+	return unfinished
+}
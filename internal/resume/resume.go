@@ -0,0 +1,184 @@
+package resume
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stwalsh4118/phanes/internal/exec"
+	"github.com/stwalsh4118/phanes/internal/files"
+	"github.com/stwalsh4118/phanes/internal/log"
+	"github.com/stwalsh4118/phanes/internal/report"
+	"github.com/stwalsh4118/phanes/internal/svcmgr"
+)
+
+const (
+	// ServiceName is the systemd unit that resumes the run after boot.
+	ServiceName = "phanes-resume.service"
+
+	// MaxBoots is the number of reboots a run may need. A run that still
+	// needs a reboot after that many stops instead of rebooting again.
+	MaxBoots = 3
+
+	stateFile     = "state.json"
+	stateDirPerm  = 0700
+	stateFilePerm = 0600
+	unitPerm      = 0644
+)
+
+// The unit and state directories. They are variables so tests can use
+// temporary directories.
+var (
+	unitDir  = "/etc/systemd/system"
+	stateDir = "/var/lib/phanes/resume"
+
+	detect = svcmgr.Detect
+)
+
+// State is a run waiting for a reboot. Paths must be absolute, since the
+// resumed run starts in /.
+type State struct {
+	// Config is the path of the configuration file.
+	Config string `json:"config"`
+
+	// Profile is the selected profile name (empty if only modules were
+	// selected).
+	Profile string `json:"profile,omitempty"`
+
+	// Remaining are the modules that run after boot, in order.
+	Remaining []string `json:"remaining"`
+
+	// Bundle is the offline bundle the run installs from (empty for none).
+	Bundle string `json:"bundle,omitempty"`
+
+	// Yes approves risky changes, Safe refuses them.
+	Yes  bool `json:"yes,omitempty"`
+	Safe bool `json:"safe,omitempty"`
+
+	// OnError is the error policy of the run (see runner.ErrorPolicy).
+	OnError string `json:"on_error,omitempty"`
+
+	// Report and ResultsJSON are where the combined report and results are
+	// written (empty for none). Timings shows the timing breakdown.
+	Report      string `json:"report,omitempty"`
+	ResultsJSON string `json:"results_json,omitempty"`
+	Timings     bool   `json:"timings,omitempty"`
+
+	// Boots is the number of reboots the run needed so far.
+	Boots int `json:"boots"`
+
+	// Results are the results before the reboot. Results.Modules lists all
+	// modules of the run and Results.StartedAt is when it started.
+	Results report.Results `json:"results"`
+}
+
+// StatePath returns the path of the saved state.
+func StatePath() string {
+	return filepath.Join(stateDir, stateFile)
+}
+
+// ServiceUnit returns the one-shot systemd service that runs
+// '<binary> resume' after boot while a state is saved.
+func ServiceUnit(binary string) string {
+	var b strings.Builder
+	b.WriteString("# Managed by phanes (resume)\n")
+	b.WriteString("[Unit]\n")
+	b.WriteString("Description=Resume the phanes run after reboot\n")
+	b.WriteString("Wants=network-online.target\n")
+	b.WriteString("After=network-online.target\n")
+	fmt.Fprintf(&b, "ConditionPathExists=%s\n", StatePath())
+	b.WriteString("\n[Service]\n")
+	b.WriteString("Type=oneshot\n")
+	fmt.Fprintf(&b, "ExecStart=%s resume\n", svcmgr.QuoteExecArg(binary))
+	b.WriteString("StandardOutput=journal+console\n")
+	b.WriteString("\n[Install]\n")
+	b.WriteString("WantedBy=multi-user.target\n")
+	return b.String()
+}
+
+// Schedule saves state and enables the service resuming it with binary (an
+// absolute path) after the next boot.
+func Schedule(state State, binary string) error {
+	svc, err := detect()
+	if err != nil {
+		return err
+	}
+	if svc.Name() != svcmgr.NameSystemd {
+		return fmt.Errorf("resuming after reboot requires systemd (found %s)", svc.Name())
+	}
+
+	if err := save(state); err != nil {
+		return err
+	}
+
+	if _, err := files.Write(unitPath(), []byte(ServiceUnit(binary)), files.Options{Mode: unitPerm}); err != nil {
+		return fmt.Errorf("failed to write %s: %w", unitPath(), err)
+	}
+	if err := exec.Run("systemctl", "daemon-reload"); err != nil {
+		return fmt.Errorf("failed to reload systemd: %w", err)
+	}
+	if err := svc.Enable(ServiceName); err != nil {
+		return fmt.Errorf("failed to enable %s: %w", ServiceName, err)
+	}
+	log.Success("Scheduled %d module(s) to resume after reboot", len(state.Remaining))
+	return nil
+}
+
+// save writes state to StatePath.
+func save(state State) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode resume state: %w", err)
+	}
+	if err := os.MkdirAll(exec.Path(stateDir), stateDirPerm); err != nil {
+		return fmt.Errorf("failed to create %s: %w", stateDir, err)
+	}
+	if err := os.WriteFile(exec.Path(StatePath()), append(data, '\n'), stateFilePerm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", StatePath(), err)
+	}
+	return nil
+}
+
+// Load returns the saved state, or nil if no run is waiting for a reboot.
+func Load() (*State, error) {
+	data, err := os.ReadFile(exec.Path(StatePath()))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", StatePath(), err)
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", StatePath(), err)
+	}
+	return &state, nil
+}
+
+// Cancel disables and removes the service and the saved state, so the run is
+// not resumed (again) on the next boot.
+func Cancel() error {
+	if exec.FileExists(unitPath()) {
+		if err := exec.Run("systemctl", "disable", ServiceName); err != nil {
+			log.Warn("Failed to disable %s: %v", ServiceName, err)
+		}
+		if err := os.Remove(exec.Path(unitPath())); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", unitPath(), err)
+		}
+		if err := exec.Run("systemctl", "daemon-reload"); err != nil {
+			return fmt.Errorf("failed to reload systemd: %w", err)
+		}
+	}
+	if err := os.Remove(exec.Path(StatePath())); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", StatePath(), err)
+	}
+	return nil
+}
+
+// unitPath returns the path of the service unit.
+func unitPath() string {
+	return filepath.Join(unitDir, ServiceName)
+}
@@ -0,0 +1,118 @@
+package resume
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stwalsh4118/phanes/internal/report"
+	"github.com/stwalsh4118/phanes/internal/runner"
+)
+
+// useTempDirs points the unit and state directories at temporary directories.
+func useTempDirs(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	origUnit, origState := unitDir, stateDir
+	unitDir = filepath.Join(dir, "systemd")
+	stateDir = filepath.Join(dir, "resume")
+	t.Cleanup(func() { unitDir, stateDir = origUnit, origState })
+}
+
+func TestServiceUnit(t *testing.T) {
+	useTempDirs(t)
+	unit := ServiceUnit("/opt/my tools/phanes")
+
+	for _, want := range []string{
+		"Type=oneshot\n",
+		"After=network-online.target\n",
+		"ConditionPathExists=" + StatePath() + "\n",
+		`ExecStart="/opt/my tools/phanes" resume` + "\n",
+		"WantedBy=multi-user.target\n",
+	} {
+		if !strings.Contains(unit, want) {
+			t.Errorf("unit missing %q:\n%s", want, unit)
+		}
+	}
+}
+
+func TestSaveLoadCancel(t *testing.T) {
+	useTempDirs(t)
+
+	state, err := Load()
+	if err != nil || state != nil {
+		t.Fatalf("Load() without state = %v, %v, want nil, nil", state, err)
+	}
+
+	want := State{
+		Config:    "/etc/phanes/config.yaml",
+		Profile:   "web",
+		Remaining: []string{"docker", "caddy"},
+		Yes:       true,
+		Report:    "/root/report.html",
+		Boots:     1,
+		Results: report.Results{
+			Version:   "0.1.0",
+			Modules:   []string{"baseline", "docker", "caddy"},
+			StartedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+			Results: []report.ModuleResult{
+				{Name: "baseline", Status: "installed", DurationSeconds: 12},
+				{Name: "docker", Status: "deferred"},
+				{Name: "caddy", Status: "deferred"},
+			},
+		},
+	}
+	if err := save(want); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+	state, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(*state, want) {
+		t.Errorf("Load() = %+v, want %+v", *state, want)
+	}
+
+	if err := Cancel(); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	if state, err := Load(); err != nil || state != nil {
+		t.Errorf("Load() after Cancel() = %v, %v, want nil, nil", state, err)
+	}
+}
+
+func TestLastRun(t *testing.T) {
+	useTempDirs(t)
+
+	if results, err := LoadLastRun(); err != nil || results != nil {
+		t.Fatalf("LoadLastRun() without a run = %v, %v, want nil, nil", results, err)
+	}
+
+	run := report.Run{
+		Version: "0.1.0",
+		Profile: "coolify",
+		Modules: []string{"baseline", "user", "security", "docker", "coolify", "swap"},
+		Results: []runner.ModuleResult{
+			{Name: "baseline", Status: runner.StatusSkipped},
+			{Name: "user", Status: runner.StatusFailed, Error: errors.New("useradd failed")},
+			{Name: "security", Status: runner.StatusDependencyFailed, Error: errors.New("required module user failed")},
+			{Name: "docker", Status: runner.StatusInstalled},
+			{Name: "coolify", Status: runner.StatusNotRun},
+		},
+	}
+	if err := SaveLastRun(run); err != nil {
+		t.Fatalf("SaveLastRun() error = %v", err)
+	}
+	results, err := LoadLastRun()
+	if err != nil {
+		t.Fatalf("LoadLastRun() error = %v", err)
+	}
+
+	want := []string{"user", "security", "coolify", "swap"}
+	if got := Unfinished(results); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unfinished() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,105 @@
+package runner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/stwalsh4118/phanes/internal/config"
+	"github.com/stwalsh4118/phanes/internal/log"
+	"github.com/stwalsh4118/phanes/internal/module"
+)
+
+// PromptFunc asks the operator to confirm the risky actions of a module.
+// Returns true if the operator approved the actions.
+type PromptFunc func(moduleName string, risks []module.Risk) (bool, error)
+
+// RiskPolicy controls how the runner handles modules that report risky actions.
+type RiskPolicy struct {
+	// AssumeYes approves all risky actions without prompting (--yes).
+	AssumeYes bool
+
+	// Safe refuses all risky actions when the run is non-interactive (--safe).
+	// Safe takes precedence over AssumeYes on non-interactive runs.
+	Safe bool
+
+	// Interactive indicates that an operator is available to answer prompts.
+	Interactive bool
+
+	// Prompt is used to ask for confirmation on interactive runs.
+	// If nil, interactive runs behave like non-interactive runs.
+	Prompt PromptFunc
+}
+
+// NewPrompt returns a PromptFunc that prints the risks to out and reads a
+// yes/no answer from in. Anything other than "y" or "yes" is treated as no.
+func NewPrompt(in io.Reader, out io.Writer) PromptFunc {
+	reader := bufio.NewReader(in)
+	return func(moduleName string, risks []module.Risk) (bool, error) {
+		fmt.Fprintf(out, "\nModule %s is about to perform risky changes:\n", moduleName)
+		for _, risk := range risks {
+			fmt.Fprintf(out, "  [%s] %s\n", risk.Level, risk.Action)
+			if risk.Reason != "" {
+				fmt.Fprintf(out, "         %s\n", risk.Reason)
+			}
+		}
+		fmt.Fprintf(out, "Proceed with module %s? [y/N]: ", moduleName)
+
+		answer, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return false, fmt.Errorf("failed to read confirmation: %w", err)
+		}
+
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		return answer == "y" || answer == "yes", nil
+	}
+}
+
+// assessRisks returns the risks reported by mod, or nil if the module does not
+// implement module.RiskAssessor.
+func assessRisks(mod module.Module, cfg *config.Config) ([]module.Risk, error) {
+	assessor, ok := mod.(module.RiskAssessor)
+	if !ok {
+		return nil, nil
+	}
+	return assessor.Risks(cfg)
+}
+
+// confirmRisks applies the runner's risk policy to the given risks.
+// Returns nil if the module may proceed, or an error describing why it may not.
+func (r *Runner) confirmRisks(name string, risks []module.Risk) error {
+	if len(risks) == 0 {
+		return nil
+	}
+
+	for _, risk := range risks {
+		log.Warn("Module %s: [%s risk] %s", name, risk.Level, risk.Action)
+	}
+
+	policy := r.riskPolicy
+	interactive := policy.Interactive && policy.Prompt != nil
+
+	if policy.Safe && !interactive {
+		return fmt.Errorf("refused %d risky change(s) in safe mode", len(risks))
+	}
+
+	if policy.AssumeYes {
+		log.Info("Risky changes for module %s approved by --yes", name)
+		return nil
+	}
+
+	if !interactive {
+		return fmt.Errorf("%d risky change(s) require confirmation; rerun interactively or with --yes", len(risks))
+	}
+
+	approved, err := policy.Prompt(name, risks)
+	if err != nil {
+		return err
+	}
+	if !approved {
+		return fmt.Errorf("risky changes were declined")
+	}
+
+	return nil
+}
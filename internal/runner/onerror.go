@@ -0,0 +1,41 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/stwalsh4118/phanes/internal/module"
+)
+
+// ErrorPolicy is what the runner does with the remaining modules after a
+// module fails.
+type ErrorPolicy string
+
+const (
+	// OnErrorSkipDependents skips the modules that require a failed module
+	// (see module.Metadata.Requires) and runs all others. This is the default.
+	OnErrorSkipDependents ErrorPolicy = "skip-dependents"
+
+	// OnErrorStop stops the run at the first failure.
+	OnErrorStop ErrorPolicy = "stop"
+
+	// OnErrorContinue runs all remaining modules, including those requiring
+	// a failed module.
+	OnErrorContinue ErrorPolicy = "continue"
+)
+
+// ParseErrorPolicy parses "stop", "continue" or "skip-dependents".
+func ParseErrorPolicy(s string) (ErrorPolicy, error) {
+	switch ErrorPolicy(s) {
+	case OnErrorStop, OnErrorContinue, OnErrorSkipDependents:
+		return ErrorPolicy(s), nil
+	}
+	return "", fmt.Errorf("invalid error policy %q: must be %s, %s or %s", s, OnErrorStop, OnErrorContinue, OnErrorSkipDependents)
+}
+
+// requires returns the modules mod requires (see module.Metadata.Requires).
+func requires(mod module.Module) []string {
+	if describer, ok := mod.(module.Describer); ok {
+		return describer.Metadata().Requires
+	}
+	return nil
+}
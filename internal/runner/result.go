@@ -1,6 +1,10 @@
 package runner
 
-import "time"
+import (
+	"time"
+
+	"github.com/stwalsh4118/phanes/internal/timing"
+)
 
 // ModuleStatus represents the execution status of a module.
 type ModuleStatus string
@@ -21,6 +25,22 @@ const (
 	// StatusWouldInstall indicates the module would be installed in dry-run mode.
 	// This status is only used when dry-run is enabled and the module is not currently installed.
 	StatusWouldInstall ModuleStatus = "would_install"
+
+	// StatusDeclined indicates the module was not installed because its risky
+	// actions were declined, refused by safe mode, or could not be confirmed.
+	StatusDeclined ModuleStatus = "declined"
+
+	// StatusDeferred indicates the module was not processed yet because the
+	// server reboots first (see Runner.SetRebootIfNeeded).
+	StatusDeferred ModuleStatus = "deferred"
+
+	// StatusDependencyFailed indicates the module was skipped because a module
+	// it requires failed earlier in the run (see OnErrorSkipDependents).
+	StatusDependencyFailed ModuleStatus = "dependency_failed"
+
+	// StatusNotRun indicates the module was not processed because the run
+	// stopped at an earlier failure (see OnErrorStop).
+	StatusNotRun ModuleStatus = "not_run"
 )
 
 // ModuleResult represents the execution result of a single module.
@@ -31,11 +51,17 @@ type ModuleResult struct {
 	// Status indicates the execution outcome of the module.
 	Status ModuleStatus
 
-	// Error contains error details if Status is StatusFailed or StatusError.
+	// Error contains error details if Status is StatusFailed, StatusError, StatusDeclined,
+	// or StatusDependencyFailed.
 	// This field is nil for successful or skipped modules.
 	Error error
 
-	// Duration is the time taken to execute the module (optional).
-	// This field may be zero if duration tracking is not implemented.
+	// Duration is the time taken to process the module, including the
+	// IsInstalled() check, risk confirmation, and Install().
 	Duration time.Duration
+
+	// Steps are the significant steps the module tracked while it ran
+	// (e.g., apt update, package installs, downloads), in completion order.
+	// This field is empty if the module did not track any steps.
+	Steps []timing.Step
 }
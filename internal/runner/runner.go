@@ -1,27 +1,60 @@
 package runner
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/stwalsh4118/phanes/internal/config"
+	"github.com/stwalsh4118/phanes/internal/exec"
 	"github.com/stwalsh4118/phanes/internal/log"
 	"github.com/stwalsh4118/phanes/internal/module"
+	"github.com/stwalsh4118/phanes/internal/reboot"
+	"github.com/stwalsh4118/phanes/internal/timing"
 )
 
+// rebootPending reports whether the server needs a reboot. It is a variable so
+// tests can simulate a pending reboot.
+var rebootPending = reboot.Required
+
 // Runner manages a registry of modules and executes them in order.
 // It ensures idempotency by checking IsInstalled() before calling Install(),
 // and supports dry-run mode for previewing actions without executing them.
 type Runner struct {
-	modules map[string]module.Module
+	modules        map[string]module.Module
+	riskPolicy     RiskPolicy
+	errorPolicy    ErrorPolicy
+	rebootIfNeeded bool
 }
 
 // NewRunner creates a new Runner instance with an empty module registry.
 func NewRunner() *Runner {
 	return &Runner{
-		modules: make(map[string]module.Module),
+		modules:     make(map[string]module.Module),
+		errorPolicy: OnErrorSkipDependents,
 	}
 }
 
+// SetRiskPolicy configures how risky actions reported by modules are confirmed.
+// By default, risky actions are declined because no operator can confirm them.
+func (r *Runner) SetRiskPolicy(policy RiskPolicy) {
+	r.riskPolicy = policy
+}
+
+// SetErrorPolicy configures what happens to the remaining modules after a
+// module fails. By default, modules requiring a failed module are skipped.
+func (r *Runner) SetErrorPolicy(policy ErrorPolicy) {
+	r.errorPolicy = policy
+}
+
+// SetRebootIfNeeded configures how modules that need a pending reboot first
+// (see module.BootRequirer) are handled. If enabled, the run stops at such a
+// module and it and the remaining modules are deferred, so they can run after
+// the server reboots. By default, the runner warns and installs the module.
+func (r *Runner) SetRebootIfNeeded(enabled bool) {
+	r.rebootIfNeeded = enabled
+}
+
 // RegisterModule adds a module to the registry.
 // If a module with the same name is already registered, it will be overwritten
 // and a warning will be logged.
@@ -37,6 +70,10 @@ func (r *Runner) RegisterModule(mod module.Module) {
 // RunModules executes the specified modules in order.
 // It checks IsInstalled() before calling Install() to ensure idempotency.
 // If dryRun is true, it logs what would happen without actually executing Install().
+// Each result records how long the module took and the steps it tracked via the timing package.
+// If a module is deferred until after a reboot, it and all remaining modules are returned as StatusDeferred.
+// After a failure, the error policy decides which of the remaining modules run (see SetErrorPolicy):
+// skipped modules are returned as StatusDependencyFailed, modules after a stop as StatusNotRun.
 // Returns a slice of ModuleResult for each module processed and an error if any module fails.
 func (r *Runner) RunModules(names []string, cfg *config.Config, dryRun bool) ([]ModuleResult, error) {
 	if len(names) == 0 {
@@ -46,102 +83,196 @@ func (r *Runner) RunModules(names []string, cfg *config.Config, dryRun bool) ([]
 	var errors []error
 	results := make([]ModuleResult, 0, len(names))
 
-	for _, name := range names {
-		mod, exists := r.modules[name]
-		if !exists {
-			err := fmt.Errorf("module %s not found in registry", name)
-			log.Error("Failed to find module: %s", name)
-			results = append(results, ModuleResult{
+	failed := make(map[string]bool)
+	for i, name := range names {
+		if dependency := r.failedDependency(name, failed); dependency != "" {
+			log.Skip("Skipping module %s because it requires %s, which failed", name, dependency)
+			result := ModuleResult{
 				Name:   name,
-				Status: StatusError,
-				Error:  err,
-			})
-			errors = append(errors, err)
+				Status: StatusDependencyFailed,
+				Error:  fmt.Errorf("module %s: required module %s failed", name, dependency),
+			}
+			results = append(results, result)
+			errors = append(errors, result.Error)
+			failed[name] = true
 			continue
 		}
 
-		log.Info("Processing module: %s", name)
-
-		if dryRun {
-			// In dry-run mode, check IsInstalled but don't call Install
-			installed, err := mod.IsInstalled()
-			if err != nil {
-				log.Error("Failed to check if module %s is installed: %v", name, err)
-				result := ModuleResult{
-					Name:   name,
-					Status: StatusError,
-					Error:  fmt.Errorf("module %s: %w", name, err),
-				}
-				results = append(results, result)
-				errors = append(errors, result.Error)
-				continue
-			}
+		start := time.Now()
+		timing.Start()
+		result := r.runModule(name, cfg, dryRun)
+		result.Steps = timing.Stop()
+		result.Duration = time.Since(start)
 
-			if installed {
-				log.Skip("Module %s is already installed (dry-run)", name)
-				results = append(results, ModuleResult{
-					Name:   name,
-					Status: StatusSkipped,
-				})
-			} else {
-				log.Info("Would install module %s (dry-run)", name)
-				results = append(results, ModuleResult{
-					Name:   name,
-					Status: StatusWouldInstall,
-				})
-			}
-			continue
+		results = append(results, result)
+		if result.Error != nil {
+			errors = append(errors, result.Error)
+			failed[name] = true
 		}
 
-		// Check if module is already installed
-		installed, err := mod.IsInstalled()
-		if err != nil {
-			log.Error("Failed to check if module %s is installed: %v", name, err)
-			result := ModuleResult{
-				Name:   name,
-				Status: StatusError,
-				Error:  fmt.Errorf("module %s: %w", name, err),
-			}
-			results = append(results, result)
-			errors = append(errors, result.Error)
+		var rest ModuleStatus
+		switch {
+		case result.Status == StatusDeferred:
+			rest = StatusDeferred
+		case result.Error != nil && r.errorPolicy == OnErrorStop:
+			log.Warn("Stopping after module %s failed, %d module(s) not run", name, len(names)-i-1)
+			rest = StatusNotRun
+		default:
 			continue
 		}
+		for _, remaining := range names[i+1:] {
+			results = append(results, ModuleResult{Name: remaining, Status: rest})
+		}
+		break
+	}
+
+	if len(errors) > 0 {
+		return results, fmt.Errorf("failed to execute %d module(s): %v", len(errors), errors)
+	}
+
+	return results, nil
+}
+
+// runModule processes a single module and returns its result.
+// Duration and Steps are filled in by RunModules.
+func (r *Runner) runModule(name string, cfg *config.Config, dryRun bool) ModuleResult {
+	mod, exists := r.modules[name]
+	if !exists {
+		log.Error("Failed to find module: %s", name)
+		return ModuleResult{
+			Name:   name,
+			Status: StatusError,
+			Error:  fmt.Errorf("module %s not found in registry", name),
+		}
+	}
+
+	log.Info("Processing module: %s", name)
+
+	// Check if module is already installed
+	installed, err := mod.IsInstalled()
+	if err != nil {
+		log.Error("Failed to check if module %s is installed: %v", name, err)
+		return ModuleResult{
+			Name:   name,
+			Status: StatusError,
+			Error:  fmt.Errorf("module %s: %w", name, err),
+		}
+	}
 
+	if dryRun {
+		// In dry-run mode, report what would happen but don't call Install
 		if installed {
-			log.Skip("Module %s is already installed, skipping", name)
-			results = append(results, ModuleResult{
+			log.Skip("Module %s is already installed (dry-run)", name)
+			return ModuleResult{
 				Name:   name,
 				Status: StatusSkipped,
-			})
-			continue
+			}
 		}
 
-		// Install the module
-		log.Info("Installing module: %s", name)
-		if err := mod.Install(cfg); err != nil {
-			log.Error("Failed to install module %s: %v", name, err)
-			result := ModuleResult{
+		log.Info("Would install module %s (dry-run)", name)
+		if needsBoot(mod, cfg) {
+			log.Warn("Module %s would need a reboot first", name)
+		}
+		risks, err := assessRisks(mod, cfg)
+		if err != nil {
+			log.Warn("Failed to assess risks for module %s: %v", name, err)
+		}
+		for _, risk := range risks {
+			log.Warn("Would require confirmation: [%s risk] %s", risk.Level, risk.Action)
+		}
+		return ModuleResult{
+			Name:   name,
+			Status: StatusWouldInstall,
+		}
+	}
+
+	if installed {
+		log.Skip("Module %s is already installed, skipping", name)
+		return ModuleResult{
+			Name:   name,
+			Status: StatusSkipped,
+		}
+	}
+
+	// Reboot first if the module needs the latest kernel
+	if needsBoot(mod, cfg) {
+		if r.rebootIfNeeded {
+			log.Warn("Module %s needs a reboot first, deferring it until after boot", name)
+			return ModuleResult{
 				Name:   name,
-				Status: StatusFailed,
-				Error:  fmt.Errorf("module %s: %w", name, err),
+				Status: StatusDeferred,
 			}
-			results = append(results, result)
-			errors = append(errors, result.Error)
-			continue
 		}
+		log.Warn("Module %s may not work until the server reboots (use --reboot-if-needed to reboot first)", name)
+	}
 
-		log.Success("Successfully installed module: %s", name)
-		results = append(results, ModuleResult{
+	// Confirm risky actions before installing
+	risks, err := assessRisks(mod, cfg)
+	if err != nil {
+		log.Error("Failed to assess risks for module %s: %v", name, err)
+		return ModuleResult{
 			Name:   name,
-			Status: StatusInstalled,
-		})
+			Status: StatusError,
+			Error:  fmt.Errorf("module %s: %w", name, err),
+		}
+	}
+	if err := r.confirmRisks(name, risks); err != nil {
+		log.Error("Module %s was not installed: %v", name, err)
+		return ModuleResult{
+			Name:   name,
+			Status: StatusDeclined,
+			Error:  fmt.Errorf("module %s: %w", name, err),
+		}
 	}
 
-	if len(errors) > 0 {
-		return results, fmt.Errorf("failed to execute %d module(s): %v", len(errors), errors)
+	// Install the module
+	log.Info("Installing module: %s", name)
+	if err := mod.Install(cfg); err != nil {
+		// Under an alternate root, a module needing a command that cannot run
+		// there is not applied, but has not failed either
+		if errors.Is(err, exec.ErrSkipped) {
+			log.Skip("Module %s skipped: %v", name, err)
+			return ModuleResult{
+				Name:   name,
+				Status: StatusSkipped,
+			}
+		}
+		log.Error("Failed to install module %s: %v", name, err)
+		return ModuleResult{
+			Name:   name,
+			Status: StatusFailed,
+			Error:  fmt.Errorf("module %s: %w", name, err),
+		}
 	}
 
-	return results, nil
+	log.Success("Successfully installed module: %s", name)
+	return ModuleResult{
+		Name:   name,
+		Status: StatusInstalled,
+	}
+}
+
+// failedDependency returns the first module required by the module name that
+// failed earlier in the run, or an empty string if none did or the error
+// policy runs dependents anyway.
+func (r *Runner) failedDependency(name string, failed map[string]bool) string {
+	mod, exists := r.modules[name]
+	if !exists || r.errorPolicy != OnErrorSkipDependents {
+		return ""
+	}
+	for _, required := range requires(mod) {
+		if failed[required] {
+			return required
+		}
+	}
+	return ""
+}
+
+// needsBoot reports whether mod must wait for a pending reboot before
+// installing with cfg.
+func needsBoot(mod module.Module, cfg *config.Config) bool {
+	requirer, ok := mod.(module.BootRequirer)
+	return ok && requirer.RequiresBoot(cfg) && rebootPending()
 }
 
 // GetModule returns a module from the registry by name.
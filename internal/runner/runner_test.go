@@ -1,10 +1,17 @@
 package runner
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stwalsh4118/phanes/internal/config"
+	"github.com/stwalsh4118/phanes/internal/exec"
+	"github.com/stwalsh4118/phanes/internal/module"
+	"github.com/stwalsh4118/phanes/internal/timing"
 )
 
 // mockModule is a test implementation of the Module interface.
@@ -32,6 +39,59 @@ func (m *mockModule) Install(cfg *config.Config) error {
 	return m.installErr
 }
 
+// riskyModule is a mock module that reports risky actions.
+type riskyModule struct {
+	mockModule
+	risks      []module.Risk
+	installRan bool
+}
+
+func (m *riskyModule) Risks(cfg *config.Config) ([]module.Risk, error) {
+	return m.risks, nil
+}
+
+func (m *riskyModule) Install(cfg *config.Config) error {
+	m.installRan = true
+	return nil
+}
+
+func newRiskyModule() *riskyModule {
+	return &riskyModule{
+		mockModule: mockModule{name: "risky", description: "Risky module"},
+		risks: []module.Risk{
+			{Level: module.RiskHigh, Action: "Change SSH port", Reason: "May lock you out"},
+		},
+	}
+}
+
+// steppedModule is a mock module that tracks timing steps during Install.
+type steppedModule struct {
+	mockModule
+	steps []timing.Step
+}
+
+func (m *steppedModule) Install(cfg *config.Config) error {
+	for _, step := range m.steps {
+		timing.Record(step.Name, step.Duration)
+	}
+	return nil
+}
+
+// bootModule is a mock module that needs a pending reboot before installing.
+type bootModule struct {
+	mockModule
+	installRan bool
+}
+
+func (m *bootModule) RequiresBoot(cfg *config.Config) bool {
+	return true
+}
+
+func (m *bootModule) Install(cfg *config.Config) error {
+	m.installRan = true
+	return nil
+}
+
 func TestNewRunner(t *testing.T) {
 	r := NewRunner()
 	if r == nil {
@@ -197,6 +257,23 @@ func TestRunModules_InstallError(t *testing.T) {
 	}
 }
 
+func TestRunModules_InstallSkippedCommand(t *testing.T) {
+	r := NewRunner()
+	mod := &mockModule{
+		name:       "test",
+		installErr: fmt.Errorf("failed to format swap file: %w", exec.ErrSkipped),
+	}
+	r.RegisterModule(mod)
+
+	results, err := r.RunModules([]string{"test"}, config.DefaultConfig(), false)
+	if err != nil {
+		t.Fatalf("RunModules() error = %v, want nil for a skipped command", err)
+	}
+	if len(results) != 1 || results[0].Status != StatusSkipped {
+		t.Fatalf("results = %+v, want the module skipped", results)
+	}
+}
+
 func TestRunModules_IsInstalledError(t *testing.T) {
 	r := NewRunner()
 	checkErr := errors.New("check failed")
@@ -425,3 +502,250 @@ func TestListModules(t *testing.T) {
 		t.Fatal("ListModules did not return all registered modules")
 	}
 }
+
+func TestRunModules_RiskPolicy(t *testing.T) {
+	approve := func(string, []module.Risk) (bool, error) { return true, nil }
+	decline := func(string, []module.Risk) (bool, error) { return false, nil }
+
+	tests := []struct {
+		name        string
+		policy      RiskPolicy
+		wantStatus  ModuleStatus
+		wantInstall bool
+	}{
+		{
+			name:        "non-interactive without --yes is declined",
+			policy:      RiskPolicy{},
+			wantStatus:  StatusDeclined,
+			wantInstall: false,
+		},
+		{
+			name:        "non-interactive with --yes is approved",
+			policy:      RiskPolicy{AssumeYes: true},
+			wantStatus:  StatusInstalled,
+			wantInstall: true,
+		},
+		{
+			name:        "safe mode refuses non-interactive run even with --yes",
+			policy:      RiskPolicy{AssumeYes: true, Safe: true},
+			wantStatus:  StatusDeclined,
+			wantInstall: false,
+		},
+		{
+			name:        "interactive approval",
+			policy:      RiskPolicy{Interactive: true, Prompt: approve},
+			wantStatus:  StatusInstalled,
+			wantInstall: true,
+		},
+		{
+			name:        "interactive decline",
+			policy:      RiskPolicy{Interactive: true, Prompt: decline},
+			wantStatus:  StatusDeclined,
+			wantInstall: false,
+		},
+		{
+			name:        "safe mode still prompts on interactive runs",
+			policy:      RiskPolicy{Safe: true, Interactive: true, Prompt: approve},
+			wantStatus:  StatusInstalled,
+			wantInstall: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRunner()
+			mod := newRiskyModule()
+			r.RegisterModule(mod)
+			r.SetRiskPolicy(tt.policy)
+
+			results, err := r.RunModules([]string{"risky"}, config.DefaultConfig(), false)
+			if (err != nil) != (tt.wantStatus == StatusDeclined) {
+				t.Fatalf("RunModules() error = %v, want error: %v", err, tt.wantStatus == StatusDeclined)
+			}
+			if results[0].Status != tt.wantStatus {
+				t.Errorf("Expected %s, got %s", tt.wantStatus, results[0].Status)
+			}
+			if mod.installRan != tt.wantInstall {
+				t.Errorf("Install() ran = %v, want %v", mod.installRan, tt.wantInstall)
+			}
+		})
+	}
+}
+
+func TestRunModules_RiskDryRun(t *testing.T) {
+	r := NewRunner()
+	mod := newRiskyModule()
+	r.RegisterModule(mod)
+
+	results, err := r.RunModules([]string{"risky"}, config.DefaultConfig(), true)
+	if err != nil {
+		t.Fatalf("Expected no error in dry-run, got: %v", err)
+	}
+	if results[0].Status != StatusWouldInstall {
+		t.Errorf("Expected StatusWouldInstall, got %s", results[0].Status)
+	}
+	if mod.installRan {
+		t.Error("Install() should not run in dry-run mode")
+	}
+}
+
+func TestNewPrompt(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{input: "y\n", want: true},
+		{input: "YES\n", want: true},
+		{input: "n\n", want: false},
+		{input: "\n", want: false},
+		{input: "", want: false},
+	}
+
+	risks := []module.Risk{{Level: module.RiskHigh, Action: "Disable password auth"}}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		prompt := NewPrompt(strings.NewReader(tt.input), &out)
+		got, err := prompt("security", risks)
+		if err != nil {
+			t.Fatalf("prompt(%q) returned error: %v", tt.input, err)
+		}
+		if got != tt.want {
+			t.Errorf("prompt(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+		if !strings.Contains(out.String(), "Disable password auth") {
+			t.Errorf("prompt output missing risk action: %q", out.String())
+		}
+	}
+}
+
+func TestRunModules_RecordsTiming(t *testing.T) {
+	r := NewRunner()
+	r.RegisterModule(&steppedModule{
+		mockModule: mockModule{name: "first", description: "First module"},
+		steps: []timing.Step{
+			{Name: "apt-get update", Duration: 3 * time.Second},
+			{Name: "apt-get install", Duration: 5 * time.Second},
+		},
+	})
+	r.RegisterModule(&mockModule{name: "second", description: "Second module"})
+
+	results, err := r.RunModules([]string{"first", "second"}, config.DefaultConfig(), false)
+	if err != nil {
+		t.Fatalf("RunModules() error = %v", err)
+	}
+
+	for _, result := range results {
+		if result.Duration <= 0 {
+			t.Errorf("module %s: Duration = %v, want > 0", result.Name, result.Duration)
+		}
+	}
+
+	if len(results[0].Steps) != 2 {
+		t.Fatalf("module first: got %d steps, want 2", len(results[0].Steps))
+	}
+	if results[0].Steps[1].Name != "apt-get install" {
+		t.Errorf("module first: Steps[1].Name = %q, want %q", results[0].Steps[1].Name, "apt-get install")
+	}
+	if len(results[1].Steps) != 0 {
+		t.Errorf("module second: steps leaked from previous module: %+v", results[1].Steps)
+	}
+}
+
+func TestRunModules_RebootIfNeeded(t *testing.T) {
+	orig := rebootPending
+	t.Cleanup(func() { rebootPending = orig })
+
+	tests := []struct {
+		name           string
+		pending        bool
+		rebootIfNeeded bool
+		wantStatuses   []ModuleStatus
+		wantInstall    bool
+	}{
+		{"no reboot pending", false, true, []ModuleStatus{StatusInstalled, StatusInstalled, StatusInstalled}, true},
+		{"reboot pending", true, true, []ModuleStatus{StatusInstalled, StatusDeferred, StatusDeferred}, false},
+		{"reboot pending without policy", true, false, []ModuleStatus{StatusInstalled, StatusInstalled, StatusInstalled}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rebootPending = func() bool { return tt.pending }
+			boot := &bootModule{mockModule: mockModule{name: "docker", description: "Needs boot"}}
+			r := NewRunner()
+			r.SetRebootIfNeeded(tt.rebootIfNeeded)
+			r.RegisterModule(&mockModule{name: "first", description: "First module"})
+			r.RegisterModule(boot)
+			r.RegisterModule(&mockModule{name: "last", description: "Last module"})
+
+			results, err := r.RunModules([]string{"first", "docker", "last"}, config.DefaultConfig(), false)
+			if err != nil {
+				t.Fatalf("RunModules() error = %v", err)
+			}
+			if len(results) != len(tt.wantStatuses) {
+				t.Fatalf("got %d results, want %d", len(results), len(tt.wantStatuses))
+			}
+			for i, want := range tt.wantStatuses {
+				if results[i].Status != want {
+					t.Errorf("module %s: Status = %s, want %s", results[i].Name, results[i].Status, want)
+				}
+			}
+			if boot.installRan != tt.wantInstall {
+				t.Errorf("Install() ran = %v, want %v", boot.installRan, tt.wantInstall)
+			}
+		})
+	}
+}
+
+// requiringModule is a mock module that requires other modules.
+type requiringModule struct {
+	mockModule
+	requires []string
+}
+
+func (m *requiringModule) Metadata() module.Metadata {
+	return module.Metadata{Requires: m.requires}
+}
+
+func TestRunModules_ErrorPolicy(t *testing.T) {
+	tests := []struct {
+		policy       ErrorPolicy
+		wantStatuses []ModuleStatus
+	}{
+		{OnErrorSkipDependents, []ModuleStatus{StatusFailed, StatusDependencyFailed, StatusDependencyFailed, StatusInstalled}},
+		{OnErrorStop, []ModuleStatus{StatusFailed, StatusNotRun, StatusNotRun, StatusNotRun}},
+		{OnErrorContinue, []ModuleStatus{StatusFailed, StatusInstalled, StatusInstalled, StatusInstalled}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			r := NewRunner()
+			r.SetErrorPolicy(tt.policy)
+			r.RegisterModule(&mockModule{name: "user", description: "User", installErr: errors.New("useradd failed")})
+			r.RegisterModule(&requiringModule{mockModule: mockModule{name: "docker", description: "Docker"}, requires: []string{"user"}})
+			r.RegisterModule(&requiringModule{mockModule: mockModule{name: "coolify", description: "Coolify"}, requires: []string{"docker"}})
+			r.RegisterModule(&mockModule{name: "swap", description: "Swap"})
+
+			results, err := r.RunModules([]string{"user", "docker", "coolify", "swap"}, config.DefaultConfig(), false)
+			if err == nil {
+				t.Error("RunModules() should return an error")
+			}
+			if len(results) != len(tt.wantStatuses) {
+				t.Fatalf("got %d results, want %d", len(results), len(tt.wantStatuses))
+			}
+			for i, want := range tt.wantStatuses {
+				if results[i].Status != want {
+					t.Errorf("module %s: Status = %s, want %s", results[i].Name, results[i].Status, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrorPolicy(t *testing.T) {
+	for _, s := range []string{"stop", "continue", "skip-dependents"} {
+		if policy, err := ParseErrorPolicy(s); err != nil || string(policy) != s {
+			t.Errorf("ParseErrorPolicy(%q) = %q, %v", s, policy, err)
+		}
+	}
+	if _, err := ParseErrorPolicy("ignore"); err == nil {
+		t.Error("ParseErrorPolicy(\"ignore\") should fail")
+	}
+}
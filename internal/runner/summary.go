@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 )
 
 const (
@@ -15,9 +16,9 @@ const (
 )
 
 // PrintSummary displays a formatted summary table of module execution results.
-// The table shows each module's name, status, and error details (if any).
-// Status indicators are color-coded: green for installed, yellow for skipped, red for failed/error.
-// A summary line shows total counts for each status.
+// The table shows each module's name, status, duration, and error details (if any).
+// Status indicators are color-coded: green for installed, yellow for skipped/declined/deferred/not run, red for failed/error/dependency failed.
+// A summary line shows total counts for each status and the total duration.
 // If dryRun is true, a dry-run indicator is displayed.
 func PrintSummary(results []ModuleResult, dryRun bool) {
 	if len(results) == 0 {
@@ -27,7 +28,9 @@ func PrintSummary(results []ModuleResult, dryRun bool) {
 	// Calculate column widths
 	maxNameLen := len("Module")
 	maxStatusLen := len("Status")
+	maxDurationLen := len("Duration")
 	maxDetailsLen := len("Details")
+	var total time.Duration
 
 	for _, result := range results {
 		if len(result.Name) > maxNameLen {
@@ -37,6 +40,10 @@ func PrintSummary(results []ModuleResult, dryRun bool) {
 		if len(statusStr) > maxStatusLen {
 			maxStatusLen = len(statusStr)
 		}
+		if durationStr := formatDuration(result.Duration); len(durationStr) > maxDurationLen {
+			maxDurationLen = len(durationStr)
+		}
+		total += result.Duration
 		if result.Error != nil {
 			errorMsg := result.Error.Error()
 			// Truncate long error messages for display
@@ -64,25 +71,28 @@ func PrintSummary(results []ModuleResult, dryRun bool) {
 	fmt.Fprintf(os.Stdout, "\n")
 
 	// Print table header
-	headerLine := fmt.Sprintf("┌─%s─┬─%s─┬─%s─┐",
+	headerLine := fmt.Sprintf("┌─%s─┬─%s─┬─%s─┬─%s─┐",
 		strings.Repeat("─", maxNameLen),
 		strings.Repeat("─", maxStatusLen),
+		strings.Repeat("─", maxDurationLen),
 		strings.Repeat("─", maxDetailsLen))
 	fmt.Fprintf(os.Stdout, "%s\n", headerLine)
 
-	fmt.Fprintf(os.Stdout, "│ %-*s │ %-*s │ %-*s │\n",
+	fmt.Fprintf(os.Stdout, "│ %-*s │ %-*s │ %*s │ %-*s │\n",
 		maxNameLen, "Module",
 		maxStatusLen, "Status",
+		maxDurationLen, "Duration",
 		maxDetailsLen, "Details")
 
-	separatorLine := fmt.Sprintf("├─%s─┼─%s─┼─%s─┤",
+	separatorLine := fmt.Sprintf("├─%s─┼─%s─┼─%s─┼─%s─┤",
 		strings.Repeat("─", maxNameLen),
 		strings.Repeat("─", maxStatusLen),
+		strings.Repeat("─", maxDurationLen),
 		strings.Repeat("─", maxDetailsLen))
 	fmt.Fprintf(os.Stdout, "%s\n", separatorLine)
 
 	// Count totals
-	var installedCount, skippedCount, failedCount, wouldInstallCount int
+	var installedCount, skippedCount, failedCount, wouldInstallCount, declinedCount, deferredCount, dependencyFailedCount, notRunCount int
 
 	// Print table rows
 	for _, result := range results {
@@ -100,9 +110,10 @@ func PrintSummary(results []ModuleResult, dryRun bool) {
 		}
 
 		// Print row with color
-		fmt.Fprintf(os.Stdout, "│ %-*s │ %s%-*s%s │ %-*s │\n",
+		fmt.Fprintf(os.Stdout, "│ %-*s │ %s%-*s%s │ %*s │ %-*s │\n",
 			maxNameLen, result.Name,
 			color, maxStatusLen, statusStr, colorReset,
+			maxDurationLen, formatDuration(result.Duration),
 			maxDetailsLen, details)
 
 		// Count totals
@@ -115,13 +126,22 @@ func PrintSummary(results []ModuleResult, dryRun bool) {
 			wouldInstallCount++
 		case StatusFailed, StatusError:
 			failedCount++
+		case StatusDeclined:
+			declinedCount++
+		case StatusDeferred:
+			deferredCount++
+		case StatusDependencyFailed:
+			dependencyFailedCount++
+		case StatusNotRun:
+			notRunCount++
 		}
 	}
 
 	// Print table footer
-	footerLine := fmt.Sprintf("└─%s─┴─%s─┴─%s─┘",
+	footerLine := fmt.Sprintf("└─%s─┴─%s─┴─%s─┴─%s─┘",
 		strings.Repeat("─", maxNameLen),
 		strings.Repeat("─", maxStatusLen),
+		strings.Repeat("─", maxDurationLen),
 		strings.Repeat("─", maxDetailsLen))
 	fmt.Fprintf(os.Stdout, "%s\n", footerLine)
 
@@ -139,9 +159,24 @@ func PrintSummary(results []ModuleResult, dryRun bool) {
 	if failedCount > 0 {
 		summaryParts = append(summaryParts, fmt.Sprintf("%d failed", failedCount))
 	}
+	if declinedCount > 0 {
+		summaryParts = append(summaryParts, fmt.Sprintf("%d declined", declinedCount))
+	}
+	if deferredCount > 0 {
+		summaryParts = append(summaryParts, fmt.Sprintf("%d deferred", deferredCount))
+	}
+	if dependencyFailedCount > 0 {
+		summaryParts = append(summaryParts, fmt.Sprintf("%d skipped (dependency failed)", dependencyFailedCount))
+	}
+	if notRunCount > 0 {
+		summaryParts = append(summaryParts, fmt.Sprintf("%d not run", notRunCount))
+	}
 
 	if len(summaryParts) > 0 {
 		summaryLine := fmt.Sprintf("Summary: %s", strings.Join(summaryParts, ", "))
+		if total > 0 {
+			summaryLine += fmt.Sprintf(" in %s", formatDuration(total))
+		}
 		if dryRun {
 			summaryLine += " (dry-run)"
 		}
@@ -167,6 +202,14 @@ func getStatusString(status ModuleStatus) string {
 		return "✗ Failed"
 	case StatusError:
 		return "✗ Error"
+	case StatusDeclined:
+		return "⊗ Declined"
+	case StatusDeferred:
+		return "↻ Deferred"
+	case StatusDependencyFailed:
+		return "✗ Dependency Failed"
+	case StatusNotRun:
+		return "- Not Run"
 	default:
 		return string(status)
 	}
@@ -181,9 +224,26 @@ func getStatusColor(status ModuleStatus) string {
 		return colorYellow
 	case StatusWouldInstall:
 		return colorGreen // Green to indicate positive action, but different symbol distinguishes it
-	case StatusFailed, StatusError:
+	case StatusFailed, StatusError, StatusDependencyFailed:
 		return colorRed
+	case StatusDeclined, StatusDeferred, StatusNotRun:
+		return colorYellow
 	default:
 		return colorReset
 	}
 }
+
+// formatDuration formats a duration for display, returning "-" for zero values.
+// Durations under a second are shown in milliseconds, longer ones to a tenth of a second.
+func formatDuration(d time.Duration) string {
+	switch {
+	case d <= 0:
+		return "-"
+	case d < time.Millisecond:
+		return "<1ms"
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	default:
+		return d.Round(100 * time.Millisecond).String()
+	}
+}
@@ -0,0 +1,94 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/stwalsh4118/phanes/internal/timing"
+)
+
+const (
+	// slowestStepCount is the number of steps listed in the slow-step report.
+	slowestStepCount = 5
+
+	// untrackedStepName labels time spent in a module outside of tracked steps.
+	untrackedStepName = "(other)"
+)
+
+// moduleStep is a tracked step together with the module it belongs to.
+type moduleStep struct {
+	Module string
+	timing.Step
+}
+
+// PrintTimings displays a per-module breakdown of tracked steps followed by
+// the slowest steps across all modules.
+// Time spent in a module outside of tracked steps is shown as "(other)".
+func PrintTimings(results []ModuleResult) {
+	printTimings(os.Stdout, results)
+}
+
+// printTimings writes the timing breakdown to w.
+func printTimings(w io.Writer, results []ModuleResult) {
+	if len(results) == 0 {
+		return
+	}
+
+	nameWidth := len(untrackedStepName)
+	for _, result := range results {
+		if len(result.Name) > nameWidth {
+			nameWidth = len(result.Name)
+		}
+		for _, step := range result.Steps {
+			if len(step.Name)+2 > nameWidth {
+				nameWidth = len(step.Name) + 2
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "Timings:\n")
+	for _, result := range results {
+		fmt.Fprintf(w, "  %-*s  %10s\n", nameWidth, result.Name, formatDuration(result.Duration))
+
+		var tracked timing.Step
+		for _, step := range result.Steps {
+			fmt.Fprintf(w, "    %-*s  %10s\n", nameWidth-2, step.Name, formatDuration(step.Duration))
+			tracked.Duration += step.Duration
+		}
+		if len(result.Steps) > 0 && result.Duration > tracked.Duration {
+			fmt.Fprintf(w, "    %-*s  %10s\n", nameWidth-2, untrackedStepName, formatDuration(result.Duration-tracked.Duration))
+		}
+	}
+
+	slowest := slowestSteps(results, slowestStepCount)
+	fmt.Fprintf(w, "\nSlowest steps:\n")
+	if len(slowest) == 0 {
+		fmt.Fprintf(w, "  No steps were tracked\n")
+	}
+	for i, step := range slowest {
+		fmt.Fprintf(w, "  %d. %s: %s (%s)\n", i+1, step.Module, step.Name, formatDuration(step.Duration))
+	}
+
+	fmt.Fprintf(w, "\n")
+}
+
+// slowestSteps returns up to n tracked steps across all results, slowest first.
+func slowestSteps(results []ModuleResult, n int) []moduleStep {
+	var steps []moduleStep
+	for _, result := range results {
+		for _, step := range result.Steps {
+			steps = append(steps, moduleStep{Module: result.Name, Step: step})
+		}
+	}
+
+	sort.SliceStable(steps, func(i, j int) bool {
+		return steps[i].Duration > steps[j].Duration
+	})
+
+	if len(steps) > n {
+		steps = steps[:n]
+	}
+	return steps
+}
@@ -0,0 +1,103 @@
+package runner
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stwalsh4118/phanes/internal/timing"
+)
+
+func timingResults() []ModuleResult {
+	return []ModuleResult{
+		{
+			Name:     "docker",
+			Status:   StatusInstalled,
+			Duration: 100 * time.Second,
+			Steps: []timing.Step{
+				{Name: "apt-get update", Duration: 10 * time.Second},
+				{Name: "apt-get install docker", Duration: 80 * time.Second},
+			},
+		},
+		{
+			Name:     "devtools",
+			Status:   StatusInstalled,
+			Duration: 40 * time.Second,
+			Steps: []timing.Step{
+				{Name: "download go", Duration: 30 * time.Second},
+			},
+		},
+		{
+			Name:     "user",
+			Status:   StatusSkipped,
+			Duration: 5 * time.Millisecond,
+		},
+	}
+}
+
+func TestSlowestSteps(t *testing.T) {
+	steps := slowestSteps(timingResults(), 2)
+
+	if len(steps) != 2 {
+		t.Fatalf("slowestSteps() returned %d steps, want 2", len(steps))
+	}
+	if steps[0].Module != "docker" || steps[0].Name != "apt-get install docker" {
+		t.Errorf("steps[0] = %+v, want docker: apt-get install docker", steps[0])
+	}
+	if steps[1].Module != "devtools" || steps[1].Name != "download go" {
+		t.Errorf("steps[1] = %+v, want devtools: download go", steps[1])
+	}
+}
+
+func TestPrintTimings(t *testing.T) {
+	var buf bytes.Buffer
+	printTimings(&buf, timingResults())
+	output := buf.String()
+
+	for _, want := range []string{
+		"Timings:",
+		"docker",
+		"apt-get install docker",
+		"1m20s",
+		"(other)",
+		"10s",
+		"user",
+		"5ms",
+		"Slowest steps:",
+		"1. docker: apt-get install docker (1m20s)",
+		"2. devtools: download go (30s)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("printTimings() output missing %q\nOutput:\n%s", want, output)
+		}
+	}
+}
+
+func TestPrintTimings_NoSteps(t *testing.T) {
+	var buf bytes.Buffer
+	printTimings(&buf, []ModuleResult{{Name: "user", Status: StatusSkipped, Duration: time.Millisecond}})
+
+	if !strings.Contains(buf.String(), "No steps were tracked") {
+		t.Errorf("printTimings() should note missing steps, got:\n%s", buf.String())
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "-"},
+		{300 * time.Microsecond, "<1ms"},
+		{1500 * time.Microsecond, "2ms"},
+		{1234 * time.Millisecond, "1.2s"},
+		{192*time.Second + 340*time.Millisecond, "3m12.3s"},
+	}
+
+	for _, tt := range tests {
+		if got := formatDuration(tt.d); got != tt.want {
+			t.Errorf("formatDuration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
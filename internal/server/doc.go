@@ -0,0 +1,28 @@
+// Package server implements the HTTP API of 'phanes serve'. It lets other
+// tooling list modules and profiles, trigger plan, apply and check runs,
+// stream their output, and fetch the results of past runs.
+//
+// Every request must carry the API token as "Authorization: Bearer <token>".
+// Runs are executed by the phanes binary in a child process, one at a time;
+// starting a run while another is in progress fails with 409 Conflict. Each
+// run is stored in its own directory below the state directory (run.json,
+// output.log and results.json), so past runs survive restarts.
+//
+// Endpoints:
+//
+//	GET  /v1/modules          modules with their descriptions
+//	GET  /v1/profiles         profiles with their modules
+//	POST /v1/runs             start a run: {"mode": "plan", "profile": "web"}
+//	GET  /v1/runs             past runs, newest first
+//	GET  /v1/runs/{id}        a run with its results
+//	GET  /v1/runs/{id}/events the output of a run as server-sent events
+//
+// Usage:
+//
+//	srv, err := server.New(server.Options{
+//	    Token:  token,
+//	    Binary: "/usr/local/bin/phanes",
+//	    Config: "/etc/phanes/config.yaml",
+//	})
+//	http.ListenAndServe("127.0.0.1:7420", srv)
+package server
@@ -0,0 +1,369 @@
+package server
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stwalsh4118/phanes/internal/report"
+	"github.com/stwalsh4118/phanes/internal/runner"
+)
+
+// Mode is what a run does.
+type Mode string
+
+const (
+	// ModePlan previews the changes (like --dry-run).
+	ModePlan Mode = "plan"
+
+	// ModeApply applies the changes.
+	ModeApply Mode = "apply"
+
+	// ModeCheck previews the changes and reports drift if there are any.
+	ModeCheck Mode = "check"
+)
+
+// Status is the state of a run.
+type Status string
+
+const (
+	// StatusRunning indicates the run is in progress.
+	StatusRunning Status = "running"
+
+	// StatusSucceeded indicates every module succeeded.
+	StatusSucceeded Status = "succeeded"
+
+	// StatusFailed indicates the run or a module failed.
+	StatusFailed Status = "failed"
+
+	// StatusDrift indicates a check run found modules out of date.
+	StatusDrift Status = "drift"
+)
+
+const (
+	runFile     = "run.json"
+	outputFile  = "output.log"
+	resultsFile = "results.json"
+
+	runDirPerm  = 0700
+	runFilePerm = 0600
+
+	// waitDelay is how long to wait for the output to close after phanes
+	// exited.
+	waitDelay = 5 * time.Second
+)
+
+// command starts the phanes child process. It is a variable so tests can run
+// a fake instead.
+var command = exec.Command
+
+// ErrBusy is returned when a run is started while another is in progress.
+var ErrBusy = errors.New("a run is already in progress")
+
+// errNotFound is returned for unknown run IDs.
+var errNotFound = errors.New("run not found")
+
+var (
+	validID = regexp.MustCompile(`^[0-9A-Za-z-]+$`)
+	ansi    = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+)
+
+// Request is the body of POST /v1/runs.
+type Request struct {
+	Mode    Mode     `json:"mode"`
+	Profile string   `json:"profile,omitempty"`
+	Modules []string `json:"modules,omitempty"`
+	Yes     bool     `json:"yes,omitempty"`
+	Safe    bool     `json:"safe,omitempty"`
+}
+
+// Run is a run as returned by the API and stored in run.json.
+type Run struct {
+	ID string `json:"id"`
+	Request
+	Status     Status          `json:"status"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Results    *report.Results `json:"results,omitempty"`
+}
+
+// activeRun is a run started by this server, with its output so far.
+type activeRun struct {
+	mu      sync.Mutex
+	run     Run
+	lines   []string
+	done    bool
+	changed chan struct{}
+}
+
+// snapshot returns the run, its output from line from on, whether it has
+// finished, and a channel that is closed on the next change.
+func (a *activeRun) snapshot(from int) (Run, []string, bool, <-chan struct{}) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var lines []string
+	if from < len(a.lines) {
+		lines = append(lines, a.lines[from:]...)
+	}
+	return a.run, lines, a.done, a.changed
+}
+
+// finished reports whether the run has finished.
+func (a *activeRun) finished() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.done
+}
+
+// update applies fn under the lock and wakes up waiting readers.
+func (a *activeRun) update(fn func()) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	fn()
+	close(a.changed)
+	a.changed = make(chan struct{})
+}
+
+// runs starts runs and stores them below dir.
+type runs struct {
+	dir    string
+	binary string
+	config string
+
+	mu     sync.Mutex
+	active *activeRun
+}
+
+// start starts req unless another run is in progress. req must be valid.
+func (r *runs) start(req Request) (Run, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.active != nil && !r.active.finished() {
+		return Run{}, ErrBusy
+	}
+
+	run := Run{ID: newID(), Request: req, Status: StatusRunning, StartedAt: time.Now().UTC()}
+	dir := filepath.Join(r.dir, run.ID)
+	if err := os.MkdirAll(dir, runDirPerm); err != nil {
+		return Run{}, fmt.Errorf("failed to create run directory: %w", err)
+	}
+	if err := writeRun(dir, run); err != nil {
+		return Run{}, err
+	}
+	output, err := os.OpenFile(filepath.Join(dir, outputFile), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, runFilePerm)
+	if err != nil {
+		return Run{}, fmt.Errorf("failed to create output log: %w", err)
+	}
+
+	cmd := command(r.binary, r.args(req, dir)...)
+	pr, pw := io.Pipe()
+	cmd.Stdout, cmd.Stderr = pw, pw
+	// Don't wait forever for background processes that inherited the output
+	cmd.WaitDelay = waitDelay
+	if err := cmd.Start(); err != nil {
+		output.Close()
+		return Run{}, fmt.Errorf("failed to start phanes: %w", err)
+	}
+
+	active := &activeRun{run: run, changed: make(chan struct{})}
+	r.active = active
+
+	exited := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		pw.Close()
+		exited <- err
+	}()
+	go follow(active, dir, pr, output, exited)
+
+	return run, nil
+}
+
+// follow records the output of a run and its outcome once it exits.
+func follow(active *activeRun, dir string, output io.Reader, log *os.File, exited <-chan error) {
+	defer log.Close()
+
+	scanner := bufio.NewScanner(output)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := ansi.ReplaceAllString(scanner.Text(), "")
+		fmt.Fprintln(log, line)
+		active.update(func() { active.lines = append(active.lines, line) })
+	}
+	io.Copy(io.Discard, output)
+	exitErr := <-exited
+
+	active.update(func() {
+		run := &active.run
+		finished := time.Now().UTC()
+		run.FinishedAt = &finished
+		run.Status = StatusSucceeded
+
+		if results, err := report.ReadResults(filepath.Join(dir, resultsFile)); err == nil {
+			run.Results = results
+		}
+		switch {
+		case exitErr != nil:
+			run.Status = StatusFailed
+			run.Error = fmt.Sprintf("phanes exited: %v", exitErr)
+		case run.Results == nil:
+			run.Status = StatusFailed
+			run.Error = "run produced no results"
+		case !run.Results.Success:
+			run.Status = StatusFailed
+		case run.Mode == ModeCheck && hasDrift(run.Results):
+			run.Status = StatusDrift
+		}
+		if err := writeRun(dir, *run); err != nil {
+			run.Error = err.Error()
+		}
+		active.done = true
+	})
+}
+
+// args returns the phanes arguments for req.
+func (r *runs) args(req Request, dir string) []string {
+	var args []string
+	if req.Profile != "" {
+		args = append(args, "--profile", req.Profile)
+	}
+	if len(req.Modules) > 0 {
+		args = append(args, "--modules", strings.Join(req.Modules, ","))
+	}
+	args = append(args, "--config", r.config, "--results-json", filepath.Join(dir, resultsFile))
+	if req.Mode != ModeApply {
+		args = append(args, "--dry-run")
+	}
+	if req.Yes {
+		args = append(args, "--yes")
+	}
+	if req.Safe {
+		args = append(args, "--safe")
+	}
+	return args
+}
+
+// get returns a run by ID.
+func (r *runs) get(id string) (Run, error) {
+	if active := r.find(id); active != nil {
+		run, _, _, _ := active.snapshot(0)
+		return run, nil
+	}
+	return r.load(id)
+}
+
+// find returns the in-memory run with the given ID, if any.
+func (r *runs) find(id string) *activeRun {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.active != nil && r.active.run.ID == id {
+		return r.active
+	}
+	return nil
+}
+
+// load reads a stored run.
+func (r *runs) load(id string) (Run, error) {
+	var run Run
+	if !validID.MatchString(id) {
+		return run, errNotFound
+	}
+	data, err := os.ReadFile(filepath.Join(r.dir, id, runFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return run, errNotFound
+	}
+	if err != nil {
+		return run, fmt.Errorf("failed to read run %s: %w", id, err)
+	}
+	if err := json.Unmarshal(data, &run); err != nil {
+		return run, fmt.Errorf("failed to parse run %s: %w", id, err)
+	}
+	// A run left running by a previous server process never finished
+	if run.Status == StatusRunning && r.find(id) == nil {
+		run.Status = StatusFailed
+		run.Error = "interrupted"
+	}
+	return run, nil
+}
+
+// output returns the stored output of a run.
+func (r *runs) output(id string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(r.dir, id, outputFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read output of run %s: %w", id, err)
+	}
+	text := strings.TrimSuffix(string(data), "\n")
+	if text == "" {
+		return nil, nil
+	}
+	return strings.Split(text, "\n"), nil
+}
+
+// list returns all stored runs, newest first, without their results.
+func (r *runs) list() ([]Run, error) {
+	entries, err := os.ReadDir(r.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Run{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs: %w", err)
+	}
+
+	runs := []Run{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		run, err := r.get(entry.Name())
+		if err != nil {
+			continue
+		}
+		run.Results = nil
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].ID > runs[j].ID })
+	return runs, nil
+}
+
+// writeRun stores run in dir.
+func writeRun(dir string, run Run) error {
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode run: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, runFile), append(data, '\n'), runFilePerm); err != nil {
+		return fmt.Errorf("failed to write run: %w", err)
+	}
+	return nil
+}
+
+// hasDrift reports whether any module would change something.
+func hasDrift(results *report.Results) bool {
+	for _, r := range results.Results {
+		if runner.ModuleStatus(r.Status) == runner.StatusWouldInstall {
+			return true
+		}
+	}
+	return false
+}
+
+// newID returns a sortable, unique run ID.
+func newID() string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return time.Now().UTC().Format("20060102T150405") + "-" + hex.EncodeToString(suffix)
+}
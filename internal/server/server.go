@@ -0,0 +1,254 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/stwalsh4118/phanes/internal/log"
+)
+
+// DefaultStateDir is where runs are stored when Options.StateDir is empty.
+const DefaultStateDir = "/var/lib/phanes/runs"
+
+// maxRequestBody limits the size of POST /v1/runs bodies.
+const maxRequestBody = 64 * 1024
+
+// Options configures the server.
+type Options struct {
+	// Token is the API token clients must send as a bearer token.
+	Token string
+
+	// Binary is the phanes binary that executes runs.
+	Binary string
+
+	// Config is the configuration file passed to every run.
+	Config string
+
+	// StateDir is where runs are stored. Default: DefaultStateDir.
+	StateDir string
+
+	// Modules and Profiles are returned by GET /v1/modules and
+	// GET /v1/profiles.
+	Modules  interface{}
+	Profiles interface{}
+
+	// Validate checks the profile and modules of a run request before it is
+	// started.
+	Validate func(profile string, modules []string) error
+}
+
+// Server is the HTTP API. It implements http.Handler.
+type Server struct {
+	opts Options
+	runs *runs
+	mux  *http.ServeMux
+}
+
+// New creates the server.
+func New(opts Options) (*Server, error) {
+	if opts.Token == "" {
+		return nil, fmt.Errorf("an API token is required")
+	}
+	if opts.StateDir == "" {
+		opts.StateDir = DefaultStateDir
+	}
+
+	s := &Server{
+		opts: opts,
+		runs: &runs{dir: opts.StateDir, binary: opts.Binary, config: opts.Config},
+		mux:  http.NewServeMux(),
+	}
+	s.mux.HandleFunc("GET /v1/modules", s.handleModules)
+	s.mux.HandleFunc("GET /v1/profiles", s.handleProfiles)
+	s.mux.HandleFunc("POST /v1/runs", s.handleStartRun)
+	s.mux.HandleFunc("GET /v1/runs", s.handleListRuns)
+	s.mux.HandleFunc("GET /v1/runs/{id}", s.handleGetRun)
+	s.mux.HandleFunc("GET /v1/runs/{id}/events", s.handleEvents)
+	return s, nil
+}
+
+// ServeHTTP authenticates the request and dispatches it.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.Token)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="phanes"`)
+		writeError(w, http.StatusUnauthorized, "missing or invalid API token")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) handleModules(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.opts.Modules)
+}
+
+func (s *Server) handleProfiles(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.opts.Profiles)
+}
+
+func (s *Server) handleStartRun(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if err := s.validate(req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	run, err := s.runs.start(req)
+	if errors.Is(err, ErrBusy) {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		log.Error("Failed to start run: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	log.Info("Started %s run %s", run.Mode, run.ID)
+	w.Header().Set("Location", "/v1/runs/"+run.ID)
+	writeJSON(w, http.StatusAccepted, run)
+}
+
+// validate checks a run request.
+func (s *Server) validate(req Request) error {
+	switch req.Mode {
+	case ModePlan, ModeApply, ModeCheck:
+	default:
+		return fmt.Errorf("invalid mode %q: must be %s, %s or %s", req.Mode, ModePlan, ModeApply, ModeCheck)
+	}
+	if req.Profile == "" && len(req.Modules) == 0 {
+		return fmt.Errorf("either profile or modules must be specified")
+	}
+	if req.Yes && req.Safe {
+		return fmt.Errorf("yes and safe cannot be combined")
+	}
+	if s.opts.Validate != nil {
+		return s.opts.Validate(req.Profile, req.Modules)
+	}
+	return nil
+}
+
+func (s *Server) handleListRuns(w http.ResponseWriter, r *http.Request) {
+	runs, err := s.runs.list()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, runs)
+}
+
+func (s *Server) handleGetRun(w http.ResponseWriter, r *http.Request) {
+	run, err := s.runs.get(r.PathValue("id"))
+	if errors.Is(err, errNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
+
+// handleEvents streams the output of a run as server-sent "output" events,
+// followed by a "done" event with the finished run.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	run, err := s.runs.get(id)
+	if errors.Is(err, errNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	flush := func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	active := s.runs.find(id)
+	if active == nil {
+		lines, err := s.runs.output(id)
+		if err != nil {
+			log.Warn("Failed to read output of run %s: %v", id, err)
+		}
+		for _, line := range lines {
+			writeEvent(w, "output", line)
+		}
+		writeDone(w, run)
+		flush()
+		return
+	}
+
+	sent := 0
+	for {
+		run, lines, done, changed := active.snapshot(sent)
+		for _, line := range lines {
+			writeEvent(w, "output", line)
+		}
+		sent += len(lines)
+		if done {
+			writeDone(w, run)
+			flush()
+			return
+		}
+		flush()
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeEvent writes a server-sent event.
+func writeEvent(w http.ResponseWriter, event, data string) {
+	fmt.Fprintf(w, "event: %s\n", event)
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
+
+// writeDone writes the final event of a stream.
+func writeDone(w http.ResponseWriter, run Run) {
+	data, err := json.Marshal(run)
+	if err != nil {
+		data = []byte(`{}`)
+	}
+	writeEvent(w, "done", string(data))
+}
+
+// writeJSON writes v as a JSON response.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		log.Warn("Failed to write response: %v", err)
+	}
+}
+
+// writeError writes {"error": message}.
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,304 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testToken = "s3cret"
+
+// fakePhanes replaces the phanes child process with a shell script that
+// prints some output, waits until release exists, and writes results.
+func fakePhanes(t *testing.T, results string) (release string) {
+	t.Helper()
+	release = filepath.Join(t.TempDir(), "release")
+	script := `
+out=""
+while [ $# -gt 0 ]; do
+	if [ "$1" = "--results-json" ]; then out="$2"; fi
+	shift
+done
+echo "starting"
+printf '\033[32mcolored\033[0m\n'
+while [ ! -f "$RELEASE" ]; do sleep 0.01; done
+printf '%s' "$RESULTS" > "$out"
+echo "finished"
+`
+	orig := command
+	command = func(name string, args ...string) *exec.Cmd {
+		cmd := exec.Command("sh", append([]string{"-c", script, "sh"}, args...)...)
+		cmd.Env = append(os.Environ(), "RELEASE="+release, "RESULTS="+results)
+		return cmd
+	}
+	t.Cleanup(func() { command = orig })
+	return release
+}
+
+func newTestServer(t *testing.T, dir string) *httptest.Server {
+	t.Helper()
+	srv, err := New(Options{
+		Token:    testToken,
+		Binary:   "phanes",
+		Config:   "/etc/phanes/config.yaml",
+		StateDir: dir,
+		Modules:  []string{"baseline", "docker"},
+		Profiles: map[string][]string{"web": {"baseline", "docker"}},
+		Validate: func(profile string, modules []string) error {
+			if profile != "" && profile != "web" {
+				return fmt.Errorf("profile '%s' not found", profile)
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func request(t *testing.T, ts *httptest.Server, method, path, body string) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, data
+}
+
+func TestNew_RequiresToken(t *testing.T) {
+	if _, err := New(Options{}); err == nil {
+		t.Error("New() without token should fail")
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	ts := newTestServer(t, t.TempDir())
+
+	for _, header := range []string{"", "Bearer wrong", "Basic " + testToken, testToken} {
+		req, _ := http.NewRequest("GET", ts.URL+"/v1/modules", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: status = %d, want 401", header, resp.StatusCode)
+		}
+	}
+
+	resp, body := request(t, ts, "GET", "/v1/modules", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	var modules []string
+	if err := json.Unmarshal(body, &modules); err != nil || !reflect.DeepEqual(modules, []string{"baseline", "docker"}) {
+		t.Errorf("modules = %s", body)
+	}
+
+	resp, body = request(t, ts, "GET", "/v1/profiles", "")
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `"web"`) {
+		t.Errorf("profiles: status %d, body %s", resp.StatusCode, body)
+	}
+}
+
+func TestStartRun_Invalid(t *testing.T) {
+	ts := newTestServer(t, t.TempDir())
+
+	tests := map[string]string{
+		"bad json":         `{`,
+		"unknown field":    `{"mode": "plan", "profile": "web", "force": true}`,
+		"bad mode":         `{"mode": "destroy", "profile": "web"}`,
+		"nothing selected": `{"mode": "plan"}`,
+		"yes and safe":     `{"mode": "apply", "profile": "web", "yes": true, "safe": true}`,
+		"unknown profile":  `{"mode": "plan", "profile": "nope"}`,
+	}
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp, data := request(t, ts, "POST", "/v1/runs", body)
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400 (%s)", resp.StatusCode, data)
+			}
+		})
+	}
+}
+
+func TestRunLifecycle(t *testing.T) {
+	dir := t.TempDir()
+	release := fakePhanes(t, `{"success": true, "dry_run": true, "results": [{"name": "baseline", "status": "skipped"}, {"name": "docker", "status": "would_install"}]}`)
+	ts := newTestServer(t, dir)
+
+	resp, body := request(t, ts, "POST", "/v1/runs", `{"mode": "check", "profile": "web"}`)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("start: status = %d, body %s", resp.StatusCode, body)
+	}
+	var run Run
+	if err := json.Unmarshal(body, &run); err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != StatusRunning || run.Mode != ModeCheck || resp.Header.Get("Location") != "/v1/runs/"+run.ID {
+		t.Errorf("started run = %+v, Location %s", run, resp.Header.Get("Location"))
+	}
+
+	// Only one run at a time
+	resp, body = request(t, ts, "POST", "/v1/runs", `{"mode": "apply", "modules": ["docker"]}`)
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("second start: status = %d, want 409 (%s)", resp.StatusCode, body)
+	}
+
+	// Stream events while the run finishes
+	req, _ := http.NewRequest("GET", ts.URL+"/v1/runs/"+run.ID+"/events", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	stream, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Body.Close()
+	if err := os.WriteFile(release, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	events := readEvents(t, stream.Body)
+
+	var output []string
+	for _, e := range events[:len(events)-1] {
+		if e.name != "output" {
+			t.Errorf("unexpected event %s", e.name)
+		}
+		output = append(output, e.data)
+	}
+	if !reflect.DeepEqual(output, []string{"starting", "colored", "finished"}) {
+		t.Errorf("output = %q", output)
+	}
+	last := events[len(events)-1]
+	if last.name != "done" || !strings.Contains(last.data, `"status":"drift"`) {
+		t.Errorf("last event = %s %s", last.name, last.data)
+	}
+
+	resp, body = request(t, ts, "GET", "/v1/runs/"+run.ID, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("get: status = %d", resp.StatusCode)
+	}
+	var finished Run
+	json.Unmarshal(body, &finished)
+	if finished.Status != StatusDrift || finished.FinishedAt == nil || finished.Results == nil || len(finished.Results.Results) != 2 {
+		t.Errorf("finished run = %s", body)
+	}
+
+	// Past runs survive a restart
+	restarted := newTestServer(t, dir)
+	resp, body = request(t, restarted, "GET", "/v1/runs", "")
+	var runs []Run
+	json.Unmarshal(body, &runs)
+	if resp.StatusCode != http.StatusOK || len(runs) != 1 || runs[0].ID != run.ID || runs[0].Status != StatusDrift {
+		t.Errorf("list after restart = %s", body)
+	}
+	resp, body = request(t, restarted, "GET", "/v1/runs/"+run.ID+"/events", "")
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "data: colored\n") || !strings.Contains(string(body), "event: done\n") {
+		t.Errorf("stored events = %s", body)
+	}
+}
+
+func TestRunFailed(t *testing.T) {
+	release := fakePhanes(t, `not json`)
+	if err := os.WriteFile(release, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	ts := newTestServer(t, t.TempDir())
+
+	_, body := request(t, ts, "POST", "/v1/runs", `{"mode": "apply", "profile": "web"}`)
+	var run Run
+	json.Unmarshal(body, &run)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for run.Status == StatusRunning && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		_, body = request(t, ts, "GET", "/v1/runs/"+run.ID, "")
+		json.Unmarshal(body, &run)
+	}
+	if run.Status != StatusFailed || run.Error == "" {
+		t.Errorf("run = %s, want failed with error", body)
+	}
+
+	// A finished run does not block the next one
+	resp, _ := request(t, ts, "POST", "/v1/runs", `{"mode": "plan", "profile": "web"}`)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("next start: status = %d, want 202", resp.StatusCode)
+	}
+}
+
+func TestGetRun_NotFound(t *testing.T) {
+	ts := newTestServer(t, t.TempDir())
+	for _, id := range []string{"20260101T000000-abcdef", "..", "a.b"} {
+		resp, _ := request(t, ts, "GET", "/v1/runs/"+id, "")
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("GET %s: status = %d, want 404", id, resp.StatusCode)
+		}
+	}
+}
+
+func TestArgs(t *testing.T) {
+	r := &runs{config: "/etc/phanes/config.yaml"}
+	got := r.args(Request{Mode: ModePlan, Profile: "web", Modules: []string{"docker", "redis"}, Safe: true}, "/runs/1")
+	want := []string{"--profile", "web", "--modules", "docker,redis", "--config", "/etc/phanes/config.yaml", "--results-json", "/runs/1/results.json", "--dry-run", "--safe"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("args() = %q, want %q", got, want)
+	}
+
+	got = r.args(Request{Mode: ModeApply, Modules: []string{"docker"}, Yes: true}, "/runs/1")
+	want = []string{"--modules", "docker", "--config", "/etc/phanes/config.yaml", "--results-json", "/runs/1/results.json", "--yes"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("args() = %q, want %q", got, want)
+	}
+}
+
+type event struct{ name, data string }
+
+// readEvents reads server-sent events until the stream ends.
+func readEvents(t *testing.T, r io.Reader) []event {
+	t.Helper()
+	var events []event
+	var current event
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			current.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			current.data = strings.TrimPrefix(line, "data: ")
+		case line == "":
+			events = append(events, current)
+			current = event{}
+		}
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, io.EOF) {
+		t.Fatalf("failed to read events: %v", err)
+	}
+	if len(events) == 0 {
+		t.Fatal("no events received")
+	}
+	return events
+}
@@ -0,0 +1,26 @@
+// Package sockets inspects listening TCP sockets by reading /proc/net/tcp and
+// /proc/net/tcp6 and maps them to their owning processes through /proc/<pid>/fd.
+// It answers "who listens on port N, on which address" exactly, without running
+// ss or netstat and without matching substrings of their output (":80" also
+// matches ":8080").
+//
+// Owning processes can only be resolved for processes whose file descriptors
+// are readable, which in practice requires root. Listeners whose owner cannot
+// be resolved have a PID of 0.
+//
+// Under an alternate root (exec.SetRoot) nothing is running, so there are no
+// listeners.
+//
+// Usage:
+//
+//	listening, err := sockets.IsListening(80)
+//
+//	// Is nginx itself listening on port 80?
+//	ok, err := sockets.ListeningAs(80, "nginx")
+//
+//	// Who else holds port 80?
+//	conflicts, err := sockets.Conflicts(80, "nginx")
+//	for _, l := range conflicts {
+//	    log.Warn("Port 80 is already used by %s", l)
+//	}
+package sockets
@@ -0,0 +1,274 @@
+package sockets
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/stwalsh4118/phanes/internal/exec"
+)
+
+// tcpListen is the TCP_LISTEN state as shown in /proc/net/tcp.
+const tcpListen = "0A"
+
+// procRoot is the mount point of procfs. It is a variable so tests can use a fake tree.
+var procRoot = "/proc"
+
+// Listener is a listening TCP socket.
+type Listener struct {
+	// Address is the local address (0.0.0.0 or :: for all addresses).
+	Address net.IP
+
+	// Port is the local port.
+	Port int
+
+	// Inode is the socket inode, used to find the owning process.
+	Inode uint64
+
+	// PID is the owning process, or 0 if it could not be determined.
+	PID int
+
+	// Process is the owning process name (from /proc/<pid>/comm), or empty if unknown.
+	Process string
+}
+
+// String returns a description such as "nginx (pid 812) on 0.0.0.0:80".
+func (l Listener) String() string {
+	addr := net.JoinHostPort(l.Address.String(), strconv.Itoa(l.Port))
+	if l.PID == 0 {
+		return fmt.Sprintf("unknown process on %s", addr)
+	}
+	return fmt.Sprintf("%s (pid %d) on %s", l.Process, l.PID, addr)
+}
+
+// Listeners returns all listening TCP sockets (IPv4 and IPv6) with their owning
+// processes resolved where possible.
+func Listeners() ([]Listener, error) {
+	// Nothing runs inside an alternate root, and the host's sockets are not its own
+	if exec.Root() != "" {
+		return nil, nil
+	}
+
+	var listeners []Listener
+	for _, name := range []string{"tcp", "tcp6"} {
+		path := filepath.Join(procRoot, "net", name)
+		file, err := os.Open(path)
+		if err != nil {
+			// tcp6 is missing when IPv6 is disabled
+			if os.IsNotExist(err) && name == "tcp6" {
+				continue
+			}
+			return nil, fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		parsed, err := Parse(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		listeners = append(listeners, parsed...)
+	}
+
+	resolveOwners(listeners)
+	return listeners, nil
+}
+
+// ListenersOnPort returns the listening TCP sockets on port.
+func ListenersOnPort(port int) ([]Listener, error) {
+	all, err := Listeners()
+	if err != nil {
+		return nil, err
+	}
+	var listeners []Listener
+	for _, l := range all {
+		if l.Port == port {
+			listeners = append(listeners, l)
+		}
+	}
+	return listeners, nil
+}
+
+// IsListening reports whether any process listens on TCP port.
+func IsListening(port int) (bool, error) {
+	listeners, err := ListenersOnPort(port)
+	if err != nil {
+		return false, err
+	}
+	return len(listeners) > 0, nil
+}
+
+// ListeningAs reports whether one of the given processes listens on TCP port.
+// Listeners whose owner cannot be determined are assumed to match, so the
+// check degrades to "anything listens on port" when not running as root.
+func ListeningAs(port int, processes ...string) (bool, error) {
+	listeners, err := ListenersOnPort(port)
+	if err != nil {
+		return false, err
+	}
+	for _, l := range listeners {
+		if l.PID == 0 || contains(processes, l.Process) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Conflicts returns the listeners on TCP port that are not owned by one of
+// the given processes. Listeners whose owner cannot be determined are
+// reported as conflicts.
+func Conflicts(port int, processes ...string) ([]Listener, error) {
+	listeners, err := ListenersOnPort(port)
+	if err != nil {
+		return nil, err
+	}
+	var conflicts []Listener
+	for _, l := range listeners {
+		if l.PID == 0 || !contains(processes, l.Process) {
+			conflicts = append(conflicts, l)
+		}
+	}
+	return conflicts, nil
+}
+
+// Parse reads /proc/net/tcp or /proc/net/tcp6 formatted content and returns
+// the listening sockets. Owning processes are not resolved.
+func Parse(r io.Reader) ([]Listener, error) {
+	var listeners []Listener
+	scanner := bufio.NewScanner(r)
+	header := true
+	for scanner.Scan() {
+		if header {
+			header = false
+			continue
+		}
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		if fields[3] != tcpListen {
+			continue
+		}
+
+		address, port, err := parseAddress(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		inode, err := strconv.ParseUint(fields[9], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid inode %q: %w", fields[9], err)
+		}
+		listeners = append(listeners, Listener{Address: address, Port: port, Inode: inode})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return listeners, nil
+}
+
+// parseAddress parses an address such as "0100007F:0050" (127.0.0.1:80).
+// The address is printed as 32-bit words in host byte order.
+func parseAddress(s string) (net.IP, int, error) {
+	hexAddr, hexPort, ok := strings.Cut(s, ":")
+	if !ok {
+		return nil, 0, fmt.Errorf("invalid address %q", s)
+	}
+
+	port, err := strconv.ParseUint(hexPort, 16, 16)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid port in %q: %w", s, err)
+	}
+
+	raw, err := hex.DecodeString(hexAddr)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return nil, 0, fmt.Errorf("invalid address %q", s)
+	}
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		binary.NativeEndian.PutUint32(ip[i:], binary.BigEndian.Uint32(raw[i:]))
+	}
+	return ip, int(port), nil
+}
+
+// resolveOwners sets PID and Process of each listener by looking for its socket
+// inode among the open file descriptors of all processes. Processes whose file
+// descriptors cannot be read are skipped.
+func resolveOwners(listeners []Listener) {
+	if len(listeners) == 0 {
+		return
+	}
+	byInode := make(map[uint64][]int)
+	for i, l := range listeners {
+		byInode[l.Inode] = append(byInode[l.Inode], i)
+	}
+
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		fdDir := filepath.Join(procRoot, entry.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil {
+				continue
+			}
+			inode, ok := socketInode(target)
+			if !ok {
+				continue
+			}
+			for _, i := range byInode[inode] {
+				if listeners[i].PID == 0 {
+					listeners[i].PID = pid
+					listeners[i].Process = processName(pid)
+				}
+			}
+		}
+	}
+}
+
+// socketInode extracts the inode from a file descriptor link target such as "socket:[12345]".
+func socketInode(target string) (uint64, bool) {
+	rest, ok := strings.CutPrefix(target, "socket:[")
+	if !ok {
+		return 0, false
+	}
+	inode, err := strconv.ParseUint(strings.TrimSuffix(rest, "]"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return inode, true
+}
+
+// processName returns the command name of pid, or an empty string if unknown.
+func processName(pid int) string {
+	comm, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "comm"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(comm))
+}
+
+// contains reports whether items contains item.
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,210 @@
+package sockets
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	procNetTCP = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0050 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1001 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 1002 1 0000000000000000 100 0 0 10 0
+   2: 0100007F:1F90 0100007F:C350 01 00000000:00000000 00:00000000 00000000  1000        0 1003 1 0000000000000000 20 4 30 10 -1
+`
+	procNetTCP6 = `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000000000000:0050 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1004 1 0000000000000000 100 0 0 10 0
+   1: 00000000000000000000000001000000:18EB 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1005 1 0000000000000000 100 0 0 10 0
+`
+)
+
+// fakeProc creates a procfs tree with the given net files and processes
+// (pid -> comm and socket inodes) and points procRoot at it.
+func fakeProc(t *testing.T, tcp, tcp6 string, processes map[string][]string) {
+	t.Helper()
+	root := t.TempDir()
+	mustWrite := func(path, content string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mustWrite(filepath.Join(root, "net", "tcp"), tcp)
+	if tcp6 != "" {
+		mustWrite(filepath.Join(root, "net", "tcp6"), tcp6)
+	}
+	for pid, spec := range processes {
+		mustWrite(filepath.Join(root, pid, "comm"), spec[0]+"\n")
+		fdDir := filepath.Join(root, pid, "fd")
+		if err := os.MkdirAll(fdDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for i, inode := range spec[1:] {
+			if err := os.Symlink("socket:["+inode+"]", filepath.Join(fdDir, string(rune('3'+i)))); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// A non-socket descriptor that must be ignored
+		if err := os.Symlink("/dev/null", filepath.Join(fdDir, "0")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	orig := procRoot
+	procRoot = root
+	t.Cleanup(func() { procRoot = orig })
+}
+
+func TestParse(t *testing.T) {
+	listeners, err := Parse(strings.NewReader(procNetTCP))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	// The established connection (state 01) is not a listener
+	if len(listeners) != 2 {
+		t.Fatalf("Parse() returned %d listeners, want 2", len(listeners))
+	}
+	if got := listeners[0].Address.String(); got != "0.0.0.0" || listeners[0].Port != 80 || listeners[0].Inode != 1001 {
+		t.Errorf("listeners[0] = %s:%d inode %d, want 0.0.0.0:80 inode 1001", got, listeners[0].Port, listeners[0].Inode)
+	}
+	if got := listeners[1].Address.String(); got != "127.0.0.1" || listeners[1].Port != 8080 {
+		t.Errorf("listeners[1] = %s:%d, want 127.0.0.1:8080", got, listeners[1].Port)
+	}
+}
+
+func TestParseIPv6(t *testing.T) {
+	listeners, err := Parse(strings.NewReader(procNetTCP6))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(listeners) != 2 {
+		t.Fatalf("Parse() returned %d listeners, want 2", len(listeners))
+	}
+	if got := listeners[0].Address.String(); got != "::" || listeners[0].Port != 80 {
+		t.Errorf("listeners[0] = %s:%d, want [::]:80", got, listeners[0].Port)
+	}
+	if got := listeners[1].Address.String(); got != "::1" || listeners[1].Port != 6379 {
+		t.Errorf("listeners[1] = %s:%d, want [::1]:6379", got, listeners[1].Port)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	content := "header\n   0: ZZZZ:0050 00000000:0000 0A 00000000:00000000 00:00000000 00000000 0 0 1001 1\n"
+	if _, err := Parse(strings.NewReader(content)); err == nil {
+		t.Error("Parse() should fail for an invalid address")
+	}
+}
+
+func TestListenersOnPort(t *testing.T) {
+	fakeProc(t, procNetTCP, procNetTCP6, map[string][]string{
+		"812":  {"nginx", "1001", "1004"},
+		"2001": {"node", "1002"},
+	})
+
+	listeners, err := ListenersOnPort(80)
+	if err != nil {
+		t.Fatalf("ListenersOnPort() error = %v", err)
+	}
+	if len(listeners) != 2 {
+		t.Fatalf("ListenersOnPort(80) returned %d listeners, want 2", len(listeners))
+	}
+	for _, l := range listeners {
+		if l.PID != 812 || l.Process != "nginx" {
+			t.Errorf("listener %s: owner = %s (%d), want nginx (812)", l, l.Process, l.PID)
+		}
+	}
+
+	// Port 8080 must not match port 80
+	listeners, err = ListenersOnPort(8080)
+	if err != nil {
+		t.Fatalf("ListenersOnPort() error = %v", err)
+	}
+	if len(listeners) != 1 || listeners[0].Process != "node" {
+		t.Errorf("ListenersOnPort(8080) = %v, want one node listener", listeners)
+	}
+
+	// Owner of the IPv6 loopback listener is unknown
+	listeners, err = ListenersOnPort(6379)
+	if err != nil {
+		t.Fatalf("ListenersOnPort() error = %v", err)
+	}
+	if len(listeners) != 1 || listeners[0].PID != 0 {
+		t.Errorf("ListenersOnPort(6379) = %v, want one listener with unknown owner", listeners)
+	}
+	if got := listeners[0].String(); got != "unknown process on [::1]:6379" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestListeningAsAndConflicts(t *testing.T) {
+	fakeProc(t, procNetTCP, procNetTCP6, map[string][]string{
+		"812":  {"nginx", "1001", "1004"},
+		"2001": {"node", "1002"},
+	})
+
+	if ok, err := ListeningAs(80, "nginx"); err != nil || !ok {
+		t.Errorf("ListeningAs(80, nginx) = (%v, %v), want (true, nil)", ok, err)
+	}
+	if ok, err := ListeningAs(80, "caddy"); err != nil || ok {
+		t.Errorf("ListeningAs(80, caddy) = (%v, %v), want (false, nil)", ok, err)
+	}
+	// Unknown owners are assumed to match
+	if ok, err := ListeningAs(6379, "redis-server"); err != nil || !ok {
+		t.Errorf("ListeningAs(6379, redis-server) = (%v, %v), want (true, nil)", ok, err)
+	}
+
+	conflicts, err := Conflicts(80, "nginx")
+	if err != nil || len(conflicts) != 0 {
+		t.Errorf("Conflicts(80, nginx) = (%v, %v), want none", conflicts, err)
+	}
+	conflicts, err = Conflicts(80, "caddy")
+	if err != nil || len(conflicts) != 2 {
+		t.Errorf("Conflicts(80, caddy) = (%v, %v), want both nginx listeners", conflicts, err)
+	}
+	// Unknown owners are reported as conflicts
+	conflicts, err = Conflicts(6379, "redis-server")
+	if err != nil || len(conflicts) != 1 {
+		t.Errorf("Conflicts(6379, redis-server) = (%v, %v), want one", conflicts, err)
+	}
+}
+
+func TestIsListening(t *testing.T) {
+	// tcp6 is missing when IPv6 is disabled
+	fakeProc(t, procNetTCP, "", nil)
+
+	tests := []struct {
+		port int
+		want bool
+	}{
+		{80, true},
+		{8080, true},
+		{8, false},
+		{443, false},
+	}
+	for _, tt := range tests {
+		got, err := IsListening(tt.port)
+		if err != nil {
+			t.Fatalf("IsListening(%d) error = %v", tt.port, err)
+		}
+		if got != tt.want {
+			t.Errorf("IsListening(%d) = %v, want %v", tt.port, got, tt.want)
+		}
+	}
+}
+
+func TestListenerString(t *testing.T) {
+	listeners, err := Parse(strings.NewReader(procNetTCP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := listeners[0]
+	l.PID = 812
+	l.Process = "nginx"
+	if got, want := l.String(), "nginx (pid 812) on 0.0.0.0:80"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
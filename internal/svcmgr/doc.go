@@ -0,0 +1,35 @@
+// Package svcmgr provides a service manager abstraction over systemd, OpenRC,
+// and systems without an init system (e.g., containers). The backend is selected
+// from the running init system, so modules can manage services without calling
+// systemctl directly.
+//
+// Service names are given using their Debian/Ubuntu names. Names that differ on
+// other distributions are mapped automatically (e.g., "redis-server" becomes
+// "redis" on RHEL-based systems and Alpine, "ssh" becomes "sshd").
+//
+// Key Features:
+//   - Consistent enable/start/stop/restart/reload-or-restart semantics
+//   - Active, enabled, and exists checks
+//   - Waiting until a service is actually active, with a timeout
+//   - Without an init system, services are driven through their init scripts
+//     and enabling is a no-op
+//   - Under an alternate root (exec.SetRoot), services are enabled with the
+//     root's init system but never started
+//
+// Usage:
+//
+//	svc, err := svcmgr.Detect()
+//	if err != nil {
+//	    return err
+//	}
+//
+//	if err := svc.Enable("nginx"); err != nil {
+//	    return fmt.Errorf("failed to enable nginx: %w", err)
+//	}
+//	if err := svc.Start("nginx"); err != nil {
+//	    return fmt.Errorf("failed to start nginx: %w", err)
+//	}
+//	if err := svc.WaitActive("nginx", svcmgr.DefaultWaitTimeout); err != nil {
+//	    return err
+//	}
+package svcmgr
@@ -0,0 +1,63 @@
+package svcmgr
+
+import "time"
+
+// none manages services on systems without a running init system, such as
+// containers. Services are driven through their init scripts with the service
+// command, and there is no boot to enable services for.
+type none struct{}
+
+func (n *none) Name() string {
+	return NameNone
+}
+
+func (n *none) Exists(service string) (bool, error) {
+	return fileExists(initScriptDir + "/" + service), nil
+}
+
+func (n *none) IsActive(service string) (bool, error) {
+	// status exits non-zero unless the service is running
+	if err := runCommand("service", service, "status"); err != nil {
+		return false, nil
+	}
+	return true, nil
+}
+
+// IsEnabled always reports true: without an init system nothing starts on boot,
+// so there is nothing to enable.
+func (n *none) IsEnabled(service string) (bool, error) {
+	return true, nil
+}
+
+// Enable is a no-op without an init system.
+func (n *none) Enable(service string) error {
+	return nil
+}
+
+func (n *none) Start(service string) error {
+	// Init scripts may fail when starting a running service
+	if active, _ := n.IsActive(service); active {
+		return nil
+	}
+	return runCommand("service", service, "start")
+}
+
+func (n *none) Stop(service string) error {
+	return runCommand("service", service, "stop")
+}
+
+func (n *none) Restart(service string) error {
+	return runCommand("service", service, "restart")
+}
+
+func (n *none) ReloadOrRestart(service string) error {
+	active, _ := n.IsActive(service)
+	if active && runCommand("service", service, "reload") == nil {
+		return nil
+	}
+	return n.Restart(service)
+}
+
+func (n *none) WaitActive(service string, timeout time.Duration) error {
+	return waitActive(n.IsActive, service, timeout)
+}
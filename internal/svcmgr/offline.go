@@ -0,0 +1,61 @@
+package svcmgr
+
+import (
+	"time"
+
+	"github.com/stwalsh4118/phanes/internal/log"
+)
+
+// offline manages services inside an alternate root (see exec.SetRoot).
+// Nothing runs there, so starting, stopping and reloading are skipped and no
+// service is ever active. Exists, IsEnabled and Enable are delegated to the
+// backend of the init system installed in the root, since systemctl enable and
+// rc-update work without the init system running.
+type offline struct {
+	backend Manager
+}
+
+func (o *offline) Name() string {
+	return o.backend.Name()
+}
+
+func (o *offline) Exists(service string) (bool, error) {
+	return o.backend.Exists(service)
+}
+
+func (o *offline) IsActive(service string) (bool, error) {
+	return false, nil
+}
+
+func (o *offline) IsEnabled(service string) (bool, error) {
+	return o.backend.IsEnabled(service)
+}
+
+func (o *offline) Enable(service string) error {
+	return o.backend.Enable(service)
+}
+
+func (o *offline) Start(service string) error {
+	log.Skip("Skipping start of %s under alternate root", service)
+	return nil
+}
+
+func (o *offline) Stop(service string) error {
+	log.Skip("Skipping stop of %s under alternate root", service)
+	return nil
+}
+
+func (o *offline) Restart(service string) error {
+	log.Skip("Skipping restart of %s under alternate root", service)
+	return nil
+}
+
+func (o *offline) ReloadOrRestart(service string) error {
+	log.Skip("Skipping reload of %s under alternate root", service)
+	return nil
+}
+
+// WaitActive returns immediately since services are not started under an alternate root.
+func (o *offline) WaitActive(service string, timeout time.Duration) error {
+	return nil
+}
@@ -0,0 +1,74 @@
+package svcmgr
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	initScriptDir  = "/etc/init.d"
+	openrcRunlevel = "default"
+)
+
+// openrc manages services with rc-service and rc-update.
+type openrc struct{}
+
+func (o *openrc) Name() string {
+	return NameOpenRC
+}
+
+func (o *openrc) Exists(service string) (bool, error) {
+	return fileExists(initScriptDir + "/" + service), nil
+}
+
+func (o *openrc) IsActive(service string) (bool, error) {
+	// status exits non-zero unless the service is started
+	if err := runCommand("rc-service", service, "status"); err != nil {
+		return false, nil
+	}
+	return true, nil
+}
+
+func (o *openrc) IsEnabled(service string) (bool, error) {
+	output, err := runOutput("rc-update", "show", openrcRunlevel)
+	if err != nil {
+		return false, err
+	}
+	for _, line := range strings.Split(output, "\n") {
+		// Lines look like " nginx | default"
+		fields := strings.Fields(line)
+		if len(fields) > 0 && fields[0] == service {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (o *openrc) Enable(service string) error {
+	return runCommand("rc-update", "add", service, openrcRunlevel)
+}
+
+func (o *openrc) Start(service string) error {
+	return runCommand("rc-service", service, "start")
+}
+
+func (o *openrc) Stop(service string) error {
+	return runCommand("rc-service", service, "stop")
+}
+
+func (o *openrc) Restart(service string) error {
+	return runCommand("rc-service", service, "restart")
+}
+
+func (o *openrc) ReloadOrRestart(service string) error {
+	// Not every init script implements reload, and reload fails for stopped services
+	active, _ := o.IsActive(service)
+	if active && runCommand("rc-service", service, "reload") == nil {
+		return nil
+	}
+	return o.Restart(service)
+}
+
+func (o *openrc) WaitActive(service string, timeout time.Duration) error {
+	return waitActive(o.IsActive, service, timeout)
+}
@@ -0,0 +1,215 @@
+package svcmgr
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/stwalsh4118/phanes/internal/exec"
+	"github.com/stwalsh4118/phanes/internal/osinfo"
+)
+
+// Backend names returned by Manager.Name().
+const (
+	NameSystemd = "systemd"
+	NameOpenRC  = "openrc"
+	NameNone    = "none"
+)
+
+// DefaultWaitTimeout is how long modules wait for a started service to become active.
+const DefaultWaitTimeout = 30 * time.Second
+
+// Distribution families with differing service names.
+const (
+	familyDebian = "debian"
+	familyRedHat = "redhat"
+	familyAlpine = "alpine"
+)
+
+// Command runners and probes. They are variables so tests can replace them.
+var (
+	runCommand    = exec.Run
+	runOutput     = exec.RunWithOutput
+	fileExists    = exec.FileExists
+	commandExists = exec.CommandExists
+	currentRoot   = exec.Root
+
+	// pollInterval is how often WaitActive checks the service state.
+	pollInterval = 500 * time.Millisecond
+)
+
+// Paths used to detect the running init system.
+var (
+	// systemdRunDir exists only if systemd is the running init system (sd_booted).
+	systemdRunDir = "/run/systemd/system"
+	// openrcRunDir exists only if OpenRC has booted the system.
+	openrcRunDir = "/run/openrc"
+)
+
+// Manager controls system services.
+type Manager interface {
+	// Name returns the backend name ("systemd", "openrc", or "none").
+	Name() string
+
+	// Exists reports whether the service is installed.
+	Exists(service string) (bool, error)
+
+	// IsActive reports whether the service is running.
+	IsActive(service string) (bool, error)
+
+	// IsEnabled reports whether the service starts on boot.
+	IsEnabled(service string) (bool, error)
+
+	// Enable configures the service to start on boot. It does not start the service.
+	Enable(service string) error
+
+	// Start starts the service. Starting a running service is not an error.
+	Start(service string) error
+
+	// Stop stops the service.
+	Stop(service string) error
+
+	// Restart restarts the service, starting it if it is not running.
+	Restart(service string) error
+
+	// ReloadOrRestart reloads the service configuration, or restarts the
+	// service if it does not support reloading. Starts the service if it is
+	// not running.
+	ReloadOrRestart(service string) error
+
+	// WaitActive waits until the service is active.
+	// Returns an error if it is not active within timeout.
+	WaitActive(service string, timeout time.Duration) error
+}
+
+// aliases maps Debian service names to their equivalents in other distribution families.
+var aliases = map[string]map[string]string{
+	"redis-server": {
+		familyRedHat: "redis",
+		familyAlpine: "redis",
+	},
+	"ssh": {
+		familyRedHat: "sshd",
+		familyAlpine: "sshd",
+	},
+}
+
+// resolve maps a Debian service name to its name in family.
+func resolve(family, service string) string {
+	if name, ok := aliases[service][family]; ok {
+		return name
+	}
+	return service
+}
+
+// Detect returns the service manager for the running init system.
+func Detect() (Manager, error) {
+	info, err := osinfo.Detect()
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect operating system: %w", err)
+	}
+	return forOS(info), nil
+}
+
+// forOS returns the service manager for the running init system, mapping
+// service names for the distribution described by info. Under an alternate
+// root, the init system installed in the root is managed offline.
+func forOS(info *osinfo.Info) Manager {
+	family := familyDebian
+	switch {
+	case info.Is("alpine"):
+		family = familyAlpine
+	case info.Is("fedora", "rhel", "centos"):
+		family = familyRedHat
+	}
+
+	var backend Manager
+	switch {
+	case currentRoot() != "":
+		backend = &offline{backend: installedBackend()}
+	case fileExists(systemdRunDir):
+		backend = &systemd{}
+	case fileExists(openrcRunDir):
+		backend = &openrc{}
+	default:
+		backend = &none{}
+	}
+	return &mapped{backend: backend, family: family}
+}
+
+// installedBackend returns the backend for the init system installed under the
+// alternate root, which is not running.
+func installedBackend() Manager {
+	switch {
+	case commandExists("systemctl"):
+		return &systemd{}
+	case commandExists("rc-update"):
+		return &openrc{}
+	default:
+		return &none{}
+	}
+}
+
+// mapped translates service names for a distribution family before
+// delegating to the backend.
+type mapped struct {
+	backend Manager
+	family  string
+}
+
+func (m *mapped) Name() string {
+	return m.backend.Name()
+}
+
+func (m *mapped) Exists(service string) (bool, error) {
+	return m.backend.Exists(resolve(m.family, service))
+}
+
+func (m *mapped) IsActive(service string) (bool, error) {
+	return m.backend.IsActive(resolve(m.family, service))
+}
+
+func (m *mapped) IsEnabled(service string) (bool, error) {
+	return m.backend.IsEnabled(resolve(m.family, service))
+}
+
+func (m *mapped) Enable(service string) error {
+	return m.backend.Enable(resolve(m.family, service))
+}
+
+func (m *mapped) Start(service string) error {
+	return m.backend.Start(resolve(m.family, service))
+}
+
+func (m *mapped) Stop(service string) error {
+	return m.backend.Stop(resolve(m.family, service))
+}
+
+func (m *mapped) Restart(service string) error {
+	return m.backend.Restart(resolve(m.family, service))
+}
+
+func (m *mapped) ReloadOrRestart(service string) error {
+	return m.backend.ReloadOrRestart(resolve(m.family, service))
+}
+
+func (m *mapped) WaitActive(service string, timeout time.Duration) error {
+	return m.backend.WaitActive(resolve(m.family, service), timeout)
+}
+
+// waitActive polls isActive until it reports true or timeout elapses.
+func waitActive(isActive func(string) (bool, error), service string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		active, err := isActive(service)
+		if err != nil {
+			return fmt.Errorf("failed to check %s status: %w", service, err)
+		}
+		if active {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("service %s did not become active within %s", service, timeout)
+		}
+		time.Sleep(pollInterval)
+	}
+}
@@ -0,0 +1,283 @@
+package svcmgr
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stwalsh4118/phanes/internal/osinfo"
+)
+
+// recorder replaces the command runners for the duration of a test and records
+// every command line. Outputs maps a command line to its output for runOutput;
+// failing lists command lines that fail. Unknown runOutput command lines fail.
+type recorder struct {
+	commands []string
+	outputs  map[string]string
+	failing  map[string]bool
+}
+
+func record(t *testing.T, outputs map[string]string, failing ...string) *recorder {
+	t.Helper()
+	r := &recorder{outputs: outputs, failing: map[string]bool{}}
+	for _, line := range failing {
+		r.failing[line] = true
+	}
+	origRun, origOutput := runCommand, runOutput
+	runCommand = func(name string, args ...string) error {
+		line := strings.Join(append([]string{name}, args...), " ")
+		r.commands = append(r.commands, line)
+		if r.failing[line] {
+			return errors.New("exit status 1")
+		}
+		return nil
+	}
+	runOutput = func(name string, args ...string) (string, error) {
+		line := strings.Join(append([]string{name}, args...), " ")
+		r.commands = append(r.commands, line)
+		if output, ok := r.outputs[line]; ok {
+			return output, nil
+		}
+		return "", errors.New("exit status 1")
+	}
+	t.Cleanup(func() {
+		runCommand, runOutput = origRun, origOutput
+	})
+	return r
+}
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		name    string
+		run     func() error
+		failing []string
+		want    []string
+	}{
+		{"systemd enable", func() error { return (&systemd{}).Enable("nginx") }, nil, []string{"systemctl enable nginx"}},
+		{"systemd start", func() error { return (&systemd{}).Start("nginx") }, nil, []string{"systemctl start nginx"}},
+		{"systemd restart", func() error { return (&systemd{}).Restart("nginx") }, nil, []string{"systemctl restart nginx"}},
+		{"systemd reload-or-restart", func() error { return (&systemd{}).ReloadOrRestart("nginx") }, nil, []string{"systemctl reload-or-restart nginx"}},
+		{"openrc enable", func() error { return (&openrc{}).Enable("nginx") }, nil, []string{"rc-update add nginx default"}},
+		{"openrc start", func() error { return (&openrc{}).Start("nginx") }, nil, []string{"rc-service nginx start"}},
+		{
+			"openrc reload running service",
+			func() error { return (&openrc{}).ReloadOrRestart("nginx") },
+			nil,
+			[]string{"rc-service nginx status", "rc-service nginx reload"},
+		},
+		{
+			"openrc reload unsupported",
+			func() error { return (&openrc{}).ReloadOrRestart("nginx") },
+			[]string{"rc-service nginx reload"},
+			[]string{"rc-service nginx status", "rc-service nginx reload", "rc-service nginx restart"},
+		},
+		{
+			"openrc reload stopped service",
+			func() error { return (&openrc{}).ReloadOrRestart("nginx") },
+			[]string{"rc-service nginx status"},
+			[]string{"rc-service nginx status", "rc-service nginx restart"},
+		},
+		{"none enable", func() error { return (&none{}).Enable("nginx") }, nil, nil},
+		{
+			"none start stopped service",
+			func() error { return (&none{}).Start("nginx") },
+			[]string{"service nginx status"},
+			[]string{"service nginx status", "service nginx start"},
+		},
+		{
+			"none start running service",
+			func() error { return (&none{}).Start("nginx") },
+			nil,
+			[]string{"service nginx status"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := record(t, nil, tt.failing...)
+			if err := tt.run(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(r.commands, tt.want) {
+				t.Errorf("commands = %q, want %q", r.commands, tt.want)
+			}
+		})
+	}
+}
+
+func TestSystemdState(t *testing.T) {
+	record(t, map[string]string{
+		"systemctl is-active nginx":  "active\n",
+		"systemctl is-enabled nginx": "enabled\n",
+		"systemctl list-unit-files --type=service --no-pager --no-legend nginx.service": "nginx.service enabled enabled\n",
+	})
+	s := &systemd{}
+
+	if active, _ := s.IsActive("nginx"); !active {
+		t.Error("IsActive(nginx) = false, want true")
+	}
+	if enabled, _ := s.IsEnabled("nginx"); !enabled {
+		t.Error("IsEnabled(nginx) = false, want true")
+	}
+	if exists, _ := s.Exists("nginx"); !exists {
+		t.Error("Exists(nginx) = false, want true")
+	}
+	if active, _ := s.IsActive("redis"); active {
+		t.Error("IsActive(redis) = true, want false")
+	}
+	if exists, _ := s.Exists("redis"); exists {
+		t.Error("Exists(redis) = true, want false")
+	}
+}
+
+func TestOpenRCIsEnabled(t *testing.T) {
+	record(t, map[string]string{
+		"rc-update show default": "                nginx | default\n               sshd | default\n",
+	})
+	o := &openrc{}
+
+	if enabled, err := o.IsEnabled("nginx"); err != nil || !enabled {
+		t.Errorf("IsEnabled(nginx) = (%v, %v), want (true, nil)", enabled, err)
+	}
+	if enabled, err := o.IsEnabled("redis"); err != nil || enabled {
+		t.Errorf("IsEnabled(redis) = (%v, %v), want (false, nil)", enabled, err)
+	}
+}
+
+func TestWaitActive(t *testing.T) {
+	origInterval := pollInterval
+	pollInterval = time.Millisecond
+	t.Cleanup(func() { pollInterval = origInterval })
+
+	checks := 0
+	becomesActive := func(string) (bool, error) {
+		checks++
+		return checks >= 3, nil
+	}
+	if err := waitActive(becomesActive, "nginx", time.Second); err != nil {
+		t.Fatalf("waitActive() error = %v", err)
+	}
+	if checks != 3 {
+		t.Errorf("checks = %d, want 3", checks)
+	}
+
+	neverActive := func(string) (bool, error) { return false, nil }
+	err := waitActive(neverActive, "nginx", 10*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "did not become active") {
+		t.Errorf("waitActive() error = %v, want timeout error", err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		family  string
+		service string
+		want    string
+	}{
+		{familyDebian, "redis-server", "redis-server"},
+		{familyRedHat, "redis-server", "redis"},
+		{familyAlpine, "redis-server", "redis"},
+		{familyDebian, "ssh", "ssh"},
+		{familyRedHat, "ssh", "sshd"},
+		{familyRedHat, "nginx", "nginx"},
+	}
+
+	for _, tt := range tests {
+		if got := resolve(tt.family, tt.service); got != tt.want {
+			t.Errorf("resolve(%s, %s) = %s, want %s", tt.family, tt.service, got, tt.want)
+		}
+	}
+}
+
+func TestMappedResolvesNames(t *testing.T) {
+	r := record(t, nil)
+	m := &mapped{backend: &systemd{}, family: familyRedHat}
+	if err := m.Restart("redis-server"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"systemctl restart redis"}
+	if !reflect.DeepEqual(r.commands, want) {
+		t.Errorf("commands = %q, want %q", r.commands, want)
+	}
+}
+
+func TestForOS(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		want     string
+	}{
+		{"systemd", []string{systemdRunDir}, NameSystemd},
+		{"openrc", []string{openrcRunDir}, NameOpenRC},
+		{"container", nil, NameNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := fileExists
+			fileExists = func(path string) bool {
+				for _, p := range tt.existing {
+					if p == path {
+						return true
+					}
+				}
+				return false
+			}
+			t.Cleanup(func() { fileExists = orig })
+
+			svc := forOS(&osinfo.Info{ID: "debian"})
+			if svc.Name() != tt.want {
+				t.Errorf("forOS().Name() = %q, want %q", svc.Name(), tt.want)
+			}
+		})
+	}
+}
+
+func TestForOSUnderAlternateRoot(t *testing.T) {
+	origRoot, origCommandExists := currentRoot, commandExists
+	t.Cleanup(func() { currentRoot, commandExists = origRoot, origCommandExists })
+	currentRoot = func() string { return "/tmp/sysroot" }
+	commandExists = func(name string) bool { return name == "systemctl" }
+
+	r := record(t, map[string]string{})
+	svc := forOS(&osinfo.Info{ID: "debian"})
+	if svc.Name() != NameSystemd {
+		t.Errorf("forOS().Name() = %q, want %q", svc.Name(), NameSystemd)
+	}
+
+	if err := svc.Enable("nginx"); err != nil {
+		t.Fatalf("Enable() error = %v", err)
+	}
+	if err := svc.Start("nginx"); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := svc.WaitActive("nginx", time.Millisecond); err != nil {
+		t.Errorf("WaitActive() error = %v, want nil under alternate root", err)
+	}
+	if active, _ := svc.IsActive("nginx"); active {
+		t.Error("IsActive() should be false under alternate root")
+	}
+
+	want := []string{"systemctl enable nginx"}
+	if !reflect.DeepEqual(r.commands, want) {
+		t.Errorf("commands = %q, want %q", r.commands, want)
+	}
+}
+
+func TestQuoteExecArg(t *testing.T) {
+	tests := map[string]string{
+		"/usr/bin/phanes": "/usr/bin/phanes",
+		"my config.yaml":  `"my config.yaml"`,
+		`a"b`:             `"a\"b"`,
+		"100%":            `"100%%"`,
+		"$HOME":           `"$$HOME"`,
+		"":                `""`,
+	}
+	for in, want := range tests {
+		if got := QuoteExecArg(in); got != want {
+			t.Errorf("QuoteExecArg(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package svcmgr
+
+import (
+	"strings"
+	"time"
+)
+
+// systemd manages services with systemctl.
+type systemd struct{}
+
+func (s *systemd) Name() string {
+	return NameSystemd
+}
+
+func (s *systemd) Exists(service string) (bool, error) {
+	output, err := runOutput("systemctl", "list-unit-files", "--type=service", "--no-pager", "--no-legend", service+".service")
+	if err != nil {
+		// list-unit-files exits non-zero if no unit matches
+		return false, nil
+	}
+	return strings.Contains(output, service+".service"), nil
+}
+
+func (s *systemd) IsActive(service string) (bool, error) {
+	// is-active exits non-zero for inactive services
+	output, err := runOutput("systemctl", "is-active", service)
+	if err != nil {
+		return false, nil
+	}
+	return strings.TrimSpace(output) == "active", nil
+}
+
+func (s *systemd) IsEnabled(service string) (bool, error) {
+	// is-enabled exits non-zero for disabled services
+	output, err := runOutput("systemctl", "is-enabled", service)
+	if err != nil {
+		return false, nil
+	}
+	status := strings.TrimSpace(output)
+	return status == "enabled" || status == "enabled-runtime", nil
+}
+
+func (s *systemd) Enable(service string) error {
+	return runCommand("systemctl", "enable", service)
+}
+
+func (s *systemd) Start(service string) error {
+	return runCommand("systemctl", "start", service)
+}
+
+func (s *systemd) Stop(service string) error {
+	return runCommand("systemctl", "stop", service)
+}
+
+func (s *systemd) Restart(service string) error {
+	return runCommand("systemctl", "restart", service)
+}
+
+func (s *systemd) ReloadOrRestart(service string) error {
+	return runCommand("systemctl", "reload-or-restart", service)
+}
+
+func (s *systemd) WaitActive(service string, timeout time.Duration) error {
+	return waitActive(s.IsActive, service, timeout)
+}
+
+// QuoteExecArg quotes an argument of a systemd ExecStart= line if it contains
+// whitespace, quotes, backslashes or specifiers.
+func QuoteExecArg(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\"'\\%$;") {
+		return s
+	}
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "%", "%%")
+	s = strings.ReplaceAll(s, "$", "$$")
+	return `"` + s + `"`
+}
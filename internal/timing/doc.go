@@ -0,0 +1,24 @@
+// Package timing records how long significant steps inside a module take.
+// The runner starts a recording before each module and collects the steps
+// afterwards, so modules only need to wrap their slow operations.
+//
+// Features:
+//   - Named steps with durations (apt update, package installs, downloads)
+//   - Recording is scoped to the module currently being executed
+//   - Steps tracked outside a recording are ignored
+//   - Thread-safe recording operations
+//
+// Usage:
+//
+//	// In a module's Install method
+//	if err := timing.Track("apt-get update", func() error {
+//	    return exec.Run("apt-get", "update")
+//	}); err != nil {
+//	    return fmt.Errorf("failed to update package lists: %w", err)
+//	}
+//
+//	// In the runner
+//	timing.Start()
+//	err := mod.Install(cfg)
+//	steps := timing.Stop()
+package timing
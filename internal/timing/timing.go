@@ -0,0 +1,61 @@
+package timing
+
+import (
+	"sync"
+	"time"
+)
+
+// Step is the recorded duration of a named step within a module.
+type Step struct {
+	// Name describes the step.
+	// Example: "apt-get install nginx"
+	Name string
+
+	// Duration is how long the step took, including failed attempts.
+	Duration time.Duration
+}
+
+var (
+	mu        sync.Mutex
+	recording bool
+	steps     []Step
+)
+
+// Start begins a new recording, discarding any steps that were not collected.
+func Start() {
+	mu.Lock()
+	defer mu.Unlock()
+	recording = true
+	steps = nil
+}
+
+// Stop ends the current recording and returns the steps recorded since Start,
+// in the order they finished.
+func Stop() []Step {
+	mu.Lock()
+	defer mu.Unlock()
+	recorded := steps
+	recording = false
+	steps = nil
+	return recorded
+}
+
+// Record adds a step with the given duration to the current recording.
+// It does nothing if no recording is active.
+func Record(name string, d time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+	if !recording {
+		return
+	}
+	steps = append(steps, Step{Name: name, Duration: d})
+}
+
+// Track runs fn and records how long it took as a step named name.
+// The error returned by fn is passed through unchanged.
+func Track(name string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	Record(name, time.Since(start))
+	return err
+}
@@ -0,0 +1,63 @@
+package timing
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTrack_RecordsSteps(t *testing.T) {
+	Start()
+	if err := Track("first", func() error { return nil }); err != nil {
+		t.Fatalf("Track() error = %v", err)
+	}
+	Record("second", 2*time.Second)
+	steps := Stop()
+
+	if len(steps) != 2 {
+		t.Fatalf("Stop() returned %d steps, want 2", len(steps))
+	}
+	if steps[0].Name != "first" || steps[1].Name != "second" {
+		t.Errorf("Stop() steps = %+v, want first then second", steps)
+	}
+	if steps[1].Duration != 2*time.Second {
+		t.Errorf("steps[1].Duration = %v, want 2s", steps[1].Duration)
+	}
+}
+
+func TestTrack_ReturnsError(t *testing.T) {
+	wantErr := errors.New("boom")
+
+	Start()
+	err := Track("failing", func() error { return wantErr })
+	steps := Stop()
+
+	if !errors.Is(err, wantErr) {
+		t.Errorf("Track() error = %v, want %v", err, wantErr)
+	}
+	if len(steps) != 1 {
+		t.Errorf("failed steps should still be recorded, got %d steps", len(steps))
+	}
+}
+
+func TestRecord_WithoutRecording(t *testing.T) {
+	Stop()
+	Record("ignored", time.Second)
+
+	Start()
+	steps := Stop()
+	if len(steps) != 0 {
+		t.Errorf("steps recorded outside a recording should be ignored, got %+v", steps)
+	}
+}
+
+func TestStart_DiscardsUncollectedSteps(t *testing.T) {
+	Start()
+	Record("stale", time.Second)
+	Start()
+	steps := Stop()
+
+	if len(steps) != 0 {
+		t.Errorf("Start() should discard uncollected steps, got %+v", steps)
+	}
+}
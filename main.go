@@ -4,13 +4,18 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/stwalsh4118/phanes/internal/config"
+	"github.com/stwalsh4118/phanes/internal/download"
 	"github.com/stwalsh4118/phanes/internal/exec"
+	"github.com/stwalsh4118/phanes/internal/lock"
 	"github.com/stwalsh4118/phanes/internal/log"
+	"github.com/stwalsh4118/phanes/internal/module"
 	"github.com/stwalsh4118/phanes/internal/modules/baseline"
 	"github.com/stwalsh4118/phanes/internal/modules/caddy"
 	"github.com/stwalsh4118/phanes/internal/modules/coolify"
@@ -25,7 +30,13 @@ import (
 	"github.com/stwalsh4118/phanes/internal/modules/tailscale"
 	"github.com/stwalsh4118/phanes/internal/modules/updates"
 	"github.com/stwalsh4118/phanes/internal/modules/user"
+	"github.com/stwalsh4118/phanes/internal/notify"
+	"github.com/stwalsh4118/phanes/internal/pkgmgr"
 	"github.com/stwalsh4118/phanes/internal/profile"
+	"github.com/stwalsh4118/phanes/internal/proxy"
+	"github.com/stwalsh4118/phanes/internal/reboot"
+	"github.com/stwalsh4118/phanes/internal/report"
+	"github.com/stwalsh4118/phanes/internal/resume"
 	"github.com/stwalsh4118/phanes/internal/runner"
 )
 
@@ -49,6 +60,20 @@ var (
 	configFlag  string
 	dryRunFlag  bool
 	listFlag    bool
+	yesFlag     bool
+	safeFlag    bool
+	reportFlag  string
+	timingsFlag bool
+	rootFlag    string
+	bundleFlag  string
+
+	remoteFlag       string
+	remoteBinaryFlag string
+	resultsJSONFlag  string
+
+	rebootIfNeededFlag bool
+	onErrorFlag        string
+	resumeFlag         bool
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -69,6 +94,18 @@ func init() {
 	rootCmd.Flags().StringVar(&configFlag, "config", "config.yaml", "Path to configuration file")
 	rootCmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "Enable dry-run mode (preview changes without executing)")
 	rootCmd.Flags().BoolVar(&listFlag, "list", false, "List available modules and profiles")
+	rootCmd.Flags().BoolVarP(&yesFlag, "yes", "y", false, "Approve risky changes (SSH port, password auth, firewall) without prompting")
+	rootCmd.Flags().BoolVar(&safeFlag, "safe", false, "Refuse risky changes on non-interactive runs, even with --yes")
+	rootCmd.Flags().StringVar(&reportFlag, "report", "", "Write a self-contained HTML report of the run to this path")
+	rootCmd.Flags().BoolVar(&timingsFlag, "timings", false, "Show a per-step timing breakdown and the slowest steps after the run")
+	rootCmd.Flags().StringVar(&rootFlag, "root", "", "Apply modules to this directory tree instead of / (commands run with chroot or are skipped)")
+	rootCmd.Flags().StringVar(&bundleFlag, "bundle", "", "Install from an offline bundle created with 'phanes bundle' instead of the network")
+	rootCmd.Flags().StringVar(&remoteFlag, "remote", "", "Provision [user@]host[:port] over SSH instead of this machine")
+	rootCmd.Flags().StringVar(&remoteBinaryFlag, "remote-binary", "", "phanes binary to upload with --remote (default: this binary or phanes-linux-<arch> next to it)")
+	rootCmd.Flags().StringVar(&resultsJSONFlag, "results-json", "", "Write the results of the run as JSON to this path")
+	rootCmd.Flags().StringVar(&onErrorFlag, "on-error", string(runner.OnErrorSkipDependents), "What to do after a module fails: stop, continue, or skip-dependents (skip modules requiring it)")
+	rootCmd.Flags().BoolVar(&resumeFlag, "resume", false, "Rerun only the modules of the previous run that failed or did not run")
+	rootCmd.Flags().BoolVar(&rebootIfNeededFlag, "reboot-if-needed", false, "Reboot when updates or modules require it and resume the remaining modules after boot")
 
 	// Add example usage
 	rootCmd.Example = `  # Run a profile
@@ -80,6 +117,31 @@ func init() {
   # Preview changes without executing
   phanes --profile dev --config config.yaml --dry-run
 
+  # Approve risky changes (e.g., SSH port change) on an unattended run
+  phanes --profile minimal --config config.yaml --yes
+
+  # Show where the time went
+  phanes --profile dev --config config.yaml --timings
+
+  # Write an HTML report for handover
+  phanes --profile web --config config.yaml --report report.html
+
+  # Provision an image tree instead of the host
+  phanes --modules baseline,user,security --config config.yaml --root /tmp/sysroot
+
+  # Provision a server without internet access from an offline bundle
+  phanes --profile dev --config config.yaml --bundle bundle.tar
+
+  # Stop at the first failure, then rerun what is left once it is fixed
+  phanes --profile web --config config.yaml --on-error stop
+  phanes --resume --config config.yaml
+
+  # Reboot after kernel updates and finish the run after boot
+  phanes --profile dev --config config.yaml --yes --reboot-if-needed
+
+  # Provision a server from your laptop over SSH
+  phanes --remote root@203.0.113.10 --profile web --config config.yaml
+
   # List available modules and profiles
   phanes --list`
 }
@@ -100,14 +162,31 @@ func runCommand(cmd *cobra.Command, args []string) (err error) {
 		log.Info("Dry-run mode enabled. No changes will be made.")
 	}
 
+	// Resolve all file paths under the alternate root before any module runs
+	if rootFlag != "" {
+		if err := exec.SetRoot(rootFlag); err != nil {
+			return &usageError{message: fmt.Sprintf("invalid --root: %v", err)}
+		}
+	}
+	if exec.Root() != "" {
+		log.Info("Using alternate root %s. Files are written under it and commands run with chroot.", exec.Root())
+	}
+
 	// Handle --list flag (show available modules and profiles, then exit)
 	if listFlag {
 		listProfilesAndModules()
 		return nil
 	}
 
+	if _, err := runner.ParseErrorPolicy(onErrorFlag); err != nil {
+		return &usageError{message: fmt.Sprintf("invalid --on-error: %v", err)}
+	}
+	if resumeFlag && (profileFlag != "" || modulesFlag != "") {
+		return &usageError{message: "--resume cannot be combined with --profile or --modules"}
+	}
+
 	// Validate that either profile or modules is specified
-	if profileFlag == "" && modulesFlag == "" {
+	if profileFlag == "" && modulesFlag == "" && !resumeFlag {
 		log.Error("Error: Either --profile or --modules must be specified")
 		fmt.Fprintf(os.Stderr, "\n")
 		// Return usage error - Cobra will show help automatically
@@ -124,13 +203,102 @@ func runCommand(cmd *cobra.Command, args []string) (err error) {
 	if err != nil {
 		return fmt.Errorf("config loading failed: %w", err)
 	}
+	download.Configure(cfg.Downloads)
+	pkgmgr.SetLockTimeout(cfg.Packages.LockTimeout)
+	proxy.Configure(cfg.Network.Proxy)
+
+	log.Info("Config file: %s", configFlag)
+
+	var modulesToExecute []string
+	if resumeFlag {
+		if remoteFlag != "" {
+			return &usageError{message: "--resume cannot be combined with --remote"}
+		}
+		modulesToExecute, err = selectUnfinishedModules()
+	} else {
+		modulesToExecute, err = selectModules()
+	}
+	if err != nil {
+		return err
+	}
+	if len(modulesToExecute) == 0 {
+		log.Success("Nothing to resume: all modules of the previous run succeeded")
+		return nil
+	}
+
+	log.Info("Modules to execute: %s", strings.Join(modulesToExecute, ", "))
+
+	// Run on the remote server instead of this machine
+	if remoteFlag != "" {
+		if rebootIfNeededFlag {
+			return &usageError{message: "--reboot-if-needed cannot be combined with --remote"}
+		}
+		return runRemote()
+	}
+	if rebootIfNeededFlag && exec.Root() != "" {
+		return &usageError{message: "--reboot-if-needed cannot be combined with --root"}
+	}
+
+	// Only one run may change the system at a time
+	if !dryRunFlag && exec.Root() == "" {
+		release, err := acquireRunLock()
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+
+	// Serve downloads and packages from an offline bundle
+	if bundleFlag != "" {
+		if err := useBundle(bundleFlag, modulesToExecute, dryRunFlag); err != nil {
+			return fmt.Errorf("offline bundle failed: %w", err)
+		}
+	}
+
+	// Route apt through the proxy before any module installs packages
+	if err := proxy.ConfigureApt(); err != nil {
+		return fmt.Errorf("proxy configuration failed: %w", err)
+	}
 
+	// Execute modules using runner
+	if err := executeModules(modulesToExecute, cfg, dryRunFlag); err != nil {
+		if errors.Is(err, errRebooting) {
+			return nil
+		}
+		return fmt.Errorf("module execution failed: %w", err)
+	}
+
+	log.Success("All modules executed successfully")
+	return nil
+}
+
+// runLockPath is the lock held by runs that change the system.
+const runLockPath = "/var/lib/phanes/run.lock"
+
+// acquireRunLock takes the run lock, failing if another run holds it. The
+// returned function releases the lock.
+func acquireRunLock() (func(), error) {
+	release, err := lock.Acquire(runLockPath)
+	if errors.Is(err, lock.ErrLocked) {
+		if pid := lock.Holder(runLockPath); pid > 0 {
+			return nil, fmt.Errorf("another phanes run is in progress (pid %d)", pid)
+		}
+		return nil, fmt.Errorf("another phanes run is in progress")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to take the run lock: %w", err)
+	}
+	return release, nil
+}
+
+// selectModules returns the modules selected with --profile and --modules.
+func selectModules() ([]string, error) {
 	// Handle profile selection if --profile flag is set
 	var profileModules []string
 	if profileFlag != "" {
 		modules, err := getProfileModules(profileFlag)
 		if err != nil {
-			return fmt.Errorf("profile selection failed: %w", err)
+			return nil, fmt.Errorf("profile selection failed: %w", err)
 		}
 		profileModules = modules
 	}
@@ -140,27 +308,51 @@ func runCommand(cmd *cobra.Command, args []string) (err error) {
 	if modulesFlag != "" {
 		modules, err := parseModuleList(modulesFlag)
 		if err != nil {
-			return fmt.Errorf("module parsing failed: %w", err)
+			return nil, fmt.Errorf("module parsing failed: %w", err)
 		}
 		selectedModules = modules
 	}
 
-	log.Info("Config file: %s", configFlag)
-
 	// Combine profile modules and selected modules
-	modulesToExecute := combineModules(profileModules, selectedModules)
-	if len(modulesToExecute) == 0 {
-		return &usageError{message: "no modules to execute"}
+	modules := combineModules(profileModules, selectedModules)
+	if len(modules) == 0 {
+		return nil, &usageError{message: "no modules to execute"}
 	}
+	return modules, nil
+}
 
-	log.Info("Modules to execute: %s", strings.Join(modulesToExecute, ", "))
-
-	// Execute modules using runner
-	if err := executeModules(modulesToExecute, cfg, dryRunFlag); err != nil {
-		return fmt.Errorf("module execution failed: %w", err)
+// selectUnfinishedModules returns the modules of the previous run that failed
+// or did not run, for --resume. The profile of the previous run is reported
+// as the profile of this one.
+func selectUnfinishedModules() ([]string, error) {
+	last, err := resume.LoadLastRun()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the previous run: %w", err)
+	}
+	if last == nil {
+		return nil, &usageError{message: fmt.Sprintf("no previous run to resume (runs are recorded in %s)", resume.LastRunPath())}
 	}
+	profileFlag = last.Profile
+	log.Info("Resuming the run started at %s", last.StartedAt.Format("2006-01-02 15:04:05"))
+	return resume.Unfinished(last), nil
+}
 
-	log.Success("All modules executed successfully")
+// validateSelection checks that a profile (if set) and module names exist,
+// without logging. It is used for selections that run elsewhere, such as
+// fleet hosts and API requests.
+func validateSelection(profileName string, modules []string) error {
+	if profileName != "" && !profile.ProfileExists(profileName) {
+		return fmt.Errorf("profile '%s' not found. Available profiles: %s", profileName, strings.Join(profile.ListProfiles(), ", "))
+	}
+	known := make(map[string]bool)
+	for _, mod := range allModules() {
+		known[mod.Name()] = true
+	}
+	for _, name := range modules {
+		if !known[name] {
+			return fmt.Errorf("unknown module: %s", name)
+		}
+	}
 	return nil
 }
 
@@ -168,8 +360,8 @@ func runCommand(cmd *cobra.Command, args []string) (err error) {
 // If the file doesn't exist, it returns a default config with a warning.
 // If the file exists but is invalid, it returns an error with a clear, actionable message.
 func loadConfig(path string) (*config.Config, error) {
-	// Check if config file exists
-	if !exec.FileExists(path) {
+	// Check if config file exists (on the host, even under --root)
+	if _, err := os.Stat(path); err != nil {
 		log.Warn("Config file not found at %s, using default configuration", path)
 		log.Info("Note: Default config has empty username and SSH public key. These must be set in config file for module execution.")
 		return config.DefaultConfig(), nil
@@ -290,27 +482,36 @@ func parseModuleList(moduleStr string) ([]string, error) {
 	return modules, nil
 }
 
+// allModules returns a new instance of every available module in registration order.
+// Note: As more modules are implemented, they should be added here
+func allModules() []module.Module {
+	return []module.Module{
+		&baseline.BaselineModule{},
+		&user.UserModule{},
+		&security.SecurityModule{},
+		&swap.SwapModule{},
+		&updates.UpdatesModule{},
+		&docker.DockerModule{},
+		&monitoring.MonitoringModule{},
+		&nginx.NginxModule{},
+		&caddy.CaddyModule{},
+		&coolify.CoolifyModule{},
+		&tailscale.TailscaleModule{},
+		&postgres.PostgresModule{},
+		&redis.RedisModule{},
+		&devtools.DevToolsModule{},
+	}
+}
+
 // registerAllModules creates a runner instance and registers all available modules.
 // This function is used for listing modules and can be reused for module execution.
 func registerAllModules() *runner.Runner {
 	r := runner.NewRunner()
 
 	// Register all available modules
-	// Note: As more modules are implemented, they should be added here
-	r.RegisterModule(&baseline.BaselineModule{})
-	r.RegisterModule(&user.UserModule{})
-	r.RegisterModule(&security.SecurityModule{})
-	r.RegisterModule(&swap.SwapModule{})
-	r.RegisterModule(&updates.UpdatesModule{})
-	r.RegisterModule(&docker.DockerModule{})
-	r.RegisterModule(&monitoring.MonitoringModule{})
-	r.RegisterModule(&nginx.NginxModule{})
-	r.RegisterModule(&caddy.CaddyModule{})
-	r.RegisterModule(&coolify.CoolifyModule{})
-	r.RegisterModule(&tailscale.TailscaleModule{})
-	r.RegisterModule(&postgres.PostgresModule{})
-	r.RegisterModule(&redis.RedisModule{})
-	r.RegisterModule(&devtools.DevToolsModule{})
+	for _, mod := range allModules() {
+		r.RegisterModule(mod)
+	}
 
 	return r
 }
@@ -341,64 +542,300 @@ func combineModules(profileModules []string, selectedModules []string) []string
 	return result
 }
 
+// errRebooting is returned by executeModules when the server reboots to
+// finish the run after boot.
+var errRebooting = errors.New("rebooting to resume the run after boot")
+
 // executeModules creates a runner instance, registers all available modules, and executes
 // the specified modules with the given configuration and dry-run flag.
 // Returns an error if module execution fails, with actionable error messages.
+// Returns errRebooting if the server reboots to resume the run (see --reboot-if-needed).
 func executeModules(moduleNames []string, cfg *config.Config, dryRun bool) error {
-	if len(moduleNames) == 0 {
+	return continueModules(moduleNames, cfg, dryRun, nil)
+}
+
+// continueModules executes moduleNames like executeModules. If resumed is not
+// nil, the run continues the saved run after a reboot, and the results before
+// the reboot are reported together with the new ones.
+func continueModules(moduleNames []string, cfg *config.Config, dryRun bool, resumed *resume.State) error {
+	if len(moduleNames) == 0 && resumed == nil {
 		return fmt.Errorf("no modules specified")
 	}
 
 	// Create runner and register all modules
 	r := registerAllModules()
+	r.SetRiskPolicy(buildRiskPolicy())
+	r.SetErrorPolicy(runner.ErrorPolicy(onErrorFlag))
+	r.SetRebootIfNeeded(rebootIfNeededFlag && !dryRun)
+
+	requested := moduleNames
+	startedAt := time.Now()
+	var previous []runner.ModuleResult
+	boots := 0
+	if resumed != nil {
+		requested = resumed.Results.Modules
+		startedAt = resumed.Results.StartedAt
+		boots = resumed.Boots
+		for _, result := range resumed.Results.ModuleResults() {
+			if result.Status != runner.StatusDeferred {
+				previous = append(previous, result)
+			}
+		}
+	}
 
 	// Execute modules
-	log.Info("Starting module execution...")
-	results, err := r.RunModules(moduleNames, cfg, dryRun)
+	var results []runner.ModuleResult
+	var err error
+	if len(moduleNames) > 0 {
+		log.Info("Starting module execution...")
+		results, err = r.RunModules(moduleNames, cfg, dryRun)
+	}
+	finishedAt := time.Now()
+	results = append(previous, results...)
+
 	if err != nil {
-		errStr := err.Error()
+		logExecutionError(err, r)
+	}
 
-		// Check for unknown module errors
-		if strings.Contains(errStr, "not found in registry") {
-			// Extract module name from error if possible
-			log.Error("One or more modules not found in registry")
-			log.Error("Error details: %v", err)
+	// Print summary (also on error)
+	runner.PrintSummary(results, dryRun)
+	if timingsFlag {
+		runner.PrintTimings(results)
+	}
 
-			// Show available modules
-			availableModules := r.ListModules()
-			sort.Strings(availableModules)
-			log.Error("Available modules: %s", strings.Join(availableModules, ", "))
-			log.Error("Use --list to see all available modules and profiles.")
+	host := report.CollectHostFacts()
+	run := report.Run{
+		Version:    version,
+		Profile:    profileFlag,
+		Modules:    requested,
+		ConfigPath: configFlag,
+		DryRun:     dryRun,
+		StartedAt:  startedAt,
+		FinishedAt: finishedAt,
+		Host:       host,
+		Results:    results,
+		Config:     cfg,
+	}
 
-			// Print summary even on error
-			runner.PrintSummary(results, dryRun)
-			return fmt.Errorf("module execution failed: %w", err)
+	// Record the run for --resume
+	if !dryRun && exec.Root() == "" {
+		if err := resume.SaveLastRun(run); err != nil {
+			log.Warn("Failed to record the run for --resume: %v", err)
 		}
+	}
 
-		// Check for module execution failures
-		if strings.Contains(errStr, "failed to execute") || strings.Contains(errStr, "module") {
-			log.Error("Module execution failed")
-			log.Error("Error details: %v", err)
-			log.Error("Check the error messages above for details about which module failed.")
+	// Reboot if needed; the resumed run writes the reports and notifies
+	if rebootAndResume(run, boots, err != nil) {
+		return errRebooting
+	}
+	if deferred := deferredModules(results); len(deferred) > 0 && err == nil {
+		err = fmt.Errorf("modules not installed because the server needs a reboot: %s", strings.Join(deferred, ", "))
+		log.Error("Reboot the server and run phanes again to install %s", strings.Join(deferred, ", "))
+	}
 
-			// Print summary even on error
-			runner.PrintSummary(results, dryRun)
-			return fmt.Errorf("module execution failed: %w", err)
-		}
+	// Write HTML report if requested (also on error)
+	if reportFlag != "" {
+		writeReport(reportFlag, run)
+	}
 
-		// Generic error fallback
-		log.Error("Module execution failed: %v", err)
-		// Print summary even on error
-		runner.PrintSummary(results, dryRun)
-		return fmt.Errorf("module execution failed: %w", err)
+	// Write machine-readable results if requested (also on error)
+	if resultsJSONFlag != "" {
+		writeResults(resultsJSONFlag, run)
 	}
 
-	// Print summary after successful execution
-	runner.PrintSummary(results, dryRun)
+	// Send webhook notifications (also on error)
+	sendNotifications(cfg.Notifications.Webhooks, dryRun, notify.Run{
+		Version:    version,
+		Profile:    profileFlag,
+		Modules:    requested,
+		Host:       host,
+		StartedAt:  startedAt,
+		FinishedAt: finishedAt,
+		Results:    results,
+	})
+
+	if err != nil {
+		return fmt.Errorf("module execution failed: %w", err)
+	}
 
 	return nil
 }
 
+// rebootAndResume reboots the server if the run needs it and --reboot-if-needed
+// is set, after scheduling the deferred modules to run after boot. boots is
+// the number of reboots the run needed so far; failed runs do not reboot.
+// Without --reboot-if-needed, a required reboot is only reported. Returns
+// whether the server is rebooting.
+func rebootAndResume(run report.Run, boots int, failed bool) bool {
+	deferred := deferredModules(run.Results)
+	reasons := reboot.Reasons()
+	if exec.Root() != "" || (len(reasons) == 0 && len(deferred) == 0) {
+		return false
+	}
+	if len(reasons) > 0 {
+		log.Warn("A reboot is required: %s", strings.Join(reasons, "; "))
+	}
+
+	switch {
+	case run.DryRun:
+		if rebootIfNeededFlag {
+			log.Info("Would reboot and resume the run after boot (dry-run)")
+		}
+		return false
+	case !rebootIfNeededFlag:
+		log.Warn("Reboot the server to apply the changes, or run with --reboot-if-needed to reboot automatically")
+		return false
+	case failed:
+		log.Warn("Not rebooting because the run failed")
+		return false
+	case boots >= resume.MaxBoots:
+		log.Warn("The run already rebooted %d times, not rebooting again", boots)
+		return false
+	}
+
+	binary, err := os.Executable()
+	if err != nil {
+		log.Error("Failed to find the phanes binary, not rebooting: %v", err)
+		return false
+	}
+	state := resume.State{
+		Config:      absPath(configFlag),
+		Profile:     profileFlag,
+		Remaining:   deferred,
+		Bundle:      absPath(bundleFlag),
+		Yes:         yesFlag,
+		Safe:        safeFlag,
+		OnError:     onErrorFlag,
+		Report:      absPath(reportFlag),
+		ResultsJSON: absPath(resultsJSONFlag),
+		Timings:     timingsFlag,
+		Boots:       boots + 1,
+		Results:     report.NewResults(run),
+	}
+	if err := resume.Schedule(state, binary); err != nil {
+		log.Error("Failed to schedule the run to resume after reboot, not rebooting: %v", err)
+		return false
+	}
+
+	log.Info("Rebooting. The run resumes after boot (see journalctl -u %s).", resume.ServiceName)
+	if err := reboot.Now(); err != nil {
+		log.Error("Failed to reboot: %v", err)
+		if err := resume.Cancel(); err != nil {
+			log.Warn("Failed to cancel the resumed run: %v", err)
+		}
+		return false
+	}
+	return true
+}
+
+// deferredModules returns the modules deferred until after a reboot.
+func deferredModules(results []runner.ModuleResult) []string {
+	var deferred []string
+	for _, result := range results {
+		if result.Status == runner.StatusDeferred {
+			deferred = append(deferred, result.Name)
+		}
+	}
+	return deferred
+}
+
+// absPath returns path as an absolute path, or an empty string if path is
+// empty.
+func absPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// logExecutionError logs actionable error messages for a failed module execution.
+func logExecutionError(err error, r *runner.Runner) {
+	errStr := err.Error()
+
+	// Check for unknown module errors
+	if strings.Contains(errStr, "not found in registry") {
+		// Extract module name from error if possible
+		log.Error("One or more modules not found in registry")
+		log.Error("Error details: %v", err)
+
+		// Show available modules
+		availableModules := r.ListModules()
+		sort.Strings(availableModules)
+		log.Error("Available modules: %s", strings.Join(availableModules, ", "))
+		log.Error("Use --list to see all available modules and profiles.")
+		return
+	}
+
+	// Check for module execution failures
+	if strings.Contains(errStr, "failed to execute") || strings.Contains(errStr, "module") {
+		log.Error("Module execution failed")
+		log.Error("Error details: %v", err)
+		log.Error("Check the error messages above for details about which module failed.")
+		return
+	}
+
+	// Generic error fallback
+	log.Error("Module execution failed: %v", err)
+}
+
+// writeReport writes the HTML run report to path.
+// Failures are logged but do not fail the run, since provisioning already happened.
+func writeReport(path string, run report.Run) {
+	if err := report.WriteFile(path, run); err != nil {
+		log.Error("Failed to write report: %v", err)
+		return
+	}
+	log.Success("Report written to %s", path)
+}
+
+// writeResults writes the JSON results of the run to path.
+// Failures are logged but do not fail the run, since provisioning already happened.
+func writeResults(path string, run report.Run) {
+	if err := report.WriteResults(path, run); err != nil {
+		log.Error("Failed to write results: %v", err)
+		return
+	}
+	log.Success("Results written to %s", path)
+}
+
+// sendNotifications delivers the run summary to the configured webhooks.
+// Delivery failures are logged but do not fail the run.
+func sendNotifications(webhooks []config.Webhook, dryRun bool, run notify.Run) {
+	if len(webhooks) == 0 {
+		return
+	}
+	if dryRun {
+		log.Info("Would notify %d webhook(s) (dry-run)", len(webhooks))
+		return
+	}
+	if err := notify.Send(webhooks, run); err != nil {
+		log.Warn("Some notifications could not be delivered")
+	}
+}
+
+// buildRiskPolicy creates the runner risk policy from the --yes and --safe flags.
+// The run is considered interactive when stdin is a terminal.
+func buildRiskPolicy() runner.RiskPolicy {
+	return runner.RiskPolicy{
+		AssumeYes:   yesFlag,
+		Safe:        safeFlag,
+		Interactive: isTerminal(os.Stdin),
+		Prompt:      runner.NewPrompt(os.Stdin, os.Stdout),
+	}
+}
+
+// isTerminal reports whether the given file is connected to a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
 // listProfilesAndModules displays all available profiles and modules in a user-friendly format.
 // It lists profiles with their module lists, and all registered modules with their descriptions.
 func listProfilesAndModules() {
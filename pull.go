@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/stwalsh4118/phanes/internal/gitops"
+	"github.com/stwalsh4118/phanes/internal/log"
+)
+
+var (
+	pullRepoFlag        string
+	pullBranchFlag      string
+	pullPathFlag        string
+	verifySignatureFlag bool
+	allowedSignersFlag  string
+	forceFlag           bool
+)
+
+// pullCmd applies configuration from a git repository.
+var pullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Apply configuration from a git repository when it changed",
+	Long: `Fetch a branch of a git repository and apply the configuration file at
+--path from it, but only if the file (or the selected profile and modules)
+changed since the last applied commit. The applied commit SHA is recorded in
+/var/lib/phanes/pull/state.json.
+
+Any git remote works, including local paths and bare repositories; git uses
+its usual credentials (SSH keys, credential helpers) without prompting. With
+--verify-signature, the head commit must carry a valid GPG or SSH signature
+(see 'git verify-commit'); --allowed-signers names the allowed signers file for
+SSH signatures.
+
+Run it from cron or a systemd timer to keep servers converged to the
+repository.`,
+	Example: `  # Apply hosts/web1.yaml from the main branch
+  phanes pull --repo git@github.com:acme/infra.git --branch main --path hosts/web1.yaml --profile web
+
+  # Only apply signed commits
+  phanes pull --repo /srv/git/infra.git --path hosts/web1.yaml --profile web \
+    --verify-signature --allowed-signers /etc/phanes/allowed_signers`,
+	Args: cobra.NoArgs,
+	RunE: runPull,
+}
+
+func init() {
+	flags := pullCmd.Flags()
+	flags.StringVar(&pullRepoFlag, "repo", "", "Git repository URL or path")
+	flags.StringVar(&pullBranchFlag, "branch", "main", "Branch to follow")
+	flags.StringVar(&pullPathFlag, "path", "config.yaml", "Path of the configuration file in the repository")
+	flags.StringVar(&profileFlag, "profile", "", "Profile name to execute (e.g., 'dev', 'web', 'database')")
+	flags.StringVar(&modulesFlag, "modules", "", "Comma-separated list of module names to execute")
+	flags.BoolVar(&verifySignatureFlag, "verify-signature", false, "Require a valid GPG or SSH signature on the commit")
+	flags.StringVar(&allowedSignersFlag, "allowed-signers", "", "Allowed signers file for SSH-signed commits (implies --verify-signature)")
+	flags.BoolVar(&forceFlag, "force", false, "Apply even if the configuration did not change")
+	flags.BoolVar(&dryRunFlag, "dry-run", false, "Preview changes without applying them or recording the commit")
+	flags.BoolVarP(&yesFlag, "yes", "y", false, "Approve risky changes without prompting")
+	flags.BoolVar(&safeFlag, "safe", false, "Refuse risky changes on non-interactive runs, even with --yes")
+	rootCmd.AddCommand(pullCmd)
+}
+
+// runPull syncs the repository and applies the configuration if it changed.
+func runPull(cmd *cobra.Command, args []string) error {
+	if pullRepoFlag == "" {
+		return &usageError{message: "invalid usage: --repo must be specified"}
+	}
+	if profileFlag == "" && modulesFlag == "" {
+		return &usageError{message: "invalid usage: either --profile or --modules must be specified"}
+	}
+
+	repo := gitops.NewRepo(pullRepoFlag, pullBranchFlag)
+	log.Info("Fetching %s (%s)", pullRepoFlag, pullBranchFlag)
+	commit, err := repo.Sync()
+	if err != nil {
+		return err
+	}
+	subject, err := repo.Subject(commit)
+	if err != nil {
+		return fmt.Errorf("failed to read commit %s: %w", gitops.Short(commit), err)
+	}
+	log.Info("Branch %s is at %s (%s)", pullBranchFlag, gitops.Short(commit), subject)
+
+	if verifySignatureFlag || allowedSignersFlag != "" {
+		if err := repo.Verify(commit, allowedSignersFlag); err != nil {
+			return err
+		}
+		log.Success("Verified signature of commit %s", gitops.Short(commit))
+	}
+
+	path, err := repo.File(pullPathFlag)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", pullPathFlag, err)
+	}
+
+	key := gitops.Key(pullRepoFlag, pullBranchFlag, pullPathFlag)
+	fingerprint := gitops.Fingerprint(content, profileFlag, modulesFlag)
+	last, applied, err := gitops.Last(key)
+	if err != nil {
+		return err
+	}
+	if applied && last.Fingerprint == fingerprint && !forceFlag {
+		log.Skip("%s is unchanged since commit %s, nothing to apply", pullPathFlag, gitops.Short(last.Commit))
+		return nil
+	}
+	if applied {
+		log.Info("%s changed since commit %s", pullPathFlag, gitops.Short(last.Commit))
+	}
+
+	configFlag = path
+	if err := runCommand(cmd, args); err != nil {
+		return err
+	}
+
+	if dryRunFlag {
+		log.Info("Dry-run: commit %s was not recorded as applied", gitops.Short(commit))
+		return nil
+	}
+	if err := gitops.Record(key, gitops.Applied{Commit: commit, Fingerprint: fingerprint}); err != nil {
+		return fmt.Errorf("failed to record applied commit: %w", err)
+	}
+	log.Success("Applied %s at commit %s", pullPathFlag, gitops.Short(commit))
+	return nil
+}
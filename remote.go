@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/stwalsh4118/phanes/internal/log"
+	"github.com/stwalsh4118/phanes/internal/remote"
+	"github.com/stwalsh4118/phanes/internal/runner"
+)
+
+// runRemote provisions the server given with --remote over SSH with the
+// selected profile, modules and flags.
+func runRemote() error {
+	if rootFlag != "" {
+		return &usageError{message: "--root cannot be combined with --remote"}
+	}
+	target, err := remote.ParseTarget(remoteFlag)
+	if err != nil {
+		return &usageError{message: err.Error()}
+	}
+
+	// Prompts on the server (sudo, risky changes) need a terminal
+	var stdin io.Reader
+	interactive := isTerminal(os.Stdin)
+	if interactive {
+		stdin = os.Stdin
+	}
+
+	log.Info("Provisioning %s over SSH", target)
+	results, err := remote.Provision(&remote.SSH{Target: target, TTY: interactive}, remote.Options{
+		Binary:      remoteBinaryFlag,
+		Config:      configFlag,
+		Bundle:      bundleFlag,
+		Args:        remoteArgs(profileFlag, modulesFlag),
+		Report:      reportFlag,
+		ResultsJSON: resultsJSONFlag,
+		Stdin:       stdin,
+		Stdout:      os.Stdout,
+		Stderr:      os.Stderr,
+	})
+
+	if results != nil {
+		duration := time.Duration(results.DurationSeconds * float64(time.Second)).Round(time.Second)
+		if results.Success {
+			log.Success("Provisioned %s (%s) in %s", target, results.Host.Hostname, duration)
+		} else {
+			log.Error("Provisioning %s (%s) failed after %s", target, results.Host.Hostname, duration)
+		}
+		if reportFlag != "" {
+			log.Success("Report written to %s", reportFlag)
+		}
+		if resultsJSONFlag != "" {
+			log.Success("Results written to %s", resultsJSONFlag)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("remote provisioning failed: %w", err)
+	}
+	return nil
+}
+
+// remoteArgs returns the phanes arguments for a remote run of the profile and
+// comma-separated modules. --config, --bundle, --report and --results-json are
+// added by remote.Provision.
+func remoteArgs(profileName, modules string) []string {
+	var args []string
+	if profileName != "" {
+		args = append(args, "--profile", profileName)
+	}
+	if modules != "" {
+		args = append(args, "--modules", modules)
+	}
+	for _, flag := range []struct {
+		set  bool
+		name string
+	}{
+		{dryRunFlag, "--dry-run"},
+		{yesFlag, "--yes"},
+		{safeFlag, "--safe"},
+		{timingsFlag, "--timings"},
+	} {
+		if flag.set {
+			args = append(args, flag.name)
+		}
+	}
+	if onErrorFlag != "" && onErrorFlag != string(runner.OnErrorSkipDependents) {
+		args = append(args, "--on-error", onErrorFlag)
+	}
+	return args
+}
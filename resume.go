@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/stwalsh4118/phanes/internal/download"
+	"github.com/stwalsh4118/phanes/internal/log"
+	"github.com/stwalsh4118/phanes/internal/pkgmgr"
+	"github.com/stwalsh4118/phanes/internal/proxy"
+	"github.com/stwalsh4118/phanes/internal/resume"
+)
+
+// resumeCmd continues a run after the reboot requested with --reboot-if-needed.
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume a run after reboot (started by phanes-resume.service)",
+	Long: `Continue the run that rebooted the server with --reboot-if-needed: install the
+modules that were deferred until after boot with the flags of the original run,
+and report the combined result of both parts (summary, --report,
+--results-json and notifications).
+
+The command is started once by phanes-resume.service after boot, which is
+removed before the modules run. Without a saved run, it does nothing.`,
+	Args: cobra.NoArgs,
+	RunE: runResume,
+}
+
+func init() {
+	rootCmd.AddCommand(resumeCmd)
+}
+
+// runResume restores the flags of the saved run and executes its remaining
+// modules.
+func runResume(cmd *cobra.Command, args []string) error {
+	release, err := acquireRunLock()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	state, err := resume.Load()
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		log.Skip("No run is waiting to resume")
+		return nil
+	}
+
+	// Never resume the same run twice, even if it fails
+	if err := resume.Cancel(); err != nil {
+		return err
+	}
+
+	configFlag = state.Config
+	profileFlag = state.Profile
+	bundleFlag = state.Bundle
+	yesFlag = state.Yes
+	safeFlag = state.Safe
+	if state.OnError != "" {
+		onErrorFlag = state.OnError
+	}
+	reportFlag = state.Report
+	resultsJSONFlag = state.ResultsJSON
+	timingsFlag = state.Timings
+	rebootIfNeededFlag = true
+
+	log.Info("Resuming the run started at %s after reboot %d", state.Results.StartedAt.Format("2006-01-02 15:04:05"), state.Boots)
+	if len(state.Remaining) > 0 {
+		log.Info("Modules to execute: %s", strings.Join(state.Remaining, ", "))
+	}
+
+	cfg, err := loadConfig(configFlag)
+	if err != nil {
+		return fmt.Errorf("config loading failed: %w", err)
+	}
+	download.Configure(cfg.Downloads)
+	pkgmgr.SetLockTimeout(cfg.Packages.LockTimeout)
+	proxy.Configure(cfg.Network.Proxy)
+
+	if bundleFlag != "" && len(state.Remaining) > 0 {
+		if err := useBundle(bundleFlag, state.Remaining, false); err != nil {
+			return fmt.Errorf("offline bundle failed: %w", err)
+		}
+	}
+	if err := proxy.ConfigureApt(); err != nil {
+		return fmt.Errorf("proxy configuration failed: %w", err)
+	}
+
+	if err := continueModules(state.Remaining, cfg, false, state); err != nil {
+		if errors.Is(err, errRebooting) {
+			return nil
+		}
+		return fmt.Errorf("module execution failed: %w", err)
+	}
+	log.Success("All modules executed successfully")
+	return nil
+}
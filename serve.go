@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stwalsh4118/phanes/internal/log"
+	"github.com/stwalsh4118/phanes/internal/profile"
+	"github.com/stwalsh4118/phanes/internal/server"
+)
+
+const (
+	// tokenEnv is the environment variable holding the API token.
+	tokenEnv = "PHANES_API_TOKEN"
+
+	shutdownTimeout = 10 * time.Second
+)
+
+var (
+	listenFlag    string
+	tokenFileFlag string
+	stateDirFlag  string
+)
+
+// serveCmd runs the HTTP API.
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a token-authenticated HTTP API to list modules and trigger runs",
+	Long: `Serve an HTTP API that lets other tooling drive phanes on this machine:
+list modules and profiles, start plan, apply and check runs, stream their
+output, and fetch the results of past runs.
+
+Every request must send the API token as "Authorization: Bearer <token>". The
+token is read from --token-file or the PHANES_API_TOKEN environment variable.
+Only one run is executed at a time; starting another one returns 409 Conflict.
+Runs use --config and are stored in --state-dir.
+
+Endpoints:
+  GET  /v1/modules            modules with their descriptions
+  GET  /v1/profiles           profiles with their modules
+  POST /v1/runs               start a run: {"mode": "plan|apply|check", "profile": "web", "modules": [...], "yes": false, "safe": false}
+  GET  /v1/runs               past runs, newest first
+  GET  /v1/runs/{id}          a run with its results
+  GET  /v1/runs/{id}/events   the output of a run as server-sent events`,
+	Example: `  # Serve on localhost
+  PHANES_API_TOKEN=$(openssl rand -hex 32) phanes serve --config /etc/phanes/config.yaml
+
+  # Start a plan run and follow it
+  curl -H "Authorization: Bearer $TOKEN" -d '{"mode": "plan", "profile": "web"}' http://127.0.0.1:7420/v1/runs
+  curl -N -H "Authorization: Bearer $TOKEN" http://127.0.0.1:7420/v1/runs/<id>/events`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&listenFlag, "listen", "127.0.0.1:7420", "Address to listen on")
+	serveCmd.Flags().StringVar(&configFlag, "config", "config.yaml", "Path to configuration file used by runs")
+	serveCmd.Flags().StringVar(&tokenFileFlag, "token-file", "", "File containing the API token (default: $"+tokenEnv+")")
+	serveCmd.Flags().StringVar(&stateDirFlag, "state-dir", server.DefaultStateDir, "Directory where runs are stored")
+	rootCmd.AddCommand(serveCmd)
+}
+
+// runServe serves the API until interrupted.
+func runServe(cmd *cobra.Command, args []string) error {
+	token, err := readToken()
+	if err != nil {
+		return err
+	}
+
+	configPath, err := filepath.Abs(configFlag)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", configFlag, err)
+	}
+	if _, err := loadConfig(configPath); err != nil {
+		return fmt.Errorf("config loading failed: %w", err)
+	}
+
+	binary, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate phanes binary: %w", err)
+	}
+
+	modules := make([]moduleDescription, 0)
+	for _, mod := range allModules() {
+		desc, err := describeModule(mod)
+		if err != nil {
+			return err
+		}
+		modules = append(modules, desc)
+	}
+	profiles := make(map[string][]string)
+	for _, name := range profile.ListProfiles() {
+		profiles[name], _ = profile.GetProfile(name)
+	}
+
+	srv, err := server.New(server.Options{
+		Token:    token,
+		Binary:   binary,
+		Config:   configPath,
+		StateDir: stateDirFlag,
+		Modules:  modules,
+		Profiles: profiles,
+		Validate: validateSelection,
+	})
+	if err != nil {
+		return err
+	}
+
+	if host, _, err := net.SplitHostPort(listenFlag); err == nil {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			log.Warn("Listening on %s: the API is reachable from other machines. Put it behind TLS.", listenFlag)
+		}
+	}
+
+	httpServer := &http.Server{
+		Addr:              listenFlag,
+		Handler:           srv,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- httpServer.ListenAndServe()
+	}()
+	log.Info("Serving the phanes API on http://%s (runs use %s)", listenFlag, configPath)
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("failed to serve API: %w", err)
+	case <-ctx.Done():
+	}
+
+	log.Info("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("failed to shut down API: %w", err)
+	}
+	return nil
+}
+
+// readToken returns the API token from --token-file or the environment.
+func readToken() (string, error) {
+	token := os.Getenv(tokenEnv)
+	if tokenFileFlag != "" {
+		data, err := os.ReadFile(tokenFileFlag)
+		if err != nil {
+			return "", fmt.Errorf("failed to read token file: %w", err)
+		}
+		token = string(data)
+	}
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", &usageError{message: fmt.Sprintf("an API token is required: set %s or pass --token-file", tokenEnv)}
+	}
+	return token, nil
+}
@@ -0,0 +1,65 @@
+package integration
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stwalsh4118/phanes/internal/remote"
+)
+
+// TestRemoteSSHTransport exercises the SSH transport against a real sshd, such
+// as the sshd service in docker-compose.test.yml:
+//
+//	docker-compose -f docker-compose.test.yml up -d sshd
+//	PHANES_SSH_TARGET=phanes@localhost:2222 PHANES_SSH_KEY=test/ssh/id_ed25519 \
+//	    go test ./test/integration/... -run RemoteSSH
+func TestRemoteSSHTransport(t *testing.T) {
+	targetSpec := os.Getenv("PHANES_SSH_TARGET")
+	if targetSpec == "" {
+		t.Skip("Skipping SSH test - PHANES_SSH_TARGET is not set")
+	}
+	target, err := remote.ParseTarget(targetSpec)
+	if err != nil {
+		t.Fatalf("ParseTarget() error = %v", err)
+	}
+
+	options := []string{"-o", "StrictHostKeyChecking=no", "-o", "UserKnownHostsFile=/dev/null", "-o", "BatchMode=yes"}
+	if key := os.Getenv("PHANES_SSH_KEY"); key != "" {
+		options = append(options, "-i", key)
+	}
+	ssh := &remote.SSH{Target: target, Options: options}
+
+	var stdout, stderr bytes.Buffer
+	if err := ssh.Run("mktemp -d /tmp/phanes-test.XXXXXX", nil, &stdout, &stderr); err != nil {
+		t.Fatalf("Run() error = %v, stderr: %s", err, stderr.String())
+	}
+	dir := strings.TrimSpace(stdout.String())
+	defer ssh.Run("rm -rf "+dir, nil, nil, nil)
+
+	local := filepath.Join(t.TempDir(), "upload.txt")
+	if err := os.WriteFile(local, []byte("hello from phanes\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ssh.Upload(local, dir+"/upload.txt"); err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+
+	stdout.Reset()
+	if err := ssh.Run("cat "+dir+"/upload.txt", nil, &stdout, &stderr); err != nil {
+		t.Fatalf("Run() error = %v, stderr: %s", err, stderr.String())
+	}
+	if stdout.String() != "hello from phanes\n" {
+		t.Errorf("remote content = %q", stdout.String())
+	}
+
+	back := filepath.Join(t.TempDir(), "download.txt")
+	if err := ssh.Download(dir+"/upload.txt", back); err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if content, _ := os.ReadFile(back); string(content) != "hello from phanes\n" {
+		t.Errorf("downloaded content = %q", content)
+	}
+}